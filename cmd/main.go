@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -10,23 +11,33 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
 	authRepos "github.com/hfleury/horsemarketplacebk/internal/auth/repositories"
 	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
 	categoryRepos "github.com/hfleury/horsemarketplacebk/internal/categories/repositories"
 	categoryServices "github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
 	"github.com/hfleury/horsemarketplacebk/internal/db"
 	"github.com/hfleury/horsemarketplacebk/internal/email"
+	"github.com/hfleury/horsemarketplacebk/internal/feeds"
+	"github.com/hfleury/horsemarketplacebk/internal/geo"
+	"github.com/hfleury/horsemarketplacebk/internal/imports"
 	"github.com/hfleury/horsemarketplacebk/internal/media"
 	"github.com/hfleury/horsemarketplacebk/internal/middleware"
 	mockemail "github.com/hfleury/horsemarketplacebk/internal/mocks/email"
 	productHandlers "github.com/hfleury/horsemarketplacebk/internal/products/handlers"
 	productRepos "github.com/hfleury/horsemarketplacebk/internal/products/repositories"
 	productServices "github.com/hfleury/horsemarketplacebk/internal/products/services"
+	"github.com/hfleury/horsemarketplacebk/internal/promotions"
+	"github.com/hfleury/horsemarketplacebk/internal/reports"
 	"github.com/hfleury/horsemarketplacebk/internal/router"
+	savedSearchRepos "github.com/hfleury/horsemarketplacebk/internal/savedsearches/repositories"
+	savedSearchServices "github.com/hfleury/horsemarketplacebk/internal/savedsearches/services"
 	"github.com/hfleury/horsemarketplacebk/internal/system"
 	"github.com/hfleury/horsemarketplacebk/internal/tasks"
 	"github.com/hfleury/horsemarketplacebk/internal/worker"
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
@@ -56,6 +67,13 @@ func initializeApp(ctx context.Context, configService config.Configuration, newD
 
 	logger.Log(ctx, config.InfoLevel, "Application started and logging initialized", nil)
 
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" && os.Getenv("ENVIRONMENT") == "production" {
+		err := errors.New("SITE_URL is required in production")
+		logger.Logger.Error().Err(err).Msg("Missing site URL")
+		return nil, err
+	}
+
 	// Repositories
 	userRepo := authRepos.NewUserRepoPsql(db, logger)
 	sessionRepo := authRepos.NewSessionRepoPsql(db, logger)
@@ -68,6 +86,13 @@ func initializeApp(ctx context.Context, configService config.Configuration, newD
 	userService := services.NewUserService(userRepo, logger, tokenService, sessionRepo)
 	categoryService := categoryServices.NewCategoryService(categoryRepo, logger)
 	productService := productServices.NewProductService(productRepo, systemSettingsRepo, logger)
+	productService.SetAttributeDefinitions(categoryService)
+	gazetteer, err := geo.NewGazetteer()
+	if err != nil {
+		logger.Logger.Error().Err(err).Msg("Failed to load place gazetteer")
+	} else {
+		productService.SetGazetteer(gazetteer)
+	}
 
 	// Handlers
 	productHandler := productHandlers.NewProductHandler(productService, logger)
@@ -84,8 +109,9 @@ func initializeApp(ctx context.Context, configService config.Configuration, newD
 	}
 
 	redisOpt := asynq.RedisClientOpt{Addr: redisAddr}
+	// The client lives as long as the process: services enqueue from request handlers
 	asynqClient := asynq.NewClient(redisOpt)
-	defer asynqClient.Close()
+	productService.SetTaskQueue(asynqClient)
 
 	mediaRepo := media.NewPostgresMediaRepository(db.Conn)
 	mediaService, err := media.NewMediaService(mediaRepo, asynqClient, configService.GetConfig())
@@ -114,12 +140,6 @@ func initializeApp(ctx context.Context, configService config.Configuration, newD
 		mux.HandleFunc(tasks.TypeProcessImage, processor.HandleProcessImageTask)
 	}
 
-	go func() {
-		if err := asynqServer.Run(mux); err != nil {
-			logger.Logger.Fatal().Err(err).Msg("could not run server")
-		}
-	}()
-
 	// Email verification repository
 	emailVerifRepo := authRepos.NewEmailVerificationRepoPsql(db, logger)
 	userService.SetEmailVerificationRepo(emailVerifRepo)
@@ -144,6 +164,85 @@ func initializeApp(ctx context.Context, configService config.Configuration, newD
 		}
 	}
 	userService.SetEmailSender(sender)
+	productService.SetEmailSender(sender)
+	mux.HandleFunc(tasks.TypeNotifyWatchers, productService.HandleNotifyWatchersTask)
+	mux.HandleFunc(tasks.TypeListingExpiry, productService.HandleListingExpiryTask)
+
+	// Saved searches: alerts on publish and the daily digest
+	savedSearchRepo := savedSearchRepos.NewSavedSearchRepoPsql(db, logger)
+	savedSearchService := savedSearchServices.NewSavedSearchService(savedSearchRepo, productRepo, sender, logger)
+	if gazetteer != nil {
+		savedSearchService.SetGazetteer(gazetteer)
+	}
+	mux.HandleFunc(tasks.TypeProductPublished, savedSearchService.HandleProductPublishedTask)
+	mux.HandleFunc(tasks.TypePriceDropped, savedSearchService.HandlePriceDroppedTask)
+	mux.HandleFunc(tasks.TypeSavedSearchDigest, savedSearchService.HandleDailyDigestTask)
+
+	// Listing analytics: views and contact clicks are buffered in Redis
+	redisClient := redis.NewClient(&redis.Options{Addr: redisAddr})
+	statsRepo := analytics.NewStatsRepoPsql(db, logger)
+	statsService := analytics.NewStatsService(analytics.NewRedisEventBuffer(redisClient, analytics.DefaultDedupWindow), statsRepo, productRepo, logger)
+	productHandler.SetViewRecorder(statsService)
+	mux.HandleFunc(tasks.TypeFlushProductStats, statsService.HandleFlushTask)
+	productService.SetBreedingCache(productRepos.NewRedisBreedingCache(redisClient, 24*time.Hour))
+
+	// Dealer feed imports run in the background
+	importService := imports.NewImportService(imports.NewImportRepoPsql(db, logger), productService, mediaService, asynqClient, logger)
+	mux.HandleFunc(tasks.TypeListingImport, importService.HandleImportTask)
+
+	// Exchange rates are kept in the database, EXCHANGE_RATES_FILE replaces them at startup
+	rateService := currency.NewRateService(currency.NewRateRepoPsql(db, logger), logger)
+	if path := os.Getenv("EXCHANGE_RATES_FILE"); path != "" {
+		if err := loadExchangeRates(ctx, rateService, path); err != nil {
+			logger.Log(ctx, config.ErrorLevel, "Failed to load exchange rates", map[string]any{"error": err.Error(), "path": path})
+		}
+	}
+	productService.SetExchangeRates(rateService)
+
+	// Abuse reports of listings, triaged by admins
+	reportService := reports.NewReportService(reports.NewReportRepoPsql(db, logger), systemSettingsRepo, logger)
+	reportService.SetEmailSender(sender)
+
+	// Featured listings, boosted in searches until the expiry job ends them
+	promotionEvents := analytics.NewNamedEventBuffer(redisClient, "promotions", analytics.DefaultDedupWindow)
+	promotionService := promotions.NewPromotionService(promotions.NewPromotionRepoPsql(db, logger), promotionEvents, logger)
+	productHandler.SetImpressionRecorder(promotionService)
+	mux.HandleFunc(tasks.TypeExpirePromotions, promotionService.HandleExpireTask)
+	mux.HandleFunc(tasks.TypeFlushPromotions, promotionService.HandleFlushTask)
+
+	// Links in feeds and sitemaps use SITE_URL, the public site serving the listing,
+	// category and seller pages. Development falls back to the host of the request.
+	feedService := feeds.NewFeedService(productService, productRepo, categoryService, logger)
+	feedService.SetBaseURL(siteURL)
+
+	go func() {
+		if err := asynqServer.Run(mux); err != nil {
+			logger.Logger.Fatal().Err(err).Msg("could not run server")
+		}
+	}()
+
+	// Periodic tasks
+	scheduler := asynq.NewScheduler(redisOpt, nil)
+	if _, err := scheduler.Register("0 7 * * *", tasks.NewSavedSearchDigestTask()); err != nil {
+		logger.Logger.Error().Err(err).Msg("Failed to register saved search digest")
+	}
+	if _, err := scheduler.Register("@every 1m", tasks.NewFlushProductStatsTask()); err != nil {
+		logger.Logger.Error().Err(err).Msg("Failed to register analytics flush")
+	}
+	if _, err := scheduler.Register("@hourly", tasks.NewListingExpiryTask()); err != nil {
+		logger.Logger.Error().Err(err).Msg("Failed to register listing expiry")
+	}
+	if _, err := scheduler.Register("@every 5m", tasks.NewExpirePromotionsTask()); err != nil {
+		logger.Logger.Error().Err(err).Msg("Failed to register promotion expiry")
+	}
+	if _, err := scheduler.Register("@every 1m", tasks.NewFlushPromotionsTask()); err != nil {
+		logger.Logger.Error().Err(err).Msg("Failed to register promotion counters flush")
+	}
+	go func() {
+		if err := scheduler.Run(); err != nil {
+			logger.Logger.Fatal().Err(err).Msg("could not run scheduler")
+		}
+	}()
 
 	// Create the Gin router and add middleware
 	server := gin.New()
@@ -156,7 +255,7 @@ func initializeApp(ctx context.Context, configService config.Configuration, newD
 	server.Use(middleware.LoggerMiddleware(logger))
 
 	// routes
-	server = router.SetupRouter(server, logger, userService, tokenService, categoryService, mediaService, productService, productHandler)
+	server = router.SetupRouter(server, logger, userService, tokenService, categoryService, mediaService, productService, productHandler, savedSearchService, statsService, importService, feedService, rateService, reportService, promotionService)
 
 	return server, nil
 }
@@ -165,6 +264,16 @@ type Launcher struct {
 	AppInitializer func(context.Context, config.Configuration, dbFactory) (Server, error)
 }
 
+func loadExchangeRates(ctx context.Context, rates *currency.RateService, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = rates.Load(ctx, f, "")
+	return err
+}
+
 func (l *Launcher) Run(ctx context.Context, configService config.Configuration, newDB dbFactory) error {
 	server, err := l.AppInitializer(ctx, configService, newDB)
 	if err != nil {
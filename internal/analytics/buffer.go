@@ -0,0 +1,184 @@
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// EventBuffer collects deduplicated listing events until they are flushed
+// to Postgres.
+type EventBuffer interface {
+	// Record counts the event unless the visitor already triggered it for the
+	// listing within the dedup window. It reports whether it was counted.
+	Record(ctx context.Context, productID string, visitor Visitor, event EventType) (bool, error)
+	// Take returns the oldest batch left behind by a flush that failed,
+	// else moves the pending counters aside as a new batch. A batch stays
+	// in Redis until acknowledged with Done once stored.
+	Take(ctx context.Context) (string, []DailyCount, error)
+	Done(ctx context.Context, batch string) error
+}
+
+const fieldDayForm = "2006-01-02"
+
+type RedisEventBuffer struct {
+	client *redis.Client
+	window time.Duration
+	// The keys of the buffer, under its name
+	pendingKey  string
+	flushingKey string
+	seenKey     string
+}
+
+// NewRedisEventBuffer counts each visitor at most once per listing, event
+// and window.
+func NewRedisEventBuffer(client *redis.Client, window time.Duration) *RedisEventBuffer {
+	return NewNamedEventBuffer(client, "analytics", window)
+}
+
+// NewNamedEventBuffer is a buffer of its own for events of other things than
+// listings, e.g. promotions. Buffers with different names are flushed apart.
+func NewNamedEventBuffer(client *redis.Client, name string, window time.Duration) *RedisEventBuffer {
+	return &RedisEventBuffer{
+		client:      client,
+		window:      window,
+		pendingKey:  name + ":pending",
+		flushingKey: name + ":flushing:",
+		seenKey:     name + ":seen:",
+	}
+}
+
+func (b *RedisEventBuffer) Record(ctx context.Context, productID string, visitor Visitor, event EventType) (bool, error) {
+	first, err := b.client.SetNX(ctx, b.seenKey+string(event)+":"+productID+":"+visitor.key(), 1, b.window).Result()
+	if err != nil || !first {
+		return false, err
+	}
+
+	field := strings.Join([]string{productID, string(event), time.Now().UTC().Format(fieldDayForm)}, "|")
+	if err := b.client.HIncrBy(ctx, b.pendingKey, field, 1).Err(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *RedisEventBuffer) Take(ctx context.Context) (string, []DailyCount, error) {
+	batch, err := b.leftoverBatch(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if batch == "" {
+		batch = b.flushingKey + strconv.FormatInt(time.Now().UnixNano(), 10)
+		// RENAME is atomic: events recorded from now on go to a fresh hash
+		if err := b.client.Rename(ctx, b.pendingKey, batch).Err(); err != nil {
+			if strings.Contains(err.Error(), "no such key") {
+				return "", nil, nil
+			}
+			return "", nil, err
+		}
+	}
+
+	fields, err := b.client.HGetAll(ctx, batch).Result()
+	if err != nil {
+		return "", nil, err
+	}
+	counts, err := parseCounts(fields)
+	if err != nil {
+		return "", nil, err
+	}
+	return batch, counts, nil
+}
+
+// leftoverBatch returns the oldest batch taken but never acknowledged, ""
+// when there is none. Batch names end in the time they were taken.
+func (b *RedisEventBuffer) leftoverBatch(ctx context.Context) (string, error) {
+	var batches []string
+	iter := b.client.Scan(ctx, 0, b.flushingKey+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		batches = append(batches, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return "", err
+	}
+	if len(batches) == 0 {
+		return "", nil
+	}
+	slices.Sort(batches)
+	return batches[0], nil
+}
+
+func (b *RedisEventBuffer) Done(ctx context.Context, batch string) error {
+	return b.client.Del(ctx, batch).Err()
+}
+
+// parseCounts folds "id|event|day" hash fields into one row per id and day.
+func parseCounts(fields map[string]string) ([]DailyCount, error) {
+	type rowKey struct {
+		product uuid.UUID
+		day     string
+	}
+	rows := make(map[rowKey]*DailyCount)
+	var order []rowKey
+
+	for field, value := range fields {
+		parts := strings.Split(field, "|")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid analytics field %q", field)
+		}
+		productID, err := uuid.Parse(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid analytics field %q: %w", field, err)
+		}
+		day, err := time.Parse(fieldDayForm, parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid analytics field %q: %w", field, err)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid analytics count %q: %w", value, err)
+		}
+
+		key := rowKey{productID, parts[2]}
+		row, ok := rows[key]
+		if !ok {
+			row = &DailyCount{ProductID: productID, Day: day}
+			rows[key] = row
+			order = append(order, key)
+		}
+		switch EventType(parts[1]) {
+		case EventView:
+			row.Views += n
+		case EventContactClick:
+			row.ContactClicks += n
+		case EventImpression:
+			row.Impressions += n
+		case EventClick:
+			row.Clicks += n
+		default:
+			return nil, errors.New("unknown analytics event " + parts[1])
+		}
+	}
+
+	counts := make([]DailyCount, 0, len(order))
+	for _, key := range order {
+		counts = append(counts, *rows[key])
+	}
+	return counts, nil
+}
+
+// key never stores the raw IP address in Redis.
+func (v Visitor) key() string {
+	if v.UserID != "" {
+		return "u:" + v.UserID
+	}
+	sum := sha256.Sum256([]byte(v.IP + "|" + v.UserAgent))
+	return "a:" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+)
+
+type StatsHandler struct {
+	logger  config.Logging
+	service *StatsService
+}
+
+func NewStatsHandler(logger config.Logging, service *StatsService) *StatsHandler {
+	return &StatsHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// VisitorFromContext identifies the caller of a request for deduplication.
+func VisitorFromContext(c *gin.Context) Visitor {
+	return Visitor{
+		UserID:    c.GetString("user_id"),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+func (h *StatsHandler) ContactClick(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.service.RecordContactClick(c.Request.Context(), id, VisitorFromContext(c)); err != nil {
+		if err == ErrProductNotFound {
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to record contact click", map[string]any{"error": err.Error(), "id": id})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to record contact click"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Contact click recorded"))
+}
+
+func (h *StatsHandler) ProductStats(c *gin.Context) {
+	id := c.Param("id")
+	days := 0
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("days must be a positive integer"))
+			return
+		}
+		days = n
+	}
+
+	stats, err := h.service.ProductStats(c.Request.Context(), id, c.GetString("user_id"), c.GetString("role") == "admin", days)
+	if err != nil {
+		if err == ErrProductNotFound {
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to load product stats", map[string]any{"error": err.Error(), "id": id})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to load product stats"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(stats))
+}
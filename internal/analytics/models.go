@@ -0,0 +1,53 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventView         EventType = "view"
+	EventContactClick EventType = "contact_click"
+	// EventImpression and EventClick are counted per promotion, see
+	// NewNamedEventBuffer
+	EventImpression EventType = "impression"
+	EventClick      EventType = "click"
+)
+
+// Visitor identifies who triggered an event for deduplication. Signed in
+// users are identified by id, anonymous ones by client IP and user agent.
+type Visitor struct {
+	UserID    string
+	IP        string
+	UserAgent string
+}
+
+// DailyCount is a buffered counter increment for one listing and day. In a
+// buffer of promotion events ProductID is the id of the promotion.
+type DailyCount struct {
+	ProductID     uuid.UUID
+	Day           time.Time
+	Views         int
+	ContactClicks int
+	Impressions   int
+	Clicks        int
+}
+
+type DailyStat struct {
+	Date          string `json:"date"` // YYYY-MM-DD
+	Views         int    `json:"views"`
+	Favorites     int    `json:"favorites"`
+	ContactClicks int    `json:"contact_clicks"`
+}
+
+type ProductStats struct {
+	ProductID     uuid.UUID   `json:"product_id"`
+	TotalViews    int         `json:"total_views"`
+	FavoriteCount int         `json:"favorite_count"`
+	From          string      `json:"from"`
+	To            string      `json:"to"`
+	Days          []DailyStat `json:"days"`
+}
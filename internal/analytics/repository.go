@@ -0,0 +1,130 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/db"
+	"github.com/lib/pq"
+)
+
+type StatsRepository interface {
+	// AddDailyCounts stores a flushed batch and bumps products.views_count
+	// in the same transaction. A batch already stored is skipped.
+	AddDailyCounts(ctx context.Context, batch string, counts []DailyCount) error
+	// DailyStats returns one row per day between from and to, inclusive.
+	DailyStats(ctx context.Context, productID string, from, to time.Time) ([]DailyStat, error)
+}
+
+type StatsRepoPsql struct {
+	logger config.Logging
+	psql   db.Database
+}
+
+func NewStatsRepoPsql(psql db.Database, logger config.Logging) *StatsRepoPsql {
+	return &StatsRepoPsql{
+		psql:   psql,
+		logger: logger,
+	}
+}
+
+func (r *StatsRepoPsql) AddDailyCounts(ctx context.Context, batch string, counts []DailyCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(counts))
+	days := make([]string, len(counts))
+	views := make([]int64, len(counts))
+	clicks := make([]int64, len(counts))
+	for i, c := range counts {
+		ids[i] = c.ProductID.String()
+		days[i] = c.Day.Format(fieldDayForm)
+		views[i] = int64(c.Views)
+		clicks[i] = int64(c.ContactClicks)
+	}
+
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A concurrent flush of the same batch waits here for the first one
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO authentic.analytics_flushed_batches (batch) VALUES ($1)
+		ON CONFLICT DO NOTHING
+	`, batch)
+	if err != nil {
+		return err
+	}
+	if stored, _ := result.RowsAffected(); stored == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM authentic.analytics_flushed_batches WHERE flushed_at < NOW() - INTERVAL '7 days'`); err != nil {
+		return err
+	}
+
+	// Listings deleted since the events were recorded are skipped by the join
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO authentic.product_daily_stats (product_id, day, views, contact_clicks)
+		SELECT t.product_id, t.day, t.views, t.contact_clicks
+		FROM unnest($1::uuid[], $2::date[], $3::int[], $4::int[]) AS t(product_id, day, views, contact_clicks)
+		JOIN authentic.products p ON p.id = t.product_id
+		ON CONFLICT (product_id, day) DO UPDATE
+		SET views = product_daily_stats.views + EXCLUDED.views,
+		    contact_clicks = product_daily_stats.contact_clicks + EXCLUDED.contact_clicks
+	`, pq.Array(ids), pq.Array(days), pq.Array(views), pq.Array(clicks)); err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to store daily stats", map[string]any{"error": err.Error()})
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE authentic.products p
+		SET views_count = p.views_count + t.views
+		FROM (
+			SELECT product_id, SUM(views) AS views
+			FROM unnest($1::uuid[], $2::int[]) AS u(product_id, views)
+			GROUP BY product_id
+		) t
+		WHERE p.id = t.product_id AND t.views > 0
+	`, pq.Array(ids), pq.Array(views)); err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to update views count", map[string]any{"error": err.Error()})
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *StatsRepoPsql) DailyStats(ctx context.Context, productID string, from, to time.Time) ([]DailyStat, error) {
+	// Favorites are counted on the day they were added; removed favorites
+	// no longer show up in the series
+	query := `
+		SELECT to_char(d.day, 'YYYY-MM-DD'), COALESCE(s.views, 0), COALESCE(f.favorites, 0), COALESCE(s.contact_clicks, 0)
+		FROM generate_series($2::date, $3::date, INTERVAL '1 day') AS d(day)
+		LEFT JOIN authentic.product_daily_stats s ON s.product_id = $1 AND s.day = d.day::date
+		LEFT JOIN (
+			SELECT created_at::date AS day, COUNT(*) AS favorites
+			FROM authentic.user_favorites
+			WHERE product_id = $1
+			GROUP BY 1
+		) f ON f.day = d.day::date
+		ORDER BY d.day
+	`
+	rows, err := r.psql.Query(ctx, query, productID, from.Format(fieldDayForm), to.Format(fieldDayForm))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []DailyStat{}
+	for rows.Next() {
+		var s DailyStat
+		if err := rows.Scan(&s.Date, &s.Views, &s.Favorites, &s.ContactClicks); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hfleury/horsemarketplacebk/config"
+	productModels "github.com/hfleury/horsemarketplacebk/internal/products/models"
+	productRepos "github.com/hfleury/horsemarketplacebk/internal/products/repositories"
+	"github.com/hibiken/asynq"
+)
+
+var ErrProductNotFound = errors.New("product not found")
+
+const (
+	// DefaultDedupWindow is how long repeated views by the same visitor count once
+	DefaultDedupWindow = 30 * time.Minute
+	defaultStatsDays   = 30
+	maxStatsDays       = 365
+)
+
+// botMarkers are user agent fragments of crawlers, link previews and
+// scripted clients, matched case insensitively.
+var botMarkers = []string{
+	"bot", "crawler", "spider", "slurp", "facebookexternalhit", "embedly", "preview",
+	"headless", "curl", "wget", "python-requests", "go-http-client", "httpclient",
+}
+
+type StatsService struct {
+	buffer      EventBuffer
+	repo        StatsRepository
+	productRepo productRepos.ProductRepository
+	logger      config.Logging
+}
+
+func NewStatsService(buffer EventBuffer, repo StatsRepository, productRepo productRepos.ProductRepository, logger config.Logging) *StatsService {
+	return &StatsService{
+		buffer:      buffer,
+		repo:        repo,
+		productRepo: productRepo,
+		logger:      logger,
+	}
+}
+
+// RecordView counts a view of a published listing. Views by bots and by the
+// seller are ignored. Recording is best effort and never fails the request.
+func (s *StatsService) RecordView(ctx context.Context, product *productModels.Product, visitor Visitor) {
+	if product.Status != productModels.StatusPublished || visitor.UserID == product.UserID.String() || IsBot(visitor.UserAgent) {
+		return
+	}
+	if _, err := s.buffer.Record(ctx, product.ID.String(), visitor, EventView); err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to record view", map[string]any{"error": err.Error(), "product_id": product.ID})
+	}
+}
+
+// RecordContactClick counts a click on the seller's contact details.
+func (s *StatsService) RecordContactClick(ctx context.Context, productID string, visitor Visitor) error {
+	product, err := s.productRepo.FindByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if product == nil || product.Status != productModels.StatusPublished {
+		return ErrProductNotFound
+	}
+	if visitor.UserID == product.UserID.String() || IsBot(visitor.UserAgent) {
+		return nil
+	}
+	_, err = s.buffer.Record(ctx, productID, visitor, EventContactClick)
+	return err
+}
+
+// HandleFlushTask moves the buffered counters into Postgres in one batch.
+// A batch that fails to store stays in Redis and is retried by the next
+// run, which can't count it twice.
+func (s *StatsService) HandleFlushTask(ctx context.Context, t *asynq.Task) error {
+	batch, counts, err := s.buffer.Take(ctx)
+	if err != nil {
+		return fmt.Errorf("take analytics batch: %w", err)
+	}
+	if batch == "" {
+		return nil
+	}
+
+	if err := s.repo.AddDailyCounts(ctx, batch, counts); err != nil {
+		return fmt.Errorf("store analytics batch: %w", err)
+	}
+	return s.buffer.Done(ctx, batch)
+}
+
+// ProductStats returns the daily series of the last days for a listing. Only
+// the seller and admins can see them; anyone else gets ErrProductNotFound.
+func (s *StatsService) ProductStats(ctx context.Context, productID string, userID string, isAdmin bool, days int) (*ProductStats, error) {
+	product, err := s.productRepo.FindByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.Status == productModels.StatusDeleted || (!isAdmin && product.UserID.String() != userID) {
+		return nil, ErrProductNotFound
+	}
+
+	if days <= 0 {
+		days = defaultStatsDays
+	}
+	if days > maxStatsDays {
+		days = maxStatsDays
+	}
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -(days - 1))
+
+	series, err := s.repo.DailyStats(ctx, productID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProductStats{
+		ProductID:     product.ID,
+		TotalViews:    product.ViewsCount,
+		FavoriteCount: product.FavoriteCount,
+		From:          from.Format(fieldDayForm),
+		To:            to.Format(fieldDayForm),
+		Days:          series,
+	}, nil
+}
+
+// IsBot reports whether a user agent looks like a crawler or a script.
+// Requests without a user agent are treated as bots.
+func IsBot(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return true
+		}
+	}
+	return false
+}
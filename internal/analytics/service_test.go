@@ -0,0 +1,81 @@
+package analytics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	mockAnalytics "github.com/hfleury/horsemarketplacebk/internal/mocks/analytics"
+	mockProducts "github.com/hfleury/horsemarketplacebk/internal/mocks/products"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const browserUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+
+func TestIsBot(t *testing.T) {
+	assert.True(t, analytics.IsBot(""))
+	assert.True(t, analytics.IsBot("Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"))
+	assert.True(t, analytics.IsBot("curl/8.4.0"))
+	assert.False(t, analytics.IsBot(browserUA))
+}
+
+func TestRecordView_SkipsSellerAndBots(t *testing.T) {
+	buffer := new(mockAnalytics.MockEventBuffer)
+	service := analytics.NewStatsService(buffer, new(mockAnalytics.MockStatsRepo), new(mockProducts.MockProductRepo), config.NewZerologService())
+
+	product := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusPublished}
+	visitor := analytics.Visitor{IP: "10.0.0.1", UserAgent: browserUA}
+	buffer.On("Record", mock.Anything, product.ID.String(), visitor, analytics.EventView).Return(true, nil)
+
+	service.RecordView(context.Background(), product, visitor)
+	service.RecordView(context.Background(), product, analytics.Visitor{UserID: product.UserID.String(), UserAgent: browserUA})
+	service.RecordView(context.Background(), product, analytics.Visitor{IP: "10.0.0.2", UserAgent: "Googlebot/2.1"})
+
+	buffer.AssertNumberOfCalls(t, "Record", 1)
+}
+
+func TestHandleFlushTask_KeepsBatchOnFailure(t *testing.T) {
+	buffer := new(mockAnalytics.MockEventBuffer)
+	repo := new(mockAnalytics.MockStatsRepo)
+	service := analytics.NewStatsService(buffer, repo, new(mockProducts.MockProductRepo), config.NewZerologService())
+
+	counts := []analytics.DailyCount{{ProductID: uuid.New(), Views: 3}}
+	buffer.On("Take", mock.Anything).Return("analytics:flushing:1", counts, nil)
+	repo.On("AddDailyCounts", mock.Anything, "analytics:flushing:1", counts).Return(errors.New("db down")).Once()
+
+	err := service.HandleFlushTask(context.Background(), asynq.NewTask("analytics:flush", nil))
+	assert.Error(t, err)
+	buffer.AssertNotCalled(t, "Done", mock.Anything, mock.Anything)
+
+	// The next run takes the same batch again
+	repo.On("AddDailyCounts", mock.Anything, "analytics:flushing:1", counts).Return(nil).Once()
+	buffer.On("Done", mock.Anything, "analytics:flushing:1").Return(nil)
+	err = service.HandleFlushTask(context.Background(), asynq.NewTask("analytics:flush", nil))
+	assert.NoError(t, err)
+	buffer.AssertExpectations(t)
+}
+
+func TestProductStats_OnlySeller(t *testing.T) {
+	productRepo := new(mockProducts.MockProductRepo)
+	repo := new(mockAnalytics.MockStatsRepo)
+	service := analytics.NewStatsService(new(mockAnalytics.MockEventBuffer), repo, productRepo, config.NewZerologService())
+
+	product := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusPublished, ViewsCount: 42}
+	productRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	repo.On("DailyStats", mock.Anything, product.ID.String(), mock.Anything, mock.Anything).
+		Return(make([]analytics.DailyStat, 7), nil)
+
+	_, err := service.ProductStats(context.Background(), product.ID.String(), uuid.New().String(), false, 7)
+	assert.Equal(t, analytics.ErrProductNotFound, err)
+
+	stats, err := service.ProductStats(context.Background(), product.ID.String(), product.UserID.String(), false, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, stats.TotalViews)
+	assert.Len(t, stats.Days, 7)
+}
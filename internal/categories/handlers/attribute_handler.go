@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+)
+
+func (h *CategoryHandler) ListAttributes(c *gin.Context) {
+	logger := h.logger.GetLoggerFromContext(c)
+	response := common.APIResponse{}
+
+	attributes, err := h.service.ListAttributes(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			response.Status = "error"
+			response.Message = err.Error()
+			c.JSON(http.StatusNotFound, response)
+			return
+		}
+		logger.Log(c, config.ErrorLevel, "Failed to list category attributes", map[string]any{"error": err.Error(), "id": c.Param("id")})
+		response.Status = "error"
+		response.Message = "Failed to retrieve attributes"
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Status = "success"
+	response.Data = attributes
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CategoryHandler) CreateAttribute(c *gin.Context) {
+	logger := h.logger.GetLoggerFromContext(c)
+	response := common.APIResponse{}
+	var req models.CreateAttributeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log(c, config.ErrorLevel, "Failed to bind create attribute request", map[string]any{"error": err.Error()})
+		response.Status = "error"
+		response.Message = "Invalid request"
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	attribute, err := h.service.CreateAttribute(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		h.respondAttributeError(c, err, "Failed to create attribute")
+		return
+	}
+
+	response.Status = "success"
+	response.Message = "Attribute created successfully"
+	response.Data = attribute
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *CategoryHandler) UpdateAttribute(c *gin.Context) {
+	logger := h.logger.GetLoggerFromContext(c)
+	response := common.APIResponse{}
+	var req models.UpdateAttributeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Log(c, config.ErrorLevel, "Failed to bind update attribute request", map[string]any{"error": err.Error()})
+		response.Status = "error"
+		response.Message = "Invalid request"
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	attribute, err := h.service.UpdateAttribute(c.Request.Context(), c.Param("id"), c.Param("attributeId"), req)
+	if err != nil {
+		h.respondAttributeError(c, err, "Failed to update attribute")
+		return
+	}
+
+	response.Status = "success"
+	response.Message = "Attribute updated successfully"
+	response.Data = attribute
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CategoryHandler) DeleteAttribute(c *gin.Context) {
+	response := common.APIResponse{}
+
+	if err := h.service.DeleteAttribute(c.Request.Context(), c.Param("id"), c.Param("attributeId")); err != nil {
+		h.respondAttributeError(c, err, "Failed to delete attribute")
+		return
+	}
+
+	response.Status = "success"
+	response.Message = "Attribute deleted successfully"
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CategoryHandler) respondAttributeError(c *gin.Context, err error, message string) {
+	logger := h.logger.GetLoggerFromContext(c)
+	logger.Log(c, config.ErrorLevel, message, map[string]any{"error": err.Error(), "id": c.Param("id")})
+	response := common.APIResponse{Status: "error", Message: err.Error()}
+
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrAttributeNotFound):
+		c.JSON(http.StatusNotFound, response)
+	case errors.Is(err, services.ErrAttributeKeyTaken):
+		c.JSON(http.StatusConflict, response)
+	default:
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
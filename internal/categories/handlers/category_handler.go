@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -70,7 +71,15 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		return
 	}
 
-	cat, err := h.service.UpdateCategory(c.Request.Context(), id, req)
+	// Two admins editing the same category: the second save is refused
+	// rather than silently undoing the first
+	cat, err := h.service.UpdateCategory(c.Request.Context(), id, req, common.IfMatchVersions(c.Request))
+	if errors.Is(err, services.ErrVersionConflict) {
+		response.Status = "error"
+		response.Message = err.Error()
+		c.JSON(http.StatusPreconditionFailed, response)
+		return
+	}
 	if err != nil {
 		logger.Log(c, config.ErrorLevel, "Failed to update category", map[string]any{"error": err.Error(), "id": id})
 		response.Status = "error"
@@ -82,7 +91,11 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 	response.Status = "success"
 	response.Message = "Category updated successfully"
 	response.Data = cat
-	c.JSON(http.StatusOK, response)
+	if cat.UpdatedAt == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	common.WriteVersion(c, *cat.UpdatedAt, response)
 }
 
 func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
@@ -127,6 +140,38 @@ func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetCategory returns a category without its subcategories. Its ETag is
+// the version expected back in If-Match when updating it.
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	logger := h.logger.GetLoggerFromContext(c)
+	response := common.APIResponse{}
+	id := c.Param("id")
+
+	cat, err := h.service.GetCategory(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			response.Status = "error"
+			response.Message = "Category not found"
+			c.JSON(http.StatusNotFound, response)
+			return
+		}
+		logger.Log(c, config.ErrorLevel, "Failed to get category", map[string]any{"error": err.Error(), "id": id})
+		response.Status = "error"
+		response.Message = "Failed to retrieve category"
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response.Status = "success"
+	response.Data = cat
+	// Categories without a version are sent untagged
+	if cat.UpdatedAt == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	common.WriteVersioned(c, *cat.UpdatedAt, "no-cache", response)
+}
+
 func (h *CategoryHandler) GetCategoryByName(c *gin.Context) {
 	logger := h.logger.GetLoggerFromContext(c)
 	response := common.APIResponse{}
@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/handlers"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/repositories"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	mockcategories "github.com/hfleury/horsemarketplacebk/internal/mocks/categories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newCategoryRouter(repo *mockcategories.MockCategoryRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := config.NewZerologService()
+	handler := handlers.NewCategoryHandler(logger, services.NewCategoryService(repo, logger))
+	r := gin.New()
+	r.GET("/categories/:id", handler.GetCategory)
+	r.PUT("/categories/:id", handler.UpdateCategory)
+	return r
+}
+
+func TestGetCategory_ConditionalGet(t *testing.T) {
+	repo := new(mockcategories.MockCategoryRepository)
+	id, name, updatedAt := uuid.New(), "Horses", time.Now()
+	repo.On("FindByID", mock.Anything, id.String()).Return(&models.Category{Id: &id, Name: &name, UpdatedAt: &updatedAt}, nil)
+	r := newCategoryRouter(repo)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/categories/"+id.String(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/categories/"+id.String(), nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestUpdateCategory_IfMatch(t *testing.T) {
+	repo := new(mockcategories.MockCategoryRepository)
+	id, name := uuid.New(), "Horses"
+	readAt := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	changedAt := time.Now().Truncate(time.Microsecond)
+	current := &models.Category{Id: &id, Name: &name, UpdatedAt: &changedAt}
+	repo.On("FindByID", mock.Anything, id.String()).Return(current, nil)
+	// The version is checked by the write itself
+	atVersion := func(v time.Time) any {
+		return mock.MatchedBy(func(ifMatch []time.Time) bool { return len(ifMatch) == 1 && ifMatch[0].Equal(v) })
+	}
+	repo.On("Update", mock.Anything, mock.Anything, atVersion(readAt)).Return(nil, repositories.ErrVersionConflict)
+	repo.On("Update", mock.Anything, mock.Anything, atVersion(changedAt)).Return(current, nil)
+	r := newCategoryRouter(repo)
+
+	put := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/categories/"+id.String(), bytes.NewBufferString(`{"picture_url":"https://example.com/h.png"}`))
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Another admin saved after this version was read
+	w := put(common.VersionETag(readAt, []byte(`{}`)))
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/categories/"+id.String(), nil))
+	w = put(w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+}
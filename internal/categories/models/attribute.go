@@ -0,0 +1,196 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AttributeType string
+
+const (
+	AttributeEnum    AttributeType = "enum"
+	AttributeInteger AttributeType = "integer"
+	AttributeText    AttributeType = "text"
+	AttributeBoolean AttributeType = "boolean"
+
+	maxAttributeTextLength = 500
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// Attribute is a typed field admins define on a category. Listings in the
+// category, or in any of its subcategories, carry a value for it.
+type Attribute struct {
+	ID            uuid.UUID     `json:"id"`
+	CategoryID    uuid.UUID     `json:"category_id"`
+	Key           string        `json:"key"`
+	Label         string        `json:"label"`
+	Type          AttributeType `json:"type"`
+	Unit          *string       `json:"unit,omitempty"` // integer attributes only, e.g. cm
+	Required      bool          `json:"required"`
+	AllowedValues []string      `json:"allowed_values,omitempty"` // enum attributes only
+	Min           *int64        `json:"min,omitempty"`
+	Max           *int64        `json:"max,omitempty"`
+	Position      int           `json:"position"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+type CreateAttributeRequest struct {
+	Key           string        `json:"key" binding:"required"`
+	Label         string        `json:"label" binding:"required"`
+	Type          AttributeType `json:"type" binding:"required"`
+	Unit          *string       `json:"unit"`
+	Required      bool          `json:"required"`
+	AllowedValues []string      `json:"allowed_values"`
+	Min           *int64        `json:"min"`
+	Max           *int64        `json:"max"`
+	Position      int           `json:"position"`
+}
+
+// UpdateAttributeRequest cannot change the key or the type, the values
+// stored on listings depend on them.
+type UpdateAttributeRequest struct {
+	Label         *string  `json:"label"`
+	Unit          *string  `json:"unit"`
+	Required      *bool    `json:"required"`
+	AllowedValues []string `json:"allowed_values"`
+	Min           *int64   `json:"min"`
+	Max           *int64   `json:"max"`
+	Position      *int     `json:"position"`
+}
+
+// Validate checks that the definition is consistent with its type.
+func (a *Attribute) Validate() error {
+	if !attributeKeyPattern.MatchString(a.Key) {
+		return errors.New("attribute key must be lowercase letters, digits and underscores, starting with a letter")
+	}
+	if strings.TrimSpace(a.Label) == "" {
+		return errors.New("attribute label is required")
+	}
+	switch a.Type {
+	case AttributeEnum, AttributeInteger, AttributeText, AttributeBoolean:
+	default:
+		return errors.New("attribute type must be enum, integer, text or boolean")
+	}
+	if a.Type == AttributeEnum && len(a.AllowedValues) == 0 {
+		return errors.New("enum attributes need allowed values")
+	}
+	if a.Type != AttributeEnum && len(a.AllowedValues) > 0 {
+		return errors.New("only enum attributes have allowed values")
+	}
+	if a.Type != AttributeInteger && (a.Unit != nil || a.Min != nil || a.Max != nil) {
+		return errors.New("only integer attributes have a unit, min or max")
+	}
+	if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+		return errors.New("attribute min must not exceed max")
+	}
+	return nil
+}
+
+// AttributeValue is a listing's value for an attribute; only the field
+// matching the attribute type is set.
+type AttributeValue struct {
+	Text *string
+	Int  *int64
+	Bool *bool
+}
+
+// Value returns the value as it appears in JSON.
+func (v AttributeValue) Value() any {
+	switch {
+	case v.Text != nil:
+		return *v.Text
+	case v.Int != nil:
+		return *v.Int
+	case v.Bool != nil:
+		return *v.Bool
+	}
+	return nil
+}
+
+// Coerce checks a submitted value against the definition and converts it.
+// Besides JSON values it accepts strings, as found in query strings and
+// import files.
+func (a *Attribute) Coerce(raw any) (AttributeValue, error) {
+	switch a.Type {
+	case AttributeEnum:
+		s, ok := raw.(string)
+		if !ok {
+			return AttributeValue{}, errors.New("must be a string")
+		}
+		i := slices.IndexFunc(a.AllowedValues, func(v string) bool { return strings.EqualFold(v, strings.TrimSpace(s)) })
+		if i < 0 {
+			return AttributeValue{}, fmt.Errorf("must be one of %s", strings.Join(a.AllowedValues, ", "))
+		}
+		return AttributeValue{Text: &a.AllowedValues[i]}, nil
+
+	case AttributeText:
+		s, ok := raw.(string)
+		if !ok {
+			return AttributeValue{}, errors.New("must be a string")
+		}
+		s = strings.TrimSpace(s)
+		if len(s) > maxAttributeTextLength {
+			return AttributeValue{}, fmt.Errorf("must be at most %d characters", maxAttributeTextLength)
+		}
+		return AttributeValue{Text: &s}, nil
+
+	case AttributeInteger:
+		var n int64
+		switch v := raw.(type) {
+		case float64:
+			if v != math.Trunc(v) || math.Abs(v) > math.MaxInt64/2 {
+				return AttributeValue{}, errors.New("must be a whole number")
+			}
+			n = int64(v)
+		case int:
+			n = int64(v)
+		case int64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return AttributeValue{}, errors.New("must be a whole number")
+			}
+			n = parsed
+		default:
+			return AttributeValue{}, errors.New("must be a whole number")
+		}
+		if a.Min != nil && n < *a.Min {
+			return AttributeValue{}, fmt.Errorf("must be at least %d", *a.Min)
+		}
+		if a.Max != nil && n > *a.Max {
+			return AttributeValue{}, fmt.Errorf("must be at most %d", *a.Max)
+		}
+		return AttributeValue{Int: &n}, nil
+
+	case AttributeBoolean:
+		var b bool
+		switch v := raw.(type) {
+		case bool:
+			b = v
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "1":
+				b = true
+			case "false", "no", "0":
+				b = false
+			default:
+				return AttributeValue{}, errors.New("must be true or false")
+			}
+		default:
+			return AttributeValue{}, errors.New("must be true or false")
+		}
+		return AttributeValue{Bool: &b}, nil
+	}
+	return AttributeValue{}, errors.New("unknown attribute type")
+}
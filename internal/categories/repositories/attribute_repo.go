@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	"github.com/lib/pq"
+)
+
+const attributeColumns = `a.id, a.category_id, a.key, a.label, a.type, a.unit, a.required, a.allowed_values, a.min_value, a.max_value, a.position, a.created_at, a.updated_at`
+
+func scanAttribute(row interface{ Scan(...any) error }) (*models.Attribute, error) {
+	var a models.Attribute
+	var allowed pq.StringArray
+	err := row.Scan(&a.ID, &a.CategoryID, &a.Key, &a.Label, &a.Type, &a.Unit, &a.Required, &allowed, &a.Min, &a.Max, &a.Position, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.AllowedValues = allowed
+	return &a, nil
+}
+
+func (r *CategoryRepoPsql) CreateAttribute(ctx context.Context, a *models.Attribute) (*models.Attribute, error) {
+	query := `
+		INSERT INTO authentic.category_attributes AS a (id, category_id, key, label, type, unit, required, allowed_values, min_value, max_value, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING ` + attributeColumns
+	row := r.psql.QueryRow(ctx, query, a.ID, a.CategoryID, a.Key, a.Label, a.Type, a.Unit, a.Required, pq.Array(a.AllowedValues), a.Min, a.Max, a.Position)
+	created, err := scanAttribute(row)
+	if err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to create category attribute", map[string]any{"error": err.Error()})
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *CategoryRepoPsql) UpdateAttribute(ctx context.Context, a *models.Attribute) (*models.Attribute, error) {
+	query := `
+		UPDATE authentic.category_attributes AS a
+		SET label = $2, unit = $3, required = $4, allowed_values = $5, min_value = $6, max_value = $7, position = $8, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + attributeColumns
+	row := r.psql.QueryRow(ctx, query, a.ID, a.Label, a.Unit, a.Required, pq.Array(a.AllowedValues), a.Min, a.Max, a.Position)
+	updated, err := scanAttribute(row)
+	if err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to update category attribute", map[string]any{"error": err.Error()})
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *CategoryRepoPsql) DeleteAttribute(ctx context.Context, categoryID string, id string) (bool, error) {
+	result, err := r.psql.Execute(ctx, `DELETE FROM authentic.category_attributes WHERE id = $1 AND category_id = $2`, id, categoryID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *CategoryRepoPsql) FindAttribute(ctx context.Context, categoryID string, id string) (*models.Attribute, error) {
+	query := `SELECT ` + attributeColumns + ` FROM authentic.category_attributes a WHERE a.id = $1 AND a.category_id = $2`
+	a, err := scanAttribute(r.psql.QueryRow(ctx, query, id, categoryID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+func (r *CategoryRepoPsql) FindAttributes(ctx context.Context, categoryID string) ([]*models.Attribute, error) {
+	// Walk up to the root; the root's attributes come first
+	query := `
+		WITH RECURSIVE lineage AS (
+			SELECT id, parent_id, 0 AS depth FROM authentic.categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id, l.depth + 1
+			FROM authentic.categories c JOIN lineage l ON c.id = l.parent_id
+		)
+		SELECT ` + attributeColumns + `
+		FROM authentic.category_attributes a
+		JOIN lineage l ON l.id = a.category_id
+		ORDER BY l.depth DESC, a.position, a.key
+	`
+	rows, err := r.psql.Query(ctx, query, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attributes := []*models.Attribute{}
+	for rows.Next() {
+		a, err := scanAttribute(rows)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, a)
+	}
+	return attributes, rows.Err()
+}
+
+func (r *CategoryRepoPsql) AttributeKeyTaken(ctx context.Context, categoryID string, key string) (bool, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM authentic.categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id FROM authentic.categories c JOIN ancestors a ON c.id = a.parent_id
+		), descendants AS (
+			SELECT id FROM authentic.categories WHERE id = $1
+			UNION ALL
+			SELECT c.id FROM authentic.categories c JOIN descendants d ON c.parent_id = d.id
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM authentic.category_attributes
+			WHERE key = $2 AND (category_id IN (SELECT id FROM ancestors) OR category_id IN (SELECT id FROM descendants))
+		)
+	`
+	var taken bool
+	err := r.psql.QueryRow(ctx, query, categoryID, key).Scan(&taken)
+	return taken, err
+}
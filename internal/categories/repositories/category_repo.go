@@ -4,20 +4,40 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
 	"github.com/hfleury/horsemarketplacebk/internal/categories/models"
 	"github.com/hfleury/horsemarketplacebk/internal/db"
+	"github.com/lib/pq"
 )
 
+// ErrVersionConflict is returned by Update when the category is at none of
+// the versions the caller expects anymore.
+var ErrVersionConflict = errors.New("the category was changed since it was loaded")
+
 type CategoryRepository interface {
 	Create(ctx context.Context, category *models.Category) (*models.Category, error)
-	Update(ctx context.Context, category *models.Category) (*models.Category, error)
+	// Update only writes a category still at one of the ifMatch versions,
+	// nil accepting any, else returns ErrVersionConflict.
+	Update(ctx context.Context, category *models.Category, ifMatch []time.Time) (*models.Category, error)
 	Delete(ctx context.Context, id string) error
 	FindByID(ctx context.Context, id string) (*models.Category, error)
 	FindAll(ctx context.Context) ([]*models.Category, error)
 	FindByName(ctx context.Context, name string) (*models.Category, error)
+
+	CreateAttribute(ctx context.Context, attribute *models.Attribute) (*models.Attribute, error)
+	UpdateAttribute(ctx context.Context, attribute *models.Attribute) (*models.Attribute, error)
+	// DeleteAttribute reports whether the attribute existed.
+	DeleteAttribute(ctx context.Context, categoryID string, id string) (bool, error)
+	FindAttribute(ctx context.Context, categoryID string, id string) (*models.Attribute, error)
+	// FindAttributes returns the attributes of the category and those it
+	// inherits from its ancestors, the root's first.
+	FindAttributes(ctx context.Context, categoryID string) ([]*models.Attribute, error)
+	// AttributeKeyTaken reports whether the key is defined on the category,
+	// one of its ancestors or one of its descendants.
+	AttributeKeyTaken(ctx context.Context, categoryID string, key string) (bool, error)
 }
 
 type CategoryRepoPsql struct {
@@ -62,15 +82,23 @@ func (r *CategoryRepoPsql) Create(ctx context.Context, category *models.Category
 	return &created, nil
 }
 
-func (r *CategoryRepoPsql) Update(ctx context.Context, category *models.Category) (*models.Category, error) {
+func (r *CategoryRepoPsql) Update(ctx context.Context, category *models.Category, ifMatch []time.Time) (*models.Category, error) {
 	query := `
 		UPDATE authentic.categories
 		SET name = $2, picture_url = $3, parent_id = $4, updated_at = NOW()
-		WHERE id = $1
+		WHERE id = $1 AND ($5::timestamptz[] IS NULL OR updated_at = ANY($5::timestamptz[]))
 		RETURNING id, name, picture_url, parent_id, created_at, updated_at
 	`
 
-	row := r.psql.QueryRow(ctx, query, category.Id, category.Name, category.PictureURL, category.ParentID)
+	var versions any
+	if ifMatch != nil {
+		matching := make([]string, len(ifMatch))
+		for i, v := range ifMatch {
+			matching[i] = v.Format(time.RFC3339Nano)
+		}
+		versions = pq.Array(matching)
+	}
+	row := r.psql.QueryRow(ctx, query, category.Id, category.Name, category.PictureURL, category.ParentID, versions)
 
 	var updated models.Category
 	err := row.Scan(
@@ -81,6 +109,9 @@ func (r *CategoryRepoPsql) Update(ctx context.Context, category *models.Category
 		&updated.CreatedAt,
 		&updated.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) && ifMatch != nil {
+		return nil, ErrVersionConflict
+	}
 	if err != nil {
 		r.logger.Log(ctx, config.ErrorLevel, "Failed to update category", map[string]any{"error": err.Error()})
 		return nil, err
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/repositories"
+)
+
+var (
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrAttributeNotFound = errors.New("attribute not found")
+	ErrAttributeKeyTaken = errors.New("attribute key already used by this category, a parent or a subcategory")
+	ErrVersionConflict   = repositories.ErrVersionConflict
+)
+
+// CreateAttribute defines a new attribute on a category. Keys are unique
+// along the category tree so a listing never sees the same key twice.
+func (s *CategoryService) CreateAttribute(ctx context.Context, categoryID string, req models.CreateAttributeRequest) (*models.Attribute, error) {
+	category, err := s.findCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &models.Attribute{
+		ID:            uuid.New(),
+		CategoryID:    *category.Id,
+		Key:           strings.TrimSpace(req.Key),
+		Label:         strings.TrimSpace(req.Label),
+		Type:          req.Type,
+		Unit:          req.Unit,
+		Required:      req.Required,
+		AllowedValues: trimValues(req.AllowedValues),
+		Min:           req.Min,
+		Max:           req.Max,
+		Position:      req.Position,
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	taken, err := s.repo.AttributeKeyTaken(ctx, categoryID, a.Key)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrAttributeKeyTaken
+	}
+
+	return s.repo.CreateAttribute(ctx, a)
+}
+
+func (s *CategoryService) UpdateAttribute(ctx context.Context, categoryID string, id string, req models.UpdateAttributeRequest) (*models.Attribute, error) {
+	a, err := s.repo.FindAttribute(ctx, categoryID, id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrAttributeNotFound
+	}
+
+	if req.Label != nil {
+		a.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Unit != nil {
+		a.Unit = req.Unit
+		if *req.Unit == "" {
+			a.Unit = nil
+		}
+	}
+	if req.Required != nil {
+		a.Required = *req.Required
+	}
+	if req.AllowedValues != nil {
+		a.AllowedValues = trimValues(req.AllowedValues)
+	}
+	if req.Min != nil {
+		a.Min = req.Min
+	}
+	if req.Max != nil {
+		a.Max = req.Max
+	}
+	if req.Position != nil {
+		a.Position = *req.Position
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdateAttribute(ctx, a)
+}
+
+// DeleteAttribute removes the attribute and the values listings had for it.
+func (s *CategoryService) DeleteAttribute(ctx context.Context, categoryID string, id string) error {
+	deleted, err := s.repo.DeleteAttribute(ctx, categoryID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAttributeNotFound
+	}
+	return nil
+}
+
+// ListAttributes returns the attributes listings in the category carry,
+// including those inherited from parent categories.
+func (s *CategoryService) ListAttributes(ctx context.Context, categoryID string) ([]*models.Attribute, error) {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAttributes(ctx, categoryID)
+}
+
+func (s *CategoryService) findCategory(ctx context.Context, id string) (*models.Category, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	category, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// trimValues trims the allowed values of an enum and drops empty ones and
+// case-insensitive duplicates.
+func trimValues(values []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	"github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	mockcategories "github.com/hfleury/horsemarketplacebk/internal/mocks/categories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateAttribute_Validation(t *testing.T) {
+	mockRepo := new(mockcategories.MockCategoryRepository)
+	service := services.NewCategoryService(mockRepo, config.NewZerologService())
+
+	categoryID := uuid.New()
+	name := "Stables"
+	mockRepo.On("FindByID", mock.Anything, categoryID.String()).Return(&models.Category{Id: &categoryID, Name: &name}, nil)
+
+	_, err := service.CreateAttribute(context.Background(), categoryID.String(), models.CreateAttributeRequest{
+		Key: "heating", Label: "Heating", Type: models.AttributeEnum,
+	})
+	assert.EqualError(t, err, "enum attributes need allowed values")
+
+	_, err = service.CreateAttribute(context.Background(), categoryID.String(), models.CreateAttributeRequest{
+		Key: "Box Size", Label: "Box size", Type: models.AttributeInteger,
+	})
+	assert.Error(t, err)
+
+	mockRepo.On("AttributeKeyTaken", mock.Anything, categoryID.String(), "box_size").Return(true, nil).Once()
+	_, err = service.CreateAttribute(context.Background(), categoryID.String(), models.CreateAttributeRequest{
+		Key: "box_size", Label: "Box size", Type: models.AttributeInteger,
+	})
+	assert.Equal(t, services.ErrAttributeKeyTaken, err)
+
+	mockRepo.On("AttributeKeyTaken", mock.Anything, categoryID.String(), "heating").Return(false, nil)
+	mockRepo.On("CreateAttribute", mock.Anything, mock.MatchedBy(func(a *models.Attribute) bool {
+		return a.CategoryID == categoryID && len(a.AllowedValues) == 2
+	})).Return(&models.Attribute{}, nil)
+	_, err = service.CreateAttribute(context.Background(), categoryID.String(), models.CreateAttributeRequest{
+		Key: "heating", Label: "Heating", Type: models.AttributeEnum, AllowedValues: []string{"Floor", " floor ", "Radiator", ""},
+	})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	_, err = service.CreateAttribute(context.Background(), "not-a-uuid", models.CreateAttributeRequest{})
+	assert.Equal(t, services.ErrCategoryNotFound, err)
+}
+
+func TestAttributeCoerce(t *testing.T) {
+	minSize, maxSize := int64(6), int64(25)
+	size := &models.Attribute{Type: models.AttributeInteger, Min: &minSize, Max: &maxSize}
+	v, err := size.Coerce(12.0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), v.Value())
+	_, err = size.Coerce(12.5)
+	assert.Error(t, err)
+	_, err = size.Coerce("30")
+	assert.EqualError(t, err, "must be at most 25")
+
+	heating := &models.Attribute{Type: models.AttributeEnum, AllowedValues: []string{"Floor", "Radiator"}}
+	v, err = heating.Coerce(" floor")
+	assert.NoError(t, err)
+	assert.Equal(t, "Floor", v.Value())
+	_, err = heating.Coerce("Stove")
+	assert.Error(t, err)
+
+	water := &models.Attribute{Type: models.AttributeBoolean}
+	v, err = water.Coerce("yes")
+	assert.NoError(t, err)
+	assert.Equal(t, true, v.Value())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
@@ -64,7 +65,10 @@ func (s *CategoryService) CreateCategory(ctx context.Context, req models.CreateC
 	return s.repo.Create(ctx, cat)
 }
 
-func (s *CategoryService) UpdateCategory(ctx context.Context, id string, req models.UpdateCategoryRequest) (*models.Category, error) {
+// UpdateCategory only saves a category still at one of the ifMatch
+// versions, nil accepting any, so two admins editing it at once can't
+// silently undo each other.
+func (s *CategoryService) UpdateCategory(ctx context.Context, id string, req models.UpdateCategoryRequest, ifMatch []time.Time) (*models.Category, error) {
 	existing, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -119,7 +123,7 @@ func (s *CategoryService) UpdateCategory(ctx context.Context, id string, req mod
 		}
 	}
 
-	return s.repo.Update(ctx, existing)
+	return s.repo.Update(ctx, existing, ifMatch)
 }
 
 func (s *CategoryService) DeleteCategory(ctx context.Context, id string) error {
@@ -135,6 +139,12 @@ func (s *CategoryService) GetAllCategories(ctx context.Context) ([]*models.Categ
 	return buildCategoryTree(all), nil
 }
 
+// GetCategory returns the category without its subcategories, or
+// ErrCategoryNotFound.
+func (s *CategoryService) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	return s.findCategory(ctx, id)
+}
+
 func (s *CategoryService) GetCategoryByName(ctx context.Context, name string) (*models.Category, error) {
 	all, err := s.repo.FindAll(ctx)
 	if err != nil {
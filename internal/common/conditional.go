@@ -0,0 +1,127 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns a strong entity tag for a response body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagMatches reports whether an If-None-Match or If-Match header lists
+// etag. Weak tags compare equal to their strong counterpart.
+func ETagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified reports whether the client's cached copy is current.
+// If-Modified-Since is only consulted without If-None-Match.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return ETagMatches(header, etag)
+	}
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// WriteCacheable sends body with validators so clients and proxies can
+// revalidate it, answering 304 when their copy is still current.
+func WriteCacheable(c *gin.Context, contentType string, body []byte, lastModified time.Time, cacheControl string) {
+	etag := ETag(body)
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", cacheControl)
+	if NotModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// VersionETag is the entity tag of one representation of a version of a
+// resource. It starts with the version, which If-Match is checked against
+// when writing, and ends in a hash of body, which If-None-Match compares, as
+// the same version reads differently per language, currency or viewer.
+func VersionETag(updatedAt time.Time, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.FormatInt(updatedAt.UnixMicro(), 36) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// IfMatchVersions returns the versions listed by the request's If-Match
+// header, for the write to check atomically, or nil when any version is
+// accepted: without the header or with "*". Tags that are not version tags
+// are left out, so they never match.
+func IfMatchVersions(r *http.Request) []time.Time {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+	versions := []time.Time{}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return nil
+		}
+		// If-Match compares strong tags only
+		version, _, ok := strings.Cut(strings.Trim(candidate, `"`), "-")
+		if !ok || strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if micros, err := strconv.ParseInt(version, 36, 64); err == nil {
+			versions = append(versions, time.UnixMicro(micros))
+		}
+	}
+	return versions
+}
+
+// WriteVersioned sends a resource tagged with VersionETag, answering 304
+// when the client's copy is current. no-cache makes clients revalidate
+// every time, which costs a 304 at most.
+func WriteVersioned(c *gin.Context, updatedAt time.Time, cacheControl string, body any) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	etag := VersionETag(updatedAt, raw)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if header := c.GetHeader("If-None-Match"); header != "" && ETagMatches(header, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", raw)
+}
+
+// WriteVersion sends the response to a write with the new version of the
+// resource as ETag, for the client's next If-Match.
+func WriteVersion(c *gin.Context, updatedAt time.Time, body any) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Header("ETag", VersionETag(updatedAt, raw))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", raw)
+}
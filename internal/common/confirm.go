@@ -0,0 +1,44 @@
+package common
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// confirmPage asks a visitor following a link from an email to confirm its
+// action. Mail scanners and link prefetchers fetch those links too, so the
+// action only happens when the form posts the token back to the same URL.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">{{.Button}}</button></form>
+</body>
+</html>
+`))
+
+// WriteConfirmation answers the GET of an emailed link with a page whose
+// button POSTs token back to the same URL.
+func WriteConfirmation(c *gin.Context, title, message, button, token string) {
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := confirmPage.Execute(c.Writer, map[string]string{
+		"Title": title, "Message": message, "Button": button, "Token": token,
+	}); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// LinkToken is the token of an emailed link posted back by WriteConfirmation,
+// or by mail clients posting to the link itself.
+func LinkToken(c *gin.Context) string {
+	if token := c.PostForm("token"); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
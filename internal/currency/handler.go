@@ -0,0 +1,84 @@
+package currency
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+)
+
+const maxRatesFileBytes = 1 << 20
+
+type RateHandler struct {
+	logger  config.Logging
+	service *RateService
+}
+
+func NewRateHandler(logger config.Logging, service *RateService) *RateHandler {
+	return &RateHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *RateHandler) List(c *gin.Context) {
+	rates, err := h.service.Rates(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, "Failed to list exchange rates")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(rates))
+}
+
+func (h *RateHandler) Update(c *gin.Context) {
+	var req UpdateRatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid payload"))
+		return
+	}
+	rates, err := h.service.Update(c.Request.Context(), req.Rates, c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to update exchange rates")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(rates))
+}
+
+// Import loads the rates file uploaded in "file", see ParseRates.
+func (h *RateHandler) Import(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("A rates file is required"))
+		return
+	}
+	if header.Size > maxRatesFileBytes {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("The rates file is too large"))
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("The rates file could not be read"))
+		return
+	}
+	defer f.Close()
+
+	rates, err := h.service.Load(c.Request.Context(), io.LimitReader(f, maxRatesFileBytes), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to import exchange rates")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(rates))
+}
+
+func (h *RateHandler) respondError(c *gin.Context, err error, message string) {
+	var rateErr *RateError
+	if errors.As(err, &rateErr) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		return
+	}
+	h.logger.Log(c.Request.Context(), config.ErrorLevel, message, map[string]any{"error": err.Error()})
+	c.JSON(http.StatusInternalServerError, common.NewErrorResponse(message))
+}
@@ -0,0 +1,37 @@
+package currency
+
+import (
+	"fmt"
+	"time"
+)
+
+// Base is the currency prices are stored in.
+const Base = "SEK"
+
+// Supported are the currencies prices can be shown and entered in besides SEK.
+var Supported = []string{"EUR", "NOK", "DKK"}
+
+// Rate is how many SEK one unit of Currency is worth.
+type Rate struct {
+	Currency   string    `json:"currency"`
+	SEKPerUnit float64   `json:"sek_per_unit"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// UpdateRatesRequest sets the rates of the listed currencies, others are kept.
+type UpdateRatesRequest struct {
+	Rates map[string]float64 `json:"rates" binding:"required"`
+}
+
+// RateError reports an invalid rate in an update or a rates file.
+type RateError struct {
+	Currency string
+	Reason   string
+}
+
+func (e *RateError) Error() string {
+	if e.Currency == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Currency, e.Reason)
+}
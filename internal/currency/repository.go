@@ -0,0 +1,66 @@
+package currency
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/db"
+)
+
+type RateRepository interface {
+	FindAll(ctx context.Context) ([]Rate, error)
+	// Save stores the given rates in one transaction, other currencies keep theirs.
+	Save(ctx context.Context, rates map[string]float64, updatedBy *uuid.UUID) error
+}
+
+type RateRepoPsql struct {
+	logger config.Logging
+	psql   db.Database
+}
+
+func NewRateRepoPsql(psql db.Database, logger config.Logging) *RateRepoPsql {
+	return &RateRepoPsql{
+		psql:   psql,
+		logger: logger,
+	}
+}
+
+func (r *RateRepoPsql) FindAll(ctx context.Context) ([]Rate, error) {
+	rows, err := r.psql.Query(ctx, `SELECT currency, sek_per_unit, updated_at FROM authentic.exchange_rates ORDER BY currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []Rate{}
+	for rows.Next() {
+		var rate Rate
+		if err := rows.Scan(&rate.Currency, &rate.SEKPerUnit, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+func (r *RateRepoPsql) Save(ctx context.Context, rates map[string]float64, updatedBy *uuid.UUID) error {
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for currency, sekPerUnit := range rates {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO authentic.exchange_rates (currency, sek_per_unit, updated_by, updated_at)
+			VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (currency) DO UPDATE SET
+				sek_per_unit = EXCLUDED.sek_per_unit, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		`, currency, sekPerUnit, updatedBy); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to store exchange rate", map[string]any{"error": err.Error(), "currency": currency})
+			return err
+		}
+	}
+	return tx.Commit()
+}
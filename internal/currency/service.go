@@ -0,0 +1,192 @@
+package currency
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("currency must be one of SEK, EUR, NOK or DKK")
+	ErrRateUnavailable     = errors.New("no exchange rate is configured for the currency")
+)
+
+// cacheFor bounds how long another instance's rate update takes to show up.
+const cacheFor = 5 * time.Minute
+
+type RateService struct {
+	repo   RateRepository
+	logger config.Logging
+
+	mu       sync.RWMutex
+	cached   map[string]float64
+	loadedAt time.Time
+}
+
+func NewRateService(repo RateRepository, logger config.Logging) *RateService {
+	return &RateService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Normalize returns the upper case currency code, or ErrUnsupportedCurrency.
+func Normalize(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == Base || slices.Contains(Supported, currency) {
+		return currency, nil
+	}
+	return "", ErrUnsupportedCurrency
+}
+
+// Round rounds an amount to whole öre or cents.
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func (s *RateService) Rates(ctx context.Context) ([]Rate, error) {
+	return s.repo.FindAll(ctx)
+}
+
+// SEKPerUnit returns the rate of a currency, 1 for SEK.
+func (s *RateService) SEKPerUnit(ctx context.Context, currency string) (float64, error) {
+	currency, err := Normalize(currency)
+	if err != nil {
+		return 0, err
+	}
+	if currency == Base {
+		return 1, nil
+	}
+
+	rates, err := s.rates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rate, ok := rates[currency]
+	if !ok {
+		return 0, ErrRateUnavailable
+	}
+	return rate, nil
+}
+
+func (s *RateService) rates(ctx context.Context) (map[string]float64, error) {
+	s.mu.RLock()
+	cached, loadedAt := s.cached, s.loadedAt
+	s.mu.RUnlock()
+	if cached != nil && time.Since(loadedAt) < cacheFor {
+		return cached, nil
+	}
+
+	all, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]float64, len(all))
+	for _, r := range all {
+		rates[r.Currency] = r.SEKPerUnit
+	}
+
+	s.mu.Lock()
+	s.cached, s.loadedAt = rates, time.Now()
+	s.mu.Unlock()
+	return rates, nil
+}
+
+// Update stores new rates. updatedBy is the admin's id, empty when the
+// rates come from a file at startup.
+func (s *RateService) Update(ctx context.Context, rates map[string]float64, updatedBy string) ([]Rate, error) {
+	if len(rates) == 0 {
+		return nil, &RateError{Reason: "no rates given"}
+	}
+	normalized := make(map[string]float64, len(rates))
+	for currency, rate := range rates {
+		code, err := Normalize(currency)
+		if err != nil || code == Base {
+			return nil, &RateError{Currency: currency, Reason: "must be one of " + strings.Join(Supported, ", ")}
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, &RateError{Currency: currency, Reason: "must be a positive number of SEK"}
+		}
+		normalized[code] = rate
+	}
+
+	var by *uuid.UUID
+	if updatedBy != "" {
+		id, err := uuid.Parse(updatedBy)
+		if err != nil {
+			return nil, err
+		}
+		by = &id
+	}
+	if err := s.repo.Save(ctx, normalized, by); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cached = nil
+	s.mu.Unlock()
+	return s.repo.FindAll(ctx)
+}
+
+// Load stores the rates of a file, see ParseRates.
+func (s *RateService) Load(ctx context.Context, r io.Reader, updatedBy string) ([]Rate, error) {
+	rates, err := ParseRates(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.Update(ctx, rates, updatedBy)
+}
+
+// ParseRates reads a rates file: either a JSON object of currency to SEK
+// per unit, {"EUR": 11.45}, or CSV lines of currency and rate with an
+// optional header. Lines starting with # are comments.
+func ParseRates(r io.Reader) (map[string]float64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("{")) {
+		var rates map[string]float64
+		if err := json.Unmarshal(data, &rates); err != nil {
+			return nil, &RateError{Reason: "rates file is not a JSON object of currency to rate"}
+		}
+		return rates, nil
+	}
+
+	rates := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		currency, value, ok := strings.Cut(strings.ReplaceAll(text, ";", ","), ",")
+		if !ok {
+			return nil, &RateError{Reason: fmt.Sprintf("line %d: expected currency,rate", line)}
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, &RateError{Currency: strings.TrimSpace(currency), Reason: fmt.Sprintf("line %d: rate must be a number", line)}
+		}
+		rates[strings.TrimSpace(currency)] = rate
+	}
+	return rates, scanner.Err()
+}
@@ -0,0 +1,67 @@
+package currency_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	mockCurrency "github.com/hfleury/horsemarketplacebk/internal/mocks/currency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSEKPerUnit_CachesRates(t *testing.T) {
+	repo := new(mockCurrency.MockRateRepo)
+	service := currency.NewRateService(repo, config.NewZerologService())
+	repo.On("FindAll", mock.Anything).Return([]currency.Rate{{Currency: "EUR", SEKPerUnit: 11.5}}, nil).Once()
+
+	rate, err := service.SEKPerUnit(context.Background(), "eur")
+	assert.NoError(t, err)
+	assert.Equal(t, 11.5, rate)
+
+	rate, err = service.SEKPerUnit(context.Background(), "SEK")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, rate)
+
+	_, err = service.SEKPerUnit(context.Background(), "NOK")
+	assert.ErrorIs(t, err, currency.ErrRateUnavailable)
+	_, err = service.SEKPerUnit(context.Background(), "USD")
+	assert.ErrorIs(t, err, currency.ErrUnsupportedCurrency)
+
+	repo.AssertExpectations(t)
+}
+
+func TestLoad_CSVAndJSON(t *testing.T) {
+	repo := new(mockCurrency.MockRateRepo)
+	service := currency.NewRateService(repo, config.NewZerologService())
+	adminID := uuid.New()
+	repo.On("Save", mock.Anything, map[string]float64{"EUR": 11.45, "NOK": 0.98}, &adminID).Return(nil).Once()
+	repo.On("Save", mock.Anything, map[string]float64{"DKK": 1.53}, (*uuid.UUID)(nil)).Return(nil).Once()
+	repo.On("FindAll", mock.Anything).Return([]currency.Rate{}, nil)
+
+	_, err := service.Load(context.Background(), strings.NewReader("currency;sek_per_unit\n# from the bank\neur;11.45\nNOK;0.98\n"), adminID.String())
+	assert.NoError(t, err)
+
+	_, err = service.Load(context.Background(), strings.NewReader(`{"DKK": 1.53}`), "")
+	assert.NoError(t, err)
+
+	repo.AssertExpectations(t)
+}
+
+func TestUpdate_RejectsInvalidRates(t *testing.T) {
+	repo := new(mockCurrency.MockRateRepo)
+	service := currency.NewRateService(repo, config.NewZerologService())
+	var rateErr *currency.RateError
+
+	_, err := service.Update(context.Background(), map[string]float64{"SEK": 1}, "")
+	assert.ErrorAs(t, err, &rateErr)
+	_, err = service.Update(context.Background(), map[string]float64{"EUR": -2}, "")
+	assert.ErrorAs(t, err, &rateErr)
+	_, err = service.Load(context.Background(), strings.NewReader("EUR,11\nNOK,lots\n"), "")
+	assert.ErrorAs(t, err, &rateErr)
+
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,167 @@
+package feeds
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	categoryServices "github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	productHandlers "github.com/hfleury/horsemarketplacebk/internal/products/handlers"
+	productServices "github.com/hfleury/horsemarketplacebk/internal/products/services"
+)
+
+// cacheFor is how long clients and proxies may reuse a feed or sitemap
+// before revalidating it.
+const cacheFor = 5 * time.Minute
+
+var (
+	// sharedCache lets proxies keep feeds whose links come from SITE_URL.
+	sharedCache = "public, max-age=" + strconv.Itoa(int(cacheFor.Seconds()))
+	// privateCache is for feeds linking to the request's own host, which a
+	// shared cache would hand to clients that asked for another one.
+	privateCache = "private, max-age=" + strconv.Itoa(int(cacheFor.Seconds()))
+)
+
+type FeedHandler struct {
+	logger  config.Logging
+	service *FeedService
+}
+
+func NewFeedHandler(logger config.Logging, service *FeedService) *FeedHandler {
+	return &FeedHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Products is the feed of a search, taking the same query parameters.
+func (h *FeedHandler) Products(c *gin.Context) {
+	format, ok := h.format(c)
+	if !ok {
+		return
+	}
+	filters, err := productHandlers.ParseProductFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		return
+	}
+	feed, err := h.service.SearchFeed(c.Request.Context(), filters, h.origin(c), c.Request.URL.RequestURI())
+	if err != nil {
+		h.respondError(c, err, "Failed to build feed")
+		return
+	}
+	h.writeFeed(c, feed, format)
+}
+
+func (h *FeedHandler) Category(c *gin.Context) {
+	format, ok := h.format(c)
+	if !ok {
+		return
+	}
+	filters, err := productHandlers.ParseProductFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		return
+	}
+	feed, err := h.service.CategoryFeed(c.Request.Context(), c.Param("id"), filters, h.origin(c), c.Request.URL.RequestURI())
+	if err != nil {
+		h.respondError(c, err, "Failed to build feed")
+		return
+	}
+	h.writeFeed(c, feed, format)
+}
+
+func (h *FeedHandler) SitemapIndex(c *gin.Context) {
+	urls, err := h.service.SitemapIndex(c.Request.Context(), h.origin(c))
+	if err != nil {
+		h.respondError(c, err, "Failed to build sitemap")
+		return
+	}
+	h.writeSitemap(c, urls, true)
+}
+
+// Sitemap serves /sitemaps/<name>.xml.
+func (h *FeedHandler) Sitemap(c *gin.Context) {
+	name, ok := strings.CutSuffix(c.Param("name"), ".xml")
+	if !ok {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(ErrSitemapNotFound.Error()))
+		return
+	}
+	urls, err := h.service.Sitemap(c.Request.Context(), name, h.origin(c))
+	if err != nil {
+		h.respondError(c, err, "Failed to build sitemap")
+		return
+	}
+	h.writeSitemap(c, urls, false)
+}
+
+func (h *FeedHandler) format(c *gin.Context) (Format, bool) {
+	format := Format(c.Param("format"))
+	switch format {
+	case FormatRSS, FormatAtom, FormatJSON:
+		return format, true
+	}
+	c.JSON(http.StatusNotFound, common.NewErrorResponse(ErrUnknownFormat.Error()))
+	return "", false
+}
+
+func (h *FeedHandler) writeFeed(c *gin.Context, feed *Feed, format Format) {
+	body, contentType, err := Render(feed, format)
+	if err != nil {
+		h.respondError(c, err, "Failed to build feed")
+		return
+	}
+	common.WriteCacheable(c, contentType, body, feed.Updated, h.cacheControl())
+}
+
+func (h *FeedHandler) writeSitemap(c *gin.Context, urls []SitemapURL, index bool) {
+	body, err := RenderSitemap(urls, index)
+	if err != nil {
+		h.respondError(c, err, "Failed to build sitemap")
+		return
+	}
+	common.WriteCacheable(c, ContentTypeXML, body, LastModified(urls), h.cacheControl())
+}
+
+// origin is the configured base URL, or the scheme and host the request
+// came in on. The fallback is for development, production requires
+// SITE_URL.
+func (h *FeedHandler) origin(c *gin.Context) string {
+	if h.service.baseURL != "" {
+		return h.service.baseURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// cacheControl only allows shared caches when the links do not depend on
+// the request's Host and X-Forwarded-Proto headers.
+func (h *FeedHandler) cacheControl() string {
+	if h.service.baseURL != "" {
+		return sharedCache
+	}
+	return privateCache
+}
+
+func (h *FeedHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, categoryServices.ErrCategoryNotFound), errors.Is(err, ErrSitemapNotFound):
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, productServices.ErrUnknownLocation), errors.Is(err, productServices.ErrInvalidSearch):
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+	default:
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, message, map[string]any{"error": err.Error(), "path": c.Request.URL.Path})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse(message))
+	}
+}
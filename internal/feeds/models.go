@@ -0,0 +1,49 @@
+package feeds
+
+import "time"
+
+type Format string
+
+const (
+	FormatRSS  Format = "rss"
+	FormatAtom Format = "atom"
+	FormatJSON Format = "json"
+)
+
+// Feed is a list of published listings, rendered as RSS, Atom or JSON Feed.
+// Links are absolute.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the page the feed mirrors, Self the feed itself
+	Link    string
+	Self    string
+	Updated time.Time
+	Items   []Item
+}
+
+type Item struct {
+	ID        string
+	Title     string
+	Link      string
+	Summary   string
+	Published time.Time
+	Updated   time.Time
+	PriceSEK  *float64
+	Type      string
+	City      string
+	Image     *Image
+}
+
+// Image is the listing's primary image.
+type Image struct {
+	URL       string
+	MimeType  string
+	SizeBytes int64
+}
+
+// SitemapURL is one <url> or <sitemap> entry.
+type SitemapURL struct {
+	Loc          string
+	LastModified time.Time
+}
@@ -0,0 +1,275 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	// publisher is the feed level author Atom requires
+	publisher = "Horse Marketplace"
+
+	ContentTypeRSS  = "application/rss+xml; charset=utf-8"
+	ContentTypeAtom = "application/atom+xml; charset=utf-8"
+	ContentTypeJSON = "application/feed+json; charset=utf-8"
+	ContentTypeXML  = "application/xml; charset=utf-8"
+)
+
+// Render encodes the feed in the given format and returns its content type.
+func Render(feed *Feed, format Format) ([]byte, string, error) {
+	switch format {
+	case FormatRSS:
+		body, err := renderRSS(feed)
+		return body, ContentTypeRSS, err
+	case FormatAtom:
+		body, err := renderAtom(feed)
+		return body, ContentTypeAtom, err
+	case FormatJSON:
+		body, err := renderJSON(feed)
+		return body, ContentTypeJSON, err
+	}
+	return nil, "", ErrUnknownFormat
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description,omitempty"`
+	PubDate     string        `xml:"pubDate"`
+	Category    string        `xml:"category,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+func renderRSS(feed *Feed) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.Link,
+			Description: feed.Description,
+			Self:        atomLink{Href: feed.Self, Rel: "self", Type: "application/rss+xml"},
+			Items:       []rssItem{},
+		},
+	}
+	if !feed.Updated.IsZero() {
+		doc.Channel.LastBuildDate = feed.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range feed.Items {
+		entry := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.Link},
+			Description: item.Summary,
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Category:    item.Type,
+		}
+		if item.Image != nil {
+			entry.Enclosure = &rssEnclosure{URL: item.Image.URL, Length: item.Image.SizeBytes, Type: item.Image.MimeType}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, entry)
+	}
+	return encodeXML(doc)
+}
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   string     `xml:"summary,omitempty"`
+	Category  *atomTerm  `xml:"category"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+func renderAtom(feed *Feed) ([]byte, error) {
+	doc := atomDocument{
+		Title:   feed.Title,
+		ID:      feed.Self,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.Link, Rel: "alternate", Type: "text/html"},
+			{Href: feed.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+		Author: atomAuthor{Name: publisher},
+	}
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			ID:        item.Link,
+			Title:     item.Title,
+			Links:     []atomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Summary:   item.Summary,
+		}
+		if item.Type != "" {
+			entry.Category = &atomTerm{Term: item.Type}
+		}
+		if item.Image != nil {
+			entry.Links = append(entry.Links, atomLink{Href: item.Image.URL, Rel: "enclosure", Type: item.Image.MimeType, Length: item.Image.SizeBytes})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return encodeXML(doc)
+}
+
+// jsonFeed follows JSON Feed 1.1. Listing details the format has no field
+// for go in the "_marketplace" extension.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Authors     []jsonAuthor   `json:"authors"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentText   string           `json:"content_text"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Tags          []string         `json:"tags,omitempty"`
+	Marketplace   *jsonMarketplace `json:"_marketplace,omitempty"`
+}
+
+type jsonMarketplace struct {
+	PriceSEK *float64 `json:"price_sek"`
+	Type     string   `json:"type"`
+	City     string   `json:"city,omitempty"`
+}
+
+func renderJSON(feed *Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     feed.Self,
+		Description: feed.Description,
+		Authors:     []jsonAuthor{{Name: publisher}},
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range feed.Items {
+		entry := jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentText:   item.Summary,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+			Marketplace:   &jsonMarketplace{PriceSEK: item.PriceSEK, Type: item.Type, City: item.City},
+		}
+		if item.Type != "" {
+			entry.Tags = []string{item.Type}
+		}
+		if item.Image != nil {
+			entry.Image = item.Image.URL
+		}
+		doc.Items = append(doc.Items, entry)
+	}
+	return json.Marshal(doc)
+}
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapLoc `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// RenderSitemap encodes a sitemap, or a sitemap index when index is set.
+func RenderSitemap(urls []SitemapURL, index bool) ([]byte, error) {
+	locs := make([]sitemapLoc, 0, len(urls))
+	for _, u := range urls {
+		loc := sitemapLoc{Loc: u.Loc}
+		if !u.LastModified.IsZero() {
+			loc.LastMod = u.LastModified.UTC().Format(time.RFC3339)
+		}
+		locs = append(locs, loc)
+	}
+	if index {
+		return encodeXML(sitemapIndex{Sitemaps: locs})
+	}
+	return encodeXML(urlSet{URLs: locs})
+}
+
+func encodeXML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, fmt.Errorf("encode feed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,280 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hfleury/horsemarketplacebk/config"
+	categoryModels "github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	productModels "github.com/hfleury/horsemarketplacebk/internal/products/models"
+	productRepos "github.com/hfleury/horsemarketplacebk/internal/products/repositories"
+)
+
+var (
+	ErrUnknownFormat   = errors.New("format must be rss, atom or json")
+	ErrSitemapNotFound = errors.New("sitemap not found")
+)
+
+const (
+	defaultFeedItems = 50
+	maxFeedItems     = 100
+	// SitemapPageSize keeps every sitemap well below the 50 000 URL limit
+	SitemapPageSize = 10000
+	summaryRunes    = 500
+)
+
+// ProductSearcher is the part of the product service the feeds are built on.
+type ProductSearcher interface {
+	Search(ctx context.Context, filters *productModels.ProductFilters, withFacets bool) (*productModels.SearchResult, error)
+}
+
+// CategoryReader is the part of the category service the feeds need.
+type CategoryReader interface {
+	GetCategory(ctx context.Context, id string) (*categoryModels.Category, error)
+	GetAllCategories(ctx context.Context) ([]*categoryModels.Category, error)
+}
+
+type FeedService struct {
+	products   ProductSearcher
+	repo       productRepos.ProductRepository
+	categories CategoryReader
+	logger     config.Logging
+	// baseURL is the origin of the public site links are built on. The site
+	// serves the pages of listings, categories and sellers and passes
+	// /feeds and /sitemaps on to the API. Without it the origin of the
+	// request is used.
+	baseURL string
+}
+
+func NewFeedService(products ProductSearcher, repo productRepos.ProductRepository, categories CategoryReader, logger config.Logging) *FeedService {
+	return &FeedService{
+		products:   products,
+		repo:       repo,
+		categories: categories,
+		logger:     logger,
+	}
+}
+
+// SetBaseURL sets the public origin of the site, e.g. https://example.com.
+func (s *FeedService) SetBaseURL(baseURL string) {
+	s.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SearchFeed returns the newest published listings matching the filters.
+// selfPath is the request path and query of the feed, relative to baseURL.
+func (s *FeedService) SearchFeed(ctx context.Context, filters *productModels.ProductFilters, baseURL, selfPath string) (*Feed, error) {
+	title := "Latest listings"
+	if filters.Query != "" {
+		title = fmt.Sprintf("Listings matching %q", filters.Query)
+	}
+	feed := &Feed{
+		Title:       title,
+		Description: "Newly published listings on " + publisher,
+		Link:        baseURL + "/products",
+		Self:        baseURL + selfPath,
+	}
+	return feed, s.fill(ctx, feed, filters, baseURL)
+}
+
+// CategoryFeed returns the newest published listings in a category, or
+// ErrCategoryNotFound from the category service.
+func (s *FeedService) CategoryFeed(ctx context.Context, categoryID string, filters *productModels.ProductFilters, baseURL, selfPath string) (*Feed, error) {
+	category, err := s.categories.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	name := ""
+	if category.Name != nil {
+		name = *category.Name
+	}
+	filters.CategoryID = categoryID
+	feed := &Feed{
+		Title:       name,
+		Description: "Newly published " + name + " listings on " + publisher,
+		Link:        baseURL + categoryPath(categoryID),
+		Self:        baseURL + selfPath,
+	}
+	return feed, s.fill(ctx, feed, filters, baseURL)
+}
+
+// fill runs the search. Feeds only ever show published listings, newest
+// first, whatever the query string asks for.
+func (s *FeedService) fill(ctx context.Context, feed *Feed, filters *productModels.ProductFilters, baseURL string) error {
+	filters.Status = productModels.StatusPublished
+	filters.Sort = productModels.SortNewest
+	filters.Offset = 0
+	if filters.Limit <= 0 {
+		filters.Limit = defaultFeedItems
+	}
+	if filters.Limit > maxFeedItems {
+		filters.Limit = maxFeedItems
+	}
+
+	result, err := s.products.Search(ctx, filters, false)
+	if err != nil {
+		return err
+	}
+	feed.Items = make([]Item, 0, len(result.Products))
+	for _, p := range result.Products {
+		if p.Status != productModels.StatusPublished {
+			continue
+		}
+		feed.Items = append(feed.Items, feedItem(p, baseURL))
+		if p.UpdatedAt.After(feed.Updated) {
+			feed.Updated = p.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func feedItem(p *productModels.Product, baseURL string) Item {
+	item := Item{
+		ID:        p.ID.String(),
+		Title:     p.Title,
+		Link:      baseURL + productPath(p.ID.String()),
+		Published: p.CreatedAt,
+		Updated:   p.UpdatedAt,
+		PriceSEK:  p.PriceSEK,
+		Type:      string(p.Type),
+	}
+	if p.PublishedAt != nil {
+		item.Published = *p.PublishedAt
+	}
+	if p.Description != nil {
+		item.Summary = summarize(*p.Description)
+	}
+	if p.City != nil {
+		item.City = *p.City
+	}
+	for _, m := range p.Media {
+		if m.IsPrimary && m.Media != nil {
+			item.Image = &Image{URL: m.Media.URL, MimeType: m.Media.MimeType, SizeBytes: m.Media.SizeBytes}
+		}
+	}
+	return item
+}
+
+// summarize cuts long descriptions at a word boundary.
+func summarize(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= summaryRunes {
+		return text
+	}
+	cut := string([]rune(text)[:summaryRunes])
+	if i := strings.LastIndexAny(cut, " \n\t"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
+
+// SitemapIndex lists the category sitemap and one sitemap per page of
+// published listings and of sellers.
+func (s *FeedService) SitemapIndex(ctx context.Context, baseURL string) ([]SitemapURL, error) {
+	categories, err := s.categoryURLs(ctx, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	index := []SitemapURL{{Loc: baseURL + "/sitemaps/categories.xml", LastModified: LastModified(categories)}}
+
+	productPages, err := s.repo.SitemapProductPages(ctx, SitemapPageSize)
+	if err != nil {
+		return nil, err
+	}
+	for i, modified := range productPages {
+		index = append(index, SitemapURL{Loc: fmt.Sprintf("%s/sitemaps/products-%d.xml", baseURL, i+1), LastModified: modified})
+	}
+
+	sellerPages, err := s.repo.SitemapSellerPages(ctx, SitemapPageSize)
+	if err != nil {
+		return nil, err
+	}
+	for i, modified := range sellerPages {
+		index = append(index, SitemapURL{Loc: fmt.Sprintf("%s/sitemaps/sellers-%d.xml", baseURL, i+1), LastModified: modified})
+	}
+	return index, nil
+}
+
+// Sitemap returns the URLs of one sitemap from the index: "categories",
+// "products-N" or "sellers-N", pages counting from 1.
+func (s *FeedService) Sitemap(ctx context.Context, name string, baseURL string) ([]SitemapURL, error) {
+	if name == "categories" {
+		return s.categoryURLs(ctx, baseURL)
+	}
+
+	kind, pageStr, ok := strings.Cut(name, "-")
+	page, err := strconv.Atoi(pageStr)
+	if !ok || err != nil || page < 1 {
+		return nil, ErrSitemapNotFound
+	}
+	offset := (page - 1) * SitemapPageSize
+
+	var entries []productModels.SitemapEntry
+	var path func(string) string
+	switch kind {
+	case "products":
+		entries, err = s.repo.SitemapProducts(ctx, offset, SitemapPageSize)
+		path = productPath
+	case "sellers":
+		entries, err = s.repo.SitemapSellers(ctx, offset, SitemapPageSize)
+		path = sellerPath
+	default:
+		return nil, ErrSitemapNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrSitemapNotFound
+	}
+
+	urls := make([]SitemapURL, 0, len(entries))
+	for _, e := range entries {
+		urls = append(urls, SitemapURL{Loc: baseURL + path(e.ID.String()), LastModified: e.LastModified})
+	}
+	return urls, nil
+}
+
+func (s *FeedService) categoryURLs(ctx context.Context, baseURL string) ([]SitemapURL, error) {
+	tree, err := s.categories.GetAllCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var urls []SitemapURL
+	var walk func([]*categoryModels.Category)
+	walk = func(categories []*categoryModels.Category) {
+		for _, c := range categories {
+			if c.Id == nil {
+				continue
+			}
+			u := SitemapURL{Loc: baseURL + categoryPath(c.Id.String())}
+			if c.UpdatedAt != nil {
+				u.LastModified = *c.UpdatedAt
+			}
+			urls = append(urls, u)
+			walk(c.SubCategories)
+		}
+	}
+	walk(tree)
+	return urls, nil
+}
+
+// LastModified is the newest modification time among the urls.
+func LastModified(urls []SitemapURL) time.Time {
+	var t time.Time
+	for _, u := range urls {
+		if u.LastModified.After(t) {
+			t = u.LastModified
+		}
+	}
+	return t
+}
+
+// The pages of the public site, see FeedService.baseURL.
+func productPath(id string) string  { return "/products/" + id }
+func categoryPath(id string) string { return "/categories/" + id }
+func sellerPath(id string) string   { return "/sellers/" + id }
@@ -0,0 +1,203 @@
+package feeds_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	categoryModels "github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	categoryServices "github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	"github.com/hfleury/horsemarketplacebk/internal/feeds"
+	"github.com/hfleury/horsemarketplacebk/internal/media"
+	mockCategories "github.com/hfleury/horsemarketplacebk/internal/mocks/categories"
+	mockProducts "github.com/hfleury/horsemarketplacebk/internal/mocks/products"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeSearch returns its products and keeps the filters it was called with.
+type fakeSearch struct {
+	products []*models.Product
+	filters  *models.ProductFilters
+}
+
+func (f *fakeSearch) Search(ctx context.Context, filters *models.ProductFilters, withFacets bool) (*models.SearchResult, error) {
+	f.filters = filters
+	return &models.SearchResult{Products: f.products}, nil
+}
+
+func newFeedService(search *fakeSearch, repo *mockProducts.MockProductRepo, categoryRepo *mockCategories.MockCategoryRepository) *feeds.FeedService {
+	logger := config.NewZerologService()
+	return feeds.NewFeedService(search, repo, categoryServices.NewCategoryService(categoryRepo, logger), logger)
+}
+
+func listing(title string, status models.ProductStatus, updated time.Time) *models.Product {
+	price := 85000.0
+	description := "Calm and brave"
+	return &models.Product{
+		ID: uuid.New(), Type: models.TypeHorse, Status: status, Title: title, PriceSEK: &price, Description: &description,
+		CreatedAt: updated, UpdatedAt: updated,
+	}
+}
+
+func TestSearchFeed_PublishedListingsNewestFirst(t *testing.T) {
+	updated := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	bella := listing("Bella", models.StatusPublished, updated)
+	bella.Media = []models.ProductMedia{{IsPrimary: true, Media: &media.Media{URL: "https://cdn.example.com/bella.jpg", MimeType: "image/jpeg", SizeBytes: 1234}}}
+	search := &fakeSearch{products: []*models.Product{bella, listing("Draft", models.StatusDraft, updated.Add(time.Hour))}}
+	service := newFeedService(search, nil, nil)
+
+	feed, err := service.SearchFeed(context.Background(), &models.ProductFilters{Query: "bella", Status: models.StatusDraft, Limit: 500}, "https://example.com", "/feeds/products/rss?q=bella")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusPublished, search.filters.Status)
+	assert.Equal(t, models.SortNewest, search.filters.Sort)
+	assert.Equal(t, 100, search.filters.Limit)
+	if assert.Len(t, feed.Items, 1) {
+		assert.Equal(t, "https://example.com/products/"+bella.ID.String(), feed.Items[0].Link)
+	}
+	assert.Equal(t, updated, feed.Updated)
+
+	rss, contentType, err := feeds.Render(feed, feeds.FormatRSS)
+	assert.NoError(t, err)
+	assert.Equal(t, feeds.ContentTypeRSS, contentType)
+	var doc struct {
+		Items []struct {
+			Title     string `xml:"title"`
+			Enclosure struct {
+				URL    string `xml:"url,attr"`
+				Length int64  `xml:"length,attr"`
+			} `xml:"enclosure"`
+		} `xml:"channel>item"`
+	}
+	assert.NoError(t, xml.Unmarshal(rss, &doc))
+	if assert.Len(t, doc.Items, 1) {
+		assert.Equal(t, "Bella", doc.Items[0].Title)
+		assert.Equal(t, int64(1234), doc.Items[0].Enclosure.Length)
+	}
+
+	atom, _, err := feeds.Render(feed, feeds.FormatAtom)
+	assert.NoError(t, err)
+	assert.Contains(t, string(atom), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, string(atom), `<updated>2026-05-01T12:00:00Z</updated>`)
+
+	body, _, err := feeds.Render(feed, feeds.FormatJSON)
+	assert.NoError(t, err)
+	var jsonFeed map[string]any
+	assert.NoError(t, json.Unmarshal(body, &jsonFeed))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", jsonFeed["version"])
+	item := jsonFeed["items"].([]any)[0].(map[string]any)
+	assert.Equal(t, 85000.0, item["_marketplace"].(map[string]any)["price_sek"])
+}
+
+func TestCategoryFeed_UnknownCategory(t *testing.T) {
+	categoryRepo := new(mockCategories.MockCategoryRepository)
+	id := uuid.New().String()
+	categoryRepo.On("FindByID", mock.Anything, id).Return(nil, nil)
+	service := newFeedService(&fakeSearch{}, nil, categoryRepo)
+
+	_, err := service.CategoryFeed(context.Background(), id, &models.ProductFilters{}, "https://example.com", "/feeds/categories/"+id+"/rss")
+
+	assert.ErrorIs(t, err, categoryServices.ErrCategoryNotFound)
+}
+
+func TestSitemaps(t *testing.T) {
+	repo := new(mockProducts.MockProductRepo)
+	categoryRepo := new(mockCategories.MockCategoryRepository)
+	service := newFeedService(&fakeSearch{}, repo, categoryRepo)
+
+	parentID, childID := uuid.New(), uuid.New()
+	parentUpdated, childUpdated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	categoryRepo.On("FindAll", mock.Anything).Return([]*categoryModels.Category{
+		{Id: &parentID, UpdatedAt: &parentUpdated},
+		{Id: &childID, ParentID: &parentID, UpdatedAt: &childUpdated},
+	}, nil)
+	pageOne, pageTwo := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	repo.On("SitemapProductPages", mock.Anything, feeds.SitemapPageSize).Return([]time.Time{pageOne, pageTwo}, nil)
+	repo.On("SitemapSellerPages", mock.Anything, feeds.SitemapPageSize).Return([]time.Time{pageOne}, nil)
+
+	index, err := service.SitemapIndex(context.Background(), "https://example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []feeds.SitemapURL{
+		{Loc: "https://example.com/sitemaps/categories.xml", LastModified: childUpdated},
+		{Loc: "https://example.com/sitemaps/products-1.xml", LastModified: pageOne},
+		{Loc: "https://example.com/sitemaps/products-2.xml", LastModified: pageTwo},
+		{Loc: "https://example.com/sitemaps/sellers-1.xml", LastModified: pageOne},
+	}, index)
+
+	productID := uuid.New()
+	repo.On("SitemapProducts", mock.Anything, feeds.SitemapPageSize, feeds.SitemapPageSize).Return([]models.SitemapEntry{{ID: productID, LastModified: pageTwo}}, nil)
+	urls, err := service.Sitemap(context.Background(), "products-2", "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []feeds.SitemapURL{{Loc: "https://example.com/products/" + productID.String(), LastModified: pageTwo}}, urls)
+
+	categories, err := service.Sitemap(context.Background(), "categories", "https://example.com")
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+
+	repo.On("SitemapSellers", mock.Anything, feeds.SitemapPageSize, feeds.SitemapPageSize).Return(nil, nil)
+	_, err = service.Sitemap(context.Background(), "sellers-2", "https://example.com")
+	assert.ErrorIs(t, err, feeds.ErrSitemapNotFound)
+	_, err = service.Sitemap(context.Background(), "products-0", "https://example.com")
+	assert.ErrorIs(t, err, feeds.ErrSitemapNotFound)
+}
+
+func TestFeedHandler_Revalidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	updated := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	service := newFeedService(&fakeSearch{products: []*models.Product{listing("Bella", models.StatusPublished, updated)}}, nil, nil)
+	service.SetBaseURL("https://example.com/")
+	router := gin.New()
+	router.GET("/feeds/products/:format", feeds.NewFeedHandler(config.NewZerologService(), service).Products)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feeds/products/atom", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Fri, 01 May 2026 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	assert.True(t, strings.Contains(w.Body.String(), "https://example.com/feeds/products/atom"))
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds/products/atom", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/feeds/products/atom", nil)
+	req.Header.Set("If-Modified-Since", "Fri, 01 May 2026 12:00:00 GMT")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feeds/products/csv", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFeedHandler_RequestOriginNotShared(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := newFeedService(&fakeSearch{products: []*models.Product{listing("Bella", models.StatusPublished, time.Now())}}, nil, nil)
+	router := gin.New()
+	router.GET("/feeds/products/:format", feeds.NewFeedHandler(config.NewZerologService(), service).Products)
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds/products/rss", nil)
+	req.Host = "evil.example"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "http://evil.example/products/"))
+	assert.Equal(t, "private, max-age=300", w.Header().Get("Cache-Control"))
+}
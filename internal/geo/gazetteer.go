@@ -0,0 +1,185 @@
+package geo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//go:embed places_se.csv
+var placesCSV string
+
+// EarthRadiusKM is the mean Earth radius used for distance calculations.
+const EarthRadiusKM = 6371.0
+
+// Place is a town from the bundled gazetteer.
+type Place struct {
+	Name string  `json:"name"`
+	Area string  `json:"area"` // County (län)
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+}
+
+// Gazetteer resolves Swedish place names and postal codes to coordinates
+// without calling an external geocoder.
+type Gazetteer struct {
+	byName   map[string]*Place
+	byPostal map[string]*Place
+	areas    map[string]string
+	// postalWidth keeps the size of the range a postal prefix came from, so
+	// a narrow range (Södertälje 151-152) beats a wide one (Stockholm 100-199).
+	postalWidth map[string]int
+}
+
+// NewGazetteer loads the gazetteer embedded in the binary.
+func NewGazetteer() (*Gazetteer, error) {
+	return parseGazetteer(placesCSV)
+}
+
+func parseGazetteer(data string) (*Gazetteer, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read gazetteer: %w", err)
+	}
+
+	g := &Gazetteer{
+		byName:      make(map[string]*Place),
+		byPostal:    make(map[string]*Place),
+		areas:       make(map[string]string),
+		postalWidth: make(map[string]int),
+	}
+
+	for i, rec := range records {
+		if i == 0 {
+			continue // header
+		}
+		if len(rec) != 6 {
+			return nil, fmt.Errorf("gazetteer line %d: expected 6 fields, got %d", i+1, len(rec))
+		}
+		lat, err := strconv.ParseFloat(rec[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid lat: %w", i+1, err)
+		}
+		lng, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: invalid lng: %w", i+1, err)
+		}
+
+		p := &Place{Name: rec[0], Area: rec[1], Lat: lat, Lng: lng}
+		g.byName[fold(p.Name)] = p
+		for _, alias := range strings.Split(rec[5], "|") {
+			if alias != "" {
+				g.byName[fold(alias)] = p
+			}
+		}
+		g.addArea(p.Area)
+
+		if err := g.addPostalRange(rec[4], p); err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: %w", i+1, err)
+		}
+	}
+
+	return g, nil
+}
+
+func (g *Gazetteer) addArea(area string) {
+	short := strings.TrimSuffix(area, " län")
+	for _, key := range []string{area, short, strings.TrimSuffix(short, "s")} {
+		g.areas[fold(key)] = area
+	}
+}
+
+// addPostalRange registers a three digit postal prefix ("751") or an
+// inclusive range of them ("750-757").
+func (g *Gazetteer) addPostalRange(spec string, p *Place) error {
+	from, to, found := strings.Cut(spec, "-")
+	if !found {
+		to = from
+	}
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return fmt.Errorf("invalid postal prefix %q", spec)
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil || end < start {
+		return fmt.Errorf("invalid postal prefix %q", spec)
+	}
+
+	width := end - start
+	for n := start; n <= end; n++ {
+		key := strconv.Itoa(n)
+		if existing, ok := g.postalWidth[key]; ok && existing <= width {
+			continue
+		}
+		g.byPostal[key] = p
+		g.postalWidth[key] = width
+	}
+	return nil
+}
+
+// Lookup finds a place by name, ignoring case, surrounding whitespace and
+// Swedish diacritics. It returns nil when the place is unknown.
+func (g *Gazetteer) Lookup(name string) *Place {
+	return g.byName[fold(name)]
+}
+
+// LookupPostalCode finds the place a Swedish postal code ("753 20", "75320")
+// belongs to, using its three digit prefix.
+func (g *Gazetteer) LookupPostalCode(code string) *Place {
+	digits := strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(digits) != 5 {
+		return nil
+	}
+	if _, err := strconv.Atoi(digits); err != nil {
+		return nil
+	}
+	return g.byPostal[digits[:3]]
+}
+
+// NormalizeArea maps free text such as "skane" or "Stockholms" to the
+// canonical county name. The input is returned unchanged when unknown.
+func (g *Gazetteer) NormalizeArea(area string) (string, bool) {
+	if canonical, ok := g.areas[fold(area)]; ok {
+		return canonical, true
+	}
+	return strings.TrimSpace(area), false
+}
+
+// Resolve picks the best place for a listing: the city name first, then the
+// postal code. byName reports whether the city name itself matched; a
+// postal code only locates the listing near the town covering its prefix.
+func (g *Gazetteer) Resolve(city, postalCode string) (place *Place, byName bool) {
+	if city != "" {
+		if p := g.Lookup(city); p != nil {
+			return p, true
+		}
+	}
+	if postalCode != "" {
+		return g.LookupPostalCode(postalCode), false
+	}
+	return nil, false
+}
+
+// DistanceKM is the great-circle distance between two points.
+func DistanceKM(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKM * math.Asin(math.Sqrt(a))
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+var foldReplacer = strings.NewReplacer(
+	"å", "a", "ä", "a", "ö", "o", "é", "e", "ü", "u", "æ", "a", "ø", "o",
+)
+
+func fold(s string) string {
+	return foldReplacer.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
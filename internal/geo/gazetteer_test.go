@@ -0,0 +1,76 @@
+package geo_test
+
+import (
+	"testing"
+
+	"github.com/hfleury/horsemarketplacebk/internal/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGazetteer_Lookup(t *testing.T) {
+	g, err := geo.NewGazetteer()
+	assert.NoError(t, err)
+
+	p := g.Lookup("  goteborg ")
+	assert.NotNil(t, p)
+	assert.Equal(t, "Göteborg", p.Name)
+	assert.Equal(t, "Västra Götalands län", p.Area)
+
+	p = g.Lookup("Gothenburg")
+	assert.NotNil(t, p)
+	assert.Equal(t, "Göteborg", p.Name)
+
+	assert.Nil(t, g.Lookup("Atlantis"))
+}
+
+func TestGazetteer_LookupPostalCode(t *testing.T) {
+	g, err := geo.NewGazetteer()
+	assert.NoError(t, err)
+
+	p := g.LookupPostalCode("753 20")
+	assert.NotNil(t, p)
+	assert.Equal(t, "Uppsala", p.Name)
+
+	// Narrow ranges win over the wide Stockholm range
+	p = g.LookupPostalCode("15132")
+	assert.NotNil(t, p)
+	assert.Equal(t, "Södertälje", p.Name)
+
+	p = g.LookupPostalCode("11122")
+	assert.NotNil(t, p)
+	assert.Equal(t, "Stockholm", p.Name)
+
+	assert.Nil(t, g.LookupPostalCode("abc"))
+	assert.Nil(t, g.LookupPostalCode("1234"))
+}
+
+func TestGazetteer_NormalizeArea(t *testing.T) {
+	g, err := geo.NewGazetteer()
+	assert.NoError(t, err)
+
+	for _, in := range []string{"Skåne", "skane", "Skåne län"} {
+		area, ok := g.NormalizeArea(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, "Skåne län", area)
+	}
+
+	area, ok := g.NormalizeArea("Stockholm")
+	assert.True(t, ok)
+	assert.Equal(t, "Stockholms län", area)
+
+	area, ok = g.NormalizeArea(" Middle Earth ")
+	assert.False(t, ok)
+	assert.Equal(t, "Middle Earth", area)
+}
+
+func TestDistanceKM(t *testing.T) {
+	g, err := geo.NewGazetteer()
+	assert.NoError(t, err)
+
+	stockholm := g.Lookup("Stockholm")
+	uppsala := g.Lookup("Uppsala")
+
+	d := geo.DistanceKM(stockholm.Lat, stockholm.Lng, uppsala.Lat, uppsala.Lng)
+	assert.InDelta(t, 64, d, 3)
+	assert.Zero(t, geo.DistanceKM(uppsala.Lat, uppsala.Lng, uppsala.Lat, uppsala.Lng))
+}
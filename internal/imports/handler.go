@@ -0,0 +1,138 @@
+package imports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+)
+
+const (
+	maxFeedBytes    = 10 << 20
+	maxArchiveBytes = 50 << 20
+)
+
+type ImportHandler struct {
+	logger  config.Logging
+	service *ImportService
+}
+
+func NewImportHandler(logger config.Logging, service *ImportService) *ImportHandler {
+	return &ImportHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Submit accepts a multipart form with the feed in "file", an optional zip
+// of images in "archive", the mapping profile as JSON in "mapping", the
+// format when the file name does not tell it and "dry_run".
+func (h *ImportHandler) Submit(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("A feed file is required"))
+		return
+	}
+	source, err := readPart(header, maxFeedBytes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		return
+	}
+
+	req := &SubmitRequest{Source: source, Format: Format(strings.ToLower(c.PostForm("format")))}
+	if req.Format == "" {
+		req.Format = Format(strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), "."))
+	}
+	if req.Format != FormatCSV && req.Format != FormatXML {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("format must be csv or xml"))
+		return
+	}
+	if v := c.PostForm("dry_run"); v != "" {
+		if req.DryRun, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("dry_run must be true or false"))
+			return
+		}
+	}
+	if v := c.PostForm("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("mapping must be a JSON mapping profile"))
+			return
+		}
+	}
+	if archive, err := c.FormFile("archive"); err == nil {
+		if req.Archive, err = readPart(archive, maxArchiveBytes); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	imp, err := h.service.Submit(c.Request.Context(), c.GetString("user_id"), req)
+	if err != nil {
+		h.respondError(c, err, "Failed to submit import")
+		return
+	}
+	c.JSON(http.StatusAccepted, common.NewSuccessResponse(imp))
+}
+
+func (h *ImportHandler) List(c *gin.Context) {
+	imports, err := h.service.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to list imports")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(imports))
+}
+
+func (h *ImportHandler) Get(c *gin.Context) {
+	imp, err := h.service.Get(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondError(c, err, "Failed to load import")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(imp))
+}
+
+func (h *ImportHandler) Apply(c *gin.Context) {
+	imp, err := h.service.Apply(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to apply import")
+		return
+	}
+	c.JSON(http.StatusAccepted, common.NewSuccessResponse(imp))
+}
+
+func (h *ImportHandler) respondError(c *gin.Context, err error, message string) {
+	var feedErr *FeedError
+	switch {
+	case errors.As(err, &feedErr):
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, ErrImportNotFound):
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, ErrNotApplicable):
+		c.JSON(http.StatusConflict, common.NewErrorResponse(err.Error()))
+	default:
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, message, map[string]any{"error": err.Error(), "id": c.Param("id")})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse(message))
+	}
+}
+
+func readPart(header *multipart.FileHeader, limit int64) ([]byte, error) {
+	if header.Size > limit {
+		return nil, fmt.Errorf("%s is larger than %d MB", header.Filename, limit>>20)
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s could not be read", header.Filename)
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, limit))
+}
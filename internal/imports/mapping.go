@@ -0,0 +1,335 @@
+package imports
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+const (
+	defaultImageSeparator = "|"
+	maxExternalIDLength   = 100
+	attributePrefix       = "attr."
+)
+
+// setter stores a non-empty feed value on the listing.
+type setter func(p *models.Product, v string) error
+
+// fields are the listing fields a feed can fill, besides external_id,
+// type, images and attr.<key> which are handled separately.
+var fields = map[string]setter{
+	"title":            func(p *models.Product, v string) error { p.Title = v; return nil },
+	"status":           setStatus,
+	"category_id":      setCategory,
+	"price_sek":        floatField(func(p *models.Product) **float64 { return &p.PriceSEK }),
+	"description":      stringField(func(p *models.Product) **string { return &p.Description }),
+	"city":             stringField(func(p *models.Product) **string { return &p.City }),
+	"area":             stringField(func(p *models.Product) **string { return &p.Area }),
+	"postal_code":      stringField(func(p *models.Product) **string { return &p.PostalCode }),
+	"transaction_type": stringField(func(p *models.Product) **string { return &p.TransactionType }),
+
+	"horse.name":            stringField(func(p *models.Product) **string { return &horseData(p).Name }),
+	"horse.age":             intField(func(p *models.Product) **int { return &horseData(p).Age }),
+	"horse.year_of_birth":   intField(func(p *models.Product) **int { return &horseData(p).YearOfBirth }),
+	"horse.gender":          stringField(func(p *models.Product) **string { return &horseData(p).Gender }),
+	"horse.height":          intField(func(p *models.Product) **int { return &horseData(p).Height }),
+	"horse.breed":           stringField(func(p *models.Product) **string { return &horseData(p).Breed }),
+	"horse.color":           stringField(func(p *models.Product) **string { return &horseData(p).Color }),
+	"horse.dressage_level":  stringField(func(p *models.Product) **string { return &horseData(p).DressageLevel }),
+	"horse.jump_level":      stringField(func(p *models.Product) **string { return &horseData(p).JumpLevel }),
+	"horse.orientation":     stringField(func(p *models.Product) **string { return &horseData(p).Orientation }),
+	"horse.ueln":            stringField(func(p *models.Product) **string { return &horseData(p).UELN }),
+	"horse.microchip":       stringField(func(p *models.Product) **string { return &horseData(p).Microchip }),
+	"horse.passport_issuer": stringField(func(p *models.Product) **string { return &horseData(p).PassportIssuer }),
+
+	"vehicle.make":         stringField(func(p *models.Product) **string { return &vehicleData(p).Make }),
+	"vehicle.model":        stringField(func(p *models.Product) **string { return &vehicleData(p).Model }),
+	"vehicle.year":         intField(func(p *models.Product) **int { return &vehicleData(p).Year }),
+	"vehicle.load_weight":  intField(func(p *models.Product) **int { return &vehicleData(p).LoadWeight }),
+	"vehicle.total_weight": intField(func(p *models.Product) **int { return &vehicleData(p).TotalWeight }),
+	"vehicle.condition":    stringField(func(p *models.Product) **string { return &vehicleData(p).Condition }),
+
+	"equipment.make":       stringField(func(p *models.Product) **string { return &equipmentData(p).Make }),
+	"equipment.model":      stringField(func(p *models.Product) **string { return &equipmentData(p).Model }),
+	"equipment.size":       stringField(func(p *models.Product) **string { return &equipmentData(p).Size }),
+	"equipment.condition":  stringField(func(p *models.Product) **string { return &equipmentData(p).Condition }),
+	"equipment.sub_type":   stringField(func(p *models.Product) **string { return &equipmentData(p).SubType }),
+	"equipment.boom_width": stringField(func(p *models.Product) **string { return &equipmentData(p).BoomWidth }),
+
+	"property.land_area_ha":    floatField(func(p *models.Product) **float64 { return &propertyData(p).LandAreaHa }),
+	"property.stall_count":     intField(func(p *models.Product) **int { return &propertyData(p).StallCount }),
+	"property.paddock_count":   intField(func(p *models.Product) **int { return &propertyData(p).PaddockCount }),
+	"property.arena_width_m":   intField(func(p *models.Product) **int { return &propertyData(p).ArenaWidthM }),
+	"property.arena_length_m":  intField(func(p *models.Product) **int { return &propertyData(p).ArenaLengthM }),
+	"property.living_space_m2": intField(func(p *models.Product) **int { return &propertyData(p).LivingSpaceM2 }),
+
+	"service.service_kind":       stringField(func(p *models.Product) **string { return &serviceData(p).Kind }),
+	"service.coverage_areas":     listField(func(p *models.Product) *[]string { return &serviceData(p).CoverageAreas }),
+	"service.coverage_radius_km": intField(func(p *models.Product) **int { return &serviceData(p).CoverageRadiusKM }),
+	"service.pricing_unit":       stringField(func(p *models.Product) **string { return &serviceData(p).PricingUnit }),
+	"service.available_days":     listField(func(p *models.Product) *[]string { return &serviceData(p).AvailableDays }),
+	"service.availability_note":  stringField(func(p *models.Product) **string { return &serviceData(p).AvailabilityNote }),
+}
+
+var productTypes = []models.ProductType{models.TypeHorse, models.TypeVehicle, models.TypeEquipment, models.TypeProperty, models.TypeService}
+
+// Validate rejects mappings naming fields that do not exist.
+func (m *Mapping) Validate() error {
+	for _, names := range []map[string]string{m.Columns, m.Defaults} {
+		keys := make([]string, 0, len(names))
+		for k := range names {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, field := range keys {
+			if !knownField(field) {
+				return fmt.Errorf("unknown field %q in mapping", field)
+			}
+		}
+	}
+	for field, column := range m.Columns {
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("field %q is mapped to an empty column", field)
+		}
+	}
+	return nil
+}
+
+func knownField(field string) bool {
+	switch field {
+	case "external_id", "type", "images":
+		return true
+	}
+	if key, ok := strings.CutPrefix(field, attributePrefix); ok {
+		return key != ""
+	}
+	_, ok := fields[field]
+	return ok
+}
+
+func (m *Mapping) column(field string) string {
+	if c, ok := m.Columns[field]; ok {
+		return c
+	}
+	return field
+}
+
+// value reads a field from the row, falling back to the profile's default.
+func (m *Mapping) value(rec record, field string) string {
+	if v := rec.value(m.column(field)); v != "" {
+		return v
+	}
+	return strings.TrimSpace(m.Defaults[field])
+}
+
+// row is a feed row turned into a listing.
+type row struct {
+	product *models.Product
+	// images are URLs or archive file names, nil when the row lists none
+	images []string
+}
+
+// listing builds the seller's listing from a feed row.
+func (m *Mapping) listing(rec record, userID uuid.UUID) (*row, *RowError) {
+	externalID := m.value(rec, "external_id")
+	fail := func(field, message string) (*row, *RowError) {
+		return nil, &RowError{Row: rec.row, ExternalID: externalID, Field: field, Message: message}
+	}
+
+	if externalID == "" {
+		return fail("external_id", "is required")
+	}
+	if len(externalID) > maxExternalIDLength {
+		return fail("external_id", fmt.Sprintf("must be at most %d characters", maxExternalIDLength))
+	}
+
+	p := &models.Product{UserID: userID, ExternalID: &externalID, Type: models.ProductType(strings.ToLower(m.value(rec, "type")))}
+	if !validType(p.Type) {
+		return fail("type", "must be one of horse, vehicle, equipment, property or service")
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		v := m.value(rec, field)
+		if v == "" {
+			continue
+		}
+		if block, _, nested := strings.Cut(field, "."); nested && block != string(p.Type) {
+			return fail(field, "does not apply to "+string(p.Type)+" listings")
+		}
+		if err := fields[field](p, v); err != nil {
+			return fail(field, err.Error())
+		}
+	}
+	if p.Title == "" {
+		return fail("title", "is required")
+	}
+
+	// Attributes named in the mapping, plus attr.<key> columns of the feed
+	attrFields := map[string]bool{}
+	for field := range m.Columns {
+		if strings.HasPrefix(field, attributePrefix) {
+			attrFields[field] = true
+		}
+	}
+	for field := range m.Defaults {
+		if strings.HasPrefix(field, attributePrefix) {
+			attrFields[field] = true
+		}
+	}
+	for column := range rec.values {
+		if strings.HasPrefix(column, attributePrefix) {
+			attrFields[column] = true
+		}
+	}
+	for field := range attrFields {
+		if v := m.value(rec, field); v != "" {
+			if p.Attributes == nil {
+				p.Attributes = map[string]any{}
+			}
+			p.Attributes[strings.TrimPrefix(field, attributePrefix)] = v
+		}
+	}
+
+	return &row{product: p, images: m.imageRefs(rec)}, nil
+}
+
+// imageRefs lists the row's images in order, without duplicates.
+func (m *Mapping) imageRefs(rec record) []string {
+	sep := m.ImageSeparator
+	if sep == "" {
+		sep = defaultImageSeparator
+	}
+	var refs []string
+	seen := map[string]bool{}
+	for _, v := range rec.values[m.column("images")] {
+		for _, ref := range strings.Split(v, sep) {
+			ref = strings.TrimSpace(ref)
+			if ref != "" && !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+func validType(t models.ProductType) bool {
+	for _, known := range productTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func setStatus(p *models.Product, v string) error {
+	// Status only matters for new listings, imports never change it afterwards
+	switch models.ProductStatus(strings.ToLower(v)) {
+	case models.StatusPublished:
+		p.Status = models.StatusPublished
+	case models.StatusDraft:
+		p.Status = models.StatusDraft
+	default:
+		return fmt.Errorf("must be published or draft")
+	}
+	return nil
+}
+
+func setCategory(p *models.Product, v string) error {
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return fmt.Errorf("must be a category id")
+	}
+	p.CategoryID = &id
+	return nil
+}
+
+func stringField(get func(p *models.Product) **string) setter {
+	return func(p *models.Product, v string) error {
+		*get(p) = &v
+		return nil
+	}
+}
+
+func intField(get func(p *models.Product) **int) setter {
+	return func(p *models.Product, v string) error {
+		n, err := strconv.Atoi(strings.ReplaceAll(v, " ", ""))
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		*get(p) = &n
+		return nil
+	}
+}
+
+// floatField accepts decimal commas and spaces as thousands separators,
+// which Swedish exports use.
+func floatField(get func(p *models.Product) **float64) setter {
+	return func(p *models.Product, v string) error {
+		v = strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(v)
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		*get(p) = &f
+		return nil
+	}
+}
+
+// listField splits comma separated values.
+func listField(get func(p *models.Product) *[]string) setter {
+	return func(p *models.Product, v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*get(p) = list
+		return nil
+	}
+}
+
+func horseData(p *models.Product) *models.Horse {
+	if p.Horse == nil {
+		p.Horse = &models.Horse{}
+	}
+	return p.Horse
+}
+
+func vehicleData(p *models.Product) *models.Vehicle {
+	if p.Vehicle == nil {
+		p.Vehicle = &models.Vehicle{}
+	}
+	return p.Vehicle
+}
+
+func equipmentData(p *models.Product) *models.Equipment {
+	if p.Equipment == nil {
+		p.Equipment = &models.Equipment{}
+	}
+	return p.Equipment
+}
+
+func propertyData(p *models.Product) *models.Property {
+	if p.Property == nil {
+		p.Property = &models.Property{}
+	}
+	return p.Property
+}
+
+func serviceData(p *models.Product) *models.Service {
+	if p.Service == nil {
+		p.Service = &models.Service{}
+	}
+	return p.Service
+}
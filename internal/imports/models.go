@@ -0,0 +1,73 @@
+package imports
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Format string
+
+const (
+	FormatCSV Format = "csv"
+	FormatXML Format = "xml"
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// Mapping is the column-mapping profile of a dealer's feed. Fields the
+// mapping leaves out are read from the column with the field's own name,
+// so feeds using our field names need no mapping at all.
+type Mapping struct {
+	// Columns maps a listing field, e.g. price_sek, horse.breed or
+	// attr.height_cm, to the CSV header or XML element holding it.
+	Columns map[string]string `json:"columns,omitempty"`
+	// Defaults are used when a row leaves the field empty, e.g. {"type": "horse"}.
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// ImageSeparator splits a column listing several images, "|" by default.
+	ImageSeparator string `json:"image_separator,omitempty"`
+}
+
+// Import is an uploaded feed and the outcome of processing it.
+type Import struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Format     Format     `json:"format"`
+	Status     Status     `json:"status"`
+	DryRun     bool       `json:"dry_run"`
+	Mapping    Mapping    `json:"mapping"`
+	TotalRows  int        `json:"total_rows"`
+	Created    int        `json:"created"`
+	Updated    int        `json:"updated"`
+	Failed     int        `json:"failed"`
+	Errors     []RowError `json:"errors,omitempty"`
+	Failure    *string    `json:"failure,omitempty"` // why the whole feed could not be processed
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// RowError explains why a row of the feed was not imported.
+type RowError struct {
+	Row        int    `json:"row"` // 1 for the first listing of the feed
+	ExternalID string `json:"external_id,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Message    string `json:"message"`
+}
+
+// SubmitRequest is a feed uploaded for import.
+type SubmitRequest struct {
+	Format  Format
+	Mapping Mapping
+	DryRun  bool
+	Source  []byte
+	// Archive is an optional zip of the images the feed refers to by file name
+	Archive []byte
+}
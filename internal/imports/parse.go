@@ -0,0 +1,208 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxRows is the number of listings a single feed may hold.
+const MaxRows = 5000
+
+// record is one listing of a feed by column name. XML elements may repeat,
+// so a column can hold several values.
+type record struct {
+	row    int
+	values map[string][]string
+}
+
+func (r record) value(column string) string {
+	if v := r.values[column]; len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+// parseFeed reads every listing of the feed.
+func parseFeed(format Format, source []byte) ([]record, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(source)
+	case FormatXML:
+		return parseXML(source)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// parseCSV reads a CSV feed whose first line holds the column names. Both
+// comma and semicolon separated files are accepted, as spreadsheet programs
+// with a Swedish locale export the latter.
+func parseCSV(source []byte) ([]record, error) {
+	source = bytes.TrimPrefix(source, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(source) {
+		return nil, errors.New("the file must be UTF-8 encoded")
+	}
+
+	r := csv.NewReader(bytes.NewReader(source))
+	firstLine, _, _ := bytes.Cut(source, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	var records []record
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if isBlank(fields) {
+			continue
+		}
+		if len(records) == MaxRows {
+			return nil, fmt.Errorf("a feed may hold at most %d listings", MaxRows)
+		}
+		rec := record{row: len(records) + 1, values: make(map[string][]string, len(header))}
+		for i, name := range header {
+			if i < len(fields) && name != "" {
+				rec.values[name] = []string{fields[i]}
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func isBlank(fields []string) bool {
+	for _, f := range fields {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseXML reads the XML feed format:
+//
+//	<listings>
+//	  <listing>
+//	    <external_id>A-100</external_id>
+//	    <type>horse</type>
+//	    <title>Bella</title>
+//	    <price_sek>85000</price_sek>
+//	    <horse><breed>Swedish Warmblood</breed><ueln>752004001234567</ueln></horse>
+//	    <attributes><attribute key="height_cm">165</attribute></attributes>
+//	    <images><image>https://dealer.example/bella-1.jpg</image><image>bella-2.jpg</image></images>
+//	  </listing>
+//	</listings>
+//
+// Nested elements are named by their path, horse.breed above. The children
+// of attributes are named attr.<key> and every image becomes a value of the
+// images column, so the field names double as column names.
+func parseXML(source []byte) ([]record, error) {
+	dec := xml.NewDecoder(bytes.NewReader(source))
+
+	var (
+		records []record
+		current *record
+		path    []string
+		text    strings.Builder
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if current == nil {
+				if t.Name.Local == "listing" {
+					if len(records) == MaxRows {
+						return nil, fmt.Errorf("a feed may hold at most %d listings", MaxRows)
+					}
+					current = &record{row: len(records) + 1, values: map[string][]string{}}
+					path = path[:0]
+				}
+				continue
+			}
+			name := t.Name.Local
+			if len(path) == 1 && path[0] == "attributes" {
+				name = "attr." + attrValue(t, "key")
+			}
+			path = append(path, name)
+			text.Reset()
+
+		case xml.CharData:
+			if current != nil {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			if current == nil {
+				continue
+			}
+			if len(path) == 0 {
+				// </listing>
+				records = append(records, *current)
+				current = nil
+				continue
+			}
+			column := xmlColumn(path)
+			if value := strings.TrimSpace(text.String()); value != "" {
+				current.values[column] = append(current.values[column], value)
+			}
+			text.Reset()
+			path = path[:len(path)-1]
+		}
+	}
+
+	if current != nil {
+		return nil, errors.New("invalid XML: unterminated listing")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("the feed holds no listing elements")
+	}
+	return records, nil
+}
+
+func xmlColumn(path []string) string {
+	switch {
+	case len(path) == 2 && path[0] == "images":
+		return "images"
+	case len(path) == 2 && path[0] == "attributes":
+		return path[1]
+	}
+	return strings.Join(path, ".")
+}
+
+func attrValue(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package imports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/db"
+)
+
+type ImportRepository interface {
+	// Create stores a queued import with its feed and optional image archive.
+	Create(ctx context.Context, imp *Import, source, archive []byte) error
+	FindByID(ctx context.Context, id string) (*Import, error)
+	// FindByUser returns the user's most recent imports, without their row errors.
+	FindByUser(ctx context.Context, userID string, limit int) ([]*Import, error)
+	// Payload returns the feed and archive, nil once the import has been applied.
+	Payload(ctx context.Context, id string) (source, archive []byte, err error)
+	MarkRunning(ctx context.Context, id string) error
+	// Finish stores the outcome. The feed is dropped unless keepPayload is set.
+	Finish(ctx context.Context, imp *Import, keepPayload bool) error
+	// Requeue turns a completed dry run into a queued import that stores the
+	// listings. It reports false when the import is not such a dry run.
+	Requeue(ctx context.Context, id string) (bool, error)
+	// FindMedia returns the media a previous import created from source, or nil.
+	FindMedia(ctx context.Context, userID uuid.UUID, source string) (*uuid.UUID, error)
+	SaveMedia(ctx context.Context, userID uuid.UUID, source string, mediaID uuid.UUID) error
+}
+
+type ImportRepoPsql struct {
+	logger config.Logging
+	psql   db.Database
+}
+
+func NewImportRepoPsql(psql db.Database, logger config.Logging) *ImportRepoPsql {
+	return &ImportRepoPsql{
+		psql:   psql,
+		logger: logger,
+	}
+}
+
+const importColumns = `
+	id, user_id, format, status, dry_run, mapping, total_rows, created_count, updated_count, failed_count,
+	failure, created_at, started_at, finished_at`
+
+func (r *ImportRepoPsql) Create(ctx context.Context, imp *Import, source, archive []byte) error {
+	mapping, err := json.Marshal(imp.Mapping)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO authentic.listing_imports (id, user_id, format, status, dry_run, mapping, total_rows, source, archive)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at
+	`
+	err = r.psql.QueryRow(ctx, query, imp.ID, imp.UserID, imp.Format, imp.Status, imp.DryRun, mapping, imp.TotalRows, source, archive).Scan(&imp.CreatedAt)
+	if err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to store import", map[string]any{"error": err.Error()})
+	}
+	return err
+}
+
+func (r *ImportRepoPsql) scanImport(row interface{ Scan(...any) error }, extra ...any) (*Import, error) {
+	var imp Import
+	var mapping []byte
+	dest := []any{
+		&imp.ID, &imp.UserID, &imp.Format, &imp.Status, &imp.DryRun, &mapping, &imp.TotalRows, &imp.Created, &imp.Updated, &imp.Failed,
+		&imp.Failure, &imp.CreatedAt, &imp.StartedAt, &imp.FinishedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(mapping, &imp.Mapping); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+func (r *ImportRepoPsql) FindByID(ctx context.Context, id string) (*Import, error) {
+	query := `SELECT ` + importColumns + `, row_errors FROM authentic.listing_imports WHERE id = $1`
+	var rowErrors []byte
+	imp, err := r.scanImport(r.psql.QueryRow(ctx, query, id), &rowErrors)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rowErrors, &imp.Errors); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+func (r *ImportRepoPsql) FindByUser(ctx context.Context, userID string, limit int) ([]*Import, error) {
+	query := `SELECT ` + importColumns + ` FROM authentic.listing_imports WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := r.psql.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []*Import{}
+	for rows.Next() {
+		imp, err := r.scanImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+	return imports, rows.Err()
+}
+
+func (r *ImportRepoPsql) Payload(ctx context.Context, id string) ([]byte, []byte, error) {
+	var source, archive []byte
+	err := r.psql.QueryRow(ctx, `SELECT source, archive FROM authentic.listing_imports WHERE id = $1`, id).Scan(&source, &archive)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	return source, archive, err
+}
+
+func (r *ImportRepoPsql) MarkRunning(ctx context.Context, id string) error {
+	_, err := r.psql.Execute(ctx, `
+		UPDATE authentic.listing_imports SET status = 'running', started_at = NOW() WHERE id = $1
+	`, id)
+	return err
+}
+
+func (r *ImportRepoPsql) Finish(ctx context.Context, imp *Import, keepPayload bool) error {
+	rowErrors, err := json.Marshal(imp.Errors)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE authentic.listing_imports SET
+			status = $2, total_rows = $3, created_count = $4, updated_count = $5, failed_count = $6,
+			row_errors = $7, failure = $8, finished_at = NOW(),
+			source = CASE WHEN $9 THEN source END,
+			archive = CASE WHEN $9 THEN archive END
+		WHERE id = $1
+		RETURNING finished_at
+	`
+	err = r.psql.QueryRow(ctx, query, imp.ID, imp.Status, imp.TotalRows, imp.Created, imp.Updated, imp.Failed,
+		rowErrors, imp.Failure, keepPayload).Scan(&imp.FinishedAt)
+	if err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to store import outcome", map[string]any{"error": err.Error(), "import_id": imp.ID})
+	}
+	return err
+}
+
+func (r *ImportRepoPsql) Requeue(ctx context.Context, id string) (bool, error) {
+	res, err := r.psql.Execute(ctx, `
+		UPDATE authentic.listing_imports SET
+			status = 'queued', dry_run = FALSE, total_rows = 0, created_count = 0, updated_count = 0, failed_count = 0,
+			row_errors = '[]', failure = NULL, started_at = NULL, finished_at = NULL
+		WHERE id = $1 AND dry_run AND status = 'completed' AND source IS NOT NULL
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *ImportRepoPsql) FindMedia(ctx context.Context, userID uuid.UUID, source string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.psql.QueryRow(ctx, `
+		SELECT media_id FROM authentic.import_media WHERE user_id = $1 AND source = $2
+	`, userID, source).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (r *ImportRepoPsql) SaveMedia(ctx context.Context, userID uuid.UUID, source string, mediaID uuid.UUID) error {
+	_, err := r.psql.Execute(ctx, `
+		INSERT INTO authentic.import_media (user_id, source, media_id) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, source) DO UPDATE SET media_id = EXCLUDED.media_id
+	`, userID, source, mediaID)
+	return err
+}
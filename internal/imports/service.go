@@ -0,0 +1,500 @@
+package imports
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/media"
+	productModels "github.com/hfleury/horsemarketplacebk/internal/products/models"
+	productServices "github.com/hfleury/horsemarketplacebk/internal/products/services"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+	"github.com/hibiken/asynq"
+)
+
+var (
+	ErrImportNotFound = errors.New("import not found")
+	ErrNotApplicable  = errors.New("only a completed dry run can be applied")
+)
+
+const (
+	// maxImageBytes matches the limit of images uploaded from the browser
+	maxImageBytes     = 5 << 20
+	maxStoredErrors   = 500
+	listLimit         = 50
+	imageFetchTimeout = 20 * time.Second
+)
+
+// FeedError rejects an upload that cannot be processed at all.
+type FeedError struct {
+	Reason string
+}
+
+func (e *FeedError) Error() string {
+	return e.Reason
+}
+
+// ListingImporter creates or updates a listing matched by its external id.
+type ListingImporter interface {
+	ImportListing(ctx context.Context, p *productModels.Product, dryRun bool) (productServices.ImportAction, error)
+}
+
+// MediaStore uploads images on behalf of a user.
+type MediaStore interface {
+	Store(ctx context.Context, userID uuid.UUID, r io.Reader, size int64, originalName string, mimeType string) (*media.Media, error)
+}
+
+type ImportService struct {
+	repo     ImportRepository
+	listings ListingImporter
+	store    MediaStore
+	queue    tasks.Enqueuer
+	client   *http.Client
+	logger   config.Logging
+}
+
+func NewImportService(repo ImportRepository, listings ListingImporter, store MediaStore, queue tasks.Enqueuer, logger config.Logging) *ImportService {
+	return &ImportService{
+		repo:     repo,
+		listings: listings,
+		store:    store,
+		queue:    queue,
+		client:   publicHTTPClient(),
+		logger:   logger,
+	}
+}
+
+// SetHTTPClient replaces the client fetching image URLs, which refuses
+// addresses on private networks.
+func (s *ImportService) SetHTTPClient(client *http.Client) {
+	s.client = client
+}
+
+// publicHTTPClient only connects to public addresses, so feeds cannot make
+// the server fetch from itself or the internal network.
+func publicHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: imageFetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+				return fmt.Errorf("refusing to fetch from %s", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: imageFetchTimeout, Transport: transport}
+}
+
+// Submit checks that the feed can be read and queues it for processing.
+// Rows are only validated by the job, which reports errors per row.
+func (s *ImportService) Submit(ctx context.Context, userID string, req *SubmitRequest) (*Import, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, productServices.ErrUnauthorized
+	}
+	if err := req.Mapping.Validate(); err != nil {
+		return nil, &FeedError{Reason: err.Error()}
+	}
+	records, err := parseFeed(req.Format, req.Source)
+	if err != nil {
+		return nil, &FeedError{Reason: err.Error()}
+	}
+	if len(req.Archive) > 0 {
+		if _, err := openArchive(req.Archive); err != nil {
+			return nil, &FeedError{Reason: err.Error()}
+		}
+	} else {
+		req.Archive = nil
+	}
+
+	imp := &Import{
+		ID:        uuid.New(),
+		UserID:    uid,
+		Format:    req.Format,
+		Status:    StatusQueued,
+		DryRun:    req.DryRun,
+		Mapping:   req.Mapping,
+		TotalRows: len(records),
+	}
+	if err := s.repo.Create(ctx, imp, req.Source, req.Archive); err != nil {
+		return nil, err
+	}
+	if err := s.enqueue(ctx, imp); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// Apply stores the listings of a completed dry run without uploading the
+// feed again.
+func (s *ImportService) Apply(ctx context.Context, id string, userID string) (*Import, error) {
+	if _, err := s.Get(ctx, id, userID, false); err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.Requeue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotApplicable
+	}
+	imp, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enqueue(ctx, imp); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+func (s *ImportService) Get(ctx context.Context, id string, userID string, isAdmin bool) (*Import, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrImportNotFound
+	}
+	imp, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if imp == nil || (!isAdmin && imp.UserID.String() != userID) {
+		return nil, ErrImportNotFound
+	}
+	return imp, nil
+}
+
+func (s *ImportService) List(ctx context.Context, userID string) ([]*Import, error) {
+	return s.repo.FindByUser(ctx, userID, listLimit)
+}
+
+// enqueue schedules the job; an import that cannot be queued is marked
+// failed so it does not look pending forever.
+func (s *ImportService) enqueue(ctx context.Context, imp *Import) error {
+	task, err := tasks.NewListingImportTask(imp.ID.String())
+	if err == nil {
+		_, err = s.queue.Enqueue(task, asynq.MaxRetry(3))
+	}
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to enqueue listing import", map[string]any{"error": err.Error(), "import_id": imp.ID})
+		failure := "the import could not be queued, please upload it again"
+		imp.Status, imp.Failure = StatusFailed, &failure
+		if finishErr := s.repo.Finish(ctx, imp, false); finishErr != nil {
+			return errors.Join(err, finishErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// HandleImportTask processes a queued feed row by row. Rows that fail are
+// reported and skipped, the others are imported.
+func (s *ImportService) HandleImportTask(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.ListingImportPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	imp, err := s.repo.FindByID(ctx, payload.ImportID)
+	if err != nil {
+		return fmt.Errorf("find import: %w", err)
+	}
+	// Retries of a finished import have nothing left to do
+	if imp == nil || (imp.Status != StatusQueued && imp.Status != StatusRunning) {
+		return nil
+	}
+	source, archive, err := s.repo.Payload(ctx, payload.ImportID)
+	if err != nil {
+		return fmt.Errorf("load import feed: %w", err)
+	}
+	if err := s.repo.MarkRunning(ctx, payload.ImportID); err != nil {
+		return fmt.Errorf("mark import running: %w", err)
+	}
+
+	s.run(ctx, imp, source, archive)
+
+	// A dry run keeps its feed so it can be applied afterwards
+	keep := imp.DryRun && imp.Status == StatusCompleted
+	if err := s.repo.Finish(ctx, imp, keep); err != nil {
+		return fmt.Errorf("finish import: %w", err)
+	}
+	s.logger.Log(ctx, config.InfoLevel, "Listing import finished", map[string]any{
+		"import_id": imp.ID, "status": imp.Status, "dry_run": imp.DryRun,
+		"created": imp.Created, "updated": imp.Updated, "failed": imp.Failed,
+	})
+	return nil
+}
+
+func (s *ImportService) run(ctx context.Context, imp *Import, source, archive []byte) {
+	imp.Created, imp.Updated, imp.Failed, imp.Errors, imp.Failure = 0, 0, 0, nil, nil
+	fail := func(reason string) {
+		imp.Status, imp.Failure = StatusFailed, &reason
+	}
+
+	if source == nil {
+		fail("the feed is no longer available")
+		return
+	}
+	records, err := parseFeed(imp.Format, source)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	var images *imageArchive
+	if archive != nil {
+		if images, err = openArchive(archive); err != nil {
+			fail(err.Error())
+			return
+		}
+	}
+
+	imp.TotalRows = len(records)
+	seen := make(map[string]int, len(records))
+	for _, rec := range records {
+		if rowErr := s.importRow(ctx, imp, rec, images, seen); rowErr != nil {
+			imp.Failed++
+			if len(imp.Errors) < maxStoredErrors {
+				imp.Errors = append(imp.Errors, *rowErr)
+			}
+		}
+	}
+	imp.Status = StatusCompleted
+}
+
+func (s *ImportService) importRow(ctx context.Context, imp *Import, rec record, images *imageArchive, seen map[string]int) *RowError {
+	r, rowErr := imp.Mapping.listing(rec, imp.UserID)
+	if rowErr != nil {
+		return rowErr
+	}
+	externalID := *r.product.ExternalID
+	if first, ok := seen[externalID]; ok {
+		return &RowError{Row: rec.row, ExternalID: externalID, Field: "external_id", Message: fmt.Sprintf("repeats the external id of row %d", first)}
+	}
+	seen[externalID] = rec.row
+
+	if r.images != nil {
+		items, err := s.resolveImages(ctx, imp, r.images, images)
+		if err != nil {
+			return &RowError{Row: rec.row, ExternalID: externalID, Field: "images", Message: err.Error()}
+		}
+		r.product.Media = items
+	}
+
+	action, err := s.listings.ImportListing(ctx, r.product, imp.DryRun)
+	if err != nil {
+		return s.rowError(ctx, imp, rec.row, externalID, err)
+	}
+	switch action {
+	case productServices.ImportCreated:
+		imp.Created++
+	case productServices.ImportUpdated:
+		imp.Updated++
+	}
+	return nil
+}
+
+// rowError reports validation errors as they are and hides the details of
+// anything else, which is logged instead.
+func (s *ImportService) rowError(ctx context.Context, imp *Import, row int, externalID string, err error) *RowError {
+	re := &RowError{Row: row, ExternalID: externalID, Message: err.Error()}
+
+	var identityErr *productModels.IdentityError
+	var detailErr *productModels.DetailError
+	var attributeErr *productModels.AttributeError
+	var pedigreeErr *productModels.PedigreeError
+	switch {
+	case errors.As(err, &identityErr):
+		re.Field, re.Message = "horse."+identityErr.Field, identityErr.Reason
+	case errors.As(err, &detailErr):
+		re.Field, re.Message = detailErr.Field, detailErr.Reason
+	case errors.As(err, &attributeErr):
+		re.Field, re.Message = attributePrefix+attributeErr.Key, attributeErr.Reason
+	case errors.As(err, &pedigreeErr):
+	case errors.Is(err, productServices.ErrDuplicateIdentity), errors.Is(err, productServices.ErrMediaNotOwned):
+	default:
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to import listing", map[string]any{"error": err.Error(), "import_id": imp.ID, "row": row})
+		re.Message = "the listing could not be saved"
+	}
+	return re
+}
+
+// resolveImages turns the row's image URLs and archive file names into
+// media of the seller. Images imported before are reused rather than
+// fetched again. A dry run only checks that the references make sense and
+// leaves the listing's media as they are.
+func (s *ImportService) resolveImages(ctx context.Context, imp *Import, refs []string, images *imageArchive) ([]productModels.ProductMedia, error) {
+	items := make([]productModels.ProductMedia, 0, len(refs))
+	for i, ref := range refs {
+		var (
+			id  uuid.UUID
+			err error
+		)
+		if strings.Contains(ref, "://") {
+			u, parseErr := url.Parse(ref)
+			if parseErr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("%s is not an http or https URL", ref)
+			}
+			if !imp.DryRun {
+				id, err = s.fetchImage(ctx, imp.UserID, ref)
+			}
+		} else {
+			f := images.find(ref)
+			if f == nil {
+				return nil, fmt.Errorf("%s is not in the uploaded archive", ref)
+			}
+			if !imp.DryRun {
+				id, err = s.unpackImage(ctx, imp.UserID, f)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, productModels.ProductMedia{MediaID: id, Order: i, IsPrimary: i == 0})
+	}
+	if imp.DryRun {
+		return nil, nil
+	}
+	return items, nil
+}
+
+func (s *ImportService) fetchImage(ctx context.Context, userID uuid.UUID, ref string) (uuid.UUID, error) {
+	if id, err := s.repo.FindMedia(ctx, userID, ref); err != nil || id != nil {
+		return derefID(id), err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s could not be fetched", ref)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s could not be fetched", ref)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return uuid.Nil, fmt.Errorf("%s answered %d", ref, resp.StatusCode)
+	}
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mimeType, "image/") {
+		return uuid.Nil, fmt.Errorf("%s is not an image", ref)
+	}
+	data, err := readLimited(resp.Body)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", ref, err)
+	}
+
+	u, _ := url.Parse(ref)
+	return s.storeImage(ctx, userID, ref, data, path.Base(u.Path), mimeType)
+}
+
+func (s *ImportService) unpackImage(ctx context.Context, userID uuid.UUID, f *zip.File) (uuid.UUID, error) {
+	mimeType := mime.TypeByExtension(strings.ToLower(path.Ext(f.Name)))
+	if !strings.HasPrefix(mimeType, "image/") {
+		return uuid.Nil, fmt.Errorf("%s is not an image", f.Name)
+	}
+	if f.UncompressedSize64 > maxImageBytes {
+		return uuid.Nil, fmt.Errorf("%s is larger than %d MB", f.Name, maxImageBytes>>20)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s could not be unpacked", f.Name)
+	}
+	defer rc.Close()
+	data, err := readLimited(rc)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+
+	// The same file in a later archive is recognized by its content
+	sum := sha256.Sum256(data)
+	source := "sha256:" + hex.EncodeToString(sum[:])
+	if id, err := s.repo.FindMedia(ctx, userID, source); err != nil || id != nil {
+		return derefID(id), err
+	}
+	return s.storeImage(ctx, userID, source, data, path.Base(f.Name), mimeType)
+}
+
+func (s *ImportService) storeImage(ctx context.Context, userID uuid.UUID, source string, data []byte, name string, mimeType string) (uuid.UUID, error) {
+	m, err := s.store.Store(ctx, userID, bytes.NewReader(data), int64(len(data)), name, mimeType)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := s.repo.SaveMedia(ctx, userID, source, m.ID); err != nil {
+		// The image is attached anyway, a later import just fetches it again
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to remember imported image", map[string]any{"error": err.Error(), "media_id": m.ID})
+	}
+	return m.ID, nil
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImageBytes+1))
+	if err != nil {
+		return nil, errors.New("could not be read")
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("is larger than %d MB", maxImageBytes>>20)
+	}
+	return data, nil
+}
+
+func derefID(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
+}
+
+// imageArchive is an uploaded zip of images, found by path or file name.
+type imageArchive struct {
+	files map[string]*zip.File
+}
+
+func openArchive(data []byte) (*imageArchive, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("the image archive must be a zip file")
+	}
+	a := &imageArchive{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		a.files[f.Name] = f
+		if base := path.Base(f.Name); a.files[base] == nil {
+			a.files[base] = f
+		}
+	}
+	return a, nil
+}
+
+func (a *imageArchive) find(name string) *zip.File {
+	if a == nil {
+		return nil
+	}
+	if f := a.files[name]; f != nil {
+		return f
+	}
+	return a.files[path.Base(name)]
+}
@@ -0,0 +1,205 @@
+package imports_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/imports"
+	"github.com/hfleury/horsemarketplacebk/internal/media"
+	mockImports "github.com/hfleury/horsemarketplacebk/internal/mocks/imports"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeListings treats external ids it has seen before as updates.
+type fakeListings struct {
+	known    map[string]bool
+	imported []*models.Product
+	dryRuns  int
+}
+
+func (f *fakeListings) ImportListing(ctx context.Context, p *models.Product, dryRun bool) (services.ImportAction, error) {
+	if p.Horse != nil && p.Horse.UELN != nil && *p.Horse.UELN == "bad" {
+		return "", &models.IdentityError{Field: "ueln", Reason: "must be 15 characters"}
+	}
+	if dryRun {
+		f.dryRuns++
+	}
+	f.imported = append(f.imported, p)
+	if f.known[*p.ExternalID] {
+		return services.ImportUpdated, nil
+	}
+	return services.ImportCreated, nil
+}
+
+type fakeMediaStore struct {
+	stored []string
+}
+
+func (f *fakeMediaStore) Store(ctx context.Context, userID uuid.UUID, r io.Reader, size int64, originalName string, mimeType string) (*media.Media, error) {
+	f.stored = append(f.stored, originalName)
+	return &media.Media{ID: uuid.New(), UserID: &userID, OriginalName: originalName, MimeType: mimeType}, nil
+}
+
+func importTask(t *testing.T, id uuid.UUID) *asynq.Task {
+	task, err := tasks.NewListingImportTask(id.String())
+	assert.NoError(t, err)
+	return task
+}
+
+func TestHandleImportTask_DryRunReportsRowErrors(t *testing.T) {
+	repo := new(mockImports.MockImportRepo)
+	listings := &fakeListings{known: map[string]bool{"A-2": true}}
+	service := imports.NewImportService(repo, listings, &fakeMediaStore{}, nil, config.NewZerologService())
+
+	feed := "Id;Rubrik;Pris;Ras;UELN\n" +
+		"A-1;Bella;85 000;Swedish Warmblood;\n" +
+		"A-2;Max;120000,50;;\n" +
+		";No id;1000;;\n" +
+		"A-1;Bella again;1000;;\n" +
+		"A-3;Bad chip;1000;;bad\n" +
+		"A-4;Cheap;free;;\n"
+	imp := &imports.Import{
+		ID: uuid.New(), UserID: uuid.New(), Format: imports.FormatCSV, Status: imports.StatusQueued, DryRun: true,
+		Mapping: imports.Mapping{
+			Columns:  map[string]string{"external_id": "Id", "title": "Rubrik", "price_sek": "Pris", "horse.breed": "Ras", "horse.ueln": "UELN"},
+			Defaults: map[string]string{"type": "horse"},
+		},
+	}
+	repo.On("FindByID", mock.Anything, imp.ID.String()).Return(imp, nil)
+	repo.On("Payload", mock.Anything, imp.ID.String()).Return([]byte(feed), nil, nil)
+	repo.On("MarkRunning", mock.Anything, imp.ID.String()).Return(nil)
+	repo.On("Finish", mock.Anything, imp, true).Return(nil)
+
+	err := service.HandleImportTask(context.Background(), importTask(t, imp.ID))
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	assert.Equal(t, imports.StatusCompleted, imp.Status)
+	assert.Equal(t, 6, imp.TotalRows)
+	assert.Equal(t, 1, imp.Created)
+	assert.Equal(t, 1, imp.Updated)
+	assert.Equal(t, 4, imp.Failed)
+	assert.Equal(t, 2, listings.dryRuns)
+
+	assert.Equal(t, []imports.RowError{
+		{Row: 3, Field: "external_id", Message: "is required"},
+		{Row: 4, ExternalID: "A-1", Field: "external_id", Message: "repeats the external id of row 1"},
+		{Row: 5, ExternalID: "A-3", Field: "horse.ueln", Message: "must be 15 characters"},
+		{Row: 6, ExternalID: "A-4", Field: "price_sek", Message: "must be a number"},
+	}, imp.Errors)
+
+	first := listings.imported[0]
+	assert.Equal(t, models.TypeHorse, first.Type)
+	assert.Equal(t, 85000.0, *first.PriceSEK)
+	assert.Equal(t, "Swedish Warmblood", *first.Horse.Breed)
+	assert.Equal(t, 120000.5, *listings.imported[1].PriceSEK)
+}
+
+func TestHandleImportTask_XMLImagesBecomeMedia(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("jpeg bytes"))
+	}))
+	defer server.Close()
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, _ := zw.Create("photos/bella-2.jpg")
+	_, _ = f.Write([]byte("more jpeg bytes"))
+	assert.NoError(t, zw.Close())
+
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<listings>
+  <listing>
+    <external_id>A-1</external_id>
+    <type>horse</type>
+    <title>Bella</title>
+    <horse><breed>Swedish Warmblood</breed></horse>
+    <attributes><attribute key="height_cm">165</attribute></attributes>
+    <images>
+      <image>` + server.URL + `/bella-1.jpg</image>
+      <image>bella-2.jpg</image>
+    </images>
+  </listing>
+  <listing>
+    <external_id>A-2</external_id>
+    <type>horse</type>
+    <title>Max</title>
+    <images><image>missing.jpg</image></images>
+  </listing>
+</listings>`
+
+	repo := new(mockImports.MockImportRepo)
+	listings := &fakeListings{}
+	store := &fakeMediaStore{}
+	service := imports.NewImportService(repo, listings, store, nil, config.NewZerologService())
+	service.SetHTTPClient(server.Client())
+
+	imp := &imports.Import{ID: uuid.New(), UserID: uuid.New(), Format: imports.FormatXML, Status: imports.StatusQueued}
+	reused := uuid.New()
+	repo.On("FindByID", mock.Anything, imp.ID.String()).Return(imp, nil)
+	repo.On("Payload", mock.Anything, imp.ID.String()).Return([]byte(feed), archive.Bytes(), nil)
+	repo.On("MarkRunning", mock.Anything, imp.ID.String()).Return(nil)
+	repo.On("FindMedia", mock.Anything, imp.UserID, server.URL+"/bella-1.jpg").Return(nil, nil)
+	repo.On("FindMedia", mock.Anything, imp.UserID, mock.MatchedBy(func(s string) bool { return len(s) > 7 && s[:7] == "sha256:" })).Return(&reused, nil)
+	repo.On("SaveMedia", mock.Anything, imp.UserID, server.URL+"/bella-1.jpg", mock.Anything).Return(nil)
+	repo.On("Finish", mock.Anything, imp, false).Return(nil)
+
+	err := service.HandleImportTask(context.Background(), importTask(t, imp.ID))
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	assert.Equal(t, 1, imp.Created)
+	assert.Equal(t, []imports.RowError{{Row: 2, ExternalID: "A-2", Field: "images", Message: "missing.jpg is not in the uploaded archive"}}, imp.Errors)
+
+	// The URL is fetched, the archive file was imported before
+	assert.Equal(t, []string{"bella-1.jpg"}, store.stored)
+	p := listings.imported[0]
+	assert.Equal(t, map[string]any{"height_cm": "165"}, p.Attributes)
+	if assert.Len(t, p.Media, 2) {
+		assert.True(t, p.Media[0].IsPrimary)
+		assert.Equal(t, reused, p.Media[1].MediaID)
+	}
+}
+
+func TestSubmit_RejectsUnreadableFeeds(t *testing.T) {
+	service := imports.NewImportService(new(mockImports.MockImportRepo), &fakeListings{}, &fakeMediaStore{}, nil, config.NewZerologService())
+	userID := uuid.New().String()
+
+	_, err := service.Submit(context.Background(), userID, &imports.SubmitRequest{
+		Format: imports.FormatCSV, Source: []byte("title\nBella\n"),
+		Mapping: imports.Mapping{Columns: map[string]string{"horse.nickname": "Name"}},
+	})
+	var feedErr *imports.FeedError
+	assert.ErrorAs(t, err, &feedErr)
+
+	_, err = service.Submit(context.Background(), userID, &imports.SubmitRequest{Format: imports.FormatXML, Source: []byte("<listings><listing>")})
+	assert.ErrorAs(t, err, &feedErr)
+
+	_, err = service.Submit(context.Background(), userID, &imports.SubmitRequest{
+		Format: imports.FormatCSV, Source: []byte("title\nBella\n"), Archive: []byte("not a zip"),
+	})
+	assert.ErrorAs(t, err, &feedErr)
+}
+
+func TestMappingProfile_JSON(t *testing.T) {
+	var m imports.Mapping
+	err := json.Unmarshal([]byte(`{"columns": {"title": "Rubrik", "attr.height_cm": "Mankhöjd"}, "defaults": {"type": "horse"}}`), &m)
+
+	assert.NoError(t, err)
+	assert.NoError(t, m.Validate())
+	assert.Error(t, (&imports.Mapping{Columns: map[string]string{"title": " "}}).Validate())
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
 	"github.com/hfleury/horsemarketplacebk/internal/common"
 )
@@ -43,7 +44,15 @@ func (h *MediaHandler) Upload(c *gin.Context) {
 		return
 	}
 
-	media, err := h.service.UploadFile(c.Request.Context(), file, header)
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Status = "error"
+		response.Message = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	media, err := h.service.UploadFile(c.Request.Context(), userID, file, header)
 	if err != nil {
 		logger.Log(c, config.ErrorLevel, "Failed to upload file", map[string]any{"error": err.Error()})
 		response.Status = "error"
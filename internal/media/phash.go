@@ -0,0 +1,33 @@
+package media
+
+import (
+	"image"
+	"math/bits"
+
+	"github.com/disintegration/imaging"
+)
+
+// PerceptualHash is the difference hash (dHash) of an image: 64 bits telling
+// whether each pixel of a 9x8 grayscale thumbnail is brighter than its right
+// neighbour. Resized, recompressed or slightly edited copies of an image get
+// hashes only a few bits apart.
+func PerceptualHash(img image.Image) uint64 {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Box))
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[small.PixOffset(x, y)]
+			right := small.Pix[small.PixOffset(x+1, y)]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance is the number of differing bits of two perceptual hashes.
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,35 @@
+package media_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/hfleury/horsemarketplacebk/internal/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func gradient(width, height int, flip bool) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*255/width + y*97/height) % 256)
+			if flip {
+				v = 255 - v
+			}
+			img.Set(x, y, color.NRGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	original := gradient(640, 480, false)
+	resized := imaging.Resize(original, 200, 0, imaging.Lanczos)
+	other := gradient(640, 480, true)
+
+	hash := media.PerceptualHash(original)
+	assert.LessOrEqual(t, media.HashDistance(hash, media.PerceptualHash(resized)), 4)
+	assert.Greater(t, media.HashDistance(hash, media.PerceptualHash(other)), 20)
+}
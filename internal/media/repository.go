@@ -3,6 +3,7 @@ package media
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,8 @@ type MediaRepository interface {
 	Create(ctx context.Context, media *Media) (*Media, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*Media, error)
 	UpdateVariants(ctx context.Context, id uuid.UUID, variants any) error
+	// UpdatePerceptualHash stores the PerceptualHash of an image.
+	UpdatePerceptualHash(ctx context.Context, id uuid.UUID, hash uint64) error
 }
 
 type PostgresMediaRepository struct {
@@ -25,15 +28,15 @@ func NewPostgresMediaRepository(db *sql.DB) *PostgresMediaRepository {
 func (r *PostgresMediaRepository) Create(ctx context.Context, m *Media) (*Media, error) {
 	query := `
 		INSERT INTO authentic.media (
-			file_name, original_name, mime_type, size_bytes, url, bucket_name, region, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			file_name, original_name, mime_type, size_bytes, url, bucket_name, region, created_at, updated_at, user_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id
 	`
 	m.CreatedAt = time.Now()
 	m.UpdatedAt = time.Now()
 
 	err := r.DB.QueryRowContext(ctx, query,
-		m.FileName, m.OriginalName, m.MimeType, m.SizeBytes, m.URL, m.BucketName, m.Region, m.CreatedAt, m.UpdatedAt,
+		m.FileName, m.OriginalName, m.MimeType, m.SizeBytes, m.URL, m.BucketName, m.Region, m.CreatedAt, m.UpdatedAt, m.UserID,
 	).Scan(&m.ID)
 
 	if err != nil {
@@ -44,13 +47,14 @@ func (r *PostgresMediaRepository) Create(ctx context.Context, m *Media) (*Media,
 
 func (r *PostgresMediaRepository) FindByID(ctx context.Context, id uuid.UUID) (*Media, error) {
 	query := `
-		SELECT id, file_name, original_name, mime_type, size_bytes, url, bucket_name, region, created_at, updated_at
+		SELECT id, user_id, file_name, original_name, mime_type, size_bytes, url, bucket_name, region, variants, created_at, updated_at
 		FROM authentic.media
 		WHERE id = $1
 	`
 	m := &Media{}
+	var variants []byte
 	err := r.DB.QueryRowContext(ctx, query, id).Scan(
-		&m.ID, &m.FileName, &m.OriginalName, &m.MimeType, &m.SizeBytes, &m.URL, &m.BucketName, &m.Region, &m.CreatedAt, &m.UpdatedAt,
+		&m.ID, &m.UserID, &m.FileName, &m.OriginalName, &m.MimeType, &m.SizeBytes, &m.URL, &m.BucketName, &m.Region, &variants, &m.CreatedAt, &m.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -58,7 +62,9 @@ func (r *PostgresMediaRepository) FindByID(ctx context.Context, id uuid.UUID) (*
 		}
 		return nil, err
 	}
-	return m, nil
+	if variants != nil {
+		m.Variants = json.RawMessage(variants)
+	}
 	return m, nil
 }
 
@@ -71,3 +77,14 @@ func (r *PostgresMediaRepository) UpdateVariants(ctx context.Context, id uuid.UU
 	_, err := r.DB.ExecContext(ctx, query, variants, id)
 	return err
 }
+
+func (r *PostgresMediaRepository) UpdatePerceptualHash(ctx context.Context, id uuid.UUID, hash uint64) error {
+	query := `
+		UPDATE authentic.media
+		SET phash = $1
+		WHERE id = $2
+	`
+	// BIGINT is signed, the bits are kept as they are
+	_, err := r.DB.ExecContext(ctx, query, int64(hash), id)
+	return err
+}
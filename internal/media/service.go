@@ -3,6 +3,7 @@ package media
 import (
 	"context"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"path/filepath"
 	"strings"
@@ -46,17 +47,25 @@ func NewMediaService(repo MediaRepository, queue *asynq.Client, cfg *config.AllC
 	}, nil
 }
 
-func (s *MediaService) UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*Media, error) {
+// UploadFile stores the file and records userID as its uploader, who is
+// the only one allowed to attach it to a listing.
+func (s *MediaService) UploadFile(ctx context.Context, userID uuid.UUID, file multipart.File, header *multipart.FileHeader) (*Media, error) {
+	return s.Store(ctx, userID, file, header.Size, header.Filename, header.Header.Get("Content-Type"))
+}
+
+// Store uploads size bytes read from r on behalf of userID, like UploadFile
+// does for files posted by browsers.
+func (s *MediaService) Store(ctx context.Context, userID uuid.UUID, r io.Reader, size int64, originalName string, mimeType string) (*Media, error) {
 	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
+	ext := filepath.Ext(originalName)
 	uniqueName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	contentType := header.Header.Get("Content-Type")
+	contentType := mimeType
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
 
 	// Upload to MinIO
-	info, err := s.minioClient.PutObject(ctx, s.bucketName, uniqueName, file, header.Size, minio.PutObjectOptions{
+	info, err := s.minioClient.PutObject(ctx, s.bucketName, uniqueName, r, size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
@@ -82,12 +91,13 @@ func (s *MediaService) UploadFile(ctx context.Context, file multipart.File, head
 
 	media := &Media{
 		FileName:     info.Key,
-		OriginalName: header.Filename,
-		MimeType:     header.Header.Get("Content-Type"),
+		OriginalName: originalName,
+		MimeType:     mimeType,
 		SizeBytes:    info.Size,
 		URL:          url,
 		BucketName:   s.bucketName,
 		Region:       s.config.AWS.Region,
+		UserID:       &userID,
 	}
 
 	createdMedia, err := s.repo.Create(ctx, media)
@@ -65,6 +65,28 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuth identifies the caller when a valid bearer token is present
+// and lets anonymous requests through otherwise, for public routes whose
+// response depends on the viewer.
+func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" || tokenString == c.GetHeader("Authorization") {
+			c.Next()
+			return
+		}
+
+		userID, username, email, role, err := m.tokenService.VerifyToken(tokenString)
+		if err == nil {
+			c.Set("user_id", userID)
+			c.Set("username", username)
+			c.Set("email", email)
+			c.Set("role", role)
+		}
+		c.Next()
+	}
+}
+
 func (m *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
@@ -0,0 +1,48 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEventBuffer struct {
+	mock.Mock
+}
+
+func (m *MockEventBuffer) Record(ctx context.Context, productID string, visitor analytics.Visitor, event analytics.EventType) (bool, error) {
+	args := m.Called(ctx, productID, visitor, event)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockEventBuffer) Take(ctx context.Context) (string, []analytics.DailyCount, error) {
+	args := m.Called(ctx)
+	if args.Get(1) == nil {
+		return args.String(0), nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).([]analytics.DailyCount), args.Error(2)
+}
+
+func (m *MockEventBuffer) Done(ctx context.Context, batch string) error {
+	args := m.Called(ctx, batch)
+	return args.Error(0)
+}
+
+type MockStatsRepo struct {
+	mock.Mock
+}
+
+func (m *MockStatsRepo) AddDailyCounts(ctx context.Context, batch string, counts []analytics.DailyCount) error {
+	args := m.Called(ctx, batch, counts)
+	return args.Error(0)
+}
+
+func (m *MockStatsRepo) DailyStats(ctx context.Context, productID string, from, to time.Time) ([]analytics.DailyStat, error) {
+	args := m.Called(ctx, productID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]analytics.DailyStat), args.Error(1)
+}
@@ -2,6 +2,7 @@ package mockcategories
 
 import (
 	"context"
+	"time"
 
 	"github.com/hfleury/horsemarketplacebk/internal/categories/models"
 	"github.com/stretchr/testify/mock"
@@ -19,8 +20,8 @@ func (m *MockCategoryRepository) Create(ctx context.Context, category *models.Ca
 	return args.Get(0).(*models.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepository) Update(ctx context.Context, category *models.Category) (*models.Category, error) {
-	args := m.Called(ctx, category)
+func (m *MockCategoryRepository) Update(ctx context.Context, category *models.Category, ifMatch []time.Time) (*models.Category, error) {
+	args := m.Called(ctx, category, ifMatch)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -55,3 +56,45 @@ func (m *MockCategoryRepository) FindByName(ctx context.Context, name string) (*
 	}
 	return args.Get(0).(*models.Category), args.Error(1)
 }
+
+func (m *MockCategoryRepository) CreateAttribute(ctx context.Context, attribute *models.Attribute) (*models.Attribute, error) {
+	args := m.Called(ctx, attribute)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attribute), args.Error(1)
+}
+
+func (m *MockCategoryRepository) UpdateAttribute(ctx context.Context, attribute *models.Attribute) (*models.Attribute, error) {
+	args := m.Called(ctx, attribute)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attribute), args.Error(1)
+}
+
+func (m *MockCategoryRepository) DeleteAttribute(ctx context.Context, categoryID string, id string) (bool, error) {
+	args := m.Called(ctx, categoryID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindAttribute(ctx context.Context, categoryID string, id string) (*models.Attribute, error) {
+	args := m.Called(ctx, categoryID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attribute), args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindAttributes(ctx context.Context, categoryID string) ([]*models.Attribute, error) {
+	args := m.Called(ctx, categoryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Attribute), args.Error(1)
+}
+
+func (m *MockCategoryRepository) AttributeKeyTaken(ctx context.Context, categoryID string, key string) (bool, error) {
+	args := m.Called(ctx, categoryID, key)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,26 @@
+package currency
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRateRepo struct {
+	mock.Mock
+}
+
+func (m *MockRateRepo) FindAll(ctx context.Context) ([]currency.Rate, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]currency.Rate), args.Error(1)
+}
+
+func (m *MockRateRepo) Save(ctx context.Context, rates map[string]float64, updatedBy *uuid.UUID) error {
+	args := m.Called(ctx, rates, updatedBy)
+	return args.Error(0)
+}
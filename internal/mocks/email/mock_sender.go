@@ -10,6 +10,8 @@ type MockSender struct {
 	LastTo      string
 	LastSubject string
 	LastBody    string
+	// Err, when set, fails every send
+	Err error
 }
 
 func NewMockSender() *MockSender {
@@ -17,6 +19,9 @@ func NewMockSender() *MockSender {
 }
 
 func (m *MockSender) Send(ctx context.Context, to, subject, body string) error {
+	if m.Err != nil {
+		return m.Err
+	}
 	m.LastTo = to
 	m.LastSubject = subject
 	m.LastBody = body
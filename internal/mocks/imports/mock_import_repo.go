@@ -0,0 +1,74 @@
+package imports
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/imports"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockImportRepo struct {
+	mock.Mock
+}
+
+func (m *MockImportRepo) Create(ctx context.Context, imp *imports.Import, source, archive []byte) error {
+	args := m.Called(ctx, imp, source, archive)
+	return args.Error(0)
+}
+
+func (m *MockImportRepo) FindByID(ctx context.Context, id string) (*imports.Import, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*imports.Import), args.Error(1)
+}
+
+func (m *MockImportRepo) FindByUser(ctx context.Context, userID string, limit int) ([]*imports.Import, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*imports.Import), args.Error(1)
+}
+
+func (m *MockImportRepo) Payload(ctx context.Context, id string) ([]byte, []byte, error) {
+	args := m.Called(ctx, id)
+	var source, archive []byte
+	if args.Get(0) != nil {
+		source = args.Get(0).([]byte)
+	}
+	if args.Get(1) != nil {
+		archive = args.Get(1).([]byte)
+	}
+	return source, archive, args.Error(2)
+}
+
+func (m *MockImportRepo) MarkRunning(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockImportRepo) Finish(ctx context.Context, imp *imports.Import, keepPayload bool) error {
+	args := m.Called(ctx, imp, keepPayload)
+	return args.Error(0)
+}
+
+func (m *MockImportRepo) Requeue(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockImportRepo) FindMedia(ctx context.Context, userID uuid.UUID, source string) (*uuid.UUID, error) {
+	args := m.Called(ctx, userID, source)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*uuid.UUID), args.Error(1)
+}
+
+func (m *MockImportRepo) SaveMedia(ctx context.Context, userID uuid.UUID, source string, mediaID uuid.UUID) error {
+	args := m.Called(ctx, userID, source, mediaID)
+	return args.Error(0)
+}
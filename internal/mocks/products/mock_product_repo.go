@@ -2,7 +2,9 @@ package products
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/internal/products/models"
 	"github.com/stretchr/testify/mock"
 )
@@ -27,40 +29,64 @@ func (m *MockProductRepo) FindByID(ctx context.Context, id string) (*models.Prod
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
-func (m *MockProductRepo) FindAll(ctx context.Context, filters map[string]any) ([]*models.Product, error) {
-	args := m.Called(ctx, filters)
+func (m *MockProductRepo) FindAll(ctx context.Context, filters map[string]any, viewer models.Viewer) ([]*models.Product, error) {
+	args := m.Called(ctx, filters, viewer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepo) FindByCategory(ctx context.Context, categoryID string, viewer models.Viewer) ([]*models.Product, error) {
+	args := m.Called(ctx, categoryID, viewer)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*models.Product), args.Error(1)
 }
 
-func (m *MockProductRepo) FindByCategory(ctx context.Context, categoryID string) ([]*models.Product, error) {
-	args := m.Called(ctx, categoryID)
+func (m *MockProductRepo) FindByTextInDescription(ctx context.Context, text string, viewer models.Viewer) ([]*models.Product, error) {
+	args := m.Called(ctx, text, viewer)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*models.Product), args.Error(1)
 }
 
-func (m *MockProductRepo) FindByTextInDescription(ctx context.Context, text string) ([]*models.Product, error) {
-	args := m.Called(ctx, text)
+func (m *MockProductRepo) FindByField(ctx context.Context, fieldName string, value string, viewer models.Viewer) ([]*models.Product, error) {
+	args := m.Called(ctx, fieldName, value, viewer)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*models.Product), args.Error(1)
 }
 
-func (m *MockProductRepo) FindByField(ctx context.Context, fieldName string, value string) ([]*models.Product, error) {
-	args := m.Called(ctx, fieldName, value)
+func (m *MockProductRepo) Search(ctx context.Context, filters *models.ProductFilters) ([]*models.Product, error) {
+	args := m.Called(ctx, filters)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*models.Product), args.Error(1)
 }
 
-func (m *MockProductRepo) UpdateStatus(ctx context.Context, id string, status models.ProductStatus) error {
-	args := m.Called(ctx, id, status)
+func (m *MockProductRepo) FacetCounts(ctx context.Context, filters *models.ProductFilters) (map[string][]models.FacetCount, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string][]models.FacetCount), args.Error(1)
+}
+
+func (m *MockProductRepo) MatchFilters(ctx context.Context, id string, filters []*models.ProductFilters) ([]bool, error) {
+	args := m.Called(ctx, id, filters)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]bool), args.Error(1)
+}
+
+func (m *MockProductRepo) UpdateStatus(ctx context.Context, id string, status models.ProductStatus, ifMatch []time.Time) error {
+	args := m.Called(ctx, id, status, ifMatch)
 	return args.Error(0)
 }
 
@@ -68,3 +94,280 @@ func (m *MockProductRepo) Delete(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (m *MockProductRepo) AddFavorite(ctx context.Context, userID string, productID string) (bool, error) {
+	args := m.Called(ctx, userID, productID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductRepo) RemoveFavorite(ctx context.Context, userID string, productID string) (bool, error) {
+	args := m.Called(ctx, userID, productID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductRepo) FindFavorites(ctx context.Context, userID string) ([]*models.Product, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepo) FavoritedAmong(ctx context.Context, userID string, productIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	args := m.Called(ctx, userID, productIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]bool), args.Error(1)
+}
+
+func (m *MockProductRepo) FindWatchers(ctx context.Context, productID string) ([]models.Watcher, error) {
+	args := m.Called(ctx, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Watcher), args.Error(1)
+}
+
+func (m *MockProductRepo) Update(ctx context.Context, product *models.Product, rev *models.Revision, baseline *models.ListingContent, ifMatch []time.Time) (*models.Product, error) {
+	args := m.Called(ctx, product, rev, baseline, ifMatch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepo) MediaOwnedBy(ctx context.Context, userID string, mediaIDs []uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID, mediaIDs)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductRepo) AddMedia(ctx context.Context, productID string, mediaID string, isPrimary bool) (bool, error) {
+	args := m.Called(ctx, productID, mediaID, isPrimary)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductRepo) RemoveMedia(ctx context.Context, productID string, mediaID string) (bool, error) {
+	args := m.Called(ctx, productID, mediaID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductRepo) ReorderMedia(ctx context.Context, productID string, mediaIDs []uuid.UUID) error {
+	args := m.Called(ctx, productID, mediaIDs)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) SetPrimaryMedia(ctx context.Context, productID string, mediaID string) (bool, error) {
+	args := m.Called(ctx, productID, mediaID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductRepo) Publish(ctx context.Context, id string, expiresAt time.Time, ifMatch []time.Time) error {
+	args := m.Called(ctx, id, expiresAt, ifMatch)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) Renew(ctx context.Context, id string, expiresAt time.Time) error {
+	args := m.Called(ctx, id, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) ArchiveExpired(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockProductRepo) FindExpiringSoon(ctx context.Context, before time.Time) ([]models.ExpiringListing, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ExpiringListing), args.Error(1)
+}
+
+func (m *MockProductRepo) MarkReminderSent(ctx context.Context, id string, renewToken string) error {
+	args := m.Called(ctx, id, renewToken)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) FindByRenewToken(ctx context.Context, token string) (*models.Product, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepo) FindActiveByIdentity(ctx context.Context, ueln, microchip *string, excludeID uuid.UUID) (uuid.UUID, error) {
+	args := m.Called(ctx, ueln, microchip, excludeID)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockProductRepo) SearchByIdentity(ctx context.Context, identifier string, limit int) ([]*models.Product, error) {
+	args := m.Called(ctx, identifier, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepo) SetIdentityVerified(ctx context.Context, id string, verifiedBy *uuid.UUID) error {
+	args := m.Called(ctx, id, verifiedBy)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) FindByExternalID(ctx context.Context, userID string, externalID string) (*models.Product, error) {
+	args := m.Called(ctx, userID, externalID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepo) SitemapProducts(ctx context.Context, offset, limit int) ([]models.SitemapEntry, error) {
+	args := m.Called(ctx, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SitemapEntry), args.Error(1)
+}
+
+func (m *MockProductRepo) SitemapProductPages(ctx context.Context, perPage int) ([]time.Time, error) {
+	args := m.Called(ctx, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]time.Time), args.Error(1)
+}
+
+func (m *MockProductRepo) SitemapSellers(ctx context.Context, offset, limit int) ([]models.SitemapEntry, error) {
+	args := m.Called(ctx, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SitemapEntry), args.Error(1)
+}
+
+func (m *MockProductRepo) SitemapSellerPages(ctx context.Context, perPage int) ([]time.Time, error) {
+	args := m.Called(ctx, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]time.Time), args.Error(1)
+}
+
+func (m *MockProductRepo) FindPriceHistory(ctx context.Context, productID string) ([]models.PriceChange, error) {
+	args := m.Called(ctx, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PriceChange), args.Error(1)
+}
+
+func (m *MockProductRepo) FindDuplicateCandidates(ctx context.Context, q models.DuplicateQuery) ([]models.DuplicateCandidate, error) {
+	args := m.Called(ctx, q)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DuplicateCandidate), args.Error(1)
+}
+
+func (m *MockProductRepo) SaveDuplicateFlags(ctx context.Context, productID uuid.UUID, matches []models.DuplicateMatch) error {
+	args := m.Called(ctx, productID, matches)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) FindDuplicateFlags(ctx context.Context, status models.DuplicateFlagStatus, limit, offset int) ([]*models.DuplicateFlag, error) {
+	args := m.Called(ctx, status, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.DuplicateFlag), args.Error(1)
+}
+
+func (m *MockProductRepo) FindDuplicateFlag(ctx context.Context, id string) (*models.DuplicateFlag, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DuplicateFlag), args.Error(1)
+}
+
+func (m *MockProductRepo) ResolveDuplicateFlag(ctx context.Context, id string, status models.DuplicateFlagStatus, resolvedBy uuid.UUID) error {
+	args := m.Called(ctx, id, status, resolvedBy)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) AddRevision(ctx context.Context, rev *models.Revision, baseline *models.ListingContent) error {
+	args := m.Called(ctx, rev, baseline)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) FindRevisions(ctx context.Context, productID string) ([]*models.Revision, error) {
+	args := m.Called(ctx, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Revision), args.Error(1)
+}
+
+func (m *MockProductRepo) FindRevision(ctx context.Context, productID string, version int) (*models.Revision, error) {
+	args := m.Called(ctx, productID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Revision), args.Error(1)
+}
+
+func (m *MockProductRepo) SaveAutosave(ctx context.Context, a *models.Autosave) error {
+	args := m.Called(ctx, a)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) FindAutosave(ctx context.Context, productID uuid.UUID) (*models.Autosave, error) {
+	args := m.Called(ctx, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Autosave), args.Error(1)
+}
+
+func (m *MockProductRepo) DeleteAutosave(ctx context.Context, productID uuid.UUID) error {
+	args := m.Called(ctx, productID)
+	return args.Error(0)
+}
+
+func (m *MockProductRepo) FindSellerListings(ctx context.Context, userID string, f models.DashboardFilters) ([]*models.SellerListing, error) {
+	args := m.Called(ctx, userID, f)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SellerListing), args.Error(1)
+}
+
+func (m *MockProductRepo) CountSellerListings(ctx context.Context, userID string, expiringBefore time.Time) (map[models.ProductStatus]int, int, error) {
+	args := m.Called(ctx, userID, expiringBefore)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).(map[models.ProductStatus]int), args.Int(1), args.Error(2)
+}
+
+func (m *MockProductRepo) FindSellerListingIDs(ctx context.Context, userID string, status models.ProductStatus, expiringBefore *time.Time) ([]uuid.UUID, error) {
+	args := m.Called(ctx, userID, status, expiringBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockProductRepo) FindSimilar(ctx context.Context, src *models.Product, limit int) ([]*models.SimilarListing, error) {
+	args := m.Called(ctx, src, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SimilarListing), args.Error(1)
+}
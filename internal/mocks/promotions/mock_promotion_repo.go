@@ -0,0 +1,58 @@
+package promotions
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	"github.com/hfleury/horsemarketplacebk/internal/promotions"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPromotionRepo struct {
+	mock.Mock
+}
+
+func (m *MockPromotionRepo) FindListing(ctx context.Context, productID string) (*promotions.Listing, error) {
+	args := m.Called(ctx, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*promotions.Listing), args.Error(1)
+}
+
+func (m *MockPromotionRepo) Create(ctx context.Context, p *promotions.Promotion) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *MockPromotionRepo) FindByID(ctx context.Context, id string) (*promotions.Promotion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*promotions.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepo) FindAll(ctx context.Context, filters promotions.PromotionFilters) ([]*promotions.Promotion, error) {
+	args := m.Called(ctx, filters)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*promotions.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepo) Cancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPromotionRepo) ExpireEnded(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPromotionRepo) AddCounts(ctx context.Context, batch string, counts []analytics.DailyCount) error {
+	args := m.Called(ctx, batch, counts)
+	return args.Error(0)
+}
@@ -0,0 +1,66 @@
+package reports
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/reports"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReportRepo struct {
+	mock.Mock
+}
+
+func (m *MockReportRepo) FindListing(ctx context.Context, productID string) (*reports.Listing, error) {
+	args := m.Called(ctx, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*reports.Listing), args.Error(1)
+}
+
+func (m *MockReportRepo) CountSince(ctx context.Context, reporterID uuid.UUID, since time.Time) (int, error) {
+	args := m.Called(ctx, reporterID, since)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockReportRepo) Create(ctx context.Context, r *reports.Report) (bool, error) {
+	args := m.Called(ctx, r)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReportRepo) CountOpenReporters(ctx context.Context, productID uuid.UUID) (int, error) {
+	args := m.Called(ctx, productID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockReportRepo) Hide(ctx context.Context, productID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, productID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReportRepo) FindQueue(ctx context.Context, limit, offset int) ([]*reports.ListingReports, error) {
+	args := m.Called(ctx, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*reports.ListingReports), args.Error(1)
+}
+
+func (m *MockReportRepo) FindOpen(ctx context.Context, productID uuid.UUID) ([]reports.Report, error) {
+	args := m.Called(ctx, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]reports.Report), args.Error(1)
+}
+
+func (m *MockReportRepo) Resolve(ctx context.Context, productID uuid.UUID, action reports.Action, note *string, resolvedBy uuid.UUID) ([]reports.Report, error) {
+	args := m.Called(ctx, productID, action, note, resolvedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]reports.Report), args.Error(1)
+}
@@ -0,0 +1,86 @@
+package savedsearches
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSavedSearchRepo struct {
+	mock.Mock
+}
+
+func (m *MockSavedSearchRepo) Create(ctx context.Context, search *models.SavedSearch) (*models.SavedSearch, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SavedSearch), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) Update(ctx context.Context, search *models.SavedSearch) (*models.SavedSearch, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SavedSearch), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) Delete(ctx context.Context, id string, userID string) (bool, error) {
+	args := m.Called(ctx, id, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) FindByID(ctx context.Context, id string) (*models.SavedSearch, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SavedSearch), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) FindByUser(ctx context.Context, userID string) ([]*models.SavedSearch, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SavedSearch), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) FindActive(ctx context.Context) ([]*models.SavedSearch, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SavedSearch), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) DisableByToken(ctx context.Context, token string) (bool, error) {
+	args := m.Called(ctx, token)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) RecordMatch(ctx context.Context, searchID uuid.UUID, productID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, searchID, productID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) RecordPriceDrop(ctx context.Context, searchID uuid.UUID, productID uuid.UUID, reducedFromSEK float64) (bool, error) {
+	args := m.Called(ctx, searchID, productID, reducedFromSEK)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSavedSearchRepo) MarkNotified(ctx context.Context, searchID uuid.UUID, productIDs []uuid.UUID) error {
+	args := m.Called(ctx, searchID, productIDs)
+	return args.Error(0)
+}
+
+func (m *MockSavedSearchRepo) FindPendingDigestMatches(ctx context.Context) ([]*models.PendingMatch, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.PendingMatch), args.Error(1)
+}
@@ -24,3 +24,33 @@ func (m *MockSettingsRepo) IsProductApprovalRequired(ctx context.Context) (bool,
 	args := m.Called(ctx)
 	return args.Bool(0), args.Error(1)
 }
+
+func (m *MockSettingsRepo) ListingDurationDays(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSettingsRepo) ExpiryReminderDays(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSettingsRepo) PedigreeMaxGenerations(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSettingsRepo) DuplicateListingPolicy(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSettingsRepo) DuplicateListingThreshold(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSettingsRepo) ReportHideThreshold(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+)
+
+// Dashboard lists the caller's own listings, all tabs or the ?status= one.
+func (h *ProductHandler) Dashboard(c *gin.Context) {
+	f := models.DashboardFilters{Status: models.ProductStatus(c.Query("status"))}
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("invalid limit"))
+			return
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil || f.Offset < 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("invalid offset"))
+			return
+		}
+	}
+
+	dashboard, err := h.service.Dashboard(c.Request.Context(), c.GetString("user_id"), f)
+	if err != nil {
+		if err == services.ErrInvalidSearch {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("status must be published, pending_approval, draft, sold or archived"))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to load dashboard", map[string]any{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to load dashboard"))
+		return
+	}
+
+	products := make([]*models.Product, len(dashboard.Listings))
+	for i, l := range dashboard.Listings {
+		products[i] = l.Product
+	}
+	if !h.convertPrices(c, c.Query("currency"), products) {
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(dashboard))
+}
+
+// BulkAction applies a quick action to the caller's listings.
+func (h *ProductHandler) BulkAction(c *gin.Context) {
+	var req struct {
+		Action models.BulkAction `json:"action" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(services.ErrInvalidBulkAction.Error()))
+		return
+	}
+
+	result, err := h.service.BulkAction(c.Request.Context(), c.GetString("user_id"), req.Action)
+	if err != nil {
+		if err == services.ErrInvalidBulkAction {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to apply bulk action", map[string]any{"error": err.Error(), "action": req.Action})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to apply bulk action"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(result))
+}
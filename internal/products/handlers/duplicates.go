@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+)
+
+// ListDuplicateFlags is the moderation queue of likely duplicates, open
+// flags by default.
+func (h *ProductHandler) ListDuplicateFlags(c *gin.Context) {
+	var limit, offset int
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("invalid limit"))
+			return
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("invalid offset"))
+			return
+		}
+	}
+
+	flags, err := h.service.DuplicateFlags(c.Request.Context(), models.DuplicateFlagStatus(c.Query("status")), limit, offset)
+	if err != nil {
+		if err == services.ErrInvalidSearch {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("status must be open, confirmed or dismissed"))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to list duplicate flags", map[string]any{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to list duplicate flags"))
+		return
+	}
+	if flags == nil {
+		flags = []*models.DuplicateFlag{}
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(flags))
+}
+
+// ResolveDuplicateFlag confirms a duplicate, archiving it, or dismisses the flag.
+func (h *ProductHandler) ResolveDuplicateFlag(c *gin.Context) {
+	var req struct {
+		Status models.DuplicateFlagStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(services.ErrInvalidResolution.Error()))
+		return
+	}
+
+	flag, err := h.service.ResolveDuplicateFlag(c.Request.Context(), c.Param("flagId"), req.Status, c.GetString("user_id"))
+	if err != nil {
+		switch err {
+		case services.ErrInvalidResolution:
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		case services.ErrDuplicateFlagNotFound:
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+		case services.ErrUnauthorized:
+			c.JSON(http.StatusForbidden, common.NewErrorResponse(err.Error()))
+		default:
+			h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to resolve duplicate flag", map[string]any{"error": err.Error(), "id": c.Param("flagId")})
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to resolve duplicate flag"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(flag))
+}
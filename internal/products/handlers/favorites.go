@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+)
+
+func (h *ProductHandler) AddFavorite(c *gin.Context) {
+	id := c.Param("id")
+	userID := c.GetString("user_id")
+
+	if err := h.service.AddFavorite(c.Request.Context(), userID, id); err != nil {
+		if err == services.ErrProductNotFound {
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to add favorite", map[string]any{"error": err.Error(), "id": id})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to add favorite"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Product added to favorites"))
+}
+
+func (h *ProductHandler) RemoveFavorite(c *gin.Context) {
+	id := c.Param("id")
+	userID := c.GetString("user_id")
+
+	if err := h.service.RemoveFavorite(c.Request.Context(), userID, id); err != nil {
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to remove favorite", map[string]any{"error": err.Error(), "id": id})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to remove favorite"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Product removed from favorites"))
+}
+
+func (h *ProductHandler) ListFavorites(c *gin.Context) {
+	products, err := h.service.ListFavorites(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to list favorites", map[string]any{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to list favorites"))
+		return
+	}
+	if !h.convertPrices(c, c.Query("currency"), products) {
+		return
+	}
+	hidePrivate(c, products)
+	localize(c, products)
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(products))
+}
+
+// convertPrices adds prices in the ?currency= the client asked for. It
+// answers 400 and returns false when the currency can't be converted.
+func (h *ProductHandler) convertPrices(c *gin.Context, code string, products []*models.Product) bool {
+	err := h.service.ConvertPrices(c.Request.Context(), code, products)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, currency.ErrUnsupportedCurrency) || errors.Is(err, currency.ErrRateUnavailable) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		return false
+	}
+	h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to convert prices", map[string]any{"error": err.Error(), "currency": code})
+	c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to convert prices"))
+	return false
+}
+
+// markFavorited fills in is_favorited for the authenticated viewer. It is
+// best effort: a failure is logged and the flags are left false.
+func (h *ProductHandler) markFavorited(c *gin.Context, products []*models.Product) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		return
+	}
+	if err := h.service.MarkFavorited(c.Request.Context(), userID, products); err != nil {
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to load favorites", map[string]any{"error": err.Error()})
+	}
+}
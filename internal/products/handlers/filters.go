@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// ParseProductFilters reads the search filters from the query string. The
+// listing feeds accept the same parameters as the search.
+func ParseProductFilters(c *gin.Context) (*models.ProductFilters, error) {
+	f := &models.ProductFilters{
+		Query:         c.Query("q"),
+		CategoryID:    c.Query("category_id"),
+		Type:          models.ProductType(c.Query("type")),
+		Status:        models.ProductStatus(c.Query("status")),
+		Breed:         c.Query("breed"),
+		Gender:        c.Query("gender"),
+		Color:         c.Query("color"),
+		DressageLevel: c.Query("dressage_level"),
+		JumpLevel:     c.Query("jump_level"),
+		Make:          c.Query("make"),
+		Model:         c.Query("model"),
+		Condition:     c.Query("condition"),
+		Sire:          c.Query("sire"),
+		Dam:           c.Query("dam"),
+		DamSire:       c.Query("dam_sire"),
+		Ancestor:      c.Query("ancestor"),
+		ServiceKind:   c.Query("service_kind"),
+		PricingUnit:   c.Query("pricing_unit"),
+		Covers:        c.Query("covers"),
+		AvailableOn:   c.Query("available_on"),
+		HasArena:      c.Query("has_arena") == "true",
+		Reduced:       c.Query("reduced") == "true",
+		Currency:      strings.ToUpper(c.Query("currency")),
+		City:          c.Query("city"),
+		Sort:          models.SortOrder(c.Query("sort")),
+	}
+
+	var err error
+	if f.MinPrice, err = parseOptionalFloat(c.Query("min_price")); err != nil {
+		return nil, errors.New("invalid min_price")
+	}
+	if f.MaxPrice, err = parseOptionalFloat(c.Query("max_price")); err != nil {
+		return nil, errors.New("invalid max_price")
+	}
+	if near := c.Query("near"); near != "" {
+		// Either "lat,lng" or a place name / postal code the service resolves
+		if latStr, lngStr, ok := strings.Cut(near, ","); ok {
+			lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+			lng, lngErr := strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+			if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+				return nil, errors.New("invalid near, expected lat,lng")
+			}
+			f.NearLat, f.NearLng = &lat, &lng
+		} else {
+			f.NearPlace = near
+		}
+	}
+	if f.RadiusKM, err = parseOptionalFloat(c.Query("radius_km")); err != nil {
+		return nil, errors.New("invalid radius_km")
+	}
+	if f.MinLandAreaHa, err = parseOptionalFloat(c.Query("min_land_area_ha")); err != nil {
+		return nil, errors.New("invalid min_land_area_ha")
+	}
+	if f.MinStalls, err = parseOptionalInt(c.Query("min_stalls")); err != nil {
+		return nil, errors.New("invalid min_stalls")
+	}
+	if f.MinPaddocks, err = parseOptionalInt(c.Query("min_paddocks")); err != nil {
+		return nil, errors.New("invalid min_paddocks")
+	}
+	if f.MinLivingSpaceM2, err = parseOptionalInt(c.Query("min_living_space_m2")); err != nil {
+		return nil, errors.New("invalid min_living_space_m2")
+	}
+	if f.Attributes, err = parseAttributeFilters(c); err != nil {
+		return nil, err
+	}
+	if v := c.Query("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			return nil, errors.New("invalid limit")
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil || f.Offset < 0 {
+			return nil, errors.New("invalid offset")
+		}
+	}
+
+	return f, nil
+}
+
+func parseOptionalFloat(v string) (*float64, error) {
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func parseOptionalInt(v string) (*int, error) {
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// parseAttributeFilters reads attr.<key>=value, attr.<key>.min=n and
+// attr.<key>.max=n.
+func parseAttributeFilters(c *gin.Context) ([]models.AttributeFilter, error) {
+	byKey := make(map[string]*models.AttributeFilter)
+	for param, values := range c.Request.URL.Query() {
+		rest, ok := strings.CutPrefix(param, "attr.")
+		if !ok || len(values) == 0 || values[0] == "" {
+			continue
+		}
+		key, bound, _ := strings.Cut(rest, ".")
+		if key == "" {
+			return nil, errors.New("invalid " + param)
+		}
+		f := byKey[key]
+		if f == nil {
+			f = &models.AttributeFilter{Key: key}
+			byKey[key] = f
+		}
+
+		value := values[0]
+		switch bound {
+		case "":
+			f.Value = &value
+		case "min", "max":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.New("invalid " + param)
+			}
+			if bound == "min" {
+				f.Min = &n
+			} else {
+				f.Max = &n
+			}
+		default:
+			return nil, errors.New("invalid " + param)
+		}
+	}
+
+	filters := make([]models.AttributeFilter, 0, len(byKey))
+	for _, f := range byKey {
+		filters = append(filters, *f)
+	}
+	// Stable SQL for identical searches
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Key < filters[j].Key })
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return filters, nil
+}
@@ -1,19 +1,36 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
 	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
 	"github.com/hfleury/horsemarketplacebk/internal/products/models"
 	"github.com/hfleury/horsemarketplacebk/internal/products/services"
 )
 
+// ViewRecorder counts listing views for the seller's statistics.
+type ViewRecorder interface {
+	RecordView(ctx context.Context, product *models.Product, visitor analytics.Visitor)
+}
+
+// ImpressionRecorder counts how often featured listings are shown.
+type ImpressionRecorder interface {
+	RecordImpressions(ctx context.Context, promotionIDs []uuid.UUID, visitor analytics.Visitor)
+}
+
 type ProductHandler struct {
-	service services.ProductService
-	logger  config.Logging
+	service     services.ProductService
+	logger      config.Logging
+	views       ViewRecorder
+	impressions ImpressionRecorder
 }
 
 func NewProductHandler(service services.ProductService, logger config.Logging) *ProductHandler {
@@ -23,6 +40,17 @@ func NewProductHandler(service services.ProductService, logger config.Logging) *
 	}
 }
 
+// SetViewRecorder enables view counting on GET /products/:id.
+func (h *ProductHandler) SetViewRecorder(views ViewRecorder) {
+	h.views = views
+}
+
+// SetImpressionRecorder enables counting impressions of featured listings
+// on GET /products.
+func (h *ProductHandler) SetImpressionRecorder(impressions ImpressionRecorder) {
+	h.impressions = impressions
+}
+
 func (h *ProductHandler) Create(c *gin.Context) {
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
@@ -38,11 +66,12 @@ func (h *ProductHandler) Create(c *gin.Context) {
 		return
 	}
 	product.UserID = uuid.MustParse(userIDStr.(string))
+	// External ids are only assigned by feed imports
+	product.ExternalID = nil
 
 	createdProduct, err := h.service.Create(c.Request.Context(), &product)
 	if err != nil {
-		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to create product", map[string]any{"error": err.Error()})
-		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to create product"))
+		h.respondEditError(c, err, "Failed to create product")
 		return
 	}
 
@@ -56,7 +85,7 @@ func (h *ProductHandler) Get(c *gin.Context) {
 		return
 	}
 
-	product, err := h.service.FindByID(c.Request.Context(), id)
+	product, err := h.service.FindByID(c.Request.Context(), id, viewerFrom(c))
 	if err != nil {
 		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to get product", map[string]any{"error": err.Error(), "id": id})
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Internal server error"))
@@ -66,33 +95,86 @@ func (h *ProductHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusNotFound, common.NewErrorResponse("Product not found"))
 		return
 	}
+	if !h.convertPrices(c, c.Query("currency"), []*models.Product{product}) {
+		return
+	}
+	h.markFavorited(c, []*models.Product{product})
+	hidePrivate(c, []*models.Product{product})
+	localize(c, []*models.Product{product})
+	c.Header("Content-Language", product.Language)
+	if h.views != nil {
+		h.views.RecordView(c.Request.Context(), product, analytics.VisitorFromContext(c))
+	}
 
-	c.JSON(http.StatusOK, common.NewSuccessResponse(product))
+	common.WriteVersioned(c, product.UpdatedAt, productCacheControl, common.NewSuccessResponse(product))
 }
 
 func (h *ProductHandler) List(c *gin.Context) {
-	// Parse query params for search
-	query := c.Query("q")
-	categoryID := c.Query("category_id")
-
-	// Check for field specific parameters (basic implementation)
-	fieldMap := make(map[string]string)
-	if model := c.Query("model"); model != "" {
-		fieldMap["model"] = model
-	}
-	if make := c.Query("make"); make != "" {
-		fieldMap["make"] = make
+	filters, err := ParseProductFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		return
 	}
+	filters.Viewer = viewerFrom(c)
+	withFacets := c.Query("facets") == "true"
 
-	// If no search params, FindAll called inside Search via service logic
-	products, err := h.service.Search(c.Request.Context(), query, categoryID, fieldMap)
+	result, err := h.service.Search(c.Request.Context(), filters, withFacets)
 	if err != nil {
+		if errors.Is(err, services.ErrUnknownLocation) || errors.Is(err, services.ErrInvalidSearch) ||
+			errors.Is(err, currency.ErrUnsupportedCurrency) || errors.Is(err, currency.ErrRateUnavailable) {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+			return
+		}
 		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to list products", map[string]any{"error": err.Error()})
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to list products"))
 		return
 	}
 
-	c.JSON(http.StatusOK, common.NewSuccessResponse(products))
+	if !h.convertPrices(c, filters.Currency, result.Products) {
+		return
+	}
+	h.markFavorited(c, result.Products)
+	hidePrivate(c, result.Products)
+	localize(c, result.Products)
+	h.recordImpressions(c, result.Products)
+
+	// Keep the plain list response for callers that did not ask for facets
+	if !withFacets {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(result.Products))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(result))
+}
+
+func (h *ProductHandler) recordImpressions(c *gin.Context, products []*models.Product) {
+	if h.impressions == nil {
+		return
+	}
+	var ids []uuid.UUID
+	for _, p := range products {
+		if p.Featured != nil {
+			ids = append(ids, p.Featured.PromotionID)
+		}
+	}
+	h.impressions.RecordImpressions(c.Request.Context(), ids, analytics.VisitorFromContext(c))
+}
+
+func (h *ProductHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	var input models.Product
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Invalid product payload", map[string]any{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid payload"))
+		return
+	}
+
+	updated, err := h.service.Update(c.Request.Context(), id, &input, c.GetString("user_id"), c.GetString("role") == "admin", ifMatch(c))
+	if err != nil {
+		h.respondEditError(c, err, "Failed to update product")
+		return
+	}
+
+	common.WriteVersion(c, updated.UpdatedAt, common.NewSuccessResponse(updated))
 }
 
 func (h *ProductHandler) UpdateStatus(c *gin.Context) {
@@ -110,9 +192,13 @@ func (h *ProductHandler) UpdateStatus(c *gin.Context) {
 	role, _ := c.Get("role")
 	isAdmin := role == "admin" // Assuming "admin" is the role string, check constants if available
 
-	err := h.service.UpdateStatus(c.Request.Context(), id, req.Status, userIDStr.(string), isAdmin)
+	err := h.service.UpdateStatus(c.Request.Context(), id, req.Status, userIDStr.(string), isAdmin, ifMatch(c))
 	if err != nil {
-		if err == services.ErrUnauthorized {
+		if err == services.ErrVersionConflict {
+			c.JSON(http.StatusPreconditionFailed, common.NewErrorResponse(err.Error()))
+			return
+		}
+		if err == services.ErrUnauthorized || err == services.ErrListingHidden {
 			c.JSON(http.StatusForbidden, common.NewErrorResponse(err.Error()))
 			return
 		}
@@ -120,6 +206,11 @@ func (h *ProductHandler) UpdateStatus(c *gin.Context) {
 			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
 			return
 		}
+		var duplicateErr *models.DuplicateError
+		if err == services.ErrDuplicateIdentity || errors.As(err, &duplicateErr) {
+			c.JSON(http.StatusConflict, common.NewErrorResponse(err.Error()))
+			return
+		}
 
 		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to update status", map[string]any{"error": err.Error(), "id": id})
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to update status"))
@@ -151,3 +242,156 @@ func (h *ProductHandler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse("Product deleted"))
 }
+
+func (h *ProductHandler) Renew(c *gin.Context) {
+	product, err := h.service.Renew(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondRenewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(product))
+}
+
+// RenewByToken serves the one-click link of the expiry reminder email.
+// ConfirmRenewByToken is the link from the reminder email. It only asks to
+// confirm, the renewal is the POST of the page.
+func (h *ProductHandler) ConfirmRenewByToken(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Token required"))
+		return
+	}
+
+	common.WriteConfirmation(c, "Renew listing", "Keep your listing online for another listing period?", "Renew", token)
+}
+
+func (h *ProductHandler) RenewByToken(c *gin.Context) {
+	token := common.LinkToken(c)
+	if token == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Token required"))
+		return
+	}
+
+	product, err := h.service.RenewByToken(c.Request.Context(), token)
+	if err != nil {
+		h.respondRenewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(product))
+}
+
+func (h *ProductHandler) respondRenewError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrProductNotFound:
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+	case services.ErrUnauthorized:
+		c.JSON(http.StatusForbidden, common.NewErrorResponse(err.Error()))
+	case services.ErrCannotRenew, services.ErrDuplicateIdentity:
+		c.JSON(http.StatusConflict, common.NewErrorResponse(err.Error()))
+	default:
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to renew product", map[string]any{"error": err.Error(), "id": c.Param("id")})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to renew product"))
+	}
+}
+
+func (h *ProductHandler) Pedigree(c *gin.Context) {
+	generations := 0
+	if v := c.Query("generations"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("generations must be a positive integer"))
+			return
+		}
+		generations = n
+	}
+
+	chart, err := h.service.Pedigree(c.Request.Context(), c.Param("id"), generations, viewerFrom(c))
+	if err != nil {
+		if err == services.ErrProductNotFound {
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to get pedigree", map[string]any{"error": err.Error(), "id": c.Param("id")})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Internal server error"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(chart))
+}
+
+func (h *ProductHandler) PriceHistory(c *gin.Context) {
+	history, err := h.service.PriceHistory(c.Request.Context(), c.Param("id"), viewerFrom(c))
+	if err != nil {
+		if err == services.ErrProductNotFound {
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to get price history", map[string]any{"error": err.Error(), "id": c.Param("id")})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Internal server error"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(history))
+}
+
+// Similar is the "similar listings" strip of a listing, ?limit= listings at most.
+func (h *ProductHandler) Similar(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("invalid limit"))
+		return
+	}
+
+	listings, err := h.service.Similar(c.Request.Context(), c.Param("id"), limit, viewerFrom(c))
+	if err != nil {
+		if err == services.ErrProductNotFound {
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to find similar listings", map[string]any{"error": err.Error(), "id": c.Param("id")})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Internal server error"))
+		return
+	}
+
+	products := make([]*models.Product, len(listings))
+	for i, l := range listings {
+		products[i] = l.Product
+	}
+	if !h.convertPrices(c, c.Query("currency"), products) {
+		return
+	}
+	h.markFavorited(c, products)
+	hidePrivate(c, products)
+	localize(c, products)
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(listings))
+}
+
+func (h *ProductHandler) BreedingCompatibility(c *gin.Context) {
+	var req models.BreedingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid payload"))
+		return
+	}
+
+	result, err := h.service.BreedingCompatibility(c.Request.Context(), &req, viewerFrom(c))
+	if err != nil {
+		var pedigreeErr *models.PedigreeError
+		switch {
+		case errors.As(err, &pedigreeErr):
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		case err == services.ErrInvalidMating, err == services.ErrNotBreedable, err == services.ErrSameHorse:
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		case err == services.ErrProductNotFound:
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+		default:
+			h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to compute breeding compatibility", map[string]any{"error": err.Error()})
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Internal server error"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(result))
+}
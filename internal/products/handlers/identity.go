@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+)
+
+// VerifyIdentity grants the verified identity badge once an admin has
+// checked the identifiers against the passport scan.
+func (h *ProductHandler) VerifyIdentity(c *gin.Context) {
+	h.setIdentityVerified(c, true)
+}
+
+func (h *ProductHandler) RevokeIdentity(c *gin.Context) {
+	h.setIdentityVerified(c, false)
+}
+
+func (h *ProductHandler) setIdentityVerified(c *gin.Context, verified bool) {
+	product, err := h.service.VerifyIdentity(c.Request.Context(), c.Param("id"), c.GetString("user_id"), verified)
+	if err != nil {
+		switch err {
+		case services.ErrProductNotFound:
+			c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+		case services.ErrIdentityIncomplete:
+			c.JSON(http.StatusUnprocessableEntity, common.NewErrorResponse(err.Error()))
+		case services.ErrUnauthorized:
+			c.JSON(http.StatusForbidden, common.NewErrorResponse(err.Error()))
+		default:
+			h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to update identity verification", map[string]any{"error": err.Error(), "id": c.Param("id")})
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to update identity verification"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(product))
+}
+
+// SearchIdentity looks listings up by UELN or microchip, in any status.
+func (h *ProductHandler) SearchIdentity(c *gin.Context) {
+	products, err := h.service.SearchIdentity(c.Request.Context(), c.Query("q"))
+	if err != nil {
+		if err == services.ErrInvalidSearch {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("q must hold at least 3 characters of a UELN or microchip"))
+			return
+		}
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, "Failed to search identities", map[string]any{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("Failed to search identities"))
+		return
+	}
+	if products == nil {
+		products = []*models.Product{}
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(products))
+}
+
+// viewerFrom is the caller of a read. Admins and sellers see deleted
+// listings, their own for sellers, with ?include_deleted=true.
+func viewerFrom(c *gin.Context) models.Viewer {
+	return models.Viewer{
+		UserID:         c.GetString("user_id"),
+		IsAdmin:        c.GetString("role") == "admin",
+		IncludeDeleted: c.Query("include_deleted") == "true",
+	}
+}
+
+// hidePrivate strips what only the seller and admins may see, such as the
+// passport scan.
+func hidePrivate(c *gin.Context, products []*models.Product) {
+	userID, isAdmin := c.GetString("user_id"), c.GetString("role") == "admin"
+	for _, p := range products {
+		p.HidePrivate(userID, isAdmin)
+	}
+}
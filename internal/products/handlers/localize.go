@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// localize shows the listings in the language the client asked for with
+// ?lang= or Accept-Language, when they are translated to it. It returns the
+// negotiated language, "" when none was supported.
+func localize(c *gin.Context, products []*models.Product) string {
+	c.Header("Vary", "Accept-Language")
+	lang := models.NegotiateLanguage(c.Query("lang"), c.GetHeader("Accept-Language"))
+	if lang == "" {
+		return ""
+	}
+	for _, p := range products {
+		p.Localize(lang)
+	}
+	return lang
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+)
+
+func (h *ProductHandler) AddMedia(c *gin.Context) {
+	var req struct {
+		MediaID   uuid.UUID `json:"media_id" binding:"required"`
+		IsPrimary bool      `json:"is_primary"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid payload"))
+		return
+	}
+
+	err := h.service.AddMedia(c.Request.Context(), c.Param("id"), req.MediaID, req.IsPrimary, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to add media")
+		return
+	}
+
+	c.JSON(http.StatusCreated, common.NewSuccessResponse("Media added to product"))
+}
+
+func (h *ProductHandler) RemoveMedia(c *gin.Context) {
+	mediaID, err := uuid.Parse(c.Param("mediaId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid media ID"))
+		return
+	}
+
+	err = h.service.RemoveMedia(c.Request.Context(), c.Param("id"), mediaID, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to remove media")
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Media removed from product"))
+}
+
+func (h *ProductHandler) ReorderMedia(c *gin.Context) {
+	var req struct {
+		MediaIDs []uuid.UUID `json:"media_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid payload"))
+		return
+	}
+
+	err := h.service.ReorderMedia(c.Request.Context(), c.Param("id"), req.MediaIDs, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to reorder media")
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Media reordered"))
+}
+
+func (h *ProductHandler) SetPrimaryMedia(c *gin.Context) {
+	mediaID, err := uuid.Parse(c.Param("mediaId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid media ID"))
+		return
+	}
+
+	err = h.service.SetPrimaryMedia(c.Request.Context(), c.Param("id"), mediaID, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to set primary media")
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Primary media updated"))
+}
+
+// respondEditError maps the errors of product edits to status codes.
+func (h *ProductHandler) respondEditError(c *gin.Context, err error, message string) {
+	var pedigreeErr *models.PedigreeError
+	var identityErr *models.IdentityError
+	var detailErr *models.DetailError
+	var attributeErr *models.AttributeError
+	if errors.As(err, &pedigreeErr) || errors.As(err, &identityErr) || errors.As(err, &detailErr) || errors.As(err, &attributeErr) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+		return
+	}
+	var duplicateErr *models.DuplicateError
+	if errors.As(err, &duplicateErr) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse(err.Error()))
+		return
+	}
+
+	switch err {
+	case services.ErrProductNotFound, services.ErrMediaNotFound, services.ErrRevisionNotFound, services.ErrNoAutosave:
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+	case services.ErrUnauthorized, services.ErrMediaNotOwned:
+		c.JSON(http.StatusForbidden, common.NewErrorResponse(err.Error()))
+	case services.ErrMediaAlreadyAttached, services.ErrDuplicateIdentity:
+		c.JSON(http.StatusConflict, common.NewErrorResponse(err.Error()))
+	case services.ErrInvalidMediaOrder, services.ErrInvalidAutosave:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+	case services.ErrVersionConflict:
+		c.JSON(http.StatusPreconditionFailed, common.NewErrorResponse(err.Error()))
+	default:
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, message, map[string]any{"error": err.Error(), "id": c.Param("id")})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse(message))
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+)
+
+// ListRevisions returns the versions of a listing, newest first.
+func (h *ProductHandler) ListRevisions(c *gin.Context) {
+	revisions, err := h.service.Revisions(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to list revisions")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(revisions))
+}
+
+func (h *ProductHandler) GetRevision(c *gin.Context) {
+	version, ok := versionParam(c, c.Param("version"))
+	if !ok {
+		return
+	}
+	revision, err := h.service.Revision(c.Request.Context(), c.Param("id"), version, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to get revision")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(revision))
+}
+
+// DiffRevisions compares ?from= with ?to=, by default the latest version
+// with the one before it.
+func (h *ProductHandler) DiffRevisions(c *gin.Context) {
+	from, to := 0, 0
+	if v := c.Query("from"); v != "" {
+		var ok bool
+		if from, ok = versionParam(c, v); !ok {
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		var ok bool
+		if to, ok = versionParam(c, v); !ok {
+			return
+		}
+	}
+
+	diff, err := h.service.DiffRevisions(c.Request.Context(), c.Param("id"), from, to, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to compare revisions")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(diff))
+}
+
+func (h *ProductHandler) RestoreRevision(c *gin.Context) {
+	version, ok := versionParam(c, c.Param("version"))
+	if !ok {
+		return
+	}
+	product, err := h.service.RestoreRevision(c.Request.Context(), c.Param("id"), version, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to restore revision")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(product))
+}
+
+func versionParam(c *gin.Context, v string) (int, bool) {
+	version, err := strconv.Atoi(v)
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("version must be a positive integer"))
+		return 0, false
+	}
+	return version, true
+}
+
+// Autosave stores the request body as the edit in progress of a listing.
+func (h *ProductHandler) Autosave(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(services.ErrInvalidAutosave.Error()))
+		return
+	}
+	autosave, err := h.service.Autosave(c.Request.Context(), c.Param("id"), body, c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to autosave")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(autosave))
+}
+
+func (h *ProductHandler) GetAutosave(c *gin.Context) {
+	autosave, err := h.service.FindAutosave(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.GetString("role") == "admin")
+	if err != nil {
+		h.respondEditError(c, err, "Failed to get autosave")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(autosave))
+}
+
+func (h *ProductHandler) DiscardAutosave(c *gin.Context) {
+	if err := h.service.DiscardAutosave(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.GetString("role") == "admin"); err != nil {
+		h.respondEditError(c, err, "Failed to discard autosave")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Autosave discarded"))
+}
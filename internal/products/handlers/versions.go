@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+)
+
+// productCacheControl lets only the client cache listings, they differ by
+// viewer, and makes it revalidate them every time.
+const productCacheControl = "private, no-cache"
+
+// ifMatch is the versions of the listing the client edits, from If-Match,
+// nil for any. The edit only writes a listing still at one of them, so a
+// listing changed since it was loaded answers 412.
+func ifMatch(c *gin.Context) []time.Time {
+	return common.IfMatchVersions(c.Request)
+}
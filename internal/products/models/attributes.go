@@ -0,0 +1,11 @@
+package models
+
+// AttributeError tells which category attribute of a listing is invalid.
+type AttributeError struct {
+	Key    string
+	Reason string
+}
+
+func (e *AttributeError) Error() string {
+	return "attributes." + e.Key + ": " + e.Reason
+}
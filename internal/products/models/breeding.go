@@ -0,0 +1,184 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// BreedingRequest describes a hypothetical mating. Each parent is either a
+// horse listing or an ad-hoc pedigree entered by the breeder.
+type BreedingRequest struct {
+	SireID *string        `json:"sire_id"`
+	DamID  *string        `json:"dam_id"`
+	Sire   *PedigreeHorse `json:"sire"`
+	Dam    *PedigreeHorse `json:"dam"`
+}
+
+type BreedingParent struct {
+	ProductID          *string `json:"product_id,omitempty"`
+	Name               string  `json:"name"`
+	RegistrationNumber *string `json:"registration_number,omitempty"`
+	Generations        int     `json:"generations"` // known generations of the parent's own pedigree
+}
+
+// CommonAncestor appears in both the sire's and the dam's pedigree. Paths
+// are relative to the foal, so "sd" is the sire's dam.
+type CommonAncestor struct {
+	Name               string   `json:"name"`
+	RegistrationNumber *string  `json:"registration_number,omitempty"`
+	SirePaths          []string `json:"sire_paths"`
+	DamPaths           []string `json:"dam_paths"`
+	// Inbreeding is the ancestor's own coefficient, as far as its pedigree is known
+	Inbreeding float64 `json:"inbreeding"`
+	// Contribution is the share of the foal's coefficient passing through this ancestor
+	Contribution float64 `json:"contribution"`
+}
+
+type BreedingResult struct {
+	Sire BreedingParent `json:"sire"`
+	Dam  BreedingParent `json:"dam"`
+	// Coefficient is Wright's inbreeding coefficient of the foal, between 0 and 1
+	Coefficient     float64          `json:"coefficient"`
+	Percentage      float64          `json:"percentage"`
+	CommonAncestors []CommonAncestor `json:"common_ancestors"`
+}
+
+// Identity is how the same horse is recognised across pedigrees: by its
+// registration number when known, otherwise by its name.
+func (h *PedigreeHorse) Identity() string {
+	if h.RegistrationNumber != nil && *h.RegistrationNumber != "" {
+		return "reg:" + strings.ToUpper(*h.RegistrationNumber)
+	}
+	return "name:" + strings.ToLower(h.Name)
+}
+
+// lineage is a path from a parent up to one of its ancestors.
+type lineage struct {
+	path    string
+	horse   *PedigreeHorse
+	through []string // identities between the parent and the ancestor, the parent included
+}
+
+func lineages(h *PedigreeHorse) []lineage {
+	var out []lineage
+	var walk func(path string, h *PedigreeHorse, through []string)
+	walk = func(path string, h *PedigreeHorse, through []string) {
+		if h == nil {
+			return
+		}
+		out = append(out, lineage{path: path, horse: h, through: through})
+		next := append(append([]string{}, through...), h.Identity())
+		walk(path+"s", h.Sire, next)
+		walk(path+"d", h.Dam, next)
+	}
+	walk("", h, nil)
+	return out
+}
+
+// inbreeding computes Wright's coefficient with the path method. Ancestors
+// that appear several times are taken with their most complete pedigree.
+type inbreeding struct {
+	best  map[string]*PedigreeHorse
+	cache map[string]float64
+	busy  map[string]bool
+}
+
+func newInbreeding(sire, dam *PedigreeHorse) *inbreeding {
+	ib := &inbreeding{best: map[string]*PedigreeHorse{}, cache: map[string]float64{}, busy: map[string]bool{}}
+	for _, parent := range []*PedigreeHorse{sire, dam} {
+		for _, l := range lineages(parent) {
+			id := l.horse.Identity()
+			if cur, ok := ib.best[id]; !ok || l.horse.depth() > cur.depth() {
+				ib.best[id] = l.horse
+			}
+		}
+	}
+	return ib
+}
+
+// of returns the coefficient of a known ancestor.
+func (ib *inbreeding) of(id string) float64 {
+	if f, ok := ib.cache[id]; ok {
+		return f
+	}
+	// A horse listed among its own ancestors is bad data, not inbreeding
+	if ib.busy[id] {
+		return 0
+	}
+	ib.busy[id] = true
+	h := ib.best[id]
+	f, _ := ib.mating(h.Sire, h.Dam)
+	delete(ib.busy, id)
+	ib.cache[id] = f
+	return f
+}
+
+func (ib *inbreeding) mating(sire, dam *PedigreeHorse) (float64, []CommonAncestor) {
+	if sire == nil || dam == nil {
+		return 0, nil
+	}
+
+	damLines := map[string][]lineage{}
+	for _, l := range lineages(dam) {
+		id := l.horse.Identity()
+		damLines[id] = append(damLines[id], l)
+	}
+
+	byID := map[string]*CommonAncestor{}
+	var order []string
+	total := 0.0
+	for _, s := range lineages(sire) {
+		id := s.horse.Identity()
+		for _, d := range damLines[id] {
+			// The two paths may only meet at the common ancestor
+			if shareAny(s.through, d.through) {
+				continue
+			}
+			ca, ok := byID[id]
+			if !ok {
+				ca = &CommonAncestor{Name: s.horse.Name, RegistrationNumber: s.horse.RegistrationNumber, Inbreeding: ib.of(id)}
+				byID[id] = ca
+				order = append(order, id)
+			}
+			contribution := math.Pow(0.5, float64(len(s.path)+len(d.path)+1)) * (1 + ca.Inbreeding)
+			ca.Contribution += contribution
+			ca.SirePaths = appendUnique(ca.SirePaths, "s"+s.path)
+			ca.DamPaths = appendUnique(ca.DamPaths, "d"+d.path)
+			total += contribution
+		}
+	}
+
+	ancestors := make([]CommonAncestor, 0, len(order))
+	for _, id := range order {
+		ancestors = append(ancestors, *byID[id])
+	}
+	sort.SliceStable(ancestors, func(i, j int) bool { return ancestors[i].Contribution > ancestors[j].Contribution })
+	return total, ancestors
+}
+
+// Mating returns the inbreeding coefficient of a foal of sire and dam and
+// the ancestors they have in common.
+func Mating(sire, dam *PedigreeHorse) (float64, []CommonAncestor) {
+	return newInbreeding(sire, dam).mating(sire, dam)
+}
+
+func shareAny(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, x := range list {
+		if x == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,53 @@
+package models
+
+import "github.com/google/uuid"
+
+// DashboardStatuses are the status tabs of the seller dashboard, in order.
+// Deleted listings are not shown.
+var DashboardStatuses = []ProductStatus{StatusPublished, StatusPendingApproval, StatusDraft, StatusSold, StatusArchived}
+
+// DashboardFilters selects the seller's listings shown in the dashboard. An
+// empty Status is every tab.
+type DashboardFilters struct {
+	Status ProductStatus
+	Limit  int
+	Offset int
+}
+
+// SellerListing is one of the seller's listings with its performance.
+// Views and favorites are the listing's ViewsCount and FavoriteCount.
+type SellerListing struct {
+	*Product
+	// Inquiries is how many times visitors asked for the seller's contact details
+	Inquiries int `json:"inquiries"`
+	// DaysUntilExpiry is only set on published listings, 0 on the last day
+	DaysUntilExpiry *int `json:"days_until_expiry,omitempty"`
+}
+
+// Dashboard is the seller's "my listings" page.
+type Dashboard struct {
+	// Counts has every tab, including the empty ones
+	Counts   map[ProductStatus]int `json:"counts"`
+	Listings []*SellerListing      `json:"listings"`
+	// Expiring is how many published listings expire before the seller
+	// is reminded, the ones BulkRenewExpiring renews
+	Expiring int `json:"expiring"`
+}
+
+// BulkAction is a quick action applied to many of the seller's listings.
+type BulkAction string
+
+const (
+	// BulkArchiveSold archives every sold listing
+	BulkArchiveSold BulkAction = "archive_sold"
+	// BulkRenewExpiring renews every published listing about to expire
+	BulkRenewExpiring BulkAction = "renew_expiring"
+)
+
+// BulkResult tells which listings a bulk action changed. Failed has the
+// reason of each listing it could not change.
+type BulkResult struct {
+	Action  BulkAction           `json:"action"`
+	Updated []uuid.UUID          `json:"updated"`
+	Failed  map[uuid.UUID]string `json:"failed"`
+}
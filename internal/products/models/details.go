@@ -0,0 +1,94 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// DetailError tells which type specific field of a listing is invalid.
+type DetailError struct {
+	Field  string // e.g. service.pricing_unit
+	Reason string
+}
+
+func (e *DetailError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// Validate checks that the property figures are not negative.
+func (p *Property) Validate() error {
+	if p.LandAreaHa != nil && *p.LandAreaHa < 0 {
+		return &DetailError{Field: "property.land_area_ha", Reason: "must not be negative"}
+	}
+	for _, f := range []struct {
+		name  string
+		value *int
+	}{
+		{"stall_count", p.StallCount}, {"paddock_count", p.PaddockCount}, {"arena_width_m", p.ArenaWidthM},
+		{"arena_length_m", p.ArenaLengthM}, {"living_space_m2", p.LivingSpaceM2},
+	} {
+		if f.value != nil && *f.value < 0 {
+			return &DetailError{Field: "property." + f.name, Reason: "must not be negative"}
+		}
+	}
+	return nil
+}
+
+// Normalize lower-cases the enumerated values and tidies the coverage areas.
+func (s *Service) Normalize() {
+	s.Kind = lowerTrimmed(s.Kind)
+	s.PricingUnit = lowerTrimmed(s.PricingUnit)
+
+	areas := make([]string, 0, len(s.CoverageAreas))
+	for _, a := range s.CoverageAreas {
+		a = strings.Join(strings.Fields(a), " ")
+		if a != "" && !slices.ContainsFunc(areas, func(x string) bool { return strings.EqualFold(x, a) }) {
+			areas = append(areas, a)
+		}
+	}
+	s.CoverageAreas = areas
+
+	days := make([]string, 0, len(s.AvailableDays))
+	for _, d := range s.AvailableDays {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if len(d) > 3 {
+			d = d[:3] // "monday" is "mon"
+		}
+		if !slices.Contains(days, d) {
+			days = append(days, d)
+		}
+	}
+	// Keep the week in order
+	slices.SortFunc(days, func(a, b string) int { return slices.Index(Weekdays, a) - slices.Index(Weekdays, b) })
+	s.AvailableDays = days
+}
+
+// Validate checks the enumerated values. Call Normalize first.
+func (s *Service) Validate() error {
+	if s.Kind != nil && !slices.Contains(ServiceKinds, *s.Kind) {
+		return &DetailError{Field: "service.service_kind", Reason: "must be one of " + strings.Join(ServiceKinds, ", ")}
+	}
+	if s.PricingUnit != nil && !slices.Contains(PricingUnits, *s.PricingUnit) {
+		return &DetailError{Field: "service.pricing_unit", Reason: "must be one of " + strings.Join(PricingUnits, ", ")}
+	}
+	for _, d := range s.AvailableDays {
+		if !slices.Contains(Weekdays, d) {
+			return &DetailError{Field: "service.available_days", Reason: "days must be one of " + strings.Join(Weekdays, ", ")}
+		}
+	}
+	if s.CoverageRadiusKM != nil && *s.CoverageRadiusKM < 0 {
+		return &DetailError{Field: "service.coverage_radius_km", Reason: "must not be negative"}
+	}
+	return nil
+}
+
+func lowerTrimmed(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.ToLower(strings.TrimSpace(*s))
+	if v == "" {
+		return nil
+	}
+	return &v
+}
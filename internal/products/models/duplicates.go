@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a listing is taken for a duplicate of another
+const (
+	DuplicateReasonIdentifier  = "identifier"  // same UELN or microchip
+	DuplicateReasonImage       = "image"       // near identical primary image
+	DuplicateReasonTitle       = "title"       // similar title
+	DuplicateReasonDescription = "description" // similar description
+)
+
+type DuplicateFlagStatus string
+
+const (
+	DuplicateFlagOpen      DuplicateFlagStatus = "open"
+	DuplicateFlagConfirmed DuplicateFlagStatus = "confirmed"
+	DuplicateFlagDismissed DuplicateFlagStatus = "dismissed"
+)
+
+// DuplicateQuery describes a listing being published, for finding the
+// active listings it may duplicate.
+type DuplicateQuery struct {
+	ProductID uuid.UUID
+	UserID    uuid.UUID
+	Type      ProductType
+	Title     string
+	// Description is compared with the seller's own listings only, where a
+	// repost often keeps the text under a new title
+	Description string
+	// PrimaryMediaID is the image compared by perceptual hash, if any
+	PrimaryMediaID *uuid.UUID
+	// MaxImageDistance is the largest number of differing hash bits of
+	// images taken for the same
+	MaxImageDistance int
+	UELN             *string
+	Microchip        *string
+	Limit            int
+}
+
+// DuplicateCandidate is an active listing that may be duplicated: one of
+// the seller's, or one of anyone's sharing an identifier, a similar primary
+// image or a similar title.
+type DuplicateCandidate struct {
+	ProductID   uuid.UUID
+	UserID      uuid.UUID
+	Title       string
+	Description string
+	UELN        *string
+	Microchip   *string
+	// ImageDistance is nil unless both listings have a hashed primary image
+	ImageDistance *int
+}
+
+// DuplicateMatch is a candidate scoring above the duplicate threshold.
+type DuplicateMatch struct {
+	DuplicateOf uuid.UUID `json:"duplicate_of"`
+	Score       float64   `json:"score"`
+	Reasons     []string  `json:"reasons"`
+	SameSeller  bool      `json:"same_seller"`
+}
+
+// DuplicateFlag holds a listing for moderation as a likely duplicate.
+type DuplicateFlag struct {
+	ID         uuid.UUID           `json:"id"`
+	ProductID  uuid.UUID           `json:"product_id"`
+	Status     DuplicateFlagStatus `json:"status"`
+	ResolvedBy *uuid.UUID          `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time          `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	DuplicateMatch
+	// Product and Original are the flagged listing and the one it duplicates
+	Product  *Product `json:"product,omitempty"`
+	Original *Product `json:"original,omitempty"`
+}
+
+// DuplicateError refuses to publish a listing under the block policy.
+type DuplicateError struct {
+	Match DuplicateMatch
+}
+
+func (e *DuplicateError) Error() string {
+	return "this listing looks like a duplicate of listing " + e.Match.DuplicateOf.String()
+}
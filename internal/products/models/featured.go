@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Featured labels a listing boosted by a running promotion in a search.
+// Clients show it as featured and report clicks with the promotion id.
+type Featured struct {
+	PromotionID uuid.UUID `json:"promotion_id"`
+	// Placement is front_page or category
+	Placement string    `json:"placement"`
+	EndsAt    time.Time `json:"ends_at"`
+}
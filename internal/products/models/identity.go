@@ -0,0 +1,138 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxNationalChipCode is the largest national code of an ISO 11784 chip,
+// which stores it in 38 bits.
+const maxNationalChipCode = 1<<38 - 1
+
+// IdentityError tells which identity field of a horse is invalid.
+type IdentityError struct {
+	Field  string // ueln, microchip or passport_issuer
+	Reason string
+}
+
+func (e *IdentityError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// NormalizeIdentifier upper-cases an UELN or microchip number and strips
+// the spaces, dashes and dots used when printing them.
+func NormalizeIdentifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(s)))
+}
+
+// NormalizeIdentity normalizes the identifiers and drops empty ones.
+func (h *Horse) NormalizeIdentity() {
+	h.UELN = normalizeIdentifier(h.UELN)
+	h.Microchip = normalizeIdentifier(h.Microchip)
+	if h.PassportIssuer != nil {
+		issuer := strings.Join(strings.Fields(*h.PassportIssuer), " ")
+		h.PassportIssuer = &issuer
+		if issuer == "" {
+			h.PassportIssuer = nil
+		}
+	}
+}
+
+func normalizeIdentifier(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	n := NormalizeIdentifier(*s)
+	if n == "" {
+		return nil
+	}
+	return &n
+}
+
+// ValidateIdentity checks the format of the identifiers. Call
+// NormalizeIdentity first.
+func (h *Horse) ValidateIdentity() error {
+	if h.UELN != nil {
+		if err := ValidateUELN(*h.UELN); err != nil {
+			return err
+		}
+	}
+	if h.Microchip != nil {
+		if err := ValidateMicrochip(*h.Microchip); err != nil {
+			return err
+		}
+	}
+	if h.PassportIssuer != nil && len(*h.PassportIssuer) > 100 {
+		return &IdentityError{Field: "passport_issuer", Reason: "must be at most 100 characters"}
+	}
+	return nil
+}
+
+// ValidateUELN checks a Universal Equine Life Number: a 3 digit ISO 3166
+// country code, a 3 character database code and a 9 character number
+// within that database. UELN defines no check character of its own, and the
+// national numbers embedded in it use differing schemes, so only the
+// structure is checked.
+func ValidateUELN(ueln string) error {
+	if len(ueln) != 15 {
+		return &IdentityError{Field: "ueln", Reason: "must be 15 characters"}
+	}
+	if !isDigits(ueln[:3]) {
+		return &IdentityError{Field: "ueln", Reason: "must start with a 3 digit country code"}
+	}
+	if country, _ := strconv.Atoi(ueln[:3]); country == 0 || country > 899 {
+		return &IdentityError{Field: "ueln", Reason: "unknown country code " + ueln[:3]}
+	}
+	for _, r := range ueln[3:] {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'Z') {
+			return &IdentityError{Field: "ueln", Reason: "may only contain letters and digits"}
+		}
+	}
+	return nil
+}
+
+// ValidateMicrochip checks an ISO 11784 transponder number in its 15 digit
+// form: a country code (001-899) or manufacturer code (900-998) followed by
+// a 12 digit code. The standard has no check digit in this form; the codes
+// are checked against the ranges the chip can actually carry instead.
+func ValidateMicrochip(chip string) error {
+	if len(chip) != 15 || !isDigits(chip) {
+		return &IdentityError{Field: "microchip", Reason: "must be 15 digits"}
+	}
+	code, _ := strconv.Atoi(chip[:3])
+	switch {
+	case code == 0:
+		return &IdentityError{Field: "microchip", Reason: "country code 000 is not assigned"}
+	case code == 999:
+		return &IdentityError{Field: "microchip", Reason: "999 is reserved for test transponders"}
+	}
+	if national, _ := strconv.ParseInt(chip[3:], 10, 64); national > maxNationalChipCode {
+		return &IdentityError{Field: "microchip", Reason: "national code is out of range"}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// HidePrivate removes what only the seller and admins may see.
+func (p *Product) HidePrivate(viewerID string, isAdmin bool) {
+	if isAdmin || p.UserID.String() == viewerID {
+		return
+	}
+	if p.Horse != nil {
+		p.Horse.PassportMediaID = nil
+	}
+}
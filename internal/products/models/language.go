@@ -0,0 +1,80 @@
+package models
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Languages listings can be written and translated in.
+var Languages = []string{"sv", "en"}
+
+// DefaultLanguage is the language of listings whose seller didn't say.
+const DefaultLanguage = "sv"
+
+// Translation is the title and description of a listing in another
+// language than its own.
+type Translation struct {
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+}
+
+func IsLanguage(code string) bool {
+	return slices.Contains(Languages, code)
+}
+
+// NegotiateLanguage picks the language listings are shown in: the ?lang=
+// parameter, else the preferred supported language of the Accept-Language
+// header. It returns "" when neither names a supported language, listings
+// are then shown in their own language.
+func NegotiateLanguage(lang string, acceptLanguage string) string {
+	if lang = strings.ToLower(strings.TrimSpace(lang)); IsLanguage(lang) {
+		return lang
+	}
+
+	type preference struct {
+		code string
+		q    float64
+	}
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// sv-SE is Swedish, whatever the region
+		code, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if q > 0 && IsLanguage(code) {
+			prefs = append(prefs, preference{code, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	if len(prefs) == 0 {
+		return ""
+	}
+	return prefs[0].code
+}
+
+// Localize shows the listing in lang when it has a translation, otherwise
+// in its own language. The other versions are kept in Translations, so the
+// listing can be saved back as it is shown.
+func (p *Product) Localize(lang string) {
+	t, ok := p.Translations[lang]
+	if lang == p.Language || !ok {
+		return
+	}
+	translations := make(map[string]Translation, len(p.Translations))
+	for code, other := range p.Translations {
+		if code != lang {
+			translations[code] = other
+		}
+	}
+	translations[p.Language] = Translation{Title: p.Title, Description: p.Description}
+	p.Title, p.Description, p.Language, p.Translations = t.Title, t.Description, lang, translations
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	maxPedigreeNameLength = 100
+	maxRegistrationLength = 50
+)
+
+// Pedigree is the ancestry of a horse: its sire and dam, each with their
+// own sire and dam, down to a configurable number of generations.
+type Pedigree struct {
+	Sire *PedigreeHorse `json:"sire,omitempty"`
+	Dam  *PedigreeHorse `json:"dam,omitempty"`
+}
+
+type PedigreeHorse struct {
+	Name               string  `json:"name"`
+	RegistrationNumber *string `json:"registration_number,omitempty"`
+	YearOfBirth        *int    `json:"year_of_birth,omitempty"`
+	Breed              *string `json:"breed,omitempty"`
+	Color              *string `json:"color,omitempty"`
+
+	Sire *PedigreeHorse `json:"sire,omitempty"`
+	Dam  *PedigreeHorse `json:"dam,omitempty"`
+}
+
+// PedigreeError tells which ancestor of a pedigree is invalid.
+type PedigreeError struct {
+	Path   string // e.g. pedigree.dam.sire
+	Reason string
+}
+
+func (e *PedigreeError) Error() string {
+	return e.Path + ": " + e.Reason
+}
+
+// Ancestor is one entry of the flattened pedigree. Path spells the lineage
+// from the horse: "s" is the sire, "d" the dam, "ds" the dam's sire.
+type Ancestor struct {
+	Path               string
+	Name               string
+	RegistrationNumber *string
+}
+
+// Generation is 1 for the parents, 2 for the grandparents and so on.
+func (a Ancestor) Generation() int {
+	return len(a.Path)
+}
+
+// Normalize trims names and registration numbers and drops empty ones.
+func (p *Pedigree) Normalize() {
+	p.Sire = p.Sire.normalize()
+	p.Dam = p.Dam.normalize()
+}
+
+func (h *PedigreeHorse) normalize() *PedigreeHorse {
+	if h == nil {
+		return nil
+	}
+	h.Name = strings.Join(strings.Fields(h.Name), " ")
+	if h.RegistrationNumber != nil {
+		reg := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(*h.RegistrationNumber), " ", ""))
+		h.RegistrationNumber = &reg
+		if reg == "" {
+			h.RegistrationNumber = nil
+		}
+	}
+	h.Sire = h.Sire.normalize()
+	h.Dam = h.Dam.normalize()
+	return h
+}
+
+// Validate checks that every ancestor has a name and that the tree is no
+// deeper than maxGenerations. Call Normalize first.
+func (p *Pedigree) Validate(maxGenerations int) error {
+	if err := p.Sire.validate("pedigree.sire", 1, maxGenerations); err != nil {
+		return err
+	}
+	return p.Dam.validate("pedigree.dam", 1, maxGenerations)
+}
+
+func (h *PedigreeHorse) validate(path string, generation, maxGenerations int) error {
+	if h == nil {
+		return nil
+	}
+	if generation > maxGenerations {
+		return &PedigreeError{Path: path, Reason: fmt.Sprintf("pedigree is limited to %d generations", maxGenerations)}
+	}
+	if h.Name == "" {
+		return &PedigreeError{Path: path, Reason: "name is required"}
+	}
+	if len(h.Name) > maxPedigreeNameLength {
+		return &PedigreeError{Path: path, Reason: fmt.Sprintf("name must be at most %d characters", maxPedigreeNameLength)}
+	}
+	if h.RegistrationNumber != nil && len(*h.RegistrationNumber) > maxRegistrationLength {
+		return &PedigreeError{Path: path, Reason: fmt.Sprintf("registration number must be at most %d characters", maxRegistrationLength)}
+	}
+	if h.YearOfBirth != nil && (*h.YearOfBirth < 1800 || *h.YearOfBirth > 2100) {
+		return &PedigreeError{Path: path, Reason: "year of birth is out of range"}
+	}
+	if err := h.Sire.validate(path+".sire", generation+1, maxGenerations); err != nil {
+		return err
+	}
+	return h.Dam.validate(path+".dam", generation+1, maxGenerations)
+}
+
+// Ancestors flattens the pedigree generation by generation.
+func (p *Pedigree) Ancestors() []Ancestor {
+	type entry struct {
+		path  string
+		horse *PedigreeHorse
+	}
+
+	var ancestors []Ancestor
+	queue := []entry{{"s", p.Sire}, {"d", p.Dam}}
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+		if e.horse == nil {
+			continue
+		}
+		ancestors = append(ancestors, Ancestor{Path: e.path, Name: e.horse.Name, RegistrationNumber: e.horse.RegistrationNumber})
+		queue = append(queue, entry{e.path + "s", e.horse.Sire}, entry{e.path + "d", e.horse.Dam})
+	}
+	return ancestors
+}
+
+// Depth is the number of generations present.
+func (p *Pedigree) Depth() int {
+	return max(p.Sire.depth(), p.Dam.depth())
+}
+
+func (h *PedigreeHorse) depth() int {
+	if h == nil {
+		return 0
+	}
+	return 1 + max(h.Sire.depth(), h.Dam.depth())
+}
+
+// PedigreeNode is a position of the rendered pedigree chart. Positions the
+// seller left empty are present with Known set to false so the chart keeps
+// its shape.
+type PedigreeNode struct {
+	Path               string        `json:"path"`
+	Generation         int           `json:"generation"`
+	Sex                string        `json:"sex"` // male or female
+	Known              bool          `json:"known"`
+	Name               string        `json:"name,omitempty"`
+	RegistrationNumber *string       `json:"registration_number,omitempty"`
+	YearOfBirth        *int          `json:"year_of_birth,omitempty"`
+	Breed              *string       `json:"breed,omitempty"`
+	Color              *string       `json:"color,omitempty"`
+	Sire               *PedigreeNode `json:"sire,omitempty"`
+	Dam                *PedigreeNode `json:"dam,omitempty"`
+}
+
+type PedigreeChart struct {
+	ProductID   string        `json:"product_id"`
+	Name        *string       `json:"name"`
+	Generations int           `json:"generations"`
+	Sire        *PedigreeNode `json:"sire,omitempty"`
+	Dam         *PedigreeNode `json:"dam,omitempty"`
+}
+
+// Chart builds the complete chart down to the given number of generations.
+func (p *Pedigree) Chart(generations int) (sire, dam *PedigreeNode) {
+	if generations < 1 {
+		return nil, nil
+	}
+	var s, d *PedigreeHorse
+	if p != nil {
+		s, d = p.Sire, p.Dam
+	}
+	return chartNode("s", s, generations), chartNode("d", d, generations)
+}
+
+func chartNode(path string, h *PedigreeHorse, generations int) *PedigreeNode {
+	node := &PedigreeNode{Path: path, Generation: len(path), Sex: "male"}
+	if strings.HasSuffix(path, "d") {
+		node.Sex = "female"
+	}
+
+	var sire, dam *PedigreeHorse
+	if h != nil {
+		node.Known = true
+		node.Name = h.Name
+		node.RegistrationNumber = h.RegistrationNumber
+		node.YearOfBirth = h.YearOfBirth
+		node.Breed = h.Breed
+		node.Color = h.Color
+		sire, dam = h.Sire, h.Dam
+	}
+
+	if len(path) < generations {
+		node.Sire = chartNode(path+"s", sire, generations)
+		node.Dam = chartNode(path+"d", dam, generations)
+	}
+	return node
+}
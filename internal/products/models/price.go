@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PriceReducedDays is how long a listing shows the "reduced" badge after
+// its price was lowered.
+const PriceReducedDays = 30
+
+// PriceChange is one entry of a listing's price history. OldPriceSEK is nil
+// for the price the listing was created with.
+type PriceChange struct {
+	OldPriceSEK *float64  `json:"old_price_sek"`
+	PriceSEK    *float64  `json:"price_sek"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// IsDrop reports whether the change lowered the price.
+func (c PriceChange) IsDrop() bool {
+	return c.OldPriceSEK != nil && c.PriceSEK != nil && *c.PriceSEK < *c.OldPriceSEK
+}
+
+// Money is an amount in a currency other than SEK. SEKPerUnit is the rate
+// a converted price was computed with.
+type Money struct {
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+	SEKPerUnit float64 `json:"sek_per_unit,omitempty"`
+}
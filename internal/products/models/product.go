@@ -27,20 +27,51 @@ const (
 )
 
 type Product struct {
-	ID              uuid.UUID     `json:"id"`
-	UserID          uuid.UUID     `json:"user_id"`
-	CategoryID      *uuid.UUID    `json:"category_id"`
-	Type            ProductType   `json:"type"`
-	Status          ProductStatus `json:"status"`
-	Title           string        `json:"title"`
-	PriceSEK        *float64      `json:"price_sek"`
-	Description     *string       `json:"description"`
-	City            *string       `json:"city"`
-	Area            *string       `json:"area"`
-	TransactionType *string       `json:"transaction_type"`
-	ViewsCount      int           `json:"views_count"`
-	CreatedAt       time.Time     `json:"created_at"`
-	UpdatedAt       time.Time     `json:"updated_at"`
+	ID          uuid.UUID     `json:"id"`
+	UserID      uuid.UUID     `json:"user_id"`
+	CategoryID  *uuid.UUID    `json:"category_id"`
+	Type        ProductType   `json:"type"`
+	Status      ProductStatus `json:"status"`
+	Title       string        `json:"title"`
+	PriceSEK    *float64      `json:"price_sek"`
+	Description *string       `json:"description"`
+	// Language is the language of Title and Description
+	Language string `json:"language"`
+	// Translations are the title and description in other languages, by language code
+	Translations    map[string]Translation `json:"translations,omitempty"`
+	City            *string                `json:"city"`
+	Area            *string                `json:"area"`
+	TransactionType *string                `json:"transaction_type"`
+	PostalCode      *string                `json:"postal_code"`
+	Latitude        *float64               `json:"latitude"`
+	Longitude       *float64               `json:"longitude"`
+	ViewsCount      int                    `json:"views_count"`
+	FavoriteCount   int                    `json:"favorite_count"`
+	PublishedAt     *time.Time             `json:"published_at"`
+	ExpiresAt       *time.Time             `json:"expires_at"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+	// ExternalID is the dealer's own id of a listing imported from a feed
+	ExternalID *string `json:"external_id,omitempty"`
+	// ReducedFromSEK is the price before the latest reduction, set while the
+	// listing shows the "reduced" badge
+	ReducedFromSEK *float64 `json:"reduced_from_sek,omitempty"`
+	// HiddenAt is set while the listing is hidden after abuse reports or
+	// taken down by staff
+	HiddenAt *time.Time `json:"hidden_at,omitempty"`
+	// ConvertedPrice is PriceSEK in the currency asked for with ?currency=
+	ConvertedPrice *Money `json:"converted_price,omitempty"`
+	// EnteredPrice lets sellers give the price in another currency. It is
+	// converted to PriceSEK when saved and not stored itself.
+	EnteredPrice *Money `json:"entered_price,omitempty"`
+
+	// DistanceKM is only set by searches near a point
+	DistanceKM *float64 `json:"distance_km,omitempty"`
+	// Featured is only set by searches, on listings promoted in the
+	// front page or category being searched
+	Featured *Featured `json:"featured,omitempty"`
+	// IsFavorited is relative to the viewer, always false for anonymous requests
+	IsFavorited bool `json:"is_favorited"`
 
 	// Relations
 	Category *models.Category `json:"category,omitempty"`
@@ -50,6 +81,23 @@ type Product struct {
 	Horse     *Horse     `json:"horse,omitempty"`
 	Vehicle   *Vehicle   `json:"vehicle,omitempty"`
 	Equipment *Equipment `json:"equipment,omitempty"`
+	Property  *Property  `json:"property,omitempty"`
+	Service   *Service   `json:"service,omitempty"`
+
+	// Attributes holds the values of the category's attributes by key
+	Attributes map[string]any `json:"attributes,omitempty"`
+	// AttributeValues are the validated Attributes the repository stores.
+	// Stored values are replaced when it is not nil.
+	AttributeValues []ProductAttribute `json:"-"`
+	// Duplicates are the likely duplicates found when the listing was
+	// published, flagged once it is stored.
+	Duplicates []DuplicateMatch `json:"-"`
+}
+
+// ProductAttribute is the value of one category attribute on a listing.
+type ProductAttribute struct {
+	AttributeID uuid.UUID
+	Value       models.AttributeValue
 }
 
 type ProductMedia struct {
@@ -60,5 +108,25 @@ type ProductMedia struct {
 	Media     *media.Media `json:"media,omitempty"`
 }
 
+// Watcher is a user who favorited a listing and gets notified about its changes.
+type Watcher struct {
+	UserID uuid.UUID
+	Email  string
+}
+
+// ExpiringListing is a published listing due for an expiry reminder.
+type ExpiringListing struct {
+	ProductID   uuid.UUID
+	Title       string
+	ExpiresAt   time.Time
+	SellerEmail string
+}
+
 // Helper to marshal specific data for JSON responses if needed,
 // though embedding pointers above is usually sufficient for JSON APIs.
+
+// SitemapEntry is a page of the site and when it last changed.
+type SitemapEntry struct {
+	ID           uuid.UUID
+	LastModified time.Time
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListingContent is the editable content of a listing as kept in its
+// revisions: the base fields, the type specific data and the attributes.
+type ListingContent struct {
+	CategoryID      *uuid.UUID             `json:"category_id"`
+	Title           string                 `json:"title"`
+	PriceSEK        *float64               `json:"price_sek"`
+	Description     *string                `json:"description"`
+	Language        string                 `json:"language,omitempty"`
+	Translations    map[string]Translation `json:"translations,omitempty"`
+	City            *string                `json:"city"`
+	Area            *string                `json:"area"`
+	TransactionType *string                `json:"transaction_type"`
+	PostalCode      *string                `json:"postal_code"`
+	Horse           *Horse                 `json:"horse,omitempty"`
+	Vehicle         *Vehicle               `json:"vehicle,omitempty"`
+	Equipment       *Equipment             `json:"equipment,omitempty"`
+	Property        *Property              `json:"property,omitempty"`
+	Service         *Service               `json:"service,omitempty"`
+	Attributes      map[string]any         `json:"attributes,omitempty"`
+}
+
+// ContentOf returns the editable content of a listing. The identity
+// verification is left out, it is granted by staff rather than edited.
+func ContentOf(p *Product) ListingContent {
+	c := ListingContent{
+		CategoryID:      p.CategoryID,
+		Title:           p.Title,
+		PriceSEK:        p.PriceSEK,
+		Description:     p.Description,
+		Language:        p.Language,
+		Translations:    p.Translations,
+		City:            p.City,
+		Area:            p.Area,
+		TransactionType: p.TransactionType,
+		PostalCode:      p.PostalCode,
+		Vehicle:         p.Vehicle,
+		Equipment:       p.Equipment,
+		Property:        p.Property,
+		Service:         p.Service,
+		Attributes:      p.Attributes,
+	}
+	if p.Horse != nil {
+		h := *p.Horse
+		h.IdentityVerified, h.IdentityVerifiedAt, h.IdentityVerifiedBy = false, nil, nil
+		c.Horse = &h
+	}
+	return c
+}
+
+// Apply copies the content onto a listing being edited. Attributes and
+// translations are always replaced, a version without any clears them.
+func (c ListingContent) Apply(p *Product) {
+	p.CategoryID, p.Title, p.PriceSEK, p.Description = c.CategoryID, c.Title, c.PriceSEK, c.Description
+	p.City, p.Area, p.TransactionType, p.PostalCode = c.City, c.Area, c.TransactionType, c.PostalCode
+	p.Horse, p.Vehicle, p.Equipment, p.Property, p.Service = c.Horse, c.Vehicle, c.Equipment, c.Property, c.Service
+	p.Attributes = c.Attributes
+	if p.Attributes == nil {
+		p.Attributes = map[string]any{}
+	}
+	// Versions saved before listings had languages keep the current one
+	if c.Language != "" {
+		p.Language = c.Language
+	}
+	p.Translations = c.Translations
+	if p.Translations == nil {
+		p.Translations = map[string]Translation{}
+	}
+}
+
+// Revision is one saved version of a listing.
+type Revision struct {
+	ID        uuid.UUID      `json:"id"`
+	ProductID uuid.UUID      `json:"product_id"`
+	Version   int            `json:"version"`
+	Content   ListingContent `json:"content"`
+	// ChangedFields are the fields changed since the previous version
+	ChangedFields []string   `json:"changed_fields"`
+	EditedBy      *uuid.UUID `json:"edited_by,omitempty"`
+	// RestoredFrom is the version this one was restored from
+	RestoredFrom *int `json:"restored_from,omitempty"`
+	// RequiresApproval is set when the edit sent the listing back to moderation
+	RequiresApproval bool      `json:"requires_approval"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// FieldChange is a field that differs between two versions. Fields of the
+// type specific data and attributes are dotted, such as horse.breed.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type RevisionDiff struct {
+	ProductID   uuid.UUID     `json:"product_id"`
+	FromVersion int           `json:"from_version"`
+	ToVersion   int           `json:"to_version"`
+	Changes     []FieldChange `json:"changes"`
+}
+
+// nonMaterialFields can change on a published listing without it going
+// back to moderation.
+var nonMaterialFields = map[string]bool{
+	"price_sek":   true,
+	"city":        true,
+	"area":        true,
+	"postal_code": true,
+}
+
+// IsMaterial reports whether any of the changes needs a moderator to look
+// at the listing again.
+func IsMaterial(changes []FieldChange) bool {
+	for _, c := range changes {
+		if !nonMaterialFields[c.Field] {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff lists the fields that differ from c to other, sorted by field.
+func (c ListingContent) Diff(other ListingContent) []FieldChange {
+	from, to := c.flatten(), other.flatten()
+	changes := []FieldChange{}
+	for field, value := range from {
+		if next, ok := to[field]; !ok || !reflect.DeepEqual(value, next) {
+			changes = append(changes, FieldChange{Field: field, From: value, To: to[field]})
+		}
+	}
+	for field, value := range to {
+		if _, ok := from[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, To: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// flatten maps every leaf of the JSON form of the content to its dotted
+// path. Null leaves and empty lists are left out so a missing and an empty
+// field compare equal.
+func (c ListingContent) flatten() map[string]any {
+	raw, _ := json.Marshal(c)
+	var tree map[string]any
+	_ = json.Unmarshal(raw, &tree)
+
+	fields := make(map[string]any)
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			if len(v) > 0 {
+				fields[prefix] = v
+			}
+		case map[string]any:
+			for k, child := range v {
+				if prefix == "" {
+					walk(k, child)
+				} else {
+					walk(prefix+"."+k, child)
+				}
+			}
+		default:
+			fields[prefix] = v
+		}
+	}
+	walk("", tree)
+	// The id of the listing is repeated in the type specific data
+	for _, t := range []string{"horse", "vehicle", "equipment", "property", "service"} {
+		delete(fields, t+".product_id")
+	}
+	return fields
+}
+
+// Autosave is the edit a seller has in progress. Content is what the
+// client sent, it is only validated when the edit is saved.
+type Autosave struct {
+	ProductID uuid.UUID       `json:"product_id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Content   json.RawMessage `json:"content"`
+	SavedAt   time.Time       `json:"saved_at"`
+}
@@ -0,0 +1,111 @@
+package models
+
+// ProductFilters holds every filter the product search understands.
+// Empty strings and nil pointers mean "not filtered".
+type ProductFilters struct {
+	Query      string        `json:"q,omitempty"`
+	CategoryID string        `json:"category_id,omitempty"`
+	Type       ProductType   `json:"type,omitempty"`
+	Status     ProductStatus `json:"status,omitempty"`
+
+	// Type specific
+	Breed         string `json:"breed,omitempty"`
+	Gender        string `json:"gender,omitempty"`
+	Color         string `json:"color,omitempty"`
+	DressageLevel string `json:"dressage_level,omitempty"`
+	JumpLevel     string `json:"jump_level,omitempty"`
+	Make          string `json:"make,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Condition     string `json:"condition,omitempty"`
+
+	// Pedigree, by name or registration number. DamSire is "out of a dam by".
+	Sire     string `json:"sire,omitempty"`
+	Dam      string `json:"dam,omitempty"`
+	DamSire  string `json:"dam_sire,omitempty"`
+	Ancestor string `json:"ancestor,omitempty"`
+
+	// Properties
+	MinLandAreaHa    *float64 `json:"min_land_area_ha,omitempty"`
+	MinStalls        *int     `json:"min_stalls,omitempty"`
+	MinPaddocks      *int     `json:"min_paddocks,omitempty"`
+	MinLivingSpaceM2 *int     `json:"min_living_space_m2,omitempty"`
+	HasArena         bool     `json:"has_arena,omitempty"`
+
+	// Services. Covers is a city or region the service must cover.
+	ServiceKind string `json:"service_kind,omitempty"`
+	PricingUnit string `json:"pricing_unit,omitempty"`
+	Covers      string `json:"covers,omitempty"`
+	AvailableOn string `json:"available_on,omitempty"`
+
+	// Category attributes, from attr.<key>, attr.<key>.min and attr.<key>.max
+	Attributes []AttributeFilter `json:"attributes,omitempty"`
+
+	City     string   `json:"city,omitempty"`
+	MinPrice *float64 `json:"min_price,omitempty"`
+	MaxPrice *float64 `json:"max_price,omitempty"`
+	// Currency is the currency of MinPrice and MaxPrice, SEK when empty
+	Currency string `json:"currency,omitempty"`
+	// Reduced only matches listings showing the "reduced" badge
+	Reduced bool `json:"reduced,omitempty"`
+
+	// Location. NearPlace is a town name resolved to NearLat/NearLng by the service.
+	NearPlace string   `json:"near_place,omitempty"`
+	NearLat   *float64 `json:"near_lat,omitempty"`
+	NearLng   *float64 `json:"near_lng,omitempty"`
+	RadiusKM  *float64 `json:"radius_km,omitempty"`
+
+	Sort SortOrder `json:"sort,omitempty"`
+
+	// Viewer decides which statuses match, it is never persisted
+	Viewer Viewer `json:"-"`
+
+	// Pagination, not persisted with saved searches
+	Limit  int `json:"-"`
+	Offset int `json:"-"`
+}
+
+// AttributeFilter narrows a search on one category attribute. Min and Max
+// only apply to integer attributes.
+type AttributeFilter struct {
+	Key   string  `json:"key"`
+	Value *string `json:"value,omitempty"`
+	Min   *int64  `json:"min,omitempty"`
+	Max   *int64  `json:"max,omitempty"`
+}
+
+type SortOrder string
+
+const (
+	SortNewest   SortOrder = "newest"
+	SortDistance SortOrder = "distance"
+)
+
+// HasLocation reports whether the filters are anchored to a point.
+func (f *ProductFilters) HasLocation() bool {
+	return f.NearLat != nil && f.NearLng != nil
+}
+
+// Facet names returned by the search.
+const (
+	FacetBreed              = "breed"
+	FacetGender             = "gender"
+	FacetColor              = "color"
+	FacetDressageLevel      = "dressage_level"
+	FacetJumpLevel          = "jump_level"
+	FacetVehicleMake        = "vehicle_make"
+	FacetEquipmentCondition = "equipment_condition"
+	FacetServiceKind        = "service_kind"
+	FacetPrice              = "price"
+	FacetCity               = "city"
+)
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchResult is returned instead of a bare product list when facets are requested.
+type SearchResult struct {
+	Products []*Product              `json:"products"`
+	Facets   map[string][]FacetCount `json:"facets"`
+}
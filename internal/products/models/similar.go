@@ -0,0 +1,51 @@
+package models
+
+// What a similar listing has in common with the one being viewed
+const (
+	SimilarCategory    = "category"
+	SimilarPrice       = "price"        // within SimilarRatio of the price
+	SimilarLocation    = "location"     // within SimilarDistanceKM
+	SimilarBreed       = "breed"        // horses
+	SimilarAge         = "age"          // born within SimilarBirthYears
+	SimilarHeight      = "height"       // within SimilarHeightCM at the withers
+	SimilarDiscipline  = "discipline"   // same orientation
+	SimilarMake        = "make"         // vehicles and equipment
+	SimilarWeightClass = "weight_class" // trailers in the same licence class
+	SimilarYear        = "year"         // vehicles made within SimilarVehicleYears
+	SimilarSubType     = "sub_type"     // equipment
+	SimilarServiceKind = "service_kind"
+	SimilarLandArea    = "land_area" // properties within SimilarRatio of the land area
+	SimilarStalls      = "stalls"
+)
+
+const (
+	SimilarDistanceKM   = 50
+	SimilarRatio        = 0.25
+	SimilarHeightCM     = 5
+	SimilarBirthYears   = 2
+	SimilarVehicleYears = 3
+)
+
+// WeightClasses are the upper total weights in kg of the trailer licence
+// classes, the heaviest class is above the last one.
+var WeightClasses = []int{750, 2000, 3500}
+
+// WeightClass returns the bounds in kg of the class of a total weight, the
+// lower one exclusive. The heaviest class has no upper bound, max 0.
+func WeightClass(totalWeight int) (low, high int) {
+	for _, limit := range WeightClasses {
+		if totalWeight <= limit {
+			return low, limit
+		}
+		low = limit
+	}
+	return low, 0
+}
+
+// SimilarListing is a listing recommended next to another one. Score is
+// the sum of the weights of what matched, listed in Matched.
+type SimilarListing struct {
+	*Product
+	Score   int      `json:"similarity_score"`
+	Matched []string `json:"matched"`
+}
@@ -2,23 +2,38 @@ package models
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Horse struct {
-	ProductID     uuid.UUID       `json:"product_id"`
-	Name          *string         `json:"name"`
-	Age           *int            `json:"age"`
-	YearOfBirth   *int            `json:"year_of_birth"`
-	Gender        *string         `json:"gender"`
-	Height        *int            `json:"height"`
-	Breed         *string         `json:"breed"`
-	Color         *string         `json:"color"`
-	DressageLevel *string         `json:"dressage_level"`
-	JumpLevel     *string         `json:"jump_level"`
-	Orientation   *string         `json:"orientation"`
-	Pedigree      json.RawMessage `json:"pedigree"` // JSONB
+	ProductID     uuid.UUID `json:"product_id"`
+	Name          *string   `json:"name"`
+	Age           *int      `json:"age"`
+	YearOfBirth   *int      `json:"year_of_birth"`
+	Gender        *string   `json:"gender"`
+	Height        *int      `json:"height"`
+	Breed         *string   `json:"breed"`
+	Color         *string   `json:"color"`
+	DressageLevel *string   `json:"dressage_level"`
+	JumpLevel     *string   `json:"jump_level"`
+	Orientation   *string   `json:"orientation"`
+	Pedigree      *Pedigree `json:"pedigree"` // JSONB
+	// LegacyPedigree is a pedigree stored before the schema existed that
+	// does not fit it. It is kept as stored, read only, until the seller
+	// enters a pedigree.
+	LegacyPedigree json.RawMessage `json:"legacy_pedigree,omitempty"`
+
+	// Identity
+	UELN           *string `json:"ueln"`
+	Microchip      *string `json:"microchip"`
+	PassportIssuer *string `json:"passport_issuer"`
+	// PassportMediaID is the passport scan, only shown to the seller and admins
+	PassportMediaID    *uuid.UUID `json:"passport_media_id,omitempty"`
+	IdentityVerified   bool       `json:"identity_verified"`
+	IdentityVerifiedAt *time.Time `json:"identity_verified_at,omitempty"`
+	IdentityVerifiedBy *uuid.UUID `json:"-"`
 }
 
 type Vehicle struct {
@@ -40,3 +55,30 @@ type Equipment struct {
 	SubType   *string   `json:"sub_type"`
 	BoomWidth *string   `json:"boom_width"`
 }
+
+type Property struct {
+	ProductID     uuid.UUID `json:"product_id"`
+	LandAreaHa    *float64  `json:"land_area_ha"`
+	StallCount    *int      `json:"stall_count"`
+	PaddockCount  *int      `json:"paddock_count"`
+	ArenaWidthM   *int      `json:"arena_width_m"`
+	ArenaLengthM  *int      `json:"arena_length_m"`
+	LivingSpaceM2 *int      `json:"living_space_m2"`
+}
+
+// Service kinds, pricing units and weekdays accepted on service listings.
+var (
+	ServiceKinds = []string{"farrier", "transport", "veterinary", "dentistry", "physiotherapy", "training", "boarding", "other"}
+	PricingUnits = []string{"hour", "day", "week", "month", "visit", "km", "fixed"}
+	Weekdays     = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+)
+
+type Service struct {
+	ProductID        uuid.UUID `json:"product_id"`
+	Kind             *string   `json:"service_kind"`
+	CoverageAreas    []string  `json:"coverage_areas"`
+	CoverageRadiusKM *int      `json:"coverage_radius_km"`
+	PricingUnit      *string   `json:"pricing_unit"`
+	AvailableDays    []string  `json:"available_days"`
+	AvailabilityNote *string   `json:"availability_note"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// PublicStatuses are the statuses of the listings anyone can see, unless
+// hidden after reports.
+var PublicStatuses = []ProductStatus{StatusPublished, StatusSold}
+
+// Viewer is who is reading listings. The zero value is an anonymous
+// visitor, who only sees published and sold listings not hidden after
+// reports. Sellers also see their own drafts, pending and hidden listings,
+// admins see every listing. Deleted listings are only shown to their seller
+// and admins, when they ask for them.
+type Viewer struct {
+	UserID  string
+	IsAdmin bool
+	// IncludeDeleted has no effect for anonymous visitors
+	IncludeDeleted bool
+}
+
+// CanSee reports whether the viewer may read the listing.
+func (v Viewer) CanSee(p *Product) bool {
+	switch {
+	case v.IsAdmin:
+		return p.Status != StatusDeleted || v.IncludeDeleted
+	case v.UserID != "" && p.UserID.String() == v.UserID:
+		return p.Status != StatusDeleted || v.IncludeDeleted
+	default:
+		return IsPublicStatus(p.Status) && p.HiddenAt == nil
+	}
+}
+
+func IsPublicStatus(status ProductStatus) bool {
+	for _, s := range PublicStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
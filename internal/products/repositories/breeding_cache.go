@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const breedingCachePrefix = "breeding:"
+
+// RedisBreedingCache stores breeding compatibility results in Redis.
+type RedisBreedingCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisBreedingCache(client *redis.Client, ttl time.Duration) *RedisBreedingCache {
+	return &RedisBreedingCache{client: client, ttl: ttl}
+}
+
+func (c *RedisBreedingCache) Get(ctx context.Context, key string) (*models.BreedingResult, error) {
+	raw, err := c.client.Get(ctx, breedingCachePrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result models.BreedingResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *RedisBreedingCache) Set(ctx context.Context, key string, result *models.BreedingResult) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, breedingCachePrefix+key, raw, c.ttl).Err()
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	catmodels "github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+// attachRelations loads what lives outside the product row: media,
+// category attribute values and translations.
+func (r *ProductRepoPsql) attachRelations(ctx context.Context, products []*models.Product) error {
+	if err := r.attachMedia(ctx, products); err != nil {
+		return err
+	}
+	if err := r.attachAttributes(ctx, products); err != nil {
+		return err
+	}
+	return r.attachTranslations(ctx, products)
+}
+
+func (r *ProductRepoPsql) attachAttributes(ctx context.Context, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(products))
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for i, p := range products {
+		ids[i] = p.ID.String()
+		byID[p.ID] = p
+	}
+
+	query := `
+		SELECT pa.product_id, ca.key, pa.value_text, pa.value_int, pa.value_bool
+		FROM authentic.product_attributes pa
+		JOIN authentic.category_attributes ca ON ca.id = pa.attribute_id
+		WHERE pa.product_id = ANY($1::uuid[])
+	`
+	rows, err := r.psql.Query(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID uuid.UUID
+		var key string
+		var v catmodels.AttributeValue
+		if err := rows.Scan(&productID, &key, &v.Text, &v.Int, &v.Bool); err != nil {
+			return err
+		}
+		p := byID[productID]
+		if p.Attributes == nil {
+			p.Attributes = make(map[string]any)
+		}
+		p.Attributes[key] = v.Value()
+	}
+	return rows.Err()
+}
+
+func (r *ProductRepoPsql) replaceAttributes(ctx context.Context, tx *sql.Tx, productID uuid.UUID, values []models.ProductAttribute) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM authentic.product_attributes WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+	for _, a := range values {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO authentic.product_attributes (product_id, attribute_id, value_text, value_int, value_bool) VALUES ($1, $2, $3, $4, $5)`,
+			productID, a.AttributeID, a.Value.Text, a.Value.Int, a.Value.Bool,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attributeCondition matches listings whose attribute key has the value,
+// or, for integer attributes, lies within min and max.
+func (s *searchConditions) attributeCondition(f models.AttributeFilter) string {
+	conds := []string{"ca.key = " + s.arg(f.Key)}
+	if f.Value != nil {
+		ph := s.arg(strings.ToLower(*f.Value))
+		conds = append(conds, fmt.Sprintf("(LOWER(pa.value_text) = %s OR pa.value_int::text = %s OR pa.value_bool::text = %s)", ph, ph, ph))
+	}
+	if f.Min != nil {
+		conds = append(conds, "pa.value_int >= "+s.arg(*f.Min))
+	}
+	if f.Max != nil {
+		conds = append(conds, "pa.value_int <= "+s.arg(*f.Max))
+	}
+	return `EXISTS (
+		SELECT 1 FROM authentic.product_attributes pa
+		JOIN authentic.category_attributes ca ON ca.id = pa.attribute_id
+		WHERE pa.product_id = p.id AND ` + strings.Join(conds, " AND ") + `)`
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+func (r *ProductRepoPsql) FindSellerListings(ctx context.Context, userID string, f models.DashboardFilters) ([]*models.SellerListing, error) {
+	s := &searchConditions{}
+	where := `p.user_id = ` + s.arg(userID) + ` AND p.status <> 'deleted'`
+	if f.Status != "" {
+		where += ` AND p.status = ` + s.arg(f.Status)
+	}
+	query := `SELECT ` + productColumns + `,
+		(SELECT COALESCE(SUM(ds.contact_clicks), 0) FROM authentic.product_daily_stats ds WHERE ds.product_id = p.id)
+	` + productJoins + ` WHERE ` + where + `
+	ORDER BY p.updated_at DESC, p.id
+	LIMIT ` + s.arg(f.Limit) + ` OFFSET ` + s.arg(f.Offset)
+
+	rows, err := r.psql.Query(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []*models.SellerListing
+	var products []*models.Product
+	for rows.Next() {
+		var inquiries int
+		p, err := r.scanProduct(rows, &inquiries)
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, &models.SellerListing{Product: p, Inquiries: inquiries})
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
+	return listings, nil
+}
+
+func (r *ProductRepoPsql) CountSellerListings(ctx context.Context, userID string, expiringBefore time.Time) (map[models.ProductStatus]int, int, error) {
+	query := `
+		SELECT status, COUNT(*), COUNT(*) FILTER (WHERE status = 'published' AND expires_at <= $2)
+		FROM authentic.products
+		WHERE user_id = $1 AND status <> 'deleted'
+		GROUP BY status
+	`
+	rows, err := r.psql.Query(ctx, query, userID, expiringBefore)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	counts := make(map[models.ProductStatus]int)
+	var expiring int
+	for rows.Next() {
+		var status models.ProductStatus
+		var count, statusExpiring int
+		if err := rows.Scan(&status, &count, &statusExpiring); err != nil {
+			return nil, 0, err
+		}
+		counts[status] = count
+		expiring += statusExpiring
+	}
+	return counts, expiring, rows.Err()
+}
+
+func (r *ProductRepoPsql) FindSellerListingIDs(ctx context.Context, userID string, status models.ProductStatus, expiringBefore *time.Time) ([]uuid.UUID, error) {
+	s := &searchConditions{}
+	query := `SELECT id FROM authentic.products WHERE user_id = ` + s.arg(userID) + ` AND status = ` + s.arg(status)
+	if expiringBefore != nil {
+		query += ` AND expires_at <= ` + s.arg(*expiringBefore)
+	}
+	query += ` ORDER BY expires_at NULLS LAST, id`
+
+	rows, err := r.psql.Query(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+// FindDuplicateCandidates returns the published and pending listings of the
+// same type that q may duplicate: anyone's sharing an identifier, a primary
+// image within q.MaxImageDistance bits or a trigram-similar title, and the
+// seller's own with a trigram-similar description. Each kind of match is
+// looked up on its own so the indexes serve it. Shared identifiers come
+// first, then the closest images and the most similar titles, whoever the
+// seller is.
+func (r *ProductRepoPsql) FindDuplicateCandidates(ctx context.Context, q models.DuplicateQuery) ([]models.DuplicateCandidate, error) {
+	query := `
+		WITH source AS (
+			SELECT (SELECT phash FROM authentic.media WHERE id = $3::uuid) AS phash
+		), matched AS (
+			SELECT p.id FROM authentic.products p
+			WHERE p.status IN ('pending_approval', 'published') AND lower(p.title) % lower($8)
+			UNION
+			SELECT p.id FROM authentic.products p
+			WHERE p.user_id = $2 AND $10 <> '' AND lower(COALESCE(p.description, '')) % lower($10)
+			UNION
+			SELECT h.product_id FROM authentic.product_horses h
+			WHERE ($5::text IS NOT NULL AND h.ueln = $5) OR ($6::text IS NOT NULL AND h.microchip = $6)
+			UNION
+			SELECT pm.product_id
+			FROM authentic.product_media pm
+			JOIN authentic.media m ON m.id = pm.media_id
+			CROSS JOIN source
+			WHERE pm.is_primary AND length(replace(((m.phash # source.phash)::bit(64))::text, '0', '')) <= $7
+		)
+		SELECT p.id, p.user_id, p.title, COALESCE(p.description, ''), h.ueln, h.microchip,
+			CASE WHEN m.phash IS NOT NULL AND source.phash IS NOT NULL
+				THEN length(replace(((m.phash # source.phash)::bit(64))::text, '0', ''))
+			END AS image_distance
+		FROM matched
+		JOIN authentic.products p ON p.id = matched.id
+		CROSS JOIN source
+		LEFT JOIN authentic.product_horses h ON h.product_id = p.id
+		LEFT JOIN authentic.product_media pm ON pm.product_id = p.id AND pm.is_primary
+		LEFT JOIN authentic.media m ON m.id = pm.media_id
+		WHERE p.status IN ('pending_approval', 'published') AND p.id <> $1 AND p.type = $4
+		ORDER BY COALESCE(h.ueln = $5 OR h.microchip = $6, FALSE) DESC,
+			COALESCE(image_distance, 64),
+			similarity(lower(p.title), lower($8)) DESC,
+			p.created_at DESC
+		LIMIT $9
+	`
+	rows, err := r.psql.Query(ctx, query, q.ProductID, q.UserID, q.PrimaryMediaID, q.Type, q.UELN, q.Microchip, q.MaxImageDistance, q.Title, q.Limit, q.Description)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.DuplicateCandidate
+	for rows.Next() {
+		var c models.DuplicateCandidate
+		var distance sql.NullInt64
+		if err := rows.Scan(&c.ProductID, &c.UserID, &c.Title, &c.Description, &c.UELN, &c.Microchip, &distance); err != nil {
+			return nil, err
+		}
+		if distance.Valid {
+			d := int(distance.Int64)
+			c.ImageDistance = &d
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// SaveDuplicateFlags opens a flag for every match. A flag raised again for
+// the same pair of listings is reopened.
+func (r *ProductRepoPsql) SaveDuplicateFlags(ctx context.Context, productID uuid.UUID, matches []models.DuplicateMatch) error {
+	query := `
+		INSERT INTO authentic.product_duplicate_flags (product_id, duplicate_of, score, reasons, same_seller)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (product_id, duplicate_of) DO UPDATE
+		SET score = EXCLUDED.score, reasons = EXCLUDED.reasons, same_seller = EXCLUDED.same_seller,
+			status = 'open', resolved_by = NULL, resolved_at = NULL, created_at = NOW()
+	`
+	for _, m := range matches {
+		if _, err := r.psql.Execute(ctx, query, productID, m.DuplicateOf, m.Score, pq.Array(m.Reasons), m.SameSeller); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const selectDuplicateFlag = `
+	SELECT id, product_id, duplicate_of, score, reasons, same_seller, status, resolved_by, resolved_at, created_at
+	FROM authentic.product_duplicate_flags
+`
+
+func (r *ProductRepoPsql) FindDuplicateFlags(ctx context.Context, status models.DuplicateFlagStatus, limit, offset int) ([]*models.DuplicateFlag, error) {
+	rows, err := r.psql.Query(ctx, selectDuplicateFlag+`
+		WHERE status = $1
+		ORDER BY created_at, score DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []*models.DuplicateFlag
+	for rows.Next() {
+		f, err := scanDuplicateFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+func (r *ProductRepoPsql) FindDuplicateFlag(ctx context.Context, id string) (*models.DuplicateFlag, error) {
+	f, err := scanDuplicateFlag(r.psql.QueryRow(ctx, selectDuplicateFlag+` WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return f, err
+}
+
+func (r *ProductRepoPsql) ResolveDuplicateFlag(ctx context.Context, id string, status models.DuplicateFlagStatus, resolvedBy uuid.UUID) error {
+	_, err := r.psql.Execute(ctx, `
+		UPDATE authentic.product_duplicate_flags
+		SET status = $2, resolved_by = $3, resolved_at = NOW()
+		WHERE id = $1
+	`, id, status, resolvedBy)
+	return err
+}
+
+func scanDuplicateFlag(row interface{ Scan(...any) error }) (*models.DuplicateFlag, error) {
+	f := &models.DuplicateFlag{}
+	var reasons pq.StringArray
+	if err := row.Scan(&f.ID, &f.ProductID, &f.DuplicateOf, &f.Score, &reasons, &f.SameSeller, &f.Status, &f.ResolvedBy, &f.ResolvedAt, &f.CreatedAt); err != nil {
+		return nil, err
+	}
+	f.Reasons = reasons
+	return f, nil
+}
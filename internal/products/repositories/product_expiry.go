@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+func (r *ProductRepoPsql) Publish(ctx context.Context, id string, expiresAt time.Time, ifMatch []time.Time) error {
+	query := `
+		UPDATE authentic.products
+		SET status = 'published', published_at = NOW(), expires_at = $2, hidden_at = NULL,
+		    expiry_reminder_sent_at = NULL, renew_token = NULL, updated_at = NOW()
+		WHERE id = $1` + fmt.Sprintf(versionCondition, "$3")
+	result, err := r.psql.Execute(ctx, query, id, expiresAt, versionsArg(ifMatch))
+	if err != nil {
+		return identityConflict(err)
+	}
+	return checkVersion(result, ifMatch)
+}
+
+func (r *ProductRepoPsql) Renew(ctx context.Context, id string, expiresAt time.Time) error {
+	query := `
+		UPDATE authentic.products
+		SET status = 'published', expires_at = $2,
+		    expiry_reminder_sent_at = NULL, renew_token = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.psql.Execute(ctx, query, id, expiresAt)
+	return identityConflict(err)
+}
+
+func (r *ProductRepoPsql) ArchiveExpired(ctx context.Context) ([]string, error) {
+	query := `
+		UPDATE authentic.products
+		SET status = 'archived', updated_at = NOW()
+		WHERE status = 'published' AND expires_at <= NOW()
+		RETURNING id
+	`
+	rows, err := r.psql.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *ProductRepoPsql) FindExpiringSoon(ctx context.Context, before time.Time) ([]models.ExpiringListing, error) {
+	query := `
+		SELECT p.id, p.title, p.expires_at, u.email
+		FROM authentic.products p
+		JOIN authentic.users u ON u.id = p.user_id
+		WHERE p.status = 'published' AND p.expires_at > NOW() AND p.expires_at <= $1
+		  AND p.expiry_reminder_sent_at IS NULL AND u.is_active = TRUE
+		ORDER BY p.expires_at
+	`
+	rows, err := r.psql.Query(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.ExpiringListing
+	for rows.Next() {
+		var l models.ExpiringListing
+		if err := rows.Scan(&l.ProductID, &l.Title, &l.ExpiresAt, &l.SellerEmail); err != nil {
+			return nil, err
+		}
+		listings = append(listings, l)
+	}
+	return listings, rows.Err()
+}
+
+func (r *ProductRepoPsql) MarkReminderSent(ctx context.Context, id string, renewToken string) error {
+	query := `UPDATE authentic.products SET expiry_reminder_sent_at = NOW(), renew_token = $2 WHERE id = $1`
+	_, err := r.psql.Execute(ctx, query, id, renewToken)
+	return err
+}
+
+func (r *ProductRepoPsql) FindByRenewToken(ctx context.Context, token string) (*models.Product, error) {
+	p, err := r.scanProduct(r.psql.QueryRow(ctx, selectFullProduct+` WHERE p.renew_token = $1`, token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+func (r *ProductRepoPsql) AddFavorite(ctx context.Context, userID string, productID string) (bool, error) {
+	query := `
+		INSERT INTO authentic.user_favorites (user_id, product_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	result, err := r.psql.Execute(ctx, query, userID, productID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *ProductRepoPsql) RemoveFavorite(ctx context.Context, userID string, productID string) (bool, error) {
+	result, err := r.psql.Execute(ctx, `DELETE FROM authentic.user_favorites WHERE user_id = $1 AND product_id = $2`, userID, productID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *ProductRepoPsql) FindFavorites(ctx context.Context, userID string) ([]*models.Product, error) {
+	// Favorites that went back to draft or are awaiting approval are hidden
+	// until they are public again, like everywhere else.
+	s := &searchConditions{args: []any{userID}}
+	query := `SELECT ` + productColumns + productJoins + `
+	JOIN authentic.user_favorites f ON f.product_id = p.id
+	WHERE f.user_id = $1 AND ` + visibleTo(models.Viewer{UserID: userID}, s.arg) + `
+	ORDER BY f.created_at DESC`
+	return r.queryProducts(ctx, query, s.args...)
+}
+
+func (r *ProductRepoPsql) FavoritedAmong(ctx context.Context, userID string, productIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	favorited := make(map[uuid.UUID]bool)
+	if len(productIDs) == 0 {
+		return favorited, nil
+	}
+
+	ids := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := r.psql.Query(ctx, `SELECT product_id FROM authentic.user_favorites WHERE user_id = $1 AND product_id = ANY($2::uuid[])`, userID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		favorited[id] = true
+	}
+	return favorited, rows.Err()
+}
+
+func (r *ProductRepoPsql) FindWatchers(ctx context.Context, productID string) ([]models.Watcher, error) {
+	query := `
+		SELECT u.id, u.email
+		FROM authentic.user_favorites f
+		JOIN authentic.users u ON u.id = f.user_id
+		WHERE f.product_id = $1 AND u.is_active = TRUE
+	`
+	rows, err := r.psql.Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watchers []models.Watcher
+	for rows.Next() {
+		var w models.Watcher
+		if err := rows.Scan(&w.UserID, &w.Email); err != nil {
+			return nil, err
+		}
+		watchers = append(watchers, w)
+	}
+	return watchers, rows.Err()
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateIdentity is returned when a write would put a UELN or
+// microchip in a second active listing.
+var ErrDuplicateIdentity = errors.New("another active listing already has this UELN or microchip")
+
+// activeStatuses are the listings a horse can only appear in once.
+const activeStatuses = `('draft', 'pending_approval', 'published')`
+
+// identityConstraints are the unique indexes that keep the identifiers of
+// active listings apart, see migration 000036.
+var identityConstraints = map[string]bool{
+	"idx_product_horses_active_ueln":      true,
+	"idx_product_horses_active_microchip": true,
+}
+
+// identityConflict turns a violation of the identity indexes into
+// ErrDuplicateIdentity and returns any other error unchanged.
+func identityConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && identityConstraints[pqErr.Constraint] {
+		return ErrDuplicateIdentity
+	}
+	return err
+}
+
+func (r *ProductRepoPsql) FindActiveByIdentity(ctx context.Context, ueln, microchip *string, excludeID uuid.UUID) (uuid.UUID, error) {
+	query := `
+		SELECT p.id
+		FROM authentic.product_horses h
+		JOIN authentic.products p ON p.id = h.product_id
+		WHERE p.status IN ` + activeStatuses + ` AND p.id <> $3
+		  AND (($1::text IS NOT NULL AND h.ueln = $1) OR ($2::text IS NOT NULL AND h.microchip = $2))
+		LIMIT 1
+	`
+	var id uuid.UUID
+	err := r.psql.QueryRow(ctx, query, ueln, microchip, excludeID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return id, err
+}
+
+func (r *ProductRepoPsql) SearchByIdentity(ctx context.Context, identifier string, limit int) ([]*models.Product, error) {
+	query := selectFullProduct + `
+		WHERE h.ueln LIKE $1 || '%' OR h.microchip LIKE $1 || '%'
+		ORDER BY p.created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.psql.Query(ctx, query, identifier, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		p, err := r.scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *ProductRepoPsql) SetIdentityVerified(ctx context.Context, id string, verifiedBy *uuid.UUID) error {
+	query := `
+		WITH h AS (
+			UPDATE authentic.product_horses
+			SET identity_verified_at = CASE WHEN $2::uuid IS NULL THEN NULL ELSE NOW() END,
+			    identity_verified_by = $2
+			WHERE product_id = $1
+			RETURNING product_id
+		)
+		UPDATE authentic.products SET updated_at = NOW() WHERE id IN (SELECT product_id FROM h)
+	`
+	_, err := r.psql.Execute(ctx, query, id, verifiedBy)
+	return err
+}
@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+func (r *ProductRepoPsql) FindByExternalID(ctx context.Context, userID string, externalID string) (*models.Product, error) {
+	query := selectFullProduct + ` WHERE p.user_id = $1 AND p.external_id = $2 AND p.status <> 'deleted'`
+	p, err := r.scanProduct(r.psql.QueryRow(ctx, query, userID, externalID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.attachRelations(ctx, []*models.Product{p}); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/media"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+// insertMedia links media to a product. Callers pass the list already
+// ordered with at most one primary.
+func (r *ProductRepoPsql) insertMedia(ctx context.Context, tx *sql.Tx, productID uuid.UUID, items []models.ProductMedia) error {
+	for _, item := range items {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO authentic.product_media (product_id, media_id, "order", is_primary)
+			VALUES ($1, $2, $3, $4)
+		`, productID, item.MediaID, item.Order, item.IsPrimary)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachMedia loads the media of all products in a single query, primary
+// image first and then by order.
+func (r *ProductRepoPsql) attachMedia(ctx context.Context, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(products))
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for i, p := range products {
+		ids[i] = p.ID.String()
+		byID[p.ID] = p
+	}
+
+	query := `
+		SELECT pm.product_id, pm."order", pm.is_primary,
+		       m.id, m.user_id, m.file_name, m.original_name, m.mime_type, m.size_bytes, m.url, m.bucket_name, m.region, m.variants, m.created_at, m.updated_at
+		FROM authentic.product_media pm
+		JOIN authentic.media m ON m.id = pm.media_id
+		WHERE pm.product_id = ANY($1::uuid[])
+		ORDER BY pm.product_id, pm.is_primary DESC, pm."order", m.created_at
+	`
+	rows, err := r.psql.Query(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.ProductMedia
+		var m media.Media
+		var variants []byte
+		if err := rows.Scan(&item.ProductID, &item.Order, &item.IsPrimary,
+			&m.ID, &m.UserID, &m.FileName, &m.OriginalName, &m.MimeType, &m.SizeBytes, &m.URL, &m.BucketName, &m.Region, &variants, &m.CreatedAt, &m.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		if variants != nil {
+			m.Variants = json.RawMessage(variants)
+		}
+		item.MediaID = m.ID
+		item.Media = &m
+
+		p := byID[item.ProductID]
+		p.Media = append(p.Media, item)
+	}
+	return rows.Err()
+}
+
+func (r *ProductRepoPsql) MediaOwnedBy(ctx context.Context, userID string, mediaIDs []uuid.UUID) (bool, error) {
+	if len(mediaIDs) == 0 {
+		return true, nil
+	}
+
+	unique := make(map[uuid.UUID]struct{}, len(mediaIDs))
+	ids := make([]string, 0, len(mediaIDs))
+	for _, id := range mediaIDs {
+		if _, ok := unique[id]; !ok {
+			unique[id] = struct{}{}
+			ids = append(ids, id.String())
+		}
+	}
+
+	var owned int
+	err := r.psql.QueryRow(ctx, `SELECT COUNT(*) FROM authentic.media WHERE id = ANY($1::uuid[]) AND user_id = $2`, pq.Array(ids), userID).Scan(&owned)
+	if err != nil {
+		return false, err
+	}
+	return owned == len(ids), nil
+}
+
+func (r *ProductRepoPsql) AddMedia(ctx context.Context, productID string, mediaID string, isPrimary bool) (bool, error) {
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// The first image of a listing becomes its primary one
+	var hasPrimary bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM authentic.product_media WHERE product_id = $1 AND is_primary)`, productID).Scan(&hasPrimary); err != nil {
+		return false, err
+	}
+	if isPrimary && hasPrimary {
+		if _, err := tx.ExecContext(ctx, `UPDATE authentic.product_media SET is_primary = FALSE WHERE product_id = $1 AND is_primary`, productID); err != nil {
+			return false, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO authentic.product_media (product_id, media_id, "order", is_primary)
+		SELECT $1, $2, COALESCE(MAX("order") + 1, 0), $3
+		FROM authentic.product_media WHERE product_id = $1
+		ON CONFLICT DO NOTHING
+	`, productID, mediaID, isPrimary || !hasPrimary)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return false, nil
+	}
+	if err := touchProduct(ctx, tx, productID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *ProductRepoPsql) RemoveMedia(ctx context.Context, productID string, mediaID string) (bool, error) {
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var wasPrimary bool
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM authentic.product_media WHERE product_id = $1 AND media_id = $2
+		RETURNING is_primary
+	`, productID, mediaID).Scan(&wasPrimary)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// Promote the next image so a listing with media always has a primary
+	if wasPrimary {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE authentic.product_media SET is_primary = TRUE
+			WHERE product_id = $1 AND media_id = (
+				SELECT media_id FROM authentic.product_media WHERE product_id = $1 ORDER BY "order" LIMIT 1
+			)
+		`, productID); err != nil {
+			return false, err
+		}
+	}
+	if err := touchProduct(ctx, tx, productID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *ProductRepoPsql) ReorderMedia(ctx context.Context, productID string, mediaIDs []uuid.UUID) error {
+	ids := make([]string, len(mediaIDs))
+	for i, id := range mediaIDs {
+		ids[i] = id.String()
+	}
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE authentic.product_media pm
+		SET "order" = t.position - 1
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS t(media_id, position)
+		WHERE pm.product_id = $1 AND pm.media_id = t.media_id
+	`, productID, pq.Array(ids)); err != nil {
+		return err
+	}
+	if err := touchProduct(ctx, tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *ProductRepoPsql) SetPrimaryMedia(ctx context.Context, productID string, mediaID string) (bool, error) {
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE authentic.product_media SET is_primary = FALSE WHERE product_id = $1 AND is_primary AND media_id <> $2`, productID, mediaID); err != nil {
+		return false, err
+	}
+	result, err := tx.ExecContext(ctx, `UPDATE authentic.product_media SET is_primary = TRUE WHERE product_id = $1 AND media_id = $2`, productID, mediaID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return false, nil
+	}
+	if err := touchProduct(ctx, tx, productID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// touchProduct marks the listing updated when what lives outside its row
+// changes, so its version changes too.
+func touchProduct(ctx context.Context, tx *sql.Tx, productID string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE authentic.products SET updated_at = NOW() WHERE id = $1`, productID)
+	return err
+}
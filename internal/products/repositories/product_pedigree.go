@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// replaceAncestors rewrites the searchable copy of a horse's pedigree.
+func (r *ProductRepoPsql) replaceAncestors(ctx context.Context, tx *sql.Tx, productID uuid.UUID, pedigree *models.Pedigree) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM authentic.product_horse_ancestors WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+	if pedigree == nil {
+		return nil
+	}
+
+	for _, a := range pedigree.Ancestors() {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO authentic.product_horse_ancestors (product_id, path, generation, name, registration_number)
+			VALUES ($1, $2, $3, $4, $5)
+		`, productID, a.Path, a.Generation(), a.Name, a.RegistrationNumber)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ancestorCondition matches horses with an ancestor at path (or at any
+// position when path is empty) whose name or registration number is value.
+func (s *searchConditions) ancestorCondition(path string, value string) string {
+	cond := "EXISTS (SELECT 1 FROM authentic.product_horse_ancestors a WHERE a.product_id = p.id"
+	if path != "" {
+		cond += " AND a.path = " + s.arg(path)
+	}
+	ph := s.arg(value)
+	return cond + " AND (LOWER(a.name) = LOWER(" + ph + ") OR a.registration_number = UPPER(REPLACE(" + ph + ", ' ', ''))))"
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// reducedSince is the start of the window in which a lowered price earns
+// the "reduced" badge.
+var reducedSince = "NOW() - INTERVAL '" + strconv.Itoa(models.PriceReducedDays) + " days'"
+
+// reducedColumns keeps reduced_from_sek and price_reduced_at in step with
+// the new price, passed as $4. A reduction keeps the original price while the
+// previous one still shows the badge; a raise clears it.
+var reducedColumns = `
+			reduced_from_sek = CASE
+				WHEN price_sek IS NOT DISTINCT FROM $4::DECIMAL THEN reduced_from_sek
+				WHEN $4::DECIMAL < price_sek THEN
+					CASE WHEN price_reduced_at > ` + reducedSince + ` AND reduced_from_sek > price_sek THEN reduced_from_sek ELSE price_sek END
+			END,
+			price_reduced_at = CASE
+				WHEN price_sek IS NOT DISTINCT FROM $4::DECIMAL THEN price_reduced_at
+				WHEN $4::DECIMAL < price_sek THEN NOW()
+			END`
+
+// recordPriceChange adds a history entry when price differs from the
+// stored one. It must run before the listing is updated.
+func recordPriceChange(ctx context.Context, tx *sql.Tx, productID uuid.UUID, price *float64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO authentic.product_price_history (product_id, old_price_sek, price_sek)
+		SELECT id, price_sek, $2 FROM authentic.products
+		WHERE id = $1 AND price_sek IS DISTINCT FROM $2::DECIMAL
+	`, productID, price)
+	return err
+}
+
+func (r *ProductRepoPsql) FindPriceHistory(ctx context.Context, productID string) ([]models.PriceChange, error) {
+	rows, err := r.psql.Query(ctx, `
+		SELECT old_price_sek, price_sek, changed_at
+		FROM authentic.product_price_history
+		WHERE product_id = $1
+		ORDER BY changed_at, id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.PriceChange{}
+	for rows.Next() {
+		var c models.PriceChange
+		if err := rows.Scan(&c.OldPriceSEK, &c.PriceSEK, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
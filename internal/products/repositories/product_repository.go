@@ -7,23 +7,119 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
 	"github.com/hfleury/horsemarketplacebk/internal/db"
 	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
 )
 
 type ProductRepository interface {
 	Create(ctx context.Context, product *models.Product) (*models.Product, error)
 	FindByID(ctx context.Context, id string) (*models.Product, error)
-	FindAll(ctx context.Context, filters map[string]any) ([]*models.Product, error)
-	FindByCategory(ctx context.Context, categoryID string) ([]*models.Product, error)
-	FindByTextInDescription(ctx context.Context, text string) ([]*models.Product, error)
-	FindByField(ctx context.Context, fieldName string, value string) ([]*models.Product, error)
-	UpdateStatus(ctx context.Context, id string, status models.ProductStatus) error
+	// FindAll, FindByCategory, FindByTextInDescription and FindByField only
+	// return the listings the viewer may see.
+	FindAll(ctx context.Context, filters map[string]any, viewer models.Viewer) ([]*models.Product, error)
+	FindByCategory(ctx context.Context, categoryID string, viewer models.Viewer) ([]*models.Product, error)
+	FindByTextInDescription(ctx context.Context, text string, viewer models.Viewer) ([]*models.Product, error)
+	FindByField(ctx context.Context, fieldName string, value string, viewer models.Viewer) ([]*models.Product, error)
+	Search(ctx context.Context, filters *models.ProductFilters) ([]*models.Product, error)
+	FacetCounts(ctx context.Context, filters *models.ProductFilters) (map[string][]models.FacetCount, error)
+	// MatchFilters reports for each of filters whether the product with the
+	// given id satisfies it, evaluating them all at once.
+	MatchFilters(ctx context.Context, id string, filters []*models.ProductFilters) ([]bool, error)
+	// UpdateStatus only writes a listing still at one of the ifMatch
+	// versions, nil accepting any, else returns ErrVersionConflict.
+	UpdateStatus(ctx context.Context, id string, status models.ProductStatus, ifMatch []time.Time) error
+	// AddFavorite and RemoveFavorite report whether anything changed.
+	AddFavorite(ctx context.Context, userID string, productID string) (bool, error)
+	RemoveFavorite(ctx context.Context, userID string, productID string) (bool, error)
+	// FindFavorites returns the user's favorited listings, most recently favorited first.
+	FindFavorites(ctx context.Context, userID string) ([]*models.Product, error)
+	// FavoritedAmong returns which of productIDs the user has favorited.
+	FavoritedAmong(ctx context.Context, userID string, productIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	FindWatchers(ctx context.Context, productID string) ([]models.Watcher, error)
 	Delete(ctx context.Context, id string) error
-	// Add Update method later as it's complex
+	// Update saves an edit of a listing: the editable fields, the type
+	// specific data and, when product.Media is not nil, the media. With rev
+	// the edit is stored as the next version in the same transaction, see
+	// AddRevision, and a rev that RequiresApproval sends a published listing
+	// back to moderation. Like UpdateStatus it checks the ifMatch versions.
+	Update(ctx context.Context, product *models.Product, rev *models.Revision, baseline *models.ListingContent, ifMatch []time.Time) (*models.Product, error)
+	// MediaOwnedBy reports whether every media id was uploaded by the user.
+	MediaOwnedBy(ctx context.Context, userID string, mediaIDs []uuid.UUID) (bool, error)
+	// AddMedia appends media to the listing; it reports false when already attached.
+	AddMedia(ctx context.Context, productID string, mediaID string, isPrimary bool) (bool, error)
+	RemoveMedia(ctx context.Context, productID string, mediaID string) (bool, error)
+	ReorderMedia(ctx context.Context, productID string, mediaIDs []uuid.UUID) error
+	SetPrimaryMedia(ctx context.Context, productID string, mediaID string) (bool, error)
+	// Publish sets the status to published and starts a new listing period.
+	// It lifts a hide after reports, only admins publish hidden listings.
+	// Like UpdateStatus it checks the ifMatch versions.
+	Publish(ctx context.Context, id string, expiresAt time.Time, ifMatch []time.Time) error
+	// Renew extends the listing period, publishing again a listing archived on expiry.
+	Renew(ctx context.Context, id string, expiresAt time.Time) error
+	// ArchiveExpired archives every published listing past its expiry and returns their ids.
+	ArchiveExpired(ctx context.Context) ([]string, error)
+	// FindExpiringSoon returns published listings expiring before the given time
+	// whose seller has not been reminded yet.
+	FindExpiringSoon(ctx context.Context, before time.Time) ([]models.ExpiringListing, error)
+	MarkReminderSent(ctx context.Context, id string, renewToken string) error
+	FindByRenewToken(ctx context.Context, token string) (*models.Product, error)
+	// FindActiveByIdentity returns an active listing other than excludeID with
+	// the same UELN or microchip, or uuid.Nil when there is none.
+	FindActiveByIdentity(ctx context.Context, ueln, microchip *string, excludeID uuid.UUID) (uuid.UUID, error)
+	// SearchByIdentity finds listings in any status whose UELN or microchip starts with identifier.
+	SearchByIdentity(ctx context.Context, identifier string, limit int) ([]*models.Product, error)
+	// SetIdentityVerified records the admin who verified the identity, or clears it when verifiedBy is nil.
+	SetIdentityVerified(ctx context.Context, id string, verifiedBy *uuid.UUID) error
+	// FindByExternalID returns the seller's listing imported under the dealer's
+	// external id, ignoring deleted ones, or nil when there is none.
+	FindByExternalID(ctx context.Context, userID string, externalID string) (*models.Product, error)
+	// SitemapProducts pages through the published listings, oldest first.
+	SitemapProducts(ctx context.Context, offset, limit int) ([]models.SitemapEntry, error)
+	// SitemapProductPages returns when each page of SitemapProducts last changed.
+	SitemapProductPages(ctx context.Context, perPage int) ([]time.Time, error)
+	// SitemapSellers pages through the sellers with published listings.
+	SitemapSellers(ctx context.Context, offset, limit int) ([]models.SitemapEntry, error)
+	SitemapSellerPages(ctx context.Context, perPage int) ([]time.Time, error)
+	// FindPriceHistory returns every price the listing has had, oldest first.
+	FindPriceHistory(ctx context.Context, productID string) ([]models.PriceChange, error)
+	// FindDuplicateCandidates returns the active listings a listing being
+	// published may duplicate.
+	FindDuplicateCandidates(ctx context.Context, q models.DuplicateQuery) ([]models.DuplicateCandidate, error)
+	SaveDuplicateFlags(ctx context.Context, productID uuid.UUID, matches []models.DuplicateMatch) error
+	// FindDuplicateFlags pages through the flags in a status, oldest first.
+	FindDuplicateFlags(ctx context.Context, status models.DuplicateFlagStatus, limit, offset int) ([]*models.DuplicateFlag, error)
+	// FindDuplicateFlag returns nil when there is no such flag.
+	FindDuplicateFlag(ctx context.Context, id string) (*models.DuplicateFlag, error)
+	ResolveDuplicateFlag(ctx context.Context, id string, status models.DuplicateFlagStatus, resolvedBy uuid.UUID) error
+	// AddRevision stores the next version of a listing. When baseline is set
+	// and the listing has no versions yet, it is stored first as version 1.
+	AddRevision(ctx context.Context, rev *models.Revision, baseline *models.ListingContent) error
+	// FindRevisions returns every version of the listing, newest first.
+	FindRevisions(ctx context.Context, productID string) ([]*models.Revision, error)
+	// FindRevision returns nil when the listing has no such version.
+	FindRevision(ctx context.Context, productID string, version int) (*models.Revision, error)
+	// SaveAutosave replaces the edit in progress of the listing.
+	SaveAutosave(ctx context.Context, a *models.Autosave) error
+	// FindAutosave returns nil when no edit is in progress.
+	FindAutosave(ctx context.Context, productID uuid.UUID) (*models.Autosave, error)
+	DeleteAutosave(ctx context.Context, productID uuid.UUID) error
+	// FindSellerListings pages through the seller's listings in any status
+	// but deleted, most recently updated first.
+	FindSellerListings(ctx context.Context, userID string, f models.DashboardFilters) ([]*models.SellerListing, error)
+	// CountSellerListings counts the seller's listings per status, and the
+	// published ones expiring before expiringBefore.
+	CountSellerListings(ctx context.Context, userID string, expiringBefore time.Time) (map[models.ProductStatus]int, int, error)
+	// FindSellerListingIDs returns the seller's listings in a status, only
+	// those expiring before expiringBefore when it is set.
+	FindSellerListingIDs(ctx context.Context, userID string, status models.ProductStatus, expiringBefore *time.Time) ([]uuid.UUID, error)
+	// FindSimilar returns the published listings of the same type most
+	// similar to src, best first, with what they have in common.
+	FindSimilar(ctx context.Context, src *models.Product, limit int) ([]*models.SimilarListing, error)
 }
 
 type ProductRepoPsql struct {
@@ -49,8 +145,8 @@ func (r *ProductRepoPsql) Create(ctx context.Context, product *models.Product) (
 	queryProd := `
 		INSERT INTO authentic.products (
 			id, user_id, category_id, type, status, title, price_sek, description, 
-			city, area, transaction_type, views_count
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 0)
+			city, area, transaction_type, postal_code, latitude, longitude, published_at, expires_at, external_id, language, views_count
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, 0)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -62,7 +158,8 @@ func (r *ProductRepoPsql) Create(ctx context.Context, product *models.Product) (
 	err = tx.QueryRowContext(ctx, queryProd,
 		productID, product.UserID, product.CategoryID, product.Type, product.Status,
 		product.Title, product.PriceSEK, product.Description, product.City,
-		product.Area, product.TransactionType,
+		product.Area, product.TransactionType, product.PostalCode, product.Latitude, product.Longitude,
+		product.PublishedAt, product.ExpiresAt, product.ExternalID, product.Language,
 	).Scan(&product.ID, &product.CreatedAt, &product.UpdatedAt)
 
 	if err != nil {
@@ -70,13 +167,42 @@ func (r *ProductRepoPsql) Create(ctx context.Context, product *models.Product) (
 		return nil, err
 	}
 
+	if product.PriceSEK != nil {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO authentic.product_price_history (product_id, price_sek) VALUES ($1, $2)
+		`, product.ID, product.PriceSEK); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to record initial price", map[string]any{"error": err.Error()})
+			return nil, err
+		}
+	}
+
 	// 2. Insert specific data based on type
 	if err := r.insertSpecificData(ctx, tx, product); err != nil {
 		r.logger.Log(ctx, config.ErrorLevel, "Failed to insert specific product data", map[string]any{"error": err.Error(), "type": product.Type})
+		return nil, identityConflict(err)
+	}
+
+	if len(product.AttributeValues) > 0 {
+		if err := r.replaceAttributes(ctx, tx, product.ID, product.AttributeValues); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to insert product attributes", map[string]any{"error": err.Error()})
+			return nil, err
+		}
+	}
+
+	if len(product.Translations) > 0 {
+		if err := r.replaceTranslations(ctx, tx, product.ID, product.Translations); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to insert product translations", map[string]any{"error": err.Error()})
+			return nil, err
+		}
+	}
+
+	// 3. Link media
+	if err := r.insertMedia(ctx, tx, product.ID, product.Media); err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to attach product media", map[string]any{"error": err.Error()})
 		return nil, err
 	}
 
-	// 3. Commit
+	// 4. Commit
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -84,17 +210,130 @@ func (r *ProductRepoPsql) Create(ctx context.Context, product *models.Product) (
 	return product, nil
 }
 
+func (r *ProductRepoPsql) Update(ctx context.Context, product *models.Product, rev *models.Revision, baseline *models.ListingContent, ifMatch []time.Time) (*models.Product, error) {
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := recordPriceChange(ctx, tx, product.ID, product.PriceSEK); err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to record price change", map[string]any{"error": err.Error()})
+		return nil, err
+	}
+
+	// The reduced columns are assigned first, they compare with the old price
+	queryProd := `
+		UPDATE authentic.products SET` + reducedColumns + `,
+			category_id = $2, title = $3, price_sek = $4, description = $5, city = $6, area = $7,
+			transaction_type = $8, postal_code = $9, latitude = $10, longitude = $11, language = $12,
+			status = CASE WHEN $13 AND status = 'published' THEN 'pending_approval' ELSE status END, updated_at = NOW()
+		WHERE id = $1` + fmt.Sprintf(versionCondition, "$14")
+	requeue := rev != nil && rev.RequiresApproval
+	result, err := tx.ExecContext(ctx, queryProd,
+		product.ID, product.CategoryID, product.Title, product.PriceSEK, product.Description, product.City,
+		product.Area, product.TransactionType, product.PostalCode, product.Latitude, product.Longitude, product.Language, requeue,
+		versionsArg(ifMatch),
+	)
+	if err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to update product", map[string]any{"error": err.Error()})
+		return nil, err
+	}
+	if err := checkVersion(result, ifMatch); err != nil {
+		return nil, err
+	}
+
+	// The type never changes, so replacing the row of its table is enough
+	if table := specificTable(product.Type); table != "" {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM authentic.`+table+` WHERE product_id = $1`, product.ID); err != nil {
+			return nil, err
+		}
+		if err := r.insertSpecificData(ctx, tx, product); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to update specific product data", map[string]any{"error": err.Error(), "type": product.Type})
+			return nil, identityConflict(err)
+		}
+	}
+
+	if product.AttributeValues != nil {
+		if err := r.replaceAttributes(ctx, tx, product.ID, product.AttributeValues); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to update product attributes", map[string]any{"error": err.Error()})
+			return nil, err
+		}
+	}
+
+	if product.Translations != nil {
+		if err := r.replaceTranslations(ctx, tx, product.ID, product.Translations); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to update product translations", map[string]any{"error": err.Error()})
+			return nil, err
+		}
+	}
+
+	if product.Media != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM authentic.product_media WHERE product_id = $1`, product.ID); err != nil {
+			return nil, err
+		}
+		if err := r.insertMedia(ctx, tx, product.ID, product.Media); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to attach product media", map[string]any{"error": err.Error()})
+			return nil, err
+		}
+	}
+
+	if rev != nil {
+		if err := insertRevision(ctx, tx, rev, baseline); err != nil {
+			r.logger.Log(ctx, config.ErrorLevel, "Failed to record listing revision", map[string]any{"error": err.Error()})
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.FindByID(ctx, product.ID.String())
+}
+
+func specificTable(t models.ProductType) string {
+	switch t {
+	case models.TypeHorse:
+		return "product_horses"
+	case models.TypeVehicle:
+		return "product_vehicles"
+	case models.TypeEquipment:
+		return "product_equipment"
+	case models.TypeProperty:
+		return "product_properties"
+	case models.TypeService:
+		return "product_services"
+	default:
+		return ""
+	}
+}
+
 func (r *ProductRepoPsql) insertSpecificData(ctx context.Context, tx *sql.Tx, p *models.Product) error {
 	switch p.Type {
 	case models.TypeHorse:
 		if p.Horse == nil {
 			return errors.New("horse data missing")
 		}
-		q := `INSERT INTO authentic.product_horses (product_id, name, age, year_of_birth, gender, height, breed, color, dressage_level, jump_level, orientation, pedigree)
-		      VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
-
-		_, err := tx.ExecContext(ctx, q, p.ID, p.Horse.Name, p.Horse.Age, p.Horse.YearOfBirth, p.Horse.Gender, p.Horse.Height, p.Horse.Breed, p.Horse.Color, p.Horse.DressageLevel, p.Horse.JumpLevel, p.Horse.Orientation, p.Horse.Pedigree)
-		return err
+		q := `INSERT INTO authentic.product_horses (product_id, name, age, year_of_birth, gender, height, breed, color, dressage_level, jump_level, orientation, pedigree,
+		          ueln, microchip, passport_issuer, passport_media_id, identity_verified_at, identity_verified_by)
+		      VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
+
+		var pedigree []byte
+		switch {
+		case p.Horse.Pedigree != nil:
+			var err error
+			if pedigree, err = json.Marshal(p.Horse.Pedigree); err != nil {
+				return err
+			}
+		case p.Horse.LegacyPedigree != nil:
+			pedigree = p.Horse.LegacyPedigree
+		}
+		_, err := tx.ExecContext(ctx, q, p.ID, p.Horse.Name, p.Horse.Age, p.Horse.YearOfBirth, p.Horse.Gender, p.Horse.Height, p.Horse.Breed, p.Horse.Color, p.Horse.DressageLevel, p.Horse.JumpLevel, p.Horse.Orientation, pedigree,
+			p.Horse.UELN, p.Horse.Microchip, p.Horse.PassportIssuer, p.Horse.PassportMediaID, p.Horse.IdentityVerifiedAt, p.Horse.IdentityVerifiedBy)
+		if err != nil {
+			return err
+		}
+		return r.replaceAncestors(ctx, tx, p.ID, p.Horse.Pedigree)
 
 	case models.TypeVehicle:
 		if p.Vehicle == nil {
@@ -114,28 +353,59 @@ func (r *ProductRepoPsql) insertSpecificData(ctx context.Context, tx *sql.Tx, p
 		_, err := tx.ExecContext(ctx, q, p.ID, p.Equipment.Make, p.Equipment.Model, p.Equipment.Size, p.Equipment.Condition, p.Equipment.SubType, p.Equipment.BoomWidth)
 		return err
 
+	case models.TypeProperty:
+		// Listings created before properties had details have none
+		if p.Property == nil {
+			return nil
+		}
+		q := `INSERT INTO authentic.product_properties (product_id, land_area_ha, stall_count, paddock_count, arena_width_m, arena_length_m, living_space_m2)
+		      VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		_, err := tx.ExecContext(ctx, q, p.ID, p.Property.LandAreaHa, p.Property.StallCount, p.Property.PaddockCount, p.Property.ArenaWidthM, p.Property.ArenaLengthM, p.Property.LivingSpaceM2)
+		return err
+
+	case models.TypeService:
+		if p.Service == nil {
+			return nil
+		}
+		q := `INSERT INTO authentic.product_services (product_id, service_kind, coverage_areas, coverage_radius_km, pricing_unit, available_days, availability_note)
+		      VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		_, err := tx.ExecContext(ctx, q, p.ID, p.Service.Kind, pq.Array(p.Service.CoverageAreas), p.Service.CoverageRadiusKM, p.Service.PricingUnit, pq.Array(p.Service.AvailableDays), p.Service.AvailabilityNote)
+		return err
+
 	default:
-		// For other types like Service, we might only use the main table for now
 		return nil
 	}
 }
 
 // Base query to fetch all fields including joined specific tables
 // We limit SELECT * to specific aliases to avoid ambiguous columns
-var selectFullProduct = `
-	SELECT 
+// Searches that need extra computed columns append them after productColumns
+// and pass matching destinations to scanProduct.
+var productColumns = `
 		p.id, p.user_id, p.category_id, p.type, p.status, p.title, p.price_sek, p.description, 
-		p.city, p.area, p.transaction_type, p.views_count, p.created_at, p.updated_at,
+		p.city, p.area, p.transaction_type, p.postal_code, p.latitude, p.longitude, p.views_count, p.published_at, p.expires_at, p.created_at, p.updated_at, p.external_id,
+		CASE WHEN p.price_reduced_at > ` + reducedSince + ` THEN p.reduced_from_sek END, p.hidden_at, p.language,
+		(SELECT COUNT(*) FROM authentic.user_favorites uf WHERE uf.product_id = p.id) AS favorite_count,
 		h.name, h.age, h.year_of_birth, h.gender, h.height, h.breed, h.color, h.dressage_level, h.jump_level, h.orientation, h.pedigree,
+		h.ueln, h.microchip, h.passport_issuer, h.passport_media_id, h.identity_verified_at, h.identity_verified_by,
 		v.make, v.model, v.year, v.load_weight, v.total_weight, v.condition,
-		e.make, e.model, e.size, e.condition, e.sub_type, e.boom_width
+		e.make, e.model, e.size, e.condition, e.sub_type, e.boom_width,
+		pp.land_area_ha, pp.stall_count, pp.paddock_count, pp.arena_width_m, pp.arena_length_m, pp.living_space_m2,
+		ps.service_kind, ps.coverage_areas, ps.coverage_radius_km, ps.pricing_unit, ps.available_days, ps.availability_note`
+
+var productJoins = `
 	FROM authentic.products p
 	LEFT JOIN authentic.product_horses h ON p.id = h.product_id
 	LEFT JOIN authentic.product_vehicles v ON p.id = v.product_id
 	LEFT JOIN authentic.product_equipment e ON p.id = e.product_id
+	LEFT JOIN authentic.product_properties pp ON p.id = pp.product_id
+	LEFT JOIN authentic.product_services ps ON p.id = ps.product_id
 `
 
-func (r *ProductRepoPsql) scanProduct(row interface{ Scan(...any) error }) (*models.Product, error) {
+var selectFullProduct = `
+	SELECT ` + productColumns + productJoins
+
+func (r *ProductRepoPsql) scanProduct(row interface{ Scan(...any) error }, extra ...any) (*models.Product, error) {
 	var p models.Product
 	// Pointers for specific fields that might be null
 	var (
@@ -143,6 +413,9 @@ func (r *ProductRepoPsql) scanProduct(row interface{ Scan(...any) error }) (*mod
 		hName, hGender, hBreed, hColor, hDressage, hJump, hOrient *string
 		hAge, hYOB, hHeight                                       *int
 		hPedigree                                                 []byte
+		hUELN, hChip, hIssuer                                     *string
+		hPassport, hVerifiedBy                                    *uuid.UUID
+		hVerifiedAt                                               *time.Time
 
 		// Vehicle
 		vMake, vModel, vCondition *string
@@ -150,15 +423,30 @@ func (r *ProductRepoPsql) scanProduct(row interface{ Scan(...any) error }) (*mod
 
 		// Equipment
 		eMake, eModel, eSize, eCondition, eSubType, eBoom *string
+
+		// Property
+		ppLand                                             *float64
+		ppStalls, ppPaddocks, ppArenaW, ppArenaL, ppLiving *int
+
+		// Service
+		psKind, psUnit, psNote *string
+		psAreas, psDays        pq.StringArray
+		psRadius               *int
 	)
 
-	err := row.Scan(
+	dest := []any{
 		&p.ID, &p.UserID, &p.CategoryID, &p.Type, &p.Status, &p.Title, &p.PriceSEK, &p.Description,
-		&p.City, &p.Area, &p.TransactionType, &p.ViewsCount, &p.CreatedAt, &p.UpdatedAt,
+		&p.City, &p.Area, &p.TransactionType, &p.PostalCode, &p.Latitude, &p.Longitude, &p.ViewsCount, &p.PublishedAt, &p.ExpiresAt, &p.CreatedAt, &p.UpdatedAt, &p.ExternalID,
+		&p.ReducedFromSEK, &p.HiddenAt, &p.Language,
+		&p.FavoriteCount,
 		&hName, &hAge, &hYOB, &hGender, &hHeight, &hBreed, &hColor, &hDressage, &hJump, &hOrient, &hPedigree,
+		&hUELN, &hChip, &hIssuer, &hPassport, &hVerifiedAt, &hVerifiedBy,
 		&vMake, &vModel, &vYear, &vLoad, &vTotal, &vCondition,
 		&eMake, &eModel, &eSize, &eCondition, &eSubType, &eBoom,
-	)
+		&ppLand, &ppStalls, &ppPaddocks, &ppArenaW, &ppArenaL, &ppLiving,
+		&psKind, &psAreas, &psRadius, &psUnit, &psDays, &psNote,
+	}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -169,9 +457,17 @@ func (r *ProductRepoPsql) scanProduct(row interface{ Scan(...any) error }) (*mod
 		p.Horse = &models.Horse{
 			ProductID: p.ID, Name: hName, Age: hAge, YearOfBirth: hYOB, Gender: hGender, Height: hHeight,
 			Breed: hBreed, Color: hColor, DressageLevel: hDressage, JumpLevel: hJump, Orientation: hOrient,
+			UELN: hUELN, Microchip: hChip, PassportIssuer: hIssuer, PassportMediaID: hPassport,
+			IdentityVerified: hVerifiedAt != nil, IdentityVerifiedAt: hVerifiedAt, IdentityVerifiedBy: hVerifiedBy,
 		}
 		if hPedigree != nil {
-			p.Horse.Pedigree = json.RawMessage(hPedigree)
+			// Pedigrees stored before the schema existed may not parse; they are kept as stored
+			var pedigree models.Pedigree
+			if err := json.Unmarshal(hPedigree, &pedigree); err == nil {
+				p.Horse.Pedigree = &pedigree
+			} else {
+				p.Horse.LegacyPedigree = hPedigree
+			}
 		}
 	case models.TypeVehicle:
 		p.Vehicle = &models.Vehicle{
@@ -183,6 +479,16 @@ func (r *ProductRepoPsql) scanProduct(row interface{ Scan(...any) error }) (*mod
 			ProductID: p.ID, Make: eMake, Model: eModel, Size: eSize, Condition: eCondition,
 			SubType: eSubType, BoomWidth: eBoom,
 		}
+	case models.TypeProperty:
+		p.Property = &models.Property{
+			ProductID: p.ID, LandAreaHa: ppLand, StallCount: ppStalls, PaddockCount: ppPaddocks,
+			ArenaWidthM: ppArenaW, ArenaLengthM: ppArenaL, LivingSpaceM2: ppLiving,
+		}
+	case models.TypeService:
+		p.Service = &models.Service{
+			ProductID: p.ID, Kind: psKind, CoverageAreas: psAreas, CoverageRadiusKM: psRadius,
+			PricingUnit: psUnit, AvailableDays: psDays, AvailabilityNote: psNote,
+		}
 	}
 
 	return &p, nil
@@ -198,13 +504,17 @@ func (r *ProductRepoPsql) FindByID(ctx context.Context, id string) (*models.Prod
 		}
 		return nil, err
 	}
+	if err := r.attachRelations(ctx, []*models.Product{p}); err != nil {
+		return nil, err
+	}
 	return p, nil
 }
 
-func (r *ProductRepoPsql) FindAll(ctx context.Context, filters map[string]any) ([]*models.Product, error) {
+func (r *ProductRepoPsql) FindAll(ctx context.Context, filters map[string]any, viewer models.Viewer) ([]*models.Product, error) {
 	// Basic implementation pending more complex filtering logic
-	query := selectFullProduct + ` ORDER BY p.created_at DESC`
-	rows, err := r.psql.Query(ctx, query)
+	s := &searchConditions{}
+	query := selectFullProduct + ` WHERE ` + visibleTo(viewer, s.arg) + ` ORDER BY p.created_at DESC`
+	rows, err := r.psql.Query(ctx, query, s.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -218,12 +528,16 @@ func (r *ProductRepoPsql) FindAll(ctx context.Context, filters map[string]any) (
 		}
 		products = append(products, p)
 	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
 
-func (r *ProductRepoPsql) FindByCategory(ctx context.Context, categoryID string) ([]*models.Product, error) {
-	query := selectFullProduct + ` WHERE p.category_id = $1 ORDER BY p.created_at DESC`
-	rows, err := r.psql.Query(ctx, query, categoryID)
+func (r *ProductRepoPsql) FindByCategory(ctx context.Context, categoryID string, viewer models.Viewer) ([]*models.Product, error) {
+	s := &searchConditions{}
+	query := selectFullProduct + ` WHERE p.category_id = ` + s.arg(categoryID) + ` AND ` + visibleTo(viewer, s.arg) + ` ORDER BY p.created_at DESC`
+	rows, err := r.psql.Query(ctx, query, s.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -237,14 +551,17 @@ func (r *ProductRepoPsql) FindByCategory(ctx context.Context, categoryID string)
 		}
 		products = append(products, p)
 	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
 
-func (r *ProductRepoPsql) FindByTextInDescription(ctx context.Context, text string) ([]*models.Product, error) {
-	// Simple ILIKE search. For larger scale, use Full Text Search (tsvector).
-	query := selectFullProduct + ` WHERE p.description ILIKE $1 OR p.title ILIKE $1 ORDER BY p.created_at DESC`
-	searchTerm := "%" + text + "%"
-	rows, err := r.psql.Query(ctx, query, searchTerm)
+func (r *ProductRepoPsql) FindByTextInDescription(ctx context.Context, text string, viewer models.Viewer) ([]*models.Product, error) {
+	s := &searchConditions{}
+	query := selectFullProduct + ` WHERE ` + s.textCondition(text) + `
+		AND ` + visibleTo(viewer, s.arg) + ` ORDER BY p.created_at DESC`
+	rows, err := r.psql.Query(ctx, query, s.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -258,10 +575,13 @@ func (r *ProductRepoPsql) FindByTextInDescription(ctx context.Context, text stri
 		}
 		products = append(products, p)
 	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
 
-func (r *ProductRepoPsql) FindByField(ctx context.Context, fieldName string, value string) ([]*models.Product, error) {
+func (r *ProductRepoPsql) FindByField(ctx context.Context, fieldName string, value string, viewer models.Viewer) ([]*models.Product, error) {
 	// WARNING: fieldName injection risk if coming from user input.
 	// Validate fieldName against allowed list is crucial.
 	allowedFields := map[string]bool{
@@ -294,9 +614,10 @@ func (r *ProductRepoPsql) FindByField(ctx context.Context, fieldName string, val
 		return nil, fmt.Errorf("search by %s not implemented", fieldName)
 	}
 
-	query := selectFullProduct + " WHERE " + whereClause + " ORDER BY p.created_at DESC"
+	s := &searchConditions{args: []any{value}}
+	query := selectFullProduct + " WHERE (" + whereClause + ") AND " + visibleTo(viewer, s.arg) + " ORDER BY p.created_at DESC"
 
-	rows, err := r.psql.Query(ctx, query, value)
+	rows, err := r.psql.Query(ctx, query, s.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -310,17 +631,23 @@ func (r *ProductRepoPsql) FindByField(ctx context.Context, fieldName string, val
 		}
 		products = append(products, p)
 	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
 
-func (r *ProductRepoPsql) UpdateStatus(ctx context.Context, id string, status models.ProductStatus) error {
-	query := `UPDATE authentic.products SET status = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.psql.Execute(ctx, query, status, id)
-	return err
+func (r *ProductRepoPsql) UpdateStatus(ctx context.Context, id string, status models.ProductStatus, ifMatch []time.Time) error {
+	query := `UPDATE authentic.products SET status = $1, updated_at = NOW() WHERE id = $2` + fmt.Sprintf(versionCondition, "$3")
+	result, err := r.psql.Execute(ctx, query, status, id, versionsArg(ifMatch))
+	if err != nil {
+		return identityConflict(err)
+	}
+	return checkVersion(result, ifMatch)
 }
 
 func (r *ProductRepoPsql) Delete(ctx context.Context, id string) error {
 	// Virtual delete or real? User said "User can delete yours products (virtual delete)"
 	// "Admin can delete all (virtual delete)"
-	return r.UpdateStatus(ctx, id, models.StatusDeleted)
+	return r.UpdateStatus(ctx, id, models.StatusDeleted, nil)
 }
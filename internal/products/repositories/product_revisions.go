@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+func (r *ProductRepoPsql) AddRevision(ctx context.Context, rev *models.Revision, baseline *models.ListingContent) error {
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertRevision(ctx, tx, rev, baseline); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertRevision stores rev as the next version of its listing within tx.
+func insertRevision(ctx context.Context, tx *sql.Tx, rev *models.Revision, baseline *models.ListingContent) error {
+	content, err := json.Marshal(rev.Content)
+	if err != nil {
+		return err
+	}
+
+	// Concurrent edits of the listing take turns numbering their versions
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM authentic.products WHERE id = $1 FOR UPDATE`, rev.ProductID); err != nil {
+		return err
+	}
+
+	if baseline != nil {
+		before, err := json.Marshal(baseline)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO authentic.product_revisions (product_id, version, content)
+			SELECT $1, 1, $2
+			WHERE NOT EXISTS (SELECT 1 FROM authentic.product_revisions WHERE product_id = $1)
+		`, rev.ProductID, before)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO authentic.product_revisions (product_id, version, content, changed_fields, edited_by, restored_from, requires_approval)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6
+		FROM authentic.product_revisions WHERE product_id = $1
+		RETURNING id, version, created_at
+	`
+	return tx.QueryRowContext(ctx, query, rev.ProductID, content, pq.Array(rev.ChangedFields), rev.EditedBy, rev.RestoredFrom, rev.RequiresApproval).
+		Scan(&rev.ID, &rev.Version, &rev.CreatedAt)
+}
+
+const revisionColumns = `id, product_id, version, content, changed_fields, edited_by, restored_from, requires_approval, created_at`
+
+func scanRevision(row interface{ Scan(...any) error }) (*models.Revision, error) {
+	var (
+		rev     models.Revision
+		content []byte
+	)
+	err := row.Scan(&rev.ID, &rev.ProductID, &rev.Version, &content, pq.Array(&rev.ChangedFields), &rev.EditedBy, &rev.RestoredFrom, &rev.RequiresApproval, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &rev.Content); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *ProductRepoPsql) FindRevisions(ctx context.Context, productID string) ([]*models.Revision, error) {
+	rows, err := r.psql.Query(ctx, `SELECT `+revisionColumns+` FROM authentic.product_revisions WHERE product_id = $1 ORDER BY version DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.Revision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *ProductRepoPsql) FindRevision(ctx context.Context, productID string, version int) (*models.Revision, error) {
+	rev, err := scanRevision(r.psql.QueryRow(ctx, `SELECT `+revisionColumns+` FROM authentic.product_revisions WHERE product_id = $1 AND version = $2`, productID, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return rev, err
+}
+
+func (r *ProductRepoPsql) SaveAutosave(ctx context.Context, a *models.Autosave) error {
+	query := `
+		INSERT INTO authentic.product_autosaves (product_id, user_id, content)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id) DO UPDATE SET user_id = EXCLUDED.user_id, content = EXCLUDED.content, saved_at = NOW()
+		RETURNING saved_at
+	`
+	return r.psql.QueryRow(ctx, query, a.ProductID, a.UserID, []byte(a.Content)).Scan(&a.SavedAt)
+}
+
+func (r *ProductRepoPsql) FindAutosave(ctx context.Context, productID uuid.UUID) (*models.Autosave, error) {
+	var (
+		a       models.Autosave
+		content []byte
+	)
+	err := r.psql.QueryRow(ctx, `SELECT product_id, user_id, content, saved_at FROM authentic.product_autosaves WHERE product_id = $1`, productID).
+		Scan(&a.ProductID, &a.UserID, &content, &a.SavedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.Content = content
+	return &a, nil
+}
+
+func (r *ProductRepoPsql) DeleteAutosave(ctx context.Context, productID uuid.UUID) error {
+	_, err := r.psql.Execute(ctx, `DELETE FROM authentic.product_autosaves WHERE product_id = $1`, productID)
+	return err
+}
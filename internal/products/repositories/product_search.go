@@ -0,0 +1,501 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/geo"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// kmPerDegreeLat is used to turn a radius into a bounding box the
+// (latitude, longitude) index can serve before the exact distance check.
+const kmPerDegreeLat = 111.045
+
+// priceBucket is a SEK range ending (exclusive) at Max, starting where the
+// previous bucket ended. Max 0 means unbounded.
+type priceBucket struct {
+	Label string
+	Max   float64
+}
+
+var priceBuckets = []priceBucket{
+	{Label: "0-10000", Max: 10000},
+	{Label: "10000-25000", Max: 25000},
+	{Label: "25000-50000", Max: 50000},
+	{Label: "50000-100000", Max: 100000},
+	{Label: "100000-250000", Max: 250000},
+	{Label: "250000-500000", Max: 500000},
+	{Label: "500000+"},
+}
+
+// Make and condition are filtered and counted on vehicles and equipment
+// alike, a listing only has one of the two.
+const (
+	makeExpr      = "COALESCE(v.make, e.make)"
+	conditionExpr = "COALESCE(v.condition, e.condition)"
+)
+
+// facetColumns maps each facet to the column exposed by the facet CTE and
+// the expression it is read from. Order here is the order facets are queried in.
+var facetColumns = []struct {
+	Facet  string
+	Column string
+	Expr   string
+}{
+	{models.FacetBreed, "breed", "h.breed"},
+	{models.FacetGender, "gender", "h.gender"},
+	{models.FacetColor, "color", "h.color"},
+	{models.FacetDressageLevel, "dressage_level", "h.dressage_level"},
+	{models.FacetJumpLevel, "jump_level", "h.jump_level"},
+	{models.FacetVehicleMake, "vehicle_make", makeExpr},
+	{models.FacetEquipmentCondition, "equipment_condition", conditionExpr},
+	{models.FacetServiceKind, "service_kind", "ps.service_kind"},
+	{models.FacetCity, "city", "p.city"},
+	{models.FacetPrice, "price_sek", "p.price_sek"},
+}
+
+// searchConditions holds the WHERE clauses for a search. Base conditions
+// always apply; faceted conditions are keyed by the facet they narrow so the
+// facet query can drop a facet's own filter when counting that facet.
+type searchConditions struct {
+	base    []string
+	faceted map[string][]string
+	args    []any
+	// distance is the SQL expression for the distance in km to the search
+	// point, empty when the search is not anchored to a location.
+	distance string
+}
+
+func (s *searchConditions) arg(v any) string {
+	s.args = append(s.args, v)
+	return fmt.Sprintf("$%d", len(s.args))
+}
+
+func (s *searchConditions) addFaceted(facet, clause string) {
+	s.faceted[facet] = append(s.faceted[facet], clause)
+}
+
+// all returns every condition, base and faceted, in a stable order.
+func (s *searchConditions) all() []string {
+	conds := append([]string{}, s.base...)
+	for _, fc := range facetColumns {
+		conds = append(conds, s.faceted[fc.Facet]...)
+	}
+	return conds
+}
+
+func buildSearchConditions(f *models.ProductFilters) *searchConditions {
+	return appendSearchConditions(nil, f)
+}
+
+// appendSearchConditions builds the conditions of f with placeholders
+// following args, so several searches can share one query.
+func appendSearchConditions(args []any, f *models.ProductFilters) *searchConditions {
+	s := &searchConditions{faceted: make(map[string][]string), args: args}
+	if f == nil {
+		f = &models.ProductFilters{}
+	}
+	s.base = append(s.base, visibleTo(f.Viewer, s.arg))
+
+	if f.Query != "" {
+		s.base = append(s.base, s.textCondition(f.Query))
+	}
+	if f.CategoryID != "" {
+		s.base = append(s.base, "p.category_id = "+s.arg(f.CategoryID))
+	}
+	if f.Type != "" {
+		s.base = append(s.base, "p.type = "+s.arg(f.Type))
+	}
+	if f.Status != "" {
+		s.base = append(s.base, "p.status = "+s.arg(f.Status))
+	}
+	if f.Model != "" {
+		ph := s.arg(f.Model)
+		s.base = append(s.base, fmt.Sprintf("(v.model = %s OR e.model = %s)", ph, ph))
+	}
+	if f.HasLocation() {
+		s.addLocation(*f.NearLat, *f.NearLng, f.RadiusKM)
+	}
+	if f.Sire != "" {
+		s.base = append(s.base, s.ancestorCondition("s", f.Sire))
+	}
+	if f.Dam != "" {
+		s.base = append(s.base, s.ancestorCondition("d", f.Dam))
+	}
+	if f.DamSire != "" {
+		s.base = append(s.base, s.ancestorCondition("ds", f.DamSire))
+	}
+	if f.Ancestor != "" {
+		s.base = append(s.base, s.ancestorCondition("", f.Ancestor))
+	}
+	if f.MinLandAreaHa != nil {
+		s.base = append(s.base, "pp.land_area_ha >= "+s.arg(*f.MinLandAreaHa))
+	}
+	if f.MinStalls != nil {
+		s.base = append(s.base, "pp.stall_count >= "+s.arg(*f.MinStalls))
+	}
+	if f.MinPaddocks != nil {
+		s.base = append(s.base, "pp.paddock_count >= "+s.arg(*f.MinPaddocks))
+	}
+	if f.MinLivingSpaceM2 != nil {
+		s.base = append(s.base, "pp.living_space_m2 >= "+s.arg(*f.MinLivingSpaceM2))
+	}
+	if f.HasArena {
+		s.base = append(s.base, "(pp.arena_width_m > 0 AND pp.arena_length_m > 0)")
+	}
+	if f.Reduced {
+		s.base = append(s.base, "(p.price_reduced_at > "+reducedSince+" AND p.price_sek < p.reduced_from_sek)")
+	}
+	for _, af := range f.Attributes {
+		s.base = append(s.base, s.attributeCondition(af))
+	}
+	if f.PricingUnit != "" {
+		s.base = append(s.base, "ps.pricing_unit = "+s.arg(strings.ToLower(f.PricingUnit)))
+	}
+	if f.Covers != "" {
+		s.base = append(s.base, "EXISTS (SELECT 1 FROM unnest(ps.coverage_areas) area WHERE LOWER(area) = LOWER("+s.arg(f.Covers)+"))")
+	}
+	if f.AvailableOn != "" {
+		s.base = append(s.base, s.arg(strings.ToLower(f.AvailableOn))+" = ANY(ps.available_days)")
+	}
+
+	if f.Breed != "" {
+		s.addFaceted(models.FacetBreed, "h.breed = "+s.arg(f.Breed))
+	}
+	if f.Gender != "" {
+		s.addFaceted(models.FacetGender, "h.gender = "+s.arg(f.Gender))
+	}
+	if f.Color != "" {
+		s.addFaceted(models.FacetColor, "h.color = "+s.arg(f.Color))
+	}
+	if f.DressageLevel != "" {
+		s.addFaceted(models.FacetDressageLevel, "h.dressage_level = "+s.arg(f.DressageLevel))
+	}
+	if f.JumpLevel != "" {
+		s.addFaceted(models.FacetJumpLevel, "h.jump_level = "+s.arg(f.JumpLevel))
+	}
+	if f.Make != "" {
+		s.addFaceted(models.FacetVehicleMake, makeExpr+" = "+s.arg(f.Make))
+	}
+	if f.Condition != "" {
+		s.addFaceted(models.FacetEquipmentCondition, conditionExpr+" = "+s.arg(f.Condition))
+	}
+	if f.ServiceKind != "" {
+		s.addFaceted(models.FacetServiceKind, "ps.service_kind = "+s.arg(strings.ToLower(f.ServiceKind)))
+	}
+	if f.City != "" {
+		s.addFaceted(models.FacetCity, "LOWER(p.city) = LOWER("+s.arg(f.City)+")")
+	}
+	if f.MinPrice != nil {
+		s.addFaceted(models.FacetPrice, "p.price_sek >= "+s.priceSEK(*f.MinPrice, f.Currency))
+	}
+	if f.MaxPrice != nil {
+		s.addFaceted(models.FacetPrice, "p.price_sek <= "+s.priceSEK(*f.MaxPrice, f.Currency))
+	}
+
+	return s
+}
+
+// priceSEK converts a price filter to SEK with the current exchange rate,
+// so saved searches in another currency follow the rate. Without a rate the
+// filter matches nothing.
+func (s *searchConditions) priceSEK(amount float64, currency string) string {
+	if currency == "" || currency == "SEK" {
+		return s.arg(amount)
+	}
+	return fmt.Sprintf("(%s * (SELECT sek_per_unit FROM authentic.exchange_rates WHERE currency = %s))", s.arg(amount), s.arg(currency))
+}
+
+func (s *searchConditions) addLocation(lat, lng float64, radiusKM *float64) {
+	latPh, lngPh := s.arg(lat), s.arg(lng)
+	s.distance = fmt.Sprintf(
+		"(%g * 2 * ASIN(SQRT(POWER(SIN(RADIANS(p.latitude - %s) / 2), 2) + COS(RADIANS(%s)) * COS(RADIANS(p.latitude)) * POWER(SIN(RADIANS(p.longitude - %s) / 2), 2))))",
+		geo.EarthRadiusKM, latPh, latPh, lngPh,
+	)
+	if radiusKM == nil {
+		return
+	}
+
+	dLat := *radiusKM / kmPerDegreeLat
+	dLng := *radiusKM / (kmPerDegreeLat * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	s.base = append(s.base,
+		fmt.Sprintf("p.latitude BETWEEN %s AND %s", s.arg(lat-dLat), s.arg(lat+dLat)),
+		fmt.Sprintf("p.longitude BETWEEN %s AND %s", s.arg(lng-dLng), s.arg(lng+dLng)),
+		fmt.Sprintf("%s <= %s", s.distance, s.arg(*radiusKM)),
+	)
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func (r *ProductRepoPsql) Search(ctx context.Context, filters *models.ProductFilters) ([]*models.Product, error) {
+	s := buildSearchConditions(filters)
+
+	columns := productColumns
+	if s.distance != "" {
+		columns += ", " + s.distance + " AS distance_km"
+	}
+	columns += ", fp.id, fp.placement, fp.ends_at"
+	query := `SELECT ` + columns + productJoins + s.featuredJoin(filters) + whereClause(s.all())
+
+	// Featured listings are always labelled but only come first in the
+	// default ordering, an explicit sort is honoured as asked
+	switch {
+	case s.distance != "" && filters.Sort == models.SortDistance:
+		query += ` ORDER BY distance_km ASC NULLS LAST, p.created_at DESC`
+	case filters == nil || filters.Sort == "":
+		query += ` ORDER BY fp.id IS NULL, p.created_at DESC`
+	default:
+		query += ` ORDER BY p.created_at DESC`
+	}
+	if filters != nil && filters.Limit > 0 {
+		query += " LIMIT " + s.arg(filters.Limit)
+		if filters.Offset > 0 {
+			query += " OFFSET " + s.arg(filters.Offset)
+		}
+	}
+
+	rows, err := r.psql.Query(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		var (
+			distance    *float64
+			promotionID *uuid.UUID
+			placement   *string
+			endsAt      *time.Time
+		)
+		extra := []any{&promotionID, &placement, &endsAt}
+		if s.distance != "" {
+			extra = append([]any{&distance}, extra...)
+		}
+		p, err := r.scanProduct(rows, extra...)
+		if err != nil {
+			return nil, err
+		}
+		p.DistanceKM = distance
+		if promotionID != nil {
+			p.Featured = &models.Featured{PromotionID: *promotionID, Placement: *placement, EndsAt: *endsAt}
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// featuredJoin picks the running promotion of each published listing for
+// the page being searched: the category page when filtering by category,
+// otherwise the front page.
+func (s *searchConditions) featuredJoin(f *models.ProductFilters) string {
+	placement := "pr.placement = 'front_page'"
+	if f != nil && f.CategoryID != "" {
+		placement = "pr.placement = 'category' AND pr.category_id = " + s.arg(f.CategoryID)
+	}
+	return `
+	LEFT JOIN LATERAL (
+		SELECT pr.id, pr.placement, pr.ends_at
+		FROM authentic.promotions pr
+		WHERE pr.product_id = p.id AND p.status = 'published'
+			AND pr.status = 'active' AND pr.starts_at <= NOW() AND pr.ends_at > NOW()
+			AND ` + placement + `
+		ORDER BY pr.ends_at DESC
+		LIMIT 1
+	) fp ON TRUE`
+}
+
+// matchBatchSize is how many searches MatchFilters evaluates per query,
+// keeping well below the limit on bind parameters.
+const matchBatchSize = 500
+
+func (r *ProductRepoPsql) MatchFilters(ctx context.Context, id string, filters []*models.ProductFilters) ([]bool, error) {
+	matches := make([]bool, 0, len(filters))
+	for start := 0; start < len(filters); start += matchBatchSize {
+		batch := filters[start:min(start+matchBatchSize, len(filters))]
+		found, err := r.matchBatch(ctx, id, batch)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+	}
+	return matches, nil
+}
+
+// matchBatch evaluates each search's conditions as a column over the one
+// listing's row.
+func (r *ProductRepoPsql) matchBatch(ctx context.Context, id string, filters []*models.ProductFilters) ([]bool, error) {
+	args := []any{id}
+	cols := make([]string, len(filters))
+	for i, f := range filters {
+		s := appendSearchConditions(args, f)
+		args = s.args
+		cols[i] = "(" + strings.Join(s.all(), " AND ") + ") IS TRUE"
+	}
+	query := `SELECT ` + strings.Join(cols, ", ") + productJoins + ` WHERE p.id = $1`
+
+	matches := make([]bool, len(filters))
+	dest := make([]any, len(filters))
+	for i := range matches {
+		dest[i] = &matches[i]
+	}
+	// A listing that is gone matches nothing
+	err := r.psql.QueryRow(ctx, query, args...).Scan(dest...)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// FacetCounts counts the values of every facet among the products matching
+// filters. Each facet ignores its own filter so the sidebar keeps showing the
+// alternatives to the value currently selected.
+//
+// The base filters are applied once in a CTE which also evaluates each active
+// facet filter into a boolean column; every facet is then a GROUP BY over that
+// CTE, glued together with UNION ALL so the whole thing is a single round trip.
+func (r *ProductRepoPsql) FacetCounts(ctx context.Context, filters *models.ProductFilters) (map[string][]models.FacetCount, error) {
+	s := buildSearchConditions(filters)
+
+	cols := make([]string, 0, len(facetColumns)*2)
+	for _, fc := range facetColumns {
+		cols = append(cols, fc.Expr+" AS "+fc.Column)
+	}
+	for _, fc := range facetColumns {
+		if clauses := s.faceted[fc.Facet]; len(clauses) > 0 {
+			cols = append(cols, "("+strings.Join(clauses, " AND ")+") AS m_"+fc.Column)
+		}
+	}
+
+	cte := `
+		WITH base AS (
+			SELECT ` + strings.Join(cols, ", ") + `
+			FROM authentic.products p
+			LEFT JOIN authentic.product_horses h ON p.id = h.product_id
+			LEFT JOIN authentic.product_vehicles v ON p.id = v.product_id
+			LEFT JOIN authentic.product_equipment e ON p.id = e.product_id
+			LEFT JOIN authentic.product_properties pp ON p.id = pp.product_id
+			LEFT JOIN authentic.product_services ps ON p.id = ps.product_id` + whereClause(s.base) + `
+		)`
+
+	parts := make([]string, 0, len(facetColumns))
+	for _, fc := range facetColumns {
+		conds := []string{fc.Column + " IS NOT NULL"}
+		for _, other := range facetColumns {
+			if other.Facet != fc.Facet && len(s.faceted[other.Facet]) > 0 {
+				conds = append(conds, "m_"+other.Column)
+			}
+		}
+
+		valueExpr := fc.Column + "::text"
+		if fc.Facet == models.FacetPrice {
+			valueExpr = priceBucketCase(fc.Column)
+		}
+		parts = append(parts, fmt.Sprintf("SELECT '%s' AS facet, %s AS value, COUNT(*) AS cnt FROM base%s GROUP BY 2",
+			fc.Facet, valueExpr, whereClause(conds)))
+	}
+
+	query := cte + "\n" + strings.Join(parts, "\nUNION ALL\n")
+	rows, err := r.psql.Query(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := make(map[string][]models.FacetCount, len(facetColumns))
+	for _, fc := range facetColumns {
+		facets[fc.Facet] = []models.FacetCount{}
+	}
+	for rows.Next() {
+		var facet string
+		var fcount models.FacetCount
+		if err := rows.Scan(&facet, &fcount.Value, &fcount.Count); err != nil {
+			return nil, err
+		}
+		facets[facet] = append(facets[facet], fcount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, counts := range facets {
+		sortFacet(name, counts)
+	}
+	return facets, nil
+}
+
+func priceBucketCase(column string) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, pb := range priceBuckets {
+		if pb.Max > 0 {
+			fmt.Fprintf(&b, " WHEN %s < %g THEN '%s'", column, pb.Max, pb.Label)
+		} else {
+			fmt.Fprintf(&b, " ELSE '%s'", pb.Label)
+		}
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+// sortFacet orders price buckets from cheap to expensive and every other
+// facet by descending count, then value.
+func sortFacet(name string, counts []models.FacetCount) {
+	if name == models.FacetPrice {
+		order := make(map[string]int, len(priceBuckets))
+		for i, pb := range priceBuckets {
+			order[pb.Label] = i
+		}
+		sort.Slice(counts, func(i, j int) bool { return order[counts[i].Value] < order[counts[j].Value] })
+		return
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+}
+
+func (r *ProductRepoPsql) queryProducts(ctx context.Context, query string, args ...any) ([]*models.Product, error) {
+	rows, err := r.psql.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		p, err := r.scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+// similarityTerm is something a listing may have in common with another,
+// worth weight points when cond holds.
+type similarityTerm struct {
+	reason string
+	weight int
+	cond   string
+}
+
+// similarityTerms are the conditions on p matching what the source listing
+// has. Attributes the source leaves empty are not compared.
+func similarityTerms(src *models.Product, s *searchConditions) []similarityTerm {
+	var terms []similarityTerm
+	add := func(reason string, weight int, cond string) {
+		terms = append(terms, similarityTerm{reason: reason, weight: weight, cond: cond})
+	}
+	same := func(column string, value *string) string {
+		return "LOWER(" + column + ") = LOWER(" + s.arg(*value) + ")"
+	}
+	within := func(column string, value, delta float64) string {
+		return fmt.Sprintf("%s BETWEEN %s AND %s", column, s.arg(value-delta), s.arg(value+delta))
+	}
+	withinInt := func(column string, value, delta int) string {
+		return fmt.Sprintf("%s BETWEEN %s AND %s", column, s.arg(value-delta), s.arg(value+delta))
+	}
+
+	if src.CategoryID != nil {
+		add(models.SimilarCategory, 2, "p.category_id = "+s.arg(*src.CategoryID))
+	}
+	if src.PriceSEK != nil && *src.PriceSEK > 0 {
+		add(models.SimilarPrice, 2, within("p.price_sek", *src.PriceSEK, *src.PriceSEK*models.SimilarRatio))
+	}
+	if src.Latitude != nil && src.Longitude != nil {
+		s.addLocation(*src.Latitude, *src.Longitude, nil)
+		add(models.SimilarLocation, 2, fmt.Sprintf("%s <= %d", s.distance, models.SimilarDistanceKM))
+	}
+
+	if h := src.Horse; h != nil {
+		if h.Breed != nil {
+			add(models.SimilarBreed, 3, same("h.breed", h.Breed))
+		}
+		if h.YearOfBirth != nil {
+			add(models.SimilarAge, 1, withinInt("h.year_of_birth", *h.YearOfBirth, models.SimilarBirthYears))
+		}
+		if h.Height != nil {
+			add(models.SimilarHeight, 1, withinInt("h.height", *h.Height, models.SimilarHeightCM))
+		}
+		if h.Orientation != nil {
+			add(models.SimilarDiscipline, 2, same("h.orientation", h.Orientation))
+		}
+	}
+	if v := src.Vehicle; v != nil {
+		if v.Make != nil {
+			add(models.SimilarMake, 2, same("v.make", v.Make))
+		}
+		if v.TotalWeight != nil {
+			low, high := models.WeightClass(*v.TotalWeight)
+			cond := "v.total_weight > " + s.arg(low)
+			if high > 0 {
+				cond += " AND v.total_weight <= " + s.arg(high)
+			}
+			add(models.SimilarWeightClass, 2, cond)
+		}
+		if v.Year != nil {
+			add(models.SimilarYear, 1, withinInt("v.year", *v.Year, models.SimilarVehicleYears))
+		}
+	}
+	if e := src.Equipment; e != nil {
+		if e.SubType != nil {
+			add(models.SimilarSubType, 2, same("e.sub_type", e.SubType))
+		}
+		if e.Make != nil {
+			add(models.SimilarMake, 2, same("e.make", e.Make))
+		}
+	}
+	if pp := src.Property; pp != nil {
+		if pp.LandAreaHa != nil && *pp.LandAreaHa > 0 {
+			add(models.SimilarLandArea, 1, within("pp.land_area_ha", *pp.LandAreaHa, *pp.LandAreaHa*models.SimilarRatio))
+		}
+		if pp.StallCount != nil {
+			add(models.SimilarStalls, 1, withinInt("pp.stall_count", *pp.StallCount, 2))
+		}
+	}
+	if ps := src.Service; ps != nil && ps.Kind != nil {
+		add(models.SimilarServiceKind, 3, "ps.service_kind = "+s.arg(*ps.Kind))
+	}
+	return terms
+}
+
+func (r *ProductRepoPsql) FindSimilar(ctx context.Context, src *models.Product, limit int) ([]*models.SimilarListing, error) {
+	s := &searchConditions{}
+	terms := similarityTerms(src, s)
+
+	score, matched := "0", "ARRAY[]::TEXT[]"
+	if len(terms) > 0 {
+		scores := make([]string, len(terms))
+		reasons := make([]string, len(terms))
+		for i, t := range terms {
+			scores[i] = fmt.Sprintf("CASE WHEN %s THEN %d ELSE 0 END", t.cond, t.weight)
+			reasons[i] = fmt.Sprintf("CASE WHEN %s THEN '%s' END", t.cond, t.reason)
+		}
+		score = strings.Join(scores, " + ")
+		matched = "ARRAY_REMOVE(ARRAY[" + strings.Join(reasons, ", ") + "]::TEXT[], NULL)"
+	}
+
+	// Only published listings of the same type having something in common
+	// are candidates, hidden listings are never published
+	query := `SELECT ` + productColumns + `, (` + score + `) AS similarity, ` + matched +
+		productJoins + `
+		WHERE p.status = 'published' AND p.type = ` + s.arg(src.Type) + ` AND p.id <> ` + s.arg(src.ID) + `
+		AND (` + score + `) > 0
+		ORDER BY similarity DESC, p.created_at DESC
+		LIMIT ` + s.arg(limit)
+
+	rows, err := r.psql.Query(ctx, query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []*models.SimilarListing
+	var products []*models.Product
+	for rows.Next() {
+		l := &models.SimilarListing{}
+		var reasons pq.StringArray
+		if l.Product, err = r.scanProduct(rows, &l.Score, &reasons); err != nil {
+			return nil, err
+		}
+		l.Matched = reasons
+		listings = append(listings, l)
+		products = append(products, l.Product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachRelations(ctx, products); err != nil {
+		return nil, err
+	}
+	return listings, nil
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// Sitemap pages list published listings oldest first, so a listing stays on
+// the same page until older ones go offline.
+
+func (r *ProductRepoPsql) SitemapProducts(ctx context.Context, offset, limit int) ([]models.SitemapEntry, error) {
+	rows, err := r.psql.Query(ctx, `
+		SELECT p.id, p.updated_at
+		FROM authentic.products p
+		WHERE p.status = 'published'
+		ORDER BY p.created_at, p.id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanSitemapEntries(rows)
+}
+
+func (r *ProductRepoPsql) SitemapProductPages(ctx context.Context, perPage int) ([]time.Time, error) {
+	rows, err := r.psql.Query(ctx, `
+		SELECT MAX(updated_at)
+		FROM (
+			SELECT p.updated_at, (ROW_NUMBER() OVER (ORDER BY p.created_at, p.id) - 1) / $1 AS page
+			FROM authentic.products p
+			WHERE p.status = 'published'
+		) t
+		GROUP BY page
+		ORDER BY page
+	`, perPage)
+	if err != nil {
+		return nil, err
+	}
+	return scanPageTimes(rows)
+}
+
+func (r *ProductRepoPsql) SitemapSellers(ctx context.Context, offset, limit int) ([]models.SitemapEntry, error) {
+	rows, err := r.psql.Query(ctx, `
+		SELECT p.user_id, MAX(p.updated_at)
+		FROM authentic.products p
+		WHERE p.status = 'published'
+		GROUP BY p.user_id
+		ORDER BY p.user_id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanSitemapEntries(rows)
+}
+
+func (r *ProductRepoPsql) SitemapSellerPages(ctx context.Context, perPage int) ([]time.Time, error) {
+	rows, err := r.psql.Query(ctx, `
+		SELECT MAX(last_modified)
+		FROM (
+			SELECT MAX(p.updated_at) AS last_modified, (ROW_NUMBER() OVER (ORDER BY p.user_id) - 1) / $1 AS page
+			FROM authentic.products p
+			WHERE p.status = 'published'
+			GROUP BY p.user_id
+		) t
+		GROUP BY page
+		ORDER BY page
+	`, perPage)
+	if err != nil {
+		return nil, err
+	}
+	return scanPageTimes(rows)
+}
+
+func scanSitemapEntries(rows *sql.Rows) ([]models.SitemapEntry, error) {
+	defer rows.Close()
+	var entries []models.SitemapEntry
+	for rows.Next() {
+		var e models.SitemapEntry
+		if err := rows.Scan(&e.ID, &e.LastModified); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func scanPageTimes(rows *sql.Rows) ([]time.Time, error) {
+	defer rows.Close()
+	var pages []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		pages = append(pages, t)
+	}
+	return pages, rows.Err()
+}
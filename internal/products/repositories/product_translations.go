@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/lib/pq"
+)
+
+// textSearchConfigs are the Postgres text search configurations of the
+// languages, they must match the search_vector columns.
+var textSearchConfigs = map[string]string{
+	"sv": "swedish",
+	"en": "english",
+}
+
+func (r *ProductRepoPsql) attachTranslations(ctx context.Context, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(products))
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for i, p := range products {
+		ids[i] = p.ID.String()
+		byID[p.ID] = p
+	}
+
+	query := `
+		SELECT product_id, language, title, description
+		FROM authentic.product_translations
+		WHERE product_id = ANY($1::uuid[])
+	`
+	rows, err := r.psql.Query(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID uuid.UUID
+		var language string
+		var t models.Translation
+		if err := rows.Scan(&productID, &language, &t.Title, &t.Description); err != nil {
+			return err
+		}
+		p := byID[productID]
+		if p.Translations == nil {
+			p.Translations = make(map[string]models.Translation)
+		}
+		p.Translations[language] = t
+	}
+	return rows.Err()
+}
+
+func (r *ProductRepoPsql) replaceTranslations(ctx context.Context, tx *sql.Tx, productID uuid.UUID, translations map[string]models.Translation) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM authentic.product_translations WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+	for language, t := range translations {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO authentic.product_translations (product_id, language, title, description) VALUES ($1, $2, $3, $4)`,
+			productID, language, t.Title, t.Description,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// likeEscaper escapes the wildcards of LIKE, whose default escape
+// character is the backslash.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// textCondition matches listings whose title or description, in any of
+// their languages, contains the words of the query. Each language is
+// searched with its own configuration, so "hästar" finds "häst". The
+// substring match on the listing's own text is kept for partial words,
+// served by the trigram indexes on title and description.
+func (s *searchConditions) textCondition(query string) string {
+	like := s.arg("%" + likeEscaper.Replace(query) + "%")
+	q := s.arg(query)
+	conds := []string{
+		fmt.Sprintf("p.title ILIKE %s", like),
+		fmt.Sprintf("p.description ILIKE %s", like),
+	}
+	for _, language := range models.Languages {
+		config := textSearchConfigs[language]
+		conds = append(conds,
+			fmt.Sprintf("(p.language = '%s' AND p.search_vector @@ plainto_tsquery('%s', %s))", language, config, q),
+			fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM authentic.product_translations pt
+			WHERE pt.product_id = p.id AND pt.language = '%s' AND pt.search_vector @@ plainto_tsquery('%s', %s))`, language, config, q),
+		)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrVersionConflict is returned by the writes given versions to match when
+// the listing is at none of them anymore.
+var ErrVersionConflict = errors.New("the listing was changed since it was loaded")
+
+// versionCondition restricts a write to the versions given in the
+// parameter it follows, a NULL array accepting any.
+const versionCondition = ` AND (%[1]s::timestamptz[] IS NULL OR updated_at = ANY(%[1]s::timestamptz[]))`
+
+// versionsArg is the versions parameter of versionCondition, nil accepting
+// any version.
+func versionsArg(ifMatch []time.Time) any {
+	if ifMatch == nil {
+		return nil
+	}
+	versions := make([]string, len(ifMatch))
+	for i, v := range ifMatch {
+		versions[i] = v.Format(time.RFC3339Nano)
+	}
+	return pq.Array(versions)
+}
+
+// checkVersion turns a write given versions to match that changed no rows
+// into ErrVersionConflict.
+func checkVersion(result sql.Result, ifMatch []time.Time) error {
+	if ifMatch == nil {
+		return nil
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
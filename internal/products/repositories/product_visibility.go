@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"strings"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// public is the condition on p of the listings anyone can see, those in
+// models.PublicStatuses not hidden after reports.
+var public = func() string {
+	quoted := make([]string, len(models.PublicStatuses))
+	for i, status := range models.PublicStatuses {
+		quoted[i] = "'" + string(status) + "'"
+	}
+	return "(p.status IN (" + strings.Join(quoted, ", ") + ") AND p.hidden_at IS NULL)"
+}()
+
+// visibleTo is the condition on p limiting listings to those the viewer may
+// see, following models.Viewer.CanSee. arg adds a query argument and returns
+// its placeholder.
+func visibleTo(v models.Viewer, arg func(any) string) string {
+	switch {
+	case v.IsAdmin && v.IncludeDeleted:
+		return "TRUE"
+	case v.IsAdmin:
+		return "p.status <> 'deleted'"
+	case v.UserID == "":
+		return public
+	case v.IncludeDeleted:
+		return "(" + public + " OR p.user_id = " + arg(v.UserID) + ")"
+	default:
+		return "(" + public + " OR (p.user_id = " + arg(v.UserID) + " AND p.status <> 'deleted'))"
+	}
+}
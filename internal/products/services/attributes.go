@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	catmodels "github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	catservices "github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// AttributeDefinitions provides the attributes admins defined for a
+// category, including inherited ones.
+type AttributeDefinitions interface {
+	ListAttributes(ctx context.Context, categoryID string) ([]*catmodels.Attribute, error)
+}
+
+// SetAttributeDefinitions enables category attributes on listings.
+func (s *ProductServiceImp) SetAttributeDefinitions(defs AttributeDefinitions) {
+	s.attributeDefs = defs
+}
+
+// prepareAttributes validates the listing's attribute values against the
+// definitions of its category and sets AttributeValues for the repository.
+// When editing, values sent as nil are kept unless the category changed.
+// It returns a *models.AttributeError when a value is invalid.
+func (s *ProductServiceImp) prepareAttributes(ctx context.Context, p *models.Product, existing *models.Product) error {
+	p.AttributeValues = nil
+	if s.attributeDefs == nil {
+		return nil
+	}
+
+	categoryChanged := existing != nil && !sameUUID(existing.CategoryID, p.CategoryID)
+	if p.Attributes == nil && existing != nil && !categoryChanged {
+		p.Attributes = existing.Attributes
+		return nil
+	}
+
+	// Report problems in a stable order
+	keys := make([]string, 0, len(p.Attributes))
+	for key := range p.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if p.CategoryID == nil {
+		if len(keys) > 0 {
+			return &models.AttributeError{Key: keys[0], Reason: "listing has no category"}
+		}
+		if categoryChanged {
+			// The old category's values no longer apply
+			p.AttributeValues = []models.ProductAttribute{}
+		}
+		return nil
+	}
+
+	defs, err := s.attributeDefs.ListAttributes(ctx, p.CategoryID.String())
+	if errors.Is(err, catservices.ErrCategoryNotFound) {
+		return &models.DetailError{Field: "category_id", Reason: "category not found"}
+	}
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]*catmodels.Attribute, len(defs))
+	for _, d := range defs {
+		byKey[d.Key] = d
+	}
+
+	values := make([]models.ProductAttribute, 0, len(keys))
+	normalized := make(map[string]any, len(keys))
+	for _, key := range keys {
+		raw := p.Attributes[key]
+		def, ok := byKey[key]
+		if !ok {
+			return &models.AttributeError{Key: key, Reason: "not an attribute of this category"}
+		}
+		if raw == nil || raw == "" {
+			continue
+		}
+		v, err := def.Coerce(raw)
+		if err != nil {
+			return &models.AttributeError{Key: key, Reason: err.Error()}
+		}
+		values = append(values, models.ProductAttribute{AttributeID: def.ID, Value: v})
+		normalized[key] = v.Value()
+	}
+
+	for _, d := range defs {
+		if _, ok := normalized[d.Key]; d.Required && !ok {
+			return &models.AttributeError{Key: d.Key, Reason: "is required"}
+		}
+	}
+
+	p.Attributes = normalized
+	if len(normalized) == 0 {
+		p.Attributes = nil
+	}
+	p.AttributeValues = values
+	return nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+var (
+	ErrInvalidMating = errors.New("a mating needs exactly one sire and one dam, each a horse listing or a pedigree")
+	ErrNotBreedable  = errors.New("the sire must be a stallion and the dam a mare")
+	ErrSameHorse     = errors.New("sire and dam are the same horse")
+)
+
+// BreedingCache keeps computed matings. Keys change whenever a listing
+// involved is edited, so entries never need to be invalidated.
+type BreedingCache interface {
+	// Get returns nil when the key is not cached.
+	Get(ctx context.Context, key string) (*models.BreedingResult, error)
+	Set(ctx context.Context, key string, result *models.BreedingResult) error
+}
+
+// SetBreedingCache enables caching breeding compatibility results, which
+// pays off for popular stallions checked against many mares.
+func (s *ProductServiceImp) SetBreedingCache(cache BreedingCache) {
+	s.breedingCache = cache
+}
+
+// breedingParent is one side of a mating resolved to a pedigree.
+type breedingParent struct {
+	summary  models.BreedingParent
+	horse    *models.PedigreeHorse
+	cacheKey string
+	listing  bool
+}
+
+// BreedingCompatibility computes the inbreeding coefficient of the foal of
+// a hypothetical mating and the ancestors both parents share.
+func (s *ProductServiceImp) BreedingCompatibility(ctx context.Context, req *models.BreedingRequest, viewer models.Viewer) (*models.BreedingResult, error) {
+	sire, err := s.resolveParent(ctx, req.SireID, req.Sire, "sire", viewer)
+	if err != nil {
+		return nil, err
+	}
+	dam, err := s.resolveParent(ctx, req.DamID, req.Dam, "dam", viewer)
+	if err != nil {
+		return nil, err
+	}
+	if sire.horse.Identity() == dam.horse.Identity() {
+		return nil, ErrSameHorse
+	}
+
+	// Ad-hoc pairs are rarely asked twice, listings are
+	key := "v1|" + sire.cacheKey + "|" + dam.cacheKey
+	useCache := s.breedingCache != nil && (sire.listing || dam.listing)
+	if useCache {
+		cached, err := s.breedingCache.Get(ctx, key)
+		if err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to read breeding cache", map[string]any{"error": err.Error()})
+		} else if cached != nil {
+			return cached, nil
+		}
+	}
+
+	coefficient, ancestors := models.Mating(sire.horse, dam.horse)
+	if ancestors == nil {
+		ancestors = []models.CommonAncestor{}
+	}
+	result := &models.BreedingResult{
+		Sire:            sire.summary,
+		Dam:             dam.summary,
+		Coefficient:     coefficient,
+		Percentage:      math.Round(coefficient*10000) / 100,
+		CommonAncestors: ancestors,
+	}
+
+	if useCache {
+		if err := s.breedingCache.Set(ctx, key, result); err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to cache breeding result", map[string]any{"error": err.Error()})
+		}
+	}
+	return result, nil
+}
+
+func (s *ProductServiceImp) resolveParent(ctx context.Context, id *string, adhoc *models.PedigreeHorse, side string, viewer models.Viewer) (*breedingParent, error) {
+	if (id == nil) == (adhoc == nil) {
+		return nil, ErrInvalidMating
+	}
+
+	if adhoc != nil {
+		// Validate as the parent's entry in a pedigree one generation longer
+		p := &models.Pedigree{Sire: adhoc}
+		if side == "dam" {
+			p = &models.Pedigree{Dam: adhoc}
+		}
+		p.Normalize()
+		if err := p.Validate(s.pedigreeGenerations(ctx) + 1); err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(adhoc)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(raw)
+		return &breedingParent{
+			summary:  models.BreedingParent{Name: adhoc.Name, RegistrationNumber: adhoc.RegistrationNumber, Generations: pedigreeDepth(adhoc)},
+			horse:    adhoc,
+			cacheKey: "h:" + hex.EncodeToString(sum[:]),
+		}, nil
+	}
+
+	p, err := s.findVisible(ctx, *id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.Horse == nil {
+		return nil, ErrProductNotFound
+	}
+	if !breedableAs(p.Horse.Gender, side) {
+		return nil, ErrNotBreedable
+	}
+
+	horse := &models.PedigreeHorse{Name: p.Title, YearOfBirth: p.Horse.YearOfBirth, Breed: p.Horse.Breed, Color: p.Horse.Color}
+	if p.Horse.Name != nil && *p.Horse.Name != "" {
+		horse.Name = *p.Horse.Name
+	}
+	if p.Horse.Pedigree != nil {
+		horse.Sire, horse.Dam = p.Horse.Pedigree.Sire, p.Horse.Pedigree.Dam
+	}
+
+	productID := p.ID.String()
+	return &breedingParent{
+		summary:  models.BreedingParent{ProductID: &productID, Name: horse.Name, Generations: pedigreeDepth(horse)},
+		horse:    horse,
+		cacheKey: "p:" + productID + ":" + strconv.FormatInt(p.UpdatedAt.UnixNano(), 10),
+		listing:  true,
+	}, nil
+}
+
+// breedableAs accepts horses without a gender, the seller may have left it out.
+func breedableAs(gender *string, side string) bool {
+	if gender == nil || *gender == "" {
+		return true
+	}
+	g := strings.ToLower(strings.TrimSpace(*gender))
+	if side == "dam" {
+		return g == "mare"
+	}
+	return g != "mare" && g != "gelding"
+}
+
+func pedigreeDepth(h *models.PedigreeHorse) int {
+	return (&models.Pedigree{Sire: h.Sire, Dam: h.Dam}).Depth()
+}
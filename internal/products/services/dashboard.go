@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+var ErrInvalidBulkAction = errors.New("action must be archive_sold or renew_expiring")
+
+const (
+	defaultDashboardListings = 20
+	maxDashboardListings     = 100
+)
+
+// Dashboard is the seller's own listings in a status tab, with the count of
+// every tab. Listings about to expire are those the seller is reminded of.
+func (s *ProductServiceImp) Dashboard(ctx context.Context, userID string, f models.DashboardFilters) (*models.Dashboard, error) {
+	if f.Status != "" && !slices.Contains(models.DashboardStatuses, f.Status) {
+		return nil, ErrInvalidSearch
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultDashboardListings
+	}
+	f.Limit = min(f.Limit, maxDashboardListings)
+
+	counts, expiring, err := s.repo.CountSellerListings(ctx, userID, s.expiringBefore(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range models.DashboardStatuses {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
+	}
+
+	listings, err := s.repo.FindSellerListings(ctx, userID, f)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, l := range listings {
+		if l.Status == models.StatusPublished && l.ExpiresAt != nil {
+			days := max(0, int(l.ExpiresAt.Sub(now).Hours()/24))
+			l.DaysUntilExpiry = &days
+		}
+	}
+	if listings == nil {
+		listings = []*models.SellerListing{}
+	}
+
+	return &models.Dashboard{Counts: counts, Listings: listings, Expiring: expiring}, nil
+}
+
+// BulkAction applies a quick action to every matching listing of the
+// seller. Each listing goes through the same rules as when changed on its
+// own, one failing does not stop the others.
+func (s *ProductServiceImp) BulkAction(ctx context.Context, userID string, action models.BulkAction) (*models.BulkResult, error) {
+	var ids []uuid.UUID
+	var err error
+	var apply func(id string) error
+	switch action {
+	case models.BulkArchiveSold:
+		ids, err = s.repo.FindSellerListingIDs(ctx, userID, models.StatusSold, nil)
+		apply = func(id string) error {
+			return s.UpdateStatus(ctx, id, models.StatusArchived, userID, false, nil)
+		}
+	case models.BulkRenewExpiring:
+		before := s.expiringBefore(ctx)
+		ids, err = s.repo.FindSellerListingIDs(ctx, userID, models.StatusPublished, &before)
+		apply = func(id string) error {
+			_, err := s.Renew(ctx, id, userID, false)
+			return err
+		}
+	default:
+		return nil, ErrInvalidBulkAction
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BulkResult{Action: action, Updated: []uuid.UUID{}, Failed: map[uuid.UUID]string{}}
+	for _, id := range ids {
+		if err := apply(id.String()); err != nil {
+			result.Failed[id] = s.bulkFailure(ctx, id, err)
+			continue
+		}
+		result.Updated = append(result.Updated, id)
+	}
+	return result, nil
+}
+
+// bulkFailure is why a listing was left out of a bulk action, as told to
+// the seller. Unexpected errors are logged rather than shown.
+func (s *ProductServiceImp) bulkFailure(ctx context.Context, id uuid.UUID, err error) string {
+	var duplicateErr *models.DuplicateError
+	var identityErr *models.IdentityError
+	if errors.Is(err, ErrCannotRenew) || errors.Is(err, ErrDuplicateIdentity) || errors.As(err, &duplicateErr) || errors.As(err, &identityErr) {
+		return err.Error()
+	}
+	s.logger.Log(ctx, config.ErrorLevel, "Bulk action failed for listing", map[string]any{"error": err.Error(), "product_id": id})
+	return "internal error"
+}
+
+// expiringBefore is the end of the window in which sellers are reminded
+// that their listings expire.
+func (s *ProductServiceImp) expiringBefore(ctx context.Context) time.Time {
+	days, err := s.settingsRepo.ExpiryReminderDays(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to read expiry reminder days, using default", map[string]any{"error": err.Error()})
+	}
+	return time.Now().AddDate(0, 0, days)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// validateDetails normalizes and validates the property or service details
+// of a listing. It returns a *models.DetailError when they are invalid.
+func validateDetails(p *models.Product) error {
+	if p.Property != nil {
+		if err := p.Property.Validate(); err != nil {
+			return err
+		}
+	}
+	if p.Service != nil {
+		p.Service.Normalize()
+		if err := p.Service.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireContent checks that an edit carries the whole listing. Edits
+// replace the content, what is left out is cleared, so the title and the
+// details of the listing's type must be given.
+func requireContent(p *models.Product) error {
+	if strings.TrimSpace(p.Title) == "" {
+		return &models.DetailError{Field: "title", Reason: "is required"}
+	}
+	var details bool
+	switch p.Type {
+	case models.TypeHorse:
+		details = p.Horse != nil
+	case models.TypeVehicle:
+		details = p.Vehicle != nil
+	case models.TypeEquipment:
+		details = p.Equipment != nil
+	case models.TypeProperty:
+		details = p.Property != nil
+	case models.TypeService:
+		details = p.Service != nil
+	default:
+		details = true
+	}
+	if !details {
+		return &models.DetailError{Field: string(p.Type), Reason: "is required"}
+	}
+	return nil
+}
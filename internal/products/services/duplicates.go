@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/system"
+)
+
+var (
+	ErrDuplicateFlagNotFound = errors.New("duplicate flag not found")
+	ErrInvalidResolution     = errors.New("status must be confirmed or dismissed")
+)
+
+const (
+	// maxImageDistance is how many of the 64 perceptual hash bits two
+	// primary images may differ in to be taken for the same photo.
+	maxImageDistance       = 6
+	duplicateCandidates    = 50
+	maxDuplicateMatches    = 5
+	defaultDuplicateFlags  = 50
+	maxDuplicateFlags      = 200
+	similarTextRatio       = 0.8
+	titleOnlyWeight        = 0.75
+	imageMatchScore        = 0.85
+	descriptionTitleWeight = 0.4
+)
+
+// checkDuplicates compares a listing being published with the seller's and
+// everyone's active listings. It returns the likely duplicates to flag for
+// moderation, or a *DuplicateError when the policy is to block them.
+func (s *ProductServiceImp) checkDuplicates(ctx context.Context, p *models.Product) ([]models.DuplicateMatch, error) {
+	policy, err := s.settingsRepo.DuplicateListingPolicy(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.WarnLevel, "Failed to read duplicate listing policy", map[string]any{"error": err.Error()})
+	}
+	if policy == system.DuplicatePolicyOff {
+		return nil, nil
+	}
+	threshold, err := s.settingsRepo.DuplicateListingThreshold(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.WarnLevel, "Failed to read duplicate listing threshold", map[string]any{"error": err.Error()})
+	}
+
+	q := models.DuplicateQuery{
+		ProductID:        p.ID,
+		UserID:           p.UserID,
+		Type:             p.Type,
+		Title:            p.Title,
+		PrimaryMediaID:   primaryMediaID(p.Media),
+		MaxImageDistance: maxImageDistance,
+		Limit:            duplicateCandidates,
+	}
+	if p.Description != nil {
+		q.Description = strings.TrimSpace(*p.Description)
+	}
+	if p.Horse != nil {
+		q.UELN, q.Microchip = p.Horse.UELN, p.Horse.Microchip
+	}
+	candidates, err := s.repo.FindDuplicateCandidates(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []models.DuplicateMatch
+	for _, c := range candidates {
+		m := scoreDuplicate(p, c)
+		if m.Score*100 >= float64(threshold) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	slices.SortStableFunc(matches, func(a, b models.DuplicateMatch) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if len(matches) > maxDuplicateMatches {
+		matches = matches[:maxDuplicateMatches]
+	}
+
+	if policy == system.DuplicatePolicyBlock {
+		return nil, &models.DuplicateError{Match: matches[0]}
+	}
+	return matches, nil
+}
+
+// scoreDuplicate rates from 0 to 1 how likely p is a copy of the candidate.
+// A shared identifier is conclusive and a near identical photo is strong
+// evidence on its own. Otherwise the score is the text similarity, where a
+// title alone counts for less as many listings share a plain title.
+func scoreDuplicate(p *models.Product, c models.DuplicateCandidate) models.DuplicateMatch {
+	m := models.DuplicateMatch{DuplicateOf: c.ProductID, SameSeller: c.UserID == p.UserID, Reasons: []string{}}
+
+	title := textSimilarity(p.Title, c.Title)
+	if title >= similarTextRatio {
+		m.Reasons = append(m.Reasons, models.DuplicateReasonTitle)
+	}
+	text := title * titleOnlyWeight
+	if p.Description != nil && strings.TrimSpace(*p.Description) != "" && strings.TrimSpace(c.Description) != "" {
+		description := textSimilarity(*p.Description, c.Description)
+		if description >= similarTextRatio {
+			m.Reasons = append(m.Reasons, models.DuplicateReasonDescription)
+		}
+		text = descriptionTitleWeight*title + (1-descriptionTitleWeight)*description
+	}
+	m.Score = text
+
+	if c.ImageDistance != nil && *c.ImageDistance <= maxImageDistance {
+		m.Reasons = append(m.Reasons, models.DuplicateReasonImage)
+		m.Score = max(m.Score, imageMatchScore+(1-imageMatchScore)*text)
+	}
+	if p.Horse != nil && (sameIdentifier(p.Horse.UELN, c.UELN) || sameIdentifier(p.Horse.Microchip, c.Microchip)) {
+		m.Reasons = append(m.Reasons, models.DuplicateReasonIdentifier)
+		m.Score = 1
+	}
+	m.Score = float64(int(m.Score*1000+0.5)) / 1000
+	return m
+}
+
+// isListed reports whether the listing is live or waiting to be approved.
+func isListed(status models.ProductStatus) bool {
+	return status == models.StatusPublished || status == models.StatusPendingApproval
+}
+
+func sameIdentifier(a, b *string) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// textSimilarity is the Jaccard index of the words of two texts, ignoring
+// case, punctuation and single characters.
+func textSimilarity(a, b string) float64 {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+func words(text string) map[string]bool {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if len([]rune(f)) > 1 {
+			set[f] = true
+		}
+	}
+	return set
+}
+
+func primaryMediaID(media []models.ProductMedia) *uuid.UUID {
+	for _, m := range media {
+		if m.IsPrimary {
+			id := m.MediaID
+			return &id
+		}
+	}
+	if len(media) > 0 {
+		id := media[0].MediaID
+		return &id
+	}
+	return nil
+}
+
+// saveDuplicateFlags records why a listing was held for moderation. The
+// listing is already held, so a failure is only logged.
+func (s *ProductServiceImp) saveDuplicateFlags(ctx context.Context, productID uuid.UUID, matches []models.DuplicateMatch) {
+	if len(matches) == 0 {
+		return
+	}
+	if err := s.repo.SaveDuplicateFlags(ctx, productID, matches); err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to save duplicate flags", map[string]any{"error": err.Error(), "product_id": productID.String()})
+	}
+}
+
+// DuplicateFlags returns a page of flags in a status, open by default, with
+// both listings.
+func (s *ProductServiceImp) DuplicateFlags(ctx context.Context, status models.DuplicateFlagStatus, limit, offset int) ([]*models.DuplicateFlag, error) {
+	if status == "" {
+		status = models.DuplicateFlagOpen
+	}
+	switch status {
+	case models.DuplicateFlagOpen, models.DuplicateFlagConfirmed, models.DuplicateFlagDismissed:
+	default:
+		return nil, ErrInvalidSearch
+	}
+	if limit <= 0 {
+		limit = defaultDuplicateFlags
+	}
+	limit = min(limit, maxDuplicateFlags)
+
+	flags, err := s.repo.FindDuplicateFlags(ctx, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flags {
+		if err := s.attachFlagListings(ctx, f); err != nil {
+			return nil, err
+		}
+	}
+	return flags, nil
+}
+
+// ResolveDuplicateFlag records an admin's decision. A confirmed duplicate is
+// archived if it is still in the moderation queue or live; a dismissed one
+// stays pending until approved as usual.
+func (s *ProductServiceImp) ResolveDuplicateFlag(ctx context.Context, id string, status models.DuplicateFlagStatus, adminID string) (*models.DuplicateFlag, error) {
+	if status != models.DuplicateFlagConfirmed && status != models.DuplicateFlagDismissed {
+		return nil, ErrInvalidResolution
+	}
+	admin, err := uuid.Parse(adminID)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	flag, err := s.repo.FindDuplicateFlag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if flag == nil {
+		return nil, ErrDuplicateFlagNotFound
+	}
+
+	if err := s.repo.ResolveDuplicateFlag(ctx, id, status, admin); err != nil {
+		return nil, err
+	}
+	if status == models.DuplicateFlagConfirmed {
+		if err := s.archiveDuplicate(ctx, flag.ProductID.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	flag, err = s.repo.FindDuplicateFlag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return flag, s.attachFlagListings(ctx, flag)
+}
+
+func (s *ProductServiceImp) archiveDuplicate(ctx context.Context, productID string) error {
+	p, err := s.repo.FindByID(ctx, productID)
+	if err != nil || p == nil {
+		return err
+	}
+	if p.Status != models.StatusPendingApproval && p.Status != models.StatusPublished {
+		return nil
+	}
+	return s.UpdateStatus(ctx, productID, models.StatusArchived, "", true, nil)
+}
+
+func (s *ProductServiceImp) attachFlagListings(ctx context.Context, f *models.DuplicateFlag) error {
+	var err error
+	if f.Product, err = s.repo.FindByID(ctx, f.ProductID.String()); err != nil {
+		return err
+	}
+	f.Original, err = s.repo.FindByID(ctx, f.DuplicateOf.String())
+	return err
+}
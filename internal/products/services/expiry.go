@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+	"github.com/hibiken/asynq"
+)
+
+var ErrCannotRenew = errors.New("only published or expired listings can be renewed")
+
+// listingExpiry is when a listing published or renewed at from expires.
+func (s *ProductServiceImp) listingExpiry(ctx context.Context, from time.Time) time.Time {
+	days, err := s.settingsRepo.ListingDurationDays(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to read listing duration, using default", map[string]any{"error": err.Error()})
+	}
+	return from.AddDate(0, 0, days)
+}
+
+// RenewByToken renews the listing the reminder's one-click link was sent for.
+func (s *ProductServiceImp) RenewByToken(ctx context.Context, token string) (*models.Product, error) {
+	p, err := s.repo.FindByRenewToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrProductNotFound
+	}
+	return s.renew(ctx, p)
+}
+
+func (s *ProductServiceImp) Renew(ctx context.Context, id string, userID string, isAdmin bool) (*models.Product, error) {
+	p, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.renew(ctx, p)
+}
+
+// renew starts a new listing period. Archived listings can only come back
+// when they were archived because they expired, not taken down.
+func (s *ProductServiceImp) renew(ctx context.Context, p *models.Product) (*models.Product, error) {
+	now := time.Now()
+	expired := p.Status == models.StatusArchived && p.ExpiresAt != nil && !p.ExpiresAt.After(now)
+	if (p.Status != models.StatusPublished && !expired) || p.HiddenAt != nil {
+		return nil, ErrCannotRenew
+	}
+	if expired {
+		relisted := *p
+		relisted.Status = models.StatusPublished
+		if err := s.checkIdentityUnique(ctx, &relisted); err != nil {
+			return nil, err
+		}
+	}
+
+	expiresAt := s.listingExpiry(ctx, now)
+	if err := s.repo.Renew(ctx, p.ID.String(), expiresAt); err != nil {
+		return nil, err
+	}
+	if expired {
+		s.enqueueNotifyWatchers(ctx, tasks.NotifyWatchersPayload{
+			ProductID: p.ID.String(), Field: "status", OldValue: string(p.Status), NewValue: string(models.StatusPublished),
+		})
+	}
+
+	p.Status = models.StatusPublished
+	p.ExpiresAt = &expiresAt
+	return p, nil
+}
+
+// HandleListingExpiryTask archives the listings that expired and reminds
+// sellers of listings about to expire, once per listing period.
+func (s *ProductServiceImp) HandleListingExpiryTask(ctx context.Context, t *asynq.Task) error {
+	archived, err := s.repo.ArchiveExpired(ctx)
+	if err != nil {
+		return fmt.Errorf("archive expired listings: %w", err)
+	}
+	for _, id := range archived {
+		s.enqueueNotifyWatchers(ctx, tasks.NotifyWatchersPayload{
+			ProductID: id, Field: "status", OldValue: string(models.StatusPublished), NewValue: string(models.StatusArchived),
+		})
+	}
+
+	if s.sender == nil {
+		return nil
+	}
+
+	days, err := s.settingsRepo.ExpiryReminderDays(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to read expiry reminder days, using default", map[string]any{"error": err.Error()})
+	}
+	expiring, err := s.repo.FindExpiringSoon(ctx, time.Now().AddDate(0, 0, days))
+	if err != nil {
+		return fmt.Errorf("find expiring listings: %w", err)
+	}
+
+	for _, l := range expiring {
+		token := uuid.New().String()
+		// Mark first: a failed email is better than reminding every hour
+		if err := s.repo.MarkReminderSent(ctx, l.ProductID.String(), token); err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to mark expiry reminder", map[string]any{"error": err.Error(), "product_id": l.ProductID})
+			continue
+		}
+
+		subject := fmt.Sprintf("Your listing \"%s\" expires soon", l.Title)
+		body := fmt.Sprintf("Hello,\n\nYour listing \"%s\" expires on %s and will then be archived.\n\nTo keep it online, renew it here:\n/api/v1/products/renew?token=%s",
+			l.Title, l.ExpiresAt.Format("2006-01-02"), token)
+		if err := s.sender.Send(ctx, l.SellerEmail, subject, body); err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to send expiry reminder", map[string]any{"error": err.Error(), "product_id": l.ProductID})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+	"github.com/hibiken/asynq"
+)
+
+// AddFavorite bookmarks a listing for the user. Only published listings can
+// be favorited; favoriting twice is not an error.
+func (s *ProductServiceImp) AddFavorite(ctx context.Context, userID string, productID string) error {
+	p, err := s.repo.FindByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if p == nil || p.Status != models.StatusPublished {
+		return ErrProductNotFound
+	}
+
+	_, err = s.repo.AddFavorite(ctx, userID, productID)
+	return err
+}
+
+// RemoveFavorite is idempotent, removing a favorite that does not exist is
+// not an error.
+func (s *ProductServiceImp) RemoveFavorite(ctx context.Context, userID string, productID string) error {
+	_, err := s.repo.RemoveFavorite(ctx, userID, productID)
+	return err
+}
+
+func (s *ProductServiceImp) ListFavorites(ctx context.Context, userID string) ([]*models.Product, error) {
+	products, err := s.repo.FindFavorites(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		p.IsFavorited = true
+	}
+	return products, nil
+}
+
+func (s *ProductServiceImp) MarkFavorited(ctx context.Context, userID string, products []*models.Product) error {
+	if userID == "" || len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	favorited, err := s.repo.FavoritedAmong(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range products {
+		p.IsFavorited = favorited[p.ID]
+	}
+	return nil
+}
+
+// HandleNotifyWatchersTask emails every user who favorited a listing about a
+// change to its status or price. The seller is never notified about their
+// own listing.
+func (s *ProductServiceImp) HandleNotifyWatchersTask(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.NotifyWatchersPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+	if s.sender == nil {
+		return nil
+	}
+
+	product, err := s.repo.FindByID(ctx, payload.ProductID)
+	if err != nil {
+		return fmt.Errorf("find product: %w", err)
+	}
+	if product == nil {
+		return nil
+	}
+
+	watchers, err := s.repo.FindWatchers(ctx, payload.ProductID)
+	if err != nil {
+		return fmt.Errorf("find watchers: %w", err)
+	}
+
+	subject, body := watcherEmail(product, payload)
+	for _, w := range watchers {
+		if w.UserID == product.UserID {
+			continue
+		}
+		if err := s.sender.Send(ctx, w.Email, subject, body); err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to notify watcher", map[string]any{"error": err.Error(), "product_id": payload.ProductID, "user_id": w.UserID})
+		}
+	}
+	return nil
+}
+
+func watcherEmail(p *models.Product, change tasks.NotifyWatchersPayload) (string, string) {
+	link := fmt.Sprintf("/products/%s", p.ID)
+	switch change.Field {
+	case "price":
+		oldPrice, oldErr := strconv.ParseFloat(change.OldValue, 64)
+		newPrice, newErr := strconv.ParseFloat(change.NewValue, 64)
+		if oldErr == nil && newErr == nil && newPrice < oldPrice {
+			return fmt.Sprintf("Price drop on \"%s\"", p.Title),
+				fmt.Sprintf("Hello,\n\nThe price of \"%s\", a listing you are watching, dropped from %s SEK to %s SEK (-%.0f%%).\n\n%s",
+					p.Title, change.OldValue, change.NewValue, (oldPrice-newPrice)/oldPrice*100, link)
+		}
+		return fmt.Sprintf("Price change on \"%s\"", p.Title),
+			fmt.Sprintf("Hello,\n\nThe price of \"%s\", a listing you are watching, changed from %s SEK to %s SEK.\n\n%s", p.Title, change.OldValue, change.NewValue, link)
+	default:
+		return fmt.Sprintf("\"%s\" is now %s", p.Title, statusLabel(change.NewValue)),
+			fmt.Sprintf("Hello,\n\n\"%s\", a listing you are watching, is now %s.\n\n%s", p.Title, statusLabel(change.NewValue), link)
+	}
+}
+
+func statusLabel(status string) string {
+	switch models.ProductStatus(status) {
+	case models.StatusPublished:
+		return "available"
+	case models.StatusSold:
+		return "sold"
+	default:
+		return "no longer available"
+	}
+}
+
+// enqueueNotifyWatchers schedules the watcher emails for a change. Like
+// enqueuePublished it never fails the change itself.
+func (s *ProductServiceImp) enqueueNotifyWatchers(ctx context.Context, change tasks.NotifyWatchersPayload) {
+	if s.queue == nil {
+		return
+	}
+	task, err := tasks.NewNotifyWatchersTask(change)
+	if err == nil {
+		_, err = s.queue.Enqueue(task)
+	}
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to enqueue watcher notification", map[string]any{"error": err.Error(), "product_id": change.ProductID})
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/products/repositories"
+)
+
+var (
+	// ErrDuplicateIdentity is also returned by the repository when the
+	// unique indexes catch a listing that raced past checkIdentityUnique.
+	ErrDuplicateIdentity  = repositories.ErrDuplicateIdentity
+	ErrIdentityIncomplete = errors.New("a passport scan and a UELN or microchip are required to verify the identity")
+)
+
+const identitySearchLimit = 50
+
+// prepareIdentity normalizes and validates the identity of a horse listing
+// and makes sure no other active listing has the same identifiers. The
+// verification is kept from existing only while the identifiers and the
+// passport scan stay the same; sellers can never set it themselves.
+func (s *ProductServiceImp) prepareIdentity(ctx context.Context, p *models.Product, existing *models.Product) error {
+	if p.Horse == nil {
+		return nil
+	}
+	h := p.Horse
+	h.NormalizeIdentity()
+	if err := h.ValidateIdentity(); err != nil {
+		return err
+	}
+
+	var before *models.Horse
+	if existing != nil {
+		before = existing.Horse
+	}
+	h.IdentityVerified, h.IdentityVerifiedAt, h.IdentityVerifiedBy = false, nil, nil
+	if before != nil && sameIdentity(h, before) {
+		h.IdentityVerified, h.IdentityVerifiedAt, h.IdentityVerifiedBy = before.IdentityVerified, before.IdentityVerifiedAt, before.IdentityVerifiedBy
+	}
+
+	if h.PassportMediaID != nil && (before == nil || !sameUUID(before.PassportMediaID, h.PassportMediaID)) {
+		if err := s.checkMediaOwner(ctx, p.UserID.String(), []uuid.UUID{*h.PassportMediaID}); err != nil {
+			return err
+		}
+	}
+
+	return s.checkIdentityUnique(ctx, p)
+}
+
+// checkIdentityUnique fails when the listing is active and another active
+// listing has the same UELN or microchip. The database enforces the same
+// rule; checking first keeps the common case from failing mid-transaction.
+func (s *ProductServiceImp) checkIdentityUnique(ctx context.Context, p *models.Product) error {
+	if p.Horse == nil || (p.Horse.UELN == nil && p.Horse.Microchip == nil) || !isActive(p.Status) {
+		return nil
+	}
+	other, err := s.repo.FindActiveByIdentity(ctx, p.Horse.UELN, p.Horse.Microchip, p.ID)
+	if err != nil {
+		return err
+	}
+	if other != uuid.Nil {
+		return ErrDuplicateIdentity
+	}
+	return nil
+}
+
+// VerifyIdentity records that an admin checked the identifiers against the
+// passport scan, or withdraws the verification.
+func (s *ProductServiceImp) VerifyIdentity(ctx context.Context, id string, adminID string, verified bool) (*models.Product, error) {
+	p, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.Status == models.StatusDeleted || p.Horse == nil {
+		return nil, ErrProductNotFound
+	}
+
+	var verifiedBy *uuid.UUID
+	if verified {
+		if p.Horse.PassportMediaID == nil || (p.Horse.UELN == nil && p.Horse.Microchip == nil) {
+			return nil, ErrIdentityIncomplete
+		}
+		admin, err := uuid.Parse(adminID)
+		if err != nil {
+			return nil, ErrUnauthorized
+		}
+		verifiedBy = &admin
+	}
+
+	if err := s.repo.SetIdentityVerified(ctx, id, verifiedBy); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(ctx, id)
+}
+
+// SearchIdentity finds listings in any status by the beginning of their
+// UELN or microchip, for admins.
+func (s *ProductServiceImp) SearchIdentity(ctx context.Context, identifier string) ([]*models.Product, error) {
+	identifier = models.NormalizeIdentifier(identifier)
+	if len(identifier) < 3 {
+		return nil, ErrInvalidSearch
+	}
+	return s.repo.SearchByIdentity(ctx, identifier, identitySearchLimit)
+}
+
+func isActive(status models.ProductStatus) bool {
+	switch status {
+	case models.StatusDraft, models.StatusPendingApproval, models.StatusPublished:
+		return true
+	}
+	return false
+}
+
+func sameIdentity(a, b *models.Horse) bool {
+	return sameString(a.UELN, b.UELN) && sameString(a.Microchip, b.Microchip) && sameUUID(a.PassportMediaID, b.PassportMediaID)
+}
+
+func sameString(a, b *string) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func sameUUID(a, b *uuid.UUID) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// ImportAction tells what importing a listing did, or would do in a dry run.
+type ImportAction string
+
+const (
+	ImportCreated ImportAction = "created"
+	ImportUpdated ImportAction = "updated"
+)
+
+var ErrMissingExternalID = errors.New("imported listings need an external id")
+
+// ImportListing creates the seller's listing with p's external id, or
+// updates it when it was imported before. Like Update, an import keeps the
+// status of an existing listing and its media unless p.Media is set. In a
+// dry run the listing is validated the same way but nothing is stored.
+func (s *ProductServiceImp) ImportListing(ctx context.Context, p *models.Product, dryRun bool) (ImportAction, error) {
+	if p.ExternalID == nil || *p.ExternalID == "" {
+		return "", ErrMissingExternalID
+	}
+
+	existing, err := s.repo.FindByExternalID(ctx, p.UserID.String(), *p.ExternalID)
+	if err != nil {
+		return "", err
+	}
+
+	// Feeds may only carry the common fields; the type's row is still required
+	switch {
+	case p.Type == models.TypeHorse && p.Horse == nil:
+		p.Horse = &models.Horse{}
+	case p.Type == models.TypeVehicle && p.Vehicle == nil:
+		p.Vehicle = &models.Vehicle{}
+	case p.Type == models.TypeEquipment && p.Equipment == nil:
+		p.Equipment = &models.Equipment{}
+	case p.Type == models.TypeProperty && p.Property == nil:
+		p.Property = &models.Property{}
+	case p.Type == models.TypeService && p.Service == nil:
+		p.Service = &models.Service{}
+	}
+
+	if existing == nil {
+		if err := s.prepareCreate(ctx, p); err != nil {
+			return "", err
+		}
+		if !dryRun {
+			if _, err := s.saveCreated(ctx, p); err != nil {
+				return "", err
+			}
+		}
+		return ImportCreated, nil
+	}
+
+	if p.Type != existing.Type {
+		return "", &models.DetailError{Field: "type", Reason: "cannot change, the listing was imported as " + string(existing.Type)}
+	}
+	if err := s.prepareUpdate(ctx, existing, p, p.UserID.String()); err != nil {
+		return "", err
+	}
+	if !dryRun {
+		if _, err := s.saveUpdated(ctx, existing, p, edit{editorID: p.UserID.String()}); err != nil {
+			return "", err
+		}
+	}
+	return ImportUpdated, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// validatePedigree normalizes and validates the pedigree of a horse
+// listing. It returns a *models.PedigreeError when the pedigree is invalid.
+// A legacy pedigree can't be given, only kept from the existing listing
+// until a pedigree replaces it.
+func (s *ProductServiceImp) validatePedigree(ctx context.Context, p *models.Product, existing *models.Product) error {
+	if p.Horse == nil {
+		return nil
+	}
+	p.Horse.LegacyPedigree = nil
+	if p.Horse.Pedigree == nil {
+		if existing != nil && existing.Horse != nil {
+			p.Horse.LegacyPedigree = existing.Horse.LegacyPedigree
+		}
+		return nil
+	}
+	p.Horse.Pedigree.Normalize()
+	return p.Horse.Pedigree.Validate(s.pedigreeGenerations(ctx))
+}
+
+func (s *ProductServiceImp) pedigreeGenerations(ctx context.Context) int {
+	generations, err := s.settingsRepo.PedigreeMaxGenerations(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to read pedigree generations, using default", map[string]any{"error": err.Error()})
+	}
+	return generations
+}
+
+// Pedigree returns the pedigree chart of a horse listing down to the given
+// number of generations, or the configured maximum when generations is 0.
+func (s *ProductServiceImp) Pedigree(ctx context.Context, id string, generations int, viewer models.Viewer) (*models.PedigreeChart, error) {
+	p, err := s.findVisible(ctx, id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.Horse == nil {
+		return nil, ErrProductNotFound
+	}
+
+	maxGenerations := s.pedigreeGenerations(ctx)
+	if generations <= 0 || generations > maxGenerations {
+		generations = maxGenerations
+	}
+
+	chart := &models.PedigreeChart{ProductID: p.ID.String(), Name: p.Horse.Name, Generations: generations}
+	chart.Sire, chart.Dam = p.Horse.Pedigree.Chart(generations)
+	return chart, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+)
+
+// ExchangeRates provides the rates prices are converted with.
+type ExchangeRates interface {
+	// SEKPerUnit returns currency.ErrUnsupportedCurrency or
+	// currency.ErrRateUnavailable when the currency can't be converted.
+	SEKPerUnit(ctx context.Context, code string) (float64, error)
+}
+
+// SetExchangeRates enables prices in EUR, NOK and DKK: converted prices,
+// price filters and entering prices in those currencies.
+func (s *ProductServiceImp) SetExchangeRates(rates ExchangeRates) {
+	s.rates = rates
+}
+
+func (s *ProductServiceImp) sekPerUnit(ctx context.Context, code string) (float64, error) {
+	code, err := currency.Normalize(code)
+	if err != nil {
+		return 0, err
+	}
+	if code == currency.Base {
+		return 1, nil
+	}
+	if s.rates == nil {
+		return 0, currency.ErrRateUnavailable
+	}
+	return s.rates.SEKPerUnit(ctx, code)
+}
+
+// ConvertPrices sets ConvertedPrice on the products. Nothing is converted
+// for SEK or an empty currency.
+func (s *ProductServiceImp) ConvertPrices(ctx context.Context, code string, products []*models.Product) error {
+	if code == "" {
+		return nil
+	}
+	rate, err := s.sekPerUnit(ctx, code)
+	if err != nil {
+		return err
+	}
+	code, _ = currency.Normalize(code)
+	if code == currency.Base {
+		return nil
+	}
+	for _, p := range products {
+		if p.PriceSEK != nil {
+			p.ConvertedPrice = &models.Money{Amount: currency.Round(*p.PriceSEK / rate), Currency: code, SEKPerUnit: rate}
+		}
+	}
+	return nil
+}
+
+// normalizeEnteredPrice replaces PriceSEK with EnteredPrice converted at the
+// current rate. It returns a *models.DetailError when it can't be converted.
+func (s *ProductServiceImp) normalizeEnteredPrice(ctx context.Context, p *models.Product) error {
+	if p.EnteredPrice == nil {
+		return nil
+	}
+	entered := p.EnteredPrice
+	p.EnteredPrice = nil
+	if entered.Amount < 0 {
+		return &models.DetailError{Field: "entered_price.amount", Reason: "must not be negative"}
+	}
+	rate, err := s.sekPerUnit(ctx, entered.Currency)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) || errors.Is(err, currency.ErrRateUnavailable) {
+		return &models.DetailError{Field: "entered_price.currency", Reason: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	price := currency.Round(entered.Amount * rate)
+	p.PriceSEK = &price
+	return nil
+}
+
+func (s *ProductServiceImp) PriceHistory(ctx context.Context, id string, viewer models.Viewer) ([]models.PriceChange, error) {
+	p, err := s.findVisible(ctx, id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrProductNotFound
+	}
+	return s.repo.FindPriceHistory(ctx, id)
+}
+
+// enqueuePriceDropped schedules the saved search alerts for a lowered
+// price. Like enqueuePublished it never fails the change itself.
+func (s *ProductServiceImp) enqueuePriceDropped(ctx context.Context, drop tasks.PriceDroppedPayload) {
+	if s.queue == nil {
+		return
+	}
+	task, err := tasks.NewPriceDroppedTask(drop)
+	if err == nil {
+		_, err = s.queue.Enqueue(task)
+	}
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to enqueue price drop task", map[string]any{"error": err.Error(), "product_id": drop.ProductID})
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+var (
+	ErrMediaNotOwned        = errors.New("media must be uploaded by the caller")
+	ErrMediaNotFound        = errors.New("media not attached to this product")
+	ErrMediaAlreadyAttached = errors.New("media already attached to this product")
+	ErrInvalidMediaOrder    = errors.New("media order must list every attached media exactly once")
+)
+
+func (s *ProductServiceImp) AddMedia(ctx context.Context, productID string, mediaID uuid.UUID, isPrimary bool, userID string, isAdmin bool) error {
+	if _, err := s.editableProduct(ctx, productID, userID, isAdmin); err != nil {
+		return err
+	}
+	if err := s.checkMediaOwner(ctx, userID, []uuid.UUID{mediaID}); err != nil {
+		return err
+	}
+
+	added, err := s.repo.AddMedia(ctx, productID, mediaID.String(), isPrimary)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrMediaAlreadyAttached
+	}
+	return nil
+}
+
+func (s *ProductServiceImp) RemoveMedia(ctx context.Context, productID string, mediaID uuid.UUID, userID string, isAdmin bool) error {
+	if _, err := s.editableProduct(ctx, productID, userID, isAdmin); err != nil {
+		return err
+	}
+
+	removed, err := s.repo.RemoveMedia(ctx, productID, mediaID.String())
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMediaNotFound
+	}
+	return nil
+}
+
+// ReorderMedia sets the display order. mediaIDs must contain every attached
+// media exactly once.
+func (s *ProductServiceImp) ReorderMedia(ctx context.Context, productID string, mediaIDs []uuid.UUID, userID string, isAdmin bool) error {
+	p, err := s.editableProduct(ctx, productID, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	if len(mediaIDs) != len(p.Media) {
+		return ErrInvalidMediaOrder
+	}
+	attached := make(map[uuid.UUID]bool, len(p.Media))
+	for _, m := range p.Media {
+		attached[m.MediaID] = true
+	}
+	for _, id := range mediaIDs {
+		if !attached[id] {
+			return ErrInvalidMediaOrder
+		}
+		// Each id may only appear once
+		delete(attached, id)
+	}
+
+	return s.repo.ReorderMedia(ctx, productID, mediaIDs)
+}
+
+func (s *ProductServiceImp) SetPrimaryMedia(ctx context.Context, productID string, mediaID uuid.UUID, userID string, isAdmin bool) error {
+	if _, err := s.editableProduct(ctx, productID, userID, isAdmin); err != nil {
+		return err
+	}
+
+	ok, err := s.repo.SetPrimaryMedia(ctx, productID, mediaID.String())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMediaNotFound
+	}
+	return nil
+}
+
+// editableProduct loads a product the caller is allowed to modify.
+func (s *ProductServiceImp) editableProduct(ctx context.Context, id string, userID string, isAdmin bool) (*models.Product, error) {
+	p, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.Status == models.StatusDeleted {
+		return nil, ErrProductNotFound
+	}
+	if !isAdmin && p.UserID.String() != userID {
+		return nil, ErrUnauthorized
+	}
+	return p, nil
+}
+
+func (s *ProductServiceImp) checkMediaOwner(ctx context.Context, userID string, mediaIDs []uuid.UUID) error {
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+	owned, err := s.repo.MediaOwnedBy(ctx, userID, mediaIDs)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return ErrMediaNotOwned
+	}
+	return nil
+}
+
+// normalizeMedia drops duplicates, renumbers the order from zero following
+// the requested order and makes sure exactly one item is primary: the first
+// one flagged, or the first one overall.
+func normalizeMedia(items []models.ProductMedia) []models.ProductMedia {
+	if items == nil {
+		return nil
+	}
+
+	sorted := make([]models.ProductMedia, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+
+	seen := make(map[uuid.UUID]bool, len(sorted))
+	result := make([]models.ProductMedia, 0, len(sorted))
+	primary := -1
+	for _, item := range sorted {
+		if item.MediaID == uuid.Nil || seen[item.MediaID] {
+			continue
+		}
+		seen[item.MediaID] = true
+		if item.IsPrimary && primary == -1 {
+			primary = len(result)
+		}
+		result = append(result, models.ProductMedia{MediaID: item.MediaID, Order: len(result)})
+	}
+
+	if len(result) > 0 {
+		if primary == -1 {
+			primary = 0
+		}
+		result[primary].IsPrimary = true
+	}
+	return result
+}
+
+func mediaIDs(items []models.ProductMedia) []uuid.UUID {
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.MediaID
+	}
+	return ids
+}
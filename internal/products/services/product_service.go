@@ -2,34 +2,103 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/email"
+	"github.com/hfleury/horsemarketplacebk/internal/geo"
 	"github.com/hfleury/horsemarketplacebk/internal/products/models"
 	"github.com/hfleury/horsemarketplacebk/internal/products/repositories"
 	"github.com/hfleury/horsemarketplacebk/internal/system"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
 )
 
 var (
 	ErrProductNotFound = errors.New("product not found")
 	ErrUnauthorized    = errors.New("unauthorized to modify this product")
+	ErrUnknownLocation = errors.New("unknown location")
+	ErrInvalidSearch   = errors.New("invalid search parameters")
+	// ErrListingHidden is returned when a seller changes the status of a
+	// listing hidden after reports or taken down, other than to withdraw it.
+	ErrListingHidden = errors.New("the listing is hidden after reports, it can only be set to draft or deleted")
+	// ErrVersionConflict is returned by the edits when the listing is no
+	// longer at any of the versions the client expects.
+	ErrVersionConflict = repositories.ErrVersionConflict
 )
 
 type ProductService interface {
 	Create(ctx context.Context, product *models.Product) (*models.Product, error)
-	FindByID(ctx context.Context, id string) (*models.Product, error)
-	FindAll(ctx context.Context, filters map[string]any) ([]*models.Product, error)
-	UpdateStatus(ctx context.Context, id string, status models.ProductStatus, userID string, isAdmin bool) error
+	// FindByID returns nil when there is no such listing or the viewer may not see it.
+	FindByID(ctx context.Context, id string, viewer models.Viewer) (*models.Product, error)
+	FindAll(ctx context.Context, filters map[string]any, viewer models.Viewer) ([]*models.Product, error)
+	// UpdateStatus and Update only change a listing still at one of the
+	// ifMatch versions, nil accepting any.
+	UpdateStatus(ctx context.Context, id string, status models.ProductStatus, userID string, isAdmin bool, ifMatch []time.Time) error
 	Delete(ctx context.Context, id string, userID string, isAdmin bool) error
-	// Specific searches
-	Search(ctx context.Context, query string, categoryID string, fieldMap map[string]string) ([]*models.Product, error)
+	// Search returns the products matching filters. Facets are only computed when withFacets is set.
+	Search(ctx context.Context, filters *models.ProductFilters, withFacets bool) (*models.SearchResult, error)
+	AddFavorite(ctx context.Context, userID string, productID string) error
+	RemoveFavorite(ctx context.Context, userID string, productID string) error
+	ListFavorites(ctx context.Context, userID string) ([]*models.Product, error)
+	// Dashboard is the seller's own listings, by status tab.
+	Dashboard(ctx context.Context, userID string, f models.DashboardFilters) (*models.Dashboard, error)
+	// BulkAction applies a quick action to the seller's listings.
+	BulkAction(ctx context.Context, userID string, action models.BulkAction) (*models.BulkResult, error)
+	// MarkFavorited sets IsFavorited on the products the user has favorited.
+	MarkFavorited(ctx context.Context, userID string, products []*models.Product) error
+	// Update edits a listing. Type and status are kept; media are replaced when input.Media is set.
+	Update(ctx context.Context, id string, input *models.Product, userID string, isAdmin bool, ifMatch []time.Time) (*models.Product, error)
+	AddMedia(ctx context.Context, productID string, mediaID uuid.UUID, isPrimary bool, userID string, isAdmin bool) error
+	RemoveMedia(ctx context.Context, productID string, mediaID uuid.UUID, userID string, isAdmin bool) error
+	ReorderMedia(ctx context.Context, productID string, mediaIDs []uuid.UUID, userID string, isAdmin bool) error
+	SetPrimaryMedia(ctx context.Context, productID string, mediaID uuid.UUID, userID string, isAdmin bool) error
+	// Renew starts a new listing period for a published or expired listing.
+	Renew(ctx context.Context, id string, userID string, isAdmin bool) (*models.Product, error)
+	RenewByToken(ctx context.Context, token string) (*models.Product, error)
+	// Pedigree returns the chart of a horse's pedigree, ready for rendering.
+	Pedigree(ctx context.Context, id string, generations int, viewer models.Viewer) (*models.PedigreeChart, error)
+	// BreedingCompatibility computes the inbreeding coefficient of a hypothetical foal.
+	BreedingCompatibility(ctx context.Context, req *models.BreedingRequest, viewer models.Viewer) (*models.BreedingResult, error)
+	// VerifyIdentity sets or withdraws the verified identity badge, for admins.
+	VerifyIdentity(ctx context.Context, id string, adminID string, verified bool) (*models.Product, error)
+	SearchIdentity(ctx context.Context, identifier string) ([]*models.Product, error)
+	// Similar recommends published listings like the given one, best first.
+	Similar(ctx context.Context, id string, limit int, viewer models.Viewer) ([]*models.SimilarListing, error)
+	// PriceHistory returns every price the listing has had, oldest first.
+	PriceHistory(ctx context.Context, id string, viewer models.Viewer) ([]models.PriceChange, error)
+	// ConvertPrices sets ConvertedPrice in the given currency on the products.
+	ConvertPrices(ctx context.Context, currency string, products []*models.Product) error
+	// DuplicateFlags lists the listings held for moderation as likely duplicates, for admins.
+	DuplicateFlags(ctx context.Context, status models.DuplicateFlagStatus, limit, offset int) ([]*models.DuplicateFlag, error)
+	ResolveDuplicateFlag(ctx context.Context, id string, status models.DuplicateFlagStatus, adminID string) (*models.DuplicateFlag, error)
+	// Revisions returns every version of a listing, newest first, to its seller and admins.
+	Revisions(ctx context.Context, id string, userID string, isAdmin bool) ([]*models.Revision, error)
+	Revision(ctx context.Context, id string, version int, userID string, isAdmin bool) (*models.Revision, error)
+	// DiffRevisions compares two versions; zero means the latest and the one before to.
+	DiffRevisions(ctx context.Context, id string, from, to int, userID string, isAdmin bool) (*models.RevisionDiff, error)
+	// RestoreRevision saves an old version as a new edit of the listing.
+	RestoreRevision(ctx context.Context, id string, version int, userID string, isAdmin bool) (*models.Product, error)
+	Autosave(ctx context.Context, id string, content json.RawMessage, userID string, isAdmin bool) (*models.Autosave, error)
+	FindAutosave(ctx context.Context, id string, userID string, isAdmin bool) (*models.Autosave, error)
+	DiscardAutosave(ctx context.Context, id string, userID string, isAdmin bool) error
 }
 
 type ProductServiceImp struct {
 	repo         repositories.ProductRepository
 	settingsRepo system.SettingsRepository
 	logger       config.Logging
+	gazetteer    *geo.Gazetteer
+	queue        tasks.Enqueuer
+	sender       email.Sender
+
+	breedingCache BreedingCache
+	attributeDefs AttributeDefinitions
+	rates         ExchangeRates
 }
 
 func NewProductService(repo repositories.ProductRepository, settingsRepo system.SettingsRepository, logger config.Logging) *ProductServiceImp {
@@ -40,7 +109,34 @@ func NewProductService(repo repositories.ProductRepository, settingsRepo system.
 	}
 }
 
+// SetGazetteer enables resolving listing locations to coordinates and
+// radius searches around a named place.
+func (s *ProductServiceImp) SetGazetteer(g *geo.Gazetteer) {
+	s.gazetteer = g
+}
+
+// SetTaskQueue enables background jobs triggered by product changes, such
+// as saved search alerts when a listing is published.
+func (s *ProductServiceImp) SetTaskQueue(q tasks.Enqueuer) {
+	s.queue = q
+}
+
+// SetEmailSender enables notifying users who favorited a listing about
+// changes to it.
+func (s *ProductServiceImp) SetEmailSender(sender email.Sender) {
+	s.sender = sender
+}
+
 func (s *ProductServiceImp) Create(ctx context.Context, product *models.Product) (*models.Product, error) {
+	if err := s.prepareCreate(ctx, product); err != nil {
+		return nil, err
+	}
+	return s.saveCreated(ctx, product)
+}
+
+// prepareCreate sets the initial status and validates a new listing
+// without storing anything.
+func (s *ProductServiceImp) prepareCreate(ctx context.Context, product *models.Product) error {
 	// 1. Determine initial status
 	approvalRequired, err := s.settingsRepo.IsProductApprovalRequired(ctx)
 	if err != nil {
@@ -58,21 +154,91 @@ func (s *ProductServiceImp) Create(ctx context.Context, product *models.Product)
 		product.Status = models.StatusDraft
 	}
 
+	s.normalizeLocation(product)
+
+	if err := s.normalizeEnteredPrice(ctx, product); err != nil {
+		return err
+	}
+	if err := s.validatePedigree(ctx, product, nil); err != nil {
+		return err
+	}
+	if err := s.prepareIdentity(ctx, product, nil); err != nil {
+		return err
+	}
+	if err := validateDetails(product); err != nil {
+		return err
+	}
+	if err := prepareTranslations(product, nil); err != nil {
+		return err
+	}
+	if err := s.prepareAttributes(ctx, product, nil); err != nil {
+		return err
+	}
+
+	product.Media = normalizeMedia(product.Media)
+	if err := s.checkMediaOwner(ctx, product.UserID.String(), mediaIDs(product.Media)); err != nil {
+		return err
+	}
+
+	product.Duplicates = nil
+	if product.Status != models.StatusDraft {
+		duplicates, err := s.checkDuplicates(ctx, product)
+		if err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			product.Status = models.StatusPendingApproval
+			product.Duplicates = duplicates
+		}
+	}
+
 	product.CreatedAt = time.Now()
 	product.UpdatedAt = time.Now()
+	product.PublishedAt, product.ExpiresAt = nil, nil
+	if product.Status == models.StatusPublished {
+		expiresAt := s.listingExpiry(ctx, product.CreatedAt)
+		product.PublishedAt = &product.CreatedAt
+		product.ExpiresAt = &expiresAt
+	}
+	return nil
+}
 
-	return s.repo.Create(ctx, product)
+// saveCreated stores a listing checked by prepareCreate.
+func (s *ProductServiceImp) saveCreated(ctx context.Context, product *models.Product) (*models.Product, error) {
+	created, err := s.repo.Create(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+	if created.Status == models.StatusPublished {
+		s.enqueuePublished(ctx, created.ID.String())
+	}
+	s.recordCreated(ctx, created)
+	s.saveDuplicateFlags(ctx, created.ID, product.Duplicates)
+	return created, nil
 }
 
-func (s *ProductServiceImp) FindByID(ctx context.Context, id string) (*models.Product, error) {
-	return s.repo.FindByID(ctx, id)
+func (s *ProductServiceImp) FindByID(ctx context.Context, id string, viewer models.Viewer) (*models.Product, error) {
+	return s.findVisible(ctx, id, viewer)
 }
 
-func (s *ProductServiceImp) FindAll(ctx context.Context, filters map[string]any) ([]*models.Product, error) {
-	return s.repo.FindAll(ctx, filters)
+func (s *ProductServiceImp) FindAll(ctx context.Context, filters map[string]any, viewer models.Viewer) ([]*models.Product, error) {
+	return s.repo.FindAll(ctx, filters, viewer)
+}
+
+// findVisible loads a listing, returning nil when the viewer may not see it
+// so hidden listings look the same as missing ones.
+func (s *ProductServiceImp) findVisible(ctx context.Context, id string, viewer models.Viewer) (*models.Product, error) {
+	p, err := s.repo.FindByID(ctx, id)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	if !viewer.CanSee(p) {
+		return nil, nil
+	}
+	return p, nil
 }
 
-func (s *ProductServiceImp) UpdateStatus(ctx context.Context, id string, status models.ProductStatus, userID string, isAdmin bool) error {
+func (s *ProductServiceImp) UpdateStatus(ctx context.Context, id string, status models.ProductStatus, userID string, isAdmin bool, ifMatch []time.Time) error {
 	// 1. Get existing product
 	p, err := s.repo.FindByID(ctx, id)
 	if err != nil {
@@ -95,6 +261,12 @@ func (s *ProductServiceImp) UpdateStatus(ctx context.Context, id string, status
 			return ErrUnauthorized
 		}
 
+		// Only an admin brings back a listing hidden after reports or taken
+		// down, the seller may still withdraw it
+		if p.HiddenAt != nil && status != models.StatusDraft && status != models.StatusDeleted {
+			return ErrListingHidden
+		}
+
 		// If user is trying to publish, check approval again
 		if status == models.StatusPublished {
 			approvalRequired, _ := s.settingsRepo.IsProductApprovalRequired(ctx)
@@ -107,7 +279,140 @@ func (s *ProductServiceImp) UpdateStatus(ctx context.Context, id string, status
 		// But valid transitions are allowed.
 	}
 
-	return s.repo.UpdateStatus(ctx, id, status)
+	// Sellers publishing may be reposting a listing that is still up
+	var duplicates []models.DuplicateMatch
+	if !isAdmin && isListed(status) && !isListed(p.Status) {
+		publishing := *p
+		publishing.Status = status
+		if duplicates, err = s.checkDuplicates(ctx, &publishing); err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			status = models.StatusPendingApproval
+		}
+	}
+
+	if isActive(status) && !isActive(p.Status) {
+		// The horse may have been listed again in the meantime
+		reactivated := *p
+		reactivated.Status = status
+		if err := s.checkIdentityUnique(ctx, &reactivated); err != nil {
+			return err
+		}
+	}
+
+	if status == models.StatusPublished && p.Status != models.StatusPublished {
+		// Going live starts a new listing period
+		err = s.repo.Publish(ctx, id, s.listingExpiry(ctx, time.Now()), ifMatch)
+	} else {
+		err = s.repo.UpdateStatus(ctx, id, status, ifMatch)
+	}
+	if err != nil {
+		return err
+	}
+	if status == models.StatusPublished && p.Status != models.StatusPublished {
+		s.enqueuePublished(ctx, id)
+	}
+	s.saveDuplicateFlags(ctx, p.ID, duplicates)
+	if status != p.Status {
+		s.enqueueNotifyWatchers(ctx, tasks.NotifyWatchersPayload{
+			ProductID: id, Field: "status", OldValue: string(p.Status), NewValue: string(status),
+		})
+	}
+	return nil
+}
+
+func (s *ProductServiceImp) Update(ctx context.Context, id string, input *models.Product, userID string, isAdmin bool, ifMatch []time.Time) (*models.Product, error) {
+	existing, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepareUpdate(ctx, existing, input, userID); err != nil {
+		return nil, err
+	}
+	updated, err := s.saveUpdated(ctx, existing, input, edit{editorID: userID, isAdmin: isAdmin, ifMatch: ifMatch})
+	if err != nil {
+		return nil, err
+	}
+	s.discardAutosave(ctx, existing.ID)
+	return updated, nil
+}
+
+// prepareUpdate completes input from the existing listing and validates it
+// without storing anything.
+func (s *ProductServiceImp) prepareUpdate(ctx context.Context, existing *models.Product, input *models.Product, userID string) error {
+	// Ownership, type and status are not editable here
+	input.ID = existing.ID
+	input.UserID = existing.UserID
+	input.Type = existing.Type
+	input.Status = existing.Status
+	if err := requireContent(input); err != nil {
+		return err
+	}
+
+	s.normalizeLocation(input)
+
+	if err := s.normalizeEnteredPrice(ctx, input); err != nil {
+		return err
+	}
+	if err := s.validatePedigree(ctx, input, existing); err != nil {
+		return err
+	}
+	if err := s.prepareIdentity(ctx, input, existing); err != nil {
+		return err
+	}
+	if err := validateDetails(input); err != nil {
+		return err
+	}
+	if err := prepareTranslations(input, existing); err != nil {
+		return err
+	}
+	if err := s.prepareAttributes(ctx, input, existing); err != nil {
+		return err
+	}
+
+	if input.Media != nil {
+		input.Media = normalizeMedia(input.Media)
+		// Media already on the listing stay allowed, new ones must be the caller's uploads
+		attached := make(map[uuid.UUID]bool, len(existing.Media))
+		for _, m := range existing.Media {
+			attached[m.MediaID] = true
+		}
+		var added []uuid.UUID
+		for _, m := range input.Media {
+			if !attached[m.MediaID] {
+				added = append(added, m.MediaID)
+			}
+		}
+		if err := s.checkMediaOwner(ctx, userID, added); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveUpdated stores a listing checked by prepareUpdate together with its
+// new version.
+func (s *ProductServiceImp) saveUpdated(ctx context.Context, existing *models.Product, input *models.Product, e edit) (*models.Product, error) {
+	rev, baseline := s.editRevision(ctx, existing, input, e)
+	updated, err := s.repo.Update(ctx, input, rev, baseline, e.ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.PriceSEK != nil && input.PriceSEK != nil && *existing.PriceSEK != *input.PriceSEK {
+		s.enqueueNotifyWatchers(ctx, tasks.NotifyWatchersPayload{
+			ProductID: existing.ID.String(), Field: "price",
+			OldValue: strconv.FormatFloat(*existing.PriceSEK, 'f', 0, 64),
+			NewValue: strconv.FormatFloat(*input.PriceSEK, 'f', 0, 64),
+		})
+		if *input.PriceSEK < *existing.PriceSEK && existing.Status == models.StatusPublished {
+			s.enqueuePriceDropped(ctx, tasks.PriceDroppedPayload{
+				ProductID: existing.ID.String(), OldPriceSEK: *existing.PriceSEK, PriceSEK: *input.PriceSEK,
+			})
+		}
+	}
+	return updated, nil
 }
 
 func (s *ProductServiceImp) Delete(ctx context.Context, id string, userID string, isAdmin bool) error {
@@ -123,22 +428,123 @@ func (s *ProductServiceImp) Delete(ctx context.Context, id string, userID string
 		return ErrUnauthorized
 	}
 
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	// Watchers hear the listing went away like any other status change
+	if p.Status != models.StatusDeleted {
+		s.enqueueNotifyWatchers(ctx, tasks.NotifyWatchersPayload{
+			ProductID: id, Field: "status", OldValue: string(p.Status), NewValue: string(models.StatusDeleted),
+		})
+	}
+	return nil
+}
+
+func (s *ProductServiceImp) Search(ctx context.Context, filters *models.ProductFilters, withFacets bool) (*models.SearchResult, error) {
+	if filters == nil {
+		filters = &models.ProductFilters{}
+	}
+	if err := s.resolveSearchLocation(filters); err != nil {
+		return nil, err
+	}
+	// Price filters in a currency without a rate would silently match nothing
+	if filters.Currency != "" && (filters.MinPrice != nil || filters.MaxPrice != nil) {
+		if _, err := s.sekPerUnit(ctx, filters.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	products, err := s.repo.Search(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.SearchResult{Products: products}
+
+	if withFacets {
+		facets, err := s.repo.FacetCounts(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		result.Facets = facets
+	}
+
+	return result, nil
 }
 
-func (s *ProductServiceImp) Search(ctx context.Context, query string, categoryID string, fieldMap map[string]string) ([]*models.Product, error) {
-	if categoryID != "" {
-		return s.repo.FindByCategory(ctx, categoryID)
+// normalizeLocation replaces the free text city and area with the canonical
+// gazetteer names and sets the coordinates. Coordinates are always derived
+// server side; listings in places the gazetteer does not know keep their
+// text and get no coordinates.
+func (s *ProductServiceImp) normalizeLocation(p *models.Product) {
+	p.Latitude, p.Longitude = nil, nil
+	if s.gazetteer == nil {
+		return
 	}
-	if query != "" {
-		// Detect if it's a field search query e.g. "Model=R6"
-		// or just simple text search
-		return s.repo.FindByTextInDescription(ctx, query)
+
+	var city, postal string
+	if p.City != nil {
+		city = *p.City
+	}
+	if p.PostalCode != nil {
+		postal = strings.ReplaceAll(strings.TrimSpace(*p.PostalCode), " ", "")
+		p.PostalCode = &postal
+	}
+
+	place, byName := s.gazetteer.Resolve(city, postal)
+	if place != nil {
+		lat, lng := place.Lat, place.Lng
+		p.Latitude, p.Longitude = &lat, &lng
 	}
-	// Iterate field map if provided
-	for k, v := range fieldMap {
-		return s.repo.FindByField(ctx, k, v)
+	// A postal code only gives the town covering its prefix, the seller's
+	// own city stays searchable under its name
+	if byName {
+		name, area := place.Name, place.Area
+		p.City, p.Area = &name, &area
+		return
 	}
 
-	return s.repo.FindAll(ctx, nil)
+	if p.Area != nil {
+		area, _ := s.gazetteer.NormalizeArea(*p.Area)
+		p.Area = &area
+	}
+}
+
+func (s *ProductServiceImp) resolveSearchLocation(f *models.ProductFilters) error {
+	if f.NearPlace != "" && !f.HasLocation() {
+		if s.gazetteer == nil {
+			return ErrUnknownLocation
+		}
+		place := s.gazetteer.Lookup(f.NearPlace)
+		if place == nil {
+			place = s.gazetteer.LookupPostalCode(f.NearPlace)
+		}
+		if place == nil {
+			return ErrUnknownLocation
+		}
+		lat, lng := place.Lat, place.Lng
+		f.NearLat, f.NearLng = &lat, &lng
+	}
+
+	if f.RadiusKM != nil && (*f.RadiusKM <= 0 || !f.HasLocation()) {
+		return ErrInvalidSearch
+	}
+	if f.Sort == models.SortDistance && !f.HasLocation() {
+		return ErrInvalidSearch
+	}
+	return nil
+}
+
+// enqueuePublished schedules the jobs that react to a listing going live.
+// Failing to enqueue must not fail the status change itself.
+func (s *ProductServiceImp) enqueuePublished(ctx context.Context, productID string) {
+	if s.queue == nil {
+		return
+	}
+	task, err := tasks.NewProductPublishedTask(productID)
+	if err == nil {
+		_, err = s.queue.Enqueue(task)
+	}
+	if err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to enqueue product published task", map[string]any{"error": err.Error(), "product_id": productID})
+	}
 }
@@ -2,20 +2,30 @@ package services_test
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hfleury/horsemarketplacebk/config"
+	catmodels "github.com/hfleury/horsemarketplacebk/internal/categories/models"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/hfleury/horsemarketplacebk/internal/geo"
+	mockemail "github.com/hfleury/horsemarketplacebk/internal/mocks/email"
 	mockProducts "github.com/hfleury/horsemarketplacebk/internal/mocks/products"
 	mockSystem "github.com/hfleury/horsemarketplacebk/internal/mocks/system"
 	"github.com/hfleury/horsemarketplacebk/internal/products/models"
 	"github.com/hfleury/horsemarketplacebk/internal/products/services"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+	"github.com/hibiken/asynq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 func TestCreateProduct_ApprovalRequired(t *testing.T) {
 	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
 	mockSettings := new(mockSystem.MockSettingsRepo)
 	logger := config.NewZerologService()
 
@@ -23,6 +33,7 @@ func TestCreateProduct_ApprovalRequired(t *testing.T) {
 
 	// Setup: Approval IS required
 	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(true, nil)
+	mockSettings.On("DuplicateListingPolicy", mock.Anything).Return("off", nil)
 
 	inputProduct := &models.Product{
 		Title:  "Test Horse",
@@ -44,6 +55,8 @@ func TestCreateProduct_ApprovalRequired(t *testing.T) {
 
 func TestCreateProduct_NoApprovalRequired(t *testing.T) {
 	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
 	mockSettings := new(mockSystem.MockSettingsRepo)
 	logger := config.NewZerologService()
 
@@ -51,6 +64,8 @@ func TestCreateProduct_NoApprovalRequired(t *testing.T) {
 
 	// Setup: Approval NOT required
 	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+	mockSettings.On("DuplicateListingPolicy", mock.Anything).Return("off", nil)
+	mockSettings.On("ListingDurationDays", mock.Anything).Return(60, nil)
 
 	inputProduct := &models.Product{
 		Title:  "Test Horse",
@@ -84,11 +99,12 @@ func TestUpdateStatus_UserPublish_ApprovalRequired(t *testing.T) {
 
 	mockRepo.On("FindByID", mock.Anything, productID.String()).Return(existingProduct, nil)
 	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(true, nil)
+	mockSettings.On("DuplicateListingPolicy", mock.Anything).Return("off", nil)
 
 	// Expect FindByID then UpdateStatus with PENDING
-	mockRepo.On("UpdateStatus", mock.Anything, productID.String(), models.StatusPendingApproval).Return(nil)
+	mockRepo.On("UpdateStatus", mock.Anything, productID.String(), models.StatusPendingApproval, mock.Anything).Return(nil)
 
-	err := service.UpdateStatus(context.Background(), productID.String(), models.StatusPublished, userID.String(), false)
+	err := service.UpdateStatus(context.Background(), productID.String(), models.StatusPublished, userID.String(), false, nil)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
@@ -113,7 +129,1489 @@ func TestUpdateStatus_Unauthorized(t *testing.T) {
 
 	mockRepo.On("FindByID", mock.Anything, productID.String()).Return(existingProduct, nil)
 
-	err := service.UpdateStatus(context.Background(), productID.String(), models.StatusDeleted, otherUserID.String(), false)
+	err := service.UpdateStatus(context.Background(), productID.String(), models.StatusDeleted, otherUserID.String(), false, nil)
 
 	assert.Equal(t, services.ErrUnauthorized, err)
 }
+
+func TestSearch_WithoutFacets(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	logger := config.NewZerologService()
+
+	service := services.NewProductService(mockRepo, mockSettings, logger)
+
+	filters := &models.ProductFilters{Breed: "Swedish Warmblood"}
+	products := []*models.Product{{Title: "Test Horse"}}
+	mockRepo.On("Search", mock.Anything, filters).Return(products, nil)
+
+	result, err := service.Search(context.Background(), filters, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, products, result.Products)
+	assert.Nil(t, result.Facets)
+	mockRepo.AssertNotCalled(t, "FacetCounts", mock.Anything, mock.Anything)
+}
+
+func TestSearch_WithFacets(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	logger := config.NewZerologService()
+
+	service := services.NewProductService(mockRepo, mockSettings, logger)
+
+	filters := &models.ProductFilters{Gender: "Mare"}
+	facets := map[string][]models.FacetCount{
+		models.FacetBreed: {{Value: "Swedish Warmblood", Count: 42}},
+	}
+	mockRepo.On("Search", mock.Anything, filters).Return([]*models.Product{}, nil)
+	mockRepo.On("FacetCounts", mock.Anything, filters).Return(facets, nil)
+
+	result, err := service.Search(context.Background(), filters, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, facets, result.Facets)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateProduct_NormalizesLocation(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	logger := config.NewZerologService()
+
+	service := services.NewProductService(mockRepo, mockSettings, logger)
+	gazetteer, err := geo.NewGazetteer()
+	assert.NoError(t, err)
+	service.SetGazetteer(gazetteer)
+
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+
+	city := "  uppsala"
+	area := "somewhere"
+	inputProduct := &models.Product{Title: "Test Horse", City: &city, Area: &area}
+
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return *p.City == "Uppsala" && *p.Area == "Uppsala län" && p.Latitude != nil && p.Longitude != nil
+	})).Return(inputProduct, nil)
+
+	_, err = service.Create(context.Background(), inputProduct)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateProduct_PostalCodeKeepsCity(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	gazetteer, err := geo.NewGazetteer()
+	assert.NoError(t, err)
+	service.SetGazetteer(gazetteer)
+
+	// Vallentuna is not in the gazetteer, its postal code is in Stockholm's range
+	city, postal := "Vallentuna", "186 30"
+	inputProduct := &models.Product{Title: "Test Horse", City: &city, PostalCode: &postal}
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return *p.City == "Vallentuna" && p.Area == nil && *p.PostalCode == "18630" && *p.Latitude == 59.3293
+	})).Return(inputProduct, nil)
+
+	_, err = service.Create(context.Background(), inputProduct)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearch_NearPlace(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	logger := config.NewZerologService()
+
+	service := services.NewProductService(mockRepo, mockSettings, logger)
+	gazetteer, err := geo.NewGazetteer()
+	assert.NoError(t, err)
+	service.SetGazetteer(gazetteer)
+
+	radius := 100.0
+	filters := &models.ProductFilters{NearPlace: "Uppsala", RadiusKM: &radius, Sort: models.SortDistance}
+	mockRepo.On("Search", mock.Anything, mock.MatchedBy(func(f *models.ProductFilters) bool {
+		return f.HasLocation() && *f.NearLat == 59.8586
+	})).Return([]*models.Product{}, nil)
+
+	_, err = service.Search(context.Background(), filters, false)
+	assert.NoError(t, err)
+
+	_, err = service.Search(context.Background(), &models.ProductFilters{NearPlace: "Atlantis"}, false)
+	assert.Equal(t, services.ErrUnknownLocation, err)
+
+	_, err = service.Search(context.Background(), &models.ProductFilters{Sort: models.SortDistance}, false)
+	assert.Equal(t, services.ErrInvalidSearch, err)
+}
+
+func TestAddFavorite_OnlyPublished(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	userID := uuid.New().String()
+	draft := &models.Product{ID: uuid.New(), Status: models.StatusDraft}
+	published := &models.Product{ID: uuid.New(), Status: models.StatusPublished}
+
+	mockRepo.On("FindByID", mock.Anything, draft.ID.String()).Return(draft, nil)
+	mockRepo.On("FindByID", mock.Anything, published.ID.String()).Return(published, nil)
+	mockRepo.On("AddFavorite", mock.Anything, userID, published.ID.String()).Return(true, nil)
+
+	err := service.AddFavorite(context.Background(), userID, draft.ID.String())
+	assert.Equal(t, services.ErrProductNotFound, err)
+
+	err = service.AddFavorite(context.Background(), userID, published.ID.String())
+	assert.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "AddFavorite", mock.Anything, userID, draft.ID.String())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMarkFavorited(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	userID := uuid.New().String()
+	liked := &models.Product{ID: uuid.New()}
+	other := &models.Product{ID: uuid.New()}
+	mockRepo.On("FavoritedAmong", mock.Anything, userID, []uuid.UUID{liked.ID, other.ID}).
+		Return(map[uuid.UUID]bool{liked.ID: true}, nil)
+
+	err := service.MarkFavorited(context.Background(), userID, []*models.Product{liked, other})
+
+	assert.NoError(t, err)
+	assert.True(t, liked.IsFavorited)
+	assert.False(t, other.IsFavorited)
+
+	// Anonymous viewers never hit the database
+	assert.NoError(t, service.MarkFavorited(context.Background(), "", []*models.Product{liked}))
+	mockRepo.AssertNumberOfCalls(t, "FavoritedAmong", 1)
+}
+
+func TestHandleNotifyWatchers_SkipsSeller(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	sender := mockemail.NewMockSender()
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+	service.SetEmailSender(sender)
+
+	product := &models.Product{ID: uuid.New(), UserID: uuid.New(), Title: "Bay gelding", Status: models.StatusSold}
+	mockRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockRepo.On("FindWatchers", mock.Anything, product.ID.String()).Return([]models.Watcher{
+		{UserID: uuid.New(), Email: "buyer@example.com"},
+		{UserID: product.UserID, Email: "seller@example.com"},
+	}, nil)
+
+	task, _ := tasks.NewNotifyWatchersTask(tasks.NotifyWatchersPayload{
+		ProductID: product.ID.String(), Field: "status", OldValue: "published", NewValue: "sold",
+	})
+	err := service.HandleNotifyWatchersTask(context.Background(), task)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "buyer@example.com", sender.LastTo)
+	assert.Equal(t, "\"Bay gelding\" is now sold", sender.LastSubject)
+}
+
+type fakeQueue struct {
+	tasks []*asynq.Task
+}
+
+func (q *fakeQueue) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	q.tasks = append(q.tasks, task)
+	return &asynq.TaskInfo{}, nil
+}
+
+func TestCreateProduct_MediaMustBeOwned(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+	userID := uuid.New()
+	mediaID := uuid.New()
+	mockRepo.On("MediaOwnedBy", mock.Anything, userID.String(), []uuid.UUID{mediaID}).Return(false, nil)
+
+	_, err := service.Create(context.Background(), &models.Product{
+		UserID: userID,
+		Title:  "Pony",
+		Media:  []models.ProductMedia{{MediaID: mediaID}},
+	})
+
+	assert.Equal(t, services.ErrMediaNotOwned, err)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateProduct_NormalizesMedia(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+	userID := uuid.New()
+	first, second := uuid.New(), uuid.New()
+	mockRepo.On("MediaOwnedBy", mock.Anything, userID.String(), []uuid.UUID{first, second}).Return(true, nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return len(p.Media) == 2 &&
+			p.Media[0].MediaID == first && p.Media[0].Order == 0 && p.Media[0].IsPrimary &&
+			p.Media[1].MediaID == second && p.Media[1].Order == 1 && !p.Media[1].IsPrimary
+	})).Return(&models.Product{}, nil)
+
+	_, err := service.Create(context.Background(), &models.Product{
+		UserID: userID,
+		Title:  "Pony",
+		Media: []models.ProductMedia{
+			{MediaID: second, Order: 5},
+			{MediaID: first, Order: 1},
+			{MediaID: first, Order: 7},
+		},
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateProduct_KeepsTypeAndNotifiesPriceChange(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil).Maybe()
+	queue := &fakeQueue{}
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	service.SetTaskQueue(queue)
+
+	oldPrice, newPrice := 50000.0, 45000.0
+	existing := &models.Product{
+		ID: uuid.New(), UserID: uuid.New(), Type: models.TypeHorse, Status: models.StatusPublished,
+		PriceSEK: &oldPrice, Horse: &models.Horse{},
+	}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.Type == models.TypeHorse && p.Status == models.StatusPublished && p.Horse != nil && p.Media == nil
+	}), mock.Anything, mock.Anything, mock.Anything).Return(existing, nil)
+
+	_, err := service.Update(context.Background(), existing.ID.String(), &models.Product{
+		Type: models.TypeVehicle, Status: models.StatusSold, Title: "Cheaper now", PriceSEK: &newPrice, Horse: &models.Horse{},
+	}, existing.UserID.String(), false, nil)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	// Watchers hear about the change, saved searches about the drop
+	if assert.Len(t, queue.tasks, 2) {
+		assert.Equal(t, tasks.TypeNotifyWatchers, queue.tasks[0].Type())
+		assert.Equal(t, tasks.TypePriceDropped, queue.tasks[1].Type())
+	}
+}
+
+func TestDeleteProduct_NotifiesWatchers(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	queue := &fakeQueue{}
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+	service.SetTaskQueue(queue)
+
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusPublished}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockRepo.On("Delete", mock.Anything, existing.ID.String()).Return(nil)
+
+	err := service.Delete(context.Background(), existing.ID.String(), existing.UserID.String(), false)
+
+	assert.NoError(t, err)
+	if assert.Len(t, queue.tasks, 1) {
+		assert.Equal(t, tasks.TypeNotifyWatchers, queue.tasks[0].Type())
+		var payload tasks.NotifyWatchersPayload
+		assert.NoError(t, json.Unmarshal(queue.tasks[0].Payload(), &payload))
+		assert.Equal(t, tasks.NotifyWatchersPayload{
+			ProductID: existing.ID.String(), Field: "status", OldValue: "published", NewValue: "deleted",
+		}, payload)
+	}
+}
+
+func TestUpdateProduct_ReplacesContent(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	description, city := "Calm trailer", "Uppsala"
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Type: models.TypeVehicle, Status: models.StatusDraft,
+		Title: "Trailer", Description: &description, City: &city, Vehicle: &models.Vehicle{}}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	var saved *models.Product
+	mockRepo.On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.Product)
+	}).Return(existing, nil)
+
+	var detailErr *models.DetailError
+	_, err := service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Trailer"}, existing.UserID.String(), false, nil)
+	if assert.ErrorAs(t, err, &detailErr) {
+		assert.Equal(t, "vehicle", detailErr.Field)
+	}
+	_, err = service.Update(context.Background(), existing.ID.String(), &models.Product{Vehicle: &models.Vehicle{}}, existing.UserID.String(), false, nil)
+	if assert.ErrorAs(t, err, &detailErr) {
+		assert.Equal(t, "title", detailErr.Field)
+	}
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// Fields left out are cleared like the type details would be
+	_, err = service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Trailer", Vehicle: &models.Vehicle{}}, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, saved.Description)
+	assert.Nil(t, saved.City)
+}
+
+func TestHandleNotifyWatchers_PriceDrop(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	sender := mockemail.NewMockSender()
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+	service.SetEmailSender(sender)
+
+	product := &models.Product{ID: uuid.New(), UserID: uuid.New(), Title: "Bay gelding", Status: models.StatusPublished}
+	mockRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockRepo.On("FindWatchers", mock.Anything, product.ID.String()).Return([]models.Watcher{{UserID: uuid.New(), Email: "buyer@example.com"}}, nil)
+
+	task, _ := tasks.NewNotifyWatchersTask(tasks.NotifyWatchersPayload{
+		ProductID: product.ID.String(), Field: "price", OldValue: "50000", NewValue: "40000",
+	})
+	err := service.HandleNotifyWatchersTask(context.Background(), task)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Price drop on \"Bay gelding\"", sender.LastSubject)
+	assert.Contains(t, sender.LastBody, "dropped from 50000 SEK to 40000 SEK (-20%)")
+}
+
+func TestPriceHistory_DeletedListing(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	live := &models.Product{ID: uuid.New(), Status: models.StatusSold}
+	deleted := &models.Product{ID: uuid.New(), Status: models.StatusDeleted}
+	oldPrice, price := 50000.0, 45000.0
+	mockRepo.On("FindByID", mock.Anything, live.ID.String()).Return(live, nil)
+	mockRepo.On("FindByID", mock.Anything, deleted.ID.String()).Return(deleted, nil)
+	mockRepo.On("FindPriceHistory", mock.Anything, live.ID.String()).Return([]models.PriceChange{
+		{PriceSEK: &oldPrice}, {OldPriceSEK: &oldPrice, PriceSEK: &price},
+	}, nil)
+
+	history, err := service.PriceHistory(context.Background(), live.ID.String(), models.Viewer{})
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.False(t, history[0].IsDrop())
+		assert.True(t, history[1].IsDrop())
+	}
+
+	_, err = service.PriceHistory(context.Background(), deleted.ID.String(), models.Viewer{})
+	assert.Equal(t, services.ErrProductNotFound, err)
+}
+
+func TestUpdateProduct_Unauthorized(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusPublished}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+
+	_, err := service.Update(context.Background(), existing.ID.String(), &models.Product{}, uuid.New().String(), false, nil)
+
+	assert.Equal(t, services.ErrUnauthorized, err)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReorderMedia_MustListAttachedMedia(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	a, b := uuid.New(), uuid.New()
+	existing := &models.Product{
+		ID: uuid.New(), UserID: uuid.New(), Status: models.StatusPublished,
+		Media: []models.ProductMedia{{MediaID: a}, {MediaID: b}},
+	}
+	userID := existing.UserID.String()
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockRepo.On("ReorderMedia", mock.Anything, existing.ID.String(), []uuid.UUID{b, a}).Return(nil)
+
+	assert.Equal(t, services.ErrInvalidMediaOrder, service.ReorderMedia(context.Background(), existing.ID.String(), []uuid.UUID{a, a}, userID, false))
+	assert.Equal(t, services.ErrInvalidMediaOrder, service.ReorderMedia(context.Background(), existing.ID.String(), []uuid.UUID{b}, userID, false))
+	assert.NoError(t, service.ReorderMedia(context.Background(), existing.ID.String(), []uuid.UUID{b, a}, userID, false))
+	mockRepo.AssertNumberOfCalls(t, "ReorderMedia", 1)
+}
+
+func TestCreateProduct_PublishedSetsExpiry(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+	mockSettings.On("DuplicateListingPolicy", mock.Anything).Return("off", nil)
+	mockSettings.On("ListingDurationDays", mock.Anything).Return(30, nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.PublishedAt != nil && p.ExpiresAt != nil && p.ExpiresAt.Sub(*p.PublishedAt) == 30*24*time.Hour
+	})).Return(&models.Product{}, nil)
+
+	_, err := service.Create(context.Background(), &models.Product{Title: "Mare", Status: models.StatusPublished})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateStatus_AdminApprovalPublishes(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusPendingApproval}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockSettings.On("ListingDurationDays", mock.Anything).Return(60, nil)
+	mockRepo.On("Publish", mock.Anything, existing.ID.String(), mock.AnythingOfType("time.Time"), mock.Anything).Return(nil)
+
+	err := service.UpdateStatus(context.Background(), existing.ID.String(), models.StatusPublished, uuid.New().String(), true, nil)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRenew_OnlyPublishedOrExpired(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	past := time.Now().Add(-time.Hour)
+	expired := &models.Product{ID: uuid.New(), Status: models.StatusArchived, ExpiresAt: &past}
+	sold := &models.Product{ID: uuid.New(), Status: models.StatusSold, ExpiresAt: &past}
+	mockRepo.On("FindByRenewToken", mock.Anything, "expired").Return(expired, nil)
+	mockRepo.On("FindByRenewToken", mock.Anything, "sold").Return(sold, nil)
+	mockRepo.On("FindByRenewToken", mock.Anything, "unknown").Return(nil, nil)
+	mockSettings.On("ListingDurationDays", mock.Anything).Return(60, nil)
+	mockRepo.On("Renew", mock.Anything, expired.ID.String(), mock.AnythingOfType("time.Time")).Return(nil)
+
+	renewed, err := service.RenewByToken(context.Background(), "expired")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusPublished, renewed.Status)
+	assert.True(t, renewed.ExpiresAt.After(time.Now()))
+
+	_, err = service.RenewByToken(context.Background(), "sold")
+	assert.Equal(t, services.ErrCannotRenew, err)
+
+	_, err = service.RenewByToken(context.Background(), "unknown")
+	assert.Equal(t, services.ErrProductNotFound, err)
+}
+
+func TestHandleListingExpiry_ArchivesAndReminds(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	sender := mockemail.NewMockSender()
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	service.SetEmailSender(sender)
+
+	listing := models.ExpiringListing{ProductID: uuid.New(), Title: "Old saddle", ExpiresAt: time.Now().Add(48 * time.Hour), SellerEmail: "seller@example.com"}
+	mockRepo.On("ArchiveExpired", mock.Anything).Return([]string{}, nil)
+	mockSettings.On("ExpiryReminderDays", mock.Anything).Return(5, nil)
+	mockRepo.On("FindExpiringSoon", mock.Anything, mock.AnythingOfType("time.Time")).Return([]models.ExpiringListing{listing}, nil)
+	mockRepo.On("MarkReminderSent", mock.Anything, listing.ProductID.String(), mock.AnythingOfType("string")).Return(nil)
+
+	err := service.HandleListingExpiryTask(context.Background(), asynq.NewTask(tasks.TypeListingExpiry, nil))
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	assert.Equal(t, "seller@example.com", sender.LastTo)
+	assert.Contains(t, sender.LastBody, "/api/v1/products/renew?token=")
+}
+
+func TestCreateProduct_PedigreeValidation(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(true, nil)
+	mockSettings.On("PedigreeMaxGenerations", mock.Anything).Return(2, nil)
+
+	// Three generations on the dam side
+	tooDeep := &models.Pedigree{
+		Sire: &models.PedigreeHorse{Name: "Totilas"},
+		Dam: &models.PedigreeHorse{Name: "Lominka", Sire: &models.PedigreeHorse{
+			Name: "Glendale", Sire: &models.PedigreeHorse{Name: "Gribaldi"},
+		}},
+	}
+	_, err := service.Create(context.Background(), &models.Product{
+		Title: "Colt", Type: models.TypeHorse, Horse: &models.Horse{Pedigree: tooDeep},
+	})
+	var pedigreeErr *models.PedigreeError
+	assert.ErrorAs(t, err, &pedigreeErr)
+	assert.Equal(t, "pedigree.dam.sire.sire", pedigreeErr.Path)
+
+	_, err = service.Create(context.Background(), &models.Product{
+		Title: "Colt", Type: models.TypeHorse, Horse: &models.Horse{Pedigree: &models.Pedigree{Sire: &models.PedigreeHorse{Name: "  "}}},
+	})
+	assert.ErrorAs(t, err, &pedigreeErr)
+	assert.Equal(t, "pedigree.sire", pedigreeErr.Path)
+
+	reg := " swe 123 "
+	valid := &models.Pedigree{Sire: &models.PedigreeHorse{Name: " Blue  Hors Zack ", RegistrationNumber: &reg}}
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		sire := p.Horse.Pedigree.Sire
+		return sire.Name == "Blue Hors Zack" && *sire.RegistrationNumber == "SWE123"
+	})).Return(&models.Product{}, nil)
+
+	_, err = service.Create(context.Background(), &models.Product{
+		Title: "Colt", Type: models.TypeHorse, Horse: &models.Horse{Pedigree: valid},
+	})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateProduct_KeepsLegacyPedigree(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	mockSettings.On("PedigreeMaxGenerations", mock.Anything).Return(5, nil)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	legacy := json.RawMessage(`{"sire":"Totilas"}`)
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Type: models.TypeHorse, Status: models.StatusDraft, Title: "Mare",
+		Horse: &models.Horse{LegacyPedigree: legacy}}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	var saved *models.Product
+	mockRepo.On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.Product)
+	}).Return(existing, nil)
+
+	// Whatever the client sends back, the stored value is kept
+	_, err := service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Mare",
+		Horse: &models.Horse{LegacyPedigree: json.RawMessage(`{"sire":{"name":"Unchecked"}}`)}}, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, saved.Horse.LegacyPedigree)
+
+	// until a pedigree replaces it
+	_, err = service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Mare",
+		Horse: &models.Horse{Pedigree: &models.Pedigree{Sire: &models.PedigreeHorse{Name: "Totilas"}}}}, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, saved.Horse.LegacyPedigree)
+	assert.Equal(t, "Totilas", saved.Horse.Pedigree.Sire.Name)
+}
+
+func TestPedigreeChart(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	name := "Colt"
+	product := &models.Product{ID: uuid.New(), Type: models.TypeHorse, Status: models.StatusPublished, Horse: &models.Horse{
+		Name: &name,
+		Pedigree: &models.Pedigree{
+			Dam: &models.PedigreeHorse{Name: "Lominka", Sire: &models.PedigreeHorse{Name: "Glendale"}},
+		},
+	}}
+	mockRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockSettings.On("PedigreeMaxGenerations", mock.Anything).Return(5, nil)
+
+	chart, err := service.Pedigree(context.Background(), product.ID.String(), 2, models.Viewer{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, chart.Generations)
+	assert.False(t, chart.Sire.Known)
+	assert.Equal(t, "male", chart.Sire.Sex)
+	assert.NotNil(t, chart.Sire.Dam)
+	assert.Equal(t, "Lominka", chart.Dam.Name)
+	assert.Equal(t, "female", chart.Dam.Sex)
+	assert.Equal(t, "ds", chart.Dam.Sire.Path)
+	assert.Equal(t, "Glendale", chart.Dam.Sire.Name)
+	assert.Nil(t, chart.Dam.Sire.Sire)
+
+	ancestors := product.Horse.Pedigree.Ancestors()
+	assert.Len(t, ancestors, 2)
+	assert.Equal(t, "d", ancestors[0].Path)
+	assert.Equal(t, 2, ancestors[1].Generation())
+}
+
+type fakeBreedingCache struct {
+	results map[string]*models.BreedingResult
+}
+
+func (c *fakeBreedingCache) Get(ctx context.Context, key string) (*models.BreedingResult, error) {
+	return c.results[key], nil
+}
+
+func (c *fakeBreedingCache) Set(ctx context.Context, key string, result *models.BreedingResult) error {
+	c.results[key] = result
+	return nil
+}
+
+func TestBreedingCompatibility_HalfSiblingsCached(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	cache := &fakeBreedingCache{results: map[string]*models.BreedingResult{}}
+	service.SetBreedingCache(cache)
+
+	regular, spaced := "SWE123", " swe 123 "
+	name, gender := "Zack Junior", "Stallion"
+	stallion := &models.Product{ID: uuid.New(), Title: "Stallion at stud", Type: models.TypeHorse, Status: models.StatusPublished, Horse: &models.Horse{
+		Name: &name, Gender: &gender,
+		Pedigree: &models.Pedigree{Sire: &models.PedigreeHorse{Name: "Blue Hors Zack", RegistrationNumber: &regular}},
+	}}
+	mockRepo.On("FindByID", mock.Anything, stallion.ID.String()).Return(stallion, nil)
+	mockSettings.On("PedigreeMaxGenerations", mock.Anything).Return(5, nil)
+
+	stallionID := stallion.ID.String()
+	req := func() *models.BreedingRequest {
+		return &models.BreedingRequest{SireID: &stallionID, Dam: &models.PedigreeHorse{
+			Name: "My Mare", Sire: &models.PedigreeHorse{Name: "Zack", RegistrationNumber: &spaced},
+		}}
+	}
+
+	result, err := service.BreedingCompatibility(context.Background(), req(), models.Viewer{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0.125, result.Coefficient)
+	assert.Equal(t, 12.5, result.Percentage)
+	assert.Equal(t, "Zack Junior", result.Sire.Name)
+	if assert.Len(t, result.CommonAncestors, 1) {
+		assert.Equal(t, "Blue Hors Zack", result.CommonAncestors[0].Name)
+		assert.Equal(t, []string{"ss"}, result.CommonAncestors[0].SirePaths)
+		assert.Equal(t, []string{"ds"}, result.CommonAncestors[0].DamPaths)
+	}
+	assert.Len(t, cache.results, 1)
+
+	again, err := service.BreedingCompatibility(context.Background(), req(), models.Viewer{})
+	assert.NoError(t, err)
+	assert.Same(t, result, again)
+}
+
+func TestBreedingCompatibility_InbredCommonAncestor(t *testing.T) {
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(new(mockProducts.MockProductRepo), mockSettings, config.NewZerologService())
+	mockSettings.On("PedigreeMaxGenerations", mock.Anything).Return(5, nil)
+
+	// A's parents are half siblings, so A itself is inbred by 1/8
+	ancestor := func() *models.PedigreeHorse {
+		return &models.PedigreeHorse{Name: "A",
+			Sire: &models.PedigreeHorse{Name: "P", Sire: &models.PedigreeHorse{Name: "Z"}},
+			Dam:  &models.PedigreeHorse{Name: "Q", Sire: &models.PedigreeHorse{Name: "Z"}},
+		}
+	}
+	result, err := service.BreedingCompatibility(context.Background(), &models.BreedingRequest{
+		Sire: &models.PedigreeHorse{Name: "S", Sire: ancestor()},
+		Dam:  &models.PedigreeHorse{Name: "D", Sire: ancestor()},
+	}, models.Viewer{})
+
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.140625, result.Coefficient, 1e-9)
+	if assert.Len(t, result.CommonAncestors, 1) {
+		assert.Equal(t, "A", result.CommonAncestors[0].Name)
+		assert.Equal(t, 0.125, result.CommonAncestors[0].Inbreeding)
+	}
+
+	// Father to daughter
+	result, err = service.BreedingCompatibility(context.Background(), &models.BreedingRequest{
+		Sire: &models.PedigreeHorse{Name: "S"},
+		Dam:  &models.PedigreeHorse{Name: "D", Sire: &models.PedigreeHorse{Name: "s"}},
+	}, models.Viewer{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, result.Coefficient)
+}
+
+func TestBreedingCompatibility_InvalidMatings(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	gender := "Gelding"
+	gelding := &models.Product{ID: uuid.New(), Type: models.TypeHorse, Status: models.StatusPublished, Horse: &models.Horse{Gender: &gender}}
+	mockRepo.On("FindByID", mock.Anything, gelding.ID.String()).Return(gelding, nil)
+	geldingID := gelding.ID.String()
+
+	_, err := service.BreedingCompatibility(context.Background(), &models.BreedingRequest{SireID: &geldingID, DamID: &geldingID}, models.Viewer{})
+	assert.Equal(t, services.ErrNotBreedable, err)
+
+	_, err = service.BreedingCompatibility(context.Background(), &models.BreedingRequest{SireID: &geldingID, Sire: &models.PedigreeHorse{Name: "S"}}, models.Viewer{})
+	assert.Equal(t, services.ErrInvalidMating, err)
+}
+
+func TestCreateProduct_IdentityValidationAndUniqueness(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+
+	newHorse := func(ueln, chip string) *models.Product {
+		return &models.Product{Title: "Mare", Type: models.TypeHorse, Horse: &models.Horse{UELN: &ueln, Microchip: &chip}}
+	}
+
+	for _, tc := range []struct{ ueln, chip, field string }{
+		{"75200400112345", "752098100123456", "ueln"},  // 14 characters
+		{"AB2004001123456", "752098100123456", "ueln"}, // no country code
+		{"752004001123456", "75209810012345X", "microchip"},
+		{"752004001123456", "999000000000001", "microchip"}, // test transponder
+		{"752004001123456", "752999999999999", "microchip"}, // more than 38 bits
+	} {
+		_, err := service.Create(context.Background(), newHorse(tc.ueln, tc.chip))
+		var identityErr *models.IdentityError
+		if assert.ErrorAs(t, err, &identityErr, tc.ueln+" "+tc.chip) {
+			assert.Equal(t, tc.field, identityErr.Field)
+		}
+	}
+
+	ueln, chip := "752004001123456", "752098100123456"
+	mockRepo.On("FindActiveByIdentity", mock.Anything, &ueln, &chip, uuid.Nil).Return(uuid.New(), nil).Once()
+	_, err := service.Create(context.Background(), newHorse(" 752-004-001123456 ", "752 0981 0012 3456"))
+	assert.Equal(t, services.ErrDuplicateIdentity, err)
+
+	// Sellers cannot verify their own horse
+	mockRepo.On("FindActiveByIdentity", mock.Anything, &ueln, &chip, uuid.Nil).Return(uuid.Nil, nil).Once()
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return !p.Horse.IdentityVerified && p.Horse.IdentityVerifiedAt == nil
+	})).Return(&models.Product{}, nil)
+	product := newHorse(ueln, chip)
+	product.Horse.IdentityVerified = true
+	_, err = service.Create(context.Background(), product)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateProduct_ChangedIdentifierDropsVerification(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil).Maybe()
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	ueln, otherUELN := "752004001123456", "752004001999999"
+	passport, verifiedAt, admin := uuid.New(), time.Now(), uuid.New()
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Type: models.TypeHorse, Status: models.StatusPublished, Horse: &models.Horse{
+		UELN: &ueln, PassportMediaID: &passport, IdentityVerified: true, IdentityVerifiedAt: &verifiedAt, IdentityVerifiedBy: &admin,
+	}}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockRepo.On("FindActiveByIdentity", mock.Anything, mock.Anything, mock.Anything, existing.ID).Return(uuid.Nil, nil)
+	var saved *models.Product
+	mockRepo.On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.Product)
+	}).Return(existing, nil)
+
+	_, err := service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Mare", Horse: &models.Horse{
+		UELN: &ueln, PassportMediaID: &passport,
+	}}, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+	assert.True(t, saved.Horse.IdentityVerified)
+	assert.Equal(t, &admin, saved.Horse.IdentityVerifiedBy)
+
+	_, err = service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Mare", Horse: &models.Horse{
+		UELN: &otherUELN, PassportMediaID: &passport,
+	}}, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+	assert.False(t, saved.Horse.IdentityVerified)
+	assert.Nil(t, saved.Horse.IdentityVerifiedAt)
+}
+
+func TestVerifyIdentity_NeedsPassportAndIdentifier(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	chip := "752098100123456"
+	incomplete := &models.Product{ID: uuid.New(), Type: models.TypeHorse, Status: models.StatusPublished, Horse: &models.Horse{Microchip: &chip}}
+	mockRepo.On("FindByID", mock.Anything, incomplete.ID.String()).Return(incomplete, nil)
+
+	_, err := service.VerifyIdentity(context.Background(), incomplete.ID.String(), uuid.New().String(), true)
+	assert.Equal(t, services.ErrIdentityIncomplete, err)
+
+	passport, admin := uuid.New(), uuid.New()
+	complete := &models.Product{ID: uuid.New(), Type: models.TypeHorse, Status: models.StatusPublished, Horse: &models.Horse{Microchip: &chip, PassportMediaID: &passport}}
+	mockRepo.On("FindByID", mock.Anything, complete.ID.String()).Return(complete, nil)
+	mockRepo.On("SetIdentityVerified", mock.Anything, complete.ID.String(), &admin).Return(nil)
+
+	_, err = service.VerifyIdentity(context.Background(), complete.ID.String(), admin.String(), true)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	_, err = service.SearchIdentity(context.Background(), " 75")
+	assert.Equal(t, services.ErrInvalidSearch, err)
+}
+
+func TestCreateProduct_ServiceDetails(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+
+	kind, unit := " Farrier ", "Visit"
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		s := p.Service
+		return *s.Kind == "farrier" && *s.PricingUnit == "visit" &&
+			assert.ObjectsAreEqual([]string{"Uppsala", "Stockholm"}, s.CoverageAreas) &&
+			assert.ObjectsAreEqual([]string{"mon", "wed", "fri"}, s.AvailableDays)
+	})).Return(&models.Product{}, nil)
+
+	_, err := service.Create(context.Background(), &models.Product{Title: "Hovslagare", Type: models.TypeService, Service: &models.Service{
+		Kind: &kind, PricingUnit: &unit,
+		CoverageAreas: []string{" Uppsala", "Stockholm", "uppsala", ""},
+		AvailableDays: []string{"Friday", "mon", "Wed", "fri"},
+	}})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	perLitre := "litre"
+	_, err = service.Create(context.Background(), &models.Product{Title: "Hay delivery", Type: models.TypeService, Service: &models.Service{PricingUnit: &perLitre}})
+	var detailErr *models.DetailError
+	if assert.ErrorAs(t, err, &detailErr) {
+		assert.Equal(t, "service.pricing_unit", detailErr.Field)
+	}
+
+	stalls := -2
+	_, err = service.Create(context.Background(), &models.Product{Title: "Stable", Type: models.TypeProperty, Property: &models.Property{StallCount: &stalls}})
+	if assert.ErrorAs(t, err, &detailErr) {
+		assert.Equal(t, "property.stall_count", detailErr.Field)
+	}
+}
+
+type fakeAttributeDefinitions struct {
+	attributes []*catmodels.Attribute
+}
+
+func (f *fakeAttributeDefinitions) ListAttributes(ctx context.Context, categoryID string) ([]*catmodels.Attribute, error) {
+	return f.attributes, nil
+}
+
+func TestCreateProduct_CategoryAttributes(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+
+	stalls, heating := uuid.New(), uuid.New()
+	service.SetAttributeDefinitions(&fakeAttributeDefinitions{attributes: []*catmodels.Attribute{
+		{ID: stalls, Key: "stalls", Type: catmodels.AttributeInteger, Required: true},
+		{ID: heating, Key: "heating", Type: catmodels.AttributeEnum, AllowedValues: []string{"Floor", "Radiator"}},
+	}})
+	categoryID := uuid.New()
+	newListing := func(attributes map[string]any) *models.Product {
+		return &models.Product{Title: "Stable", Type: models.TypeProperty, CategoryID: &categoryID, Attributes: attributes}
+	}
+
+	var attributeErr *models.AttributeError
+	_, err := service.Create(context.Background(), newListing(map[string]any{"heating": "floor"}))
+	if assert.ErrorAs(t, err, &attributeErr) {
+		assert.Equal(t, "stalls", attributeErr.Key)
+	}
+	_, err = service.Create(context.Background(), newListing(map[string]any{"stalls": 8.0, "sauna": true}))
+	if assert.ErrorAs(t, err, &attributeErr) {
+		assert.Equal(t, "sauna", attributeErr.Key)
+	}
+	_, err = service.Create(context.Background(), newListing(map[string]any{"stalls": "eight"}))
+	assert.ErrorAs(t, err, &attributeErr)
+
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return len(p.AttributeValues) == 2 && p.Attributes["heating"] == "Floor" && p.Attributes["stalls"] == int64(8)
+	})).Return(&models.Product{}, nil)
+	_, err = service.Create(context.Background(), newListing(map[string]any{"stalls": 8.0, "heating": "floor"}))
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateProduct_KeepsAttributesUnlessCategoryChanges(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil).Maybe()
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	service.SetAttributeDefinitions(&fakeAttributeDefinitions{})
+
+	categoryID := uuid.New()
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Type: models.TypeProperty, Status: models.StatusPublished,
+		CategoryID: &categoryID, Attributes: map[string]any{"stalls": int64(8)}}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	var saved *models.Product
+	mockRepo.On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.Product)
+	}).Return(existing, nil)
+
+	_, err := service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Stable", CategoryID: &categoryID, Property: &models.Property{}}, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, saved.AttributeValues)
+
+	_, err = service.Update(context.Background(), existing.ID.String(), &models.Product{Title: "Stable", Property: &models.Property{}}, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, saved.AttributeValues)
+	assert.Empty(t, saved.AttributeValues)
+}
+
+func TestImportListing_MatchesExternalID(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+
+	userID := uuid.New()
+	newID, oldID := "A-1", "A-2"
+	existing := &models.Product{
+		ID: uuid.New(), UserID: userID, Type: models.TypeHorse, Status: models.StatusPublished,
+		ExternalID: &oldID, Horse: &models.Horse{},
+	}
+	mockRepo.On("FindByExternalID", mock.Anything, userID.String(), newID).Return(nil, nil)
+	mockRepo.On("FindByExternalID", mock.Anything, userID.String(), oldID).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.ID == existing.ID && p.Status == models.StatusPublished && p.Title == "Renamed"
+	}), mock.Anything, mock.Anything, mock.Anything).Return(existing, nil)
+
+	// A dry run validates new listings without storing them
+	action, err := service.ImportListing(context.Background(), &models.Product{
+		UserID: userID, ExternalID: &newID, Type: models.TypeHorse, Title: "Bella",
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, services.ImportCreated, action)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	action, err = service.ImportListing(context.Background(), &models.Product{
+		UserID: userID, ExternalID: &oldID, Type: models.TypeHorse, Title: "Renamed", Status: models.StatusDraft,
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, services.ImportUpdated, action)
+
+	_, err = service.ImportListing(context.Background(), &models.Product{
+		UserID: userID, ExternalID: &oldID, Type: models.TypeVehicle, Title: "Trailer",
+	}, false)
+	var detailErr *models.DetailError
+	assert.ErrorAs(t, err, &detailErr)
+	mockRepo.AssertNumberOfCalls(t, "Update", 1)
+}
+
+// fixedRates converts with constant rates.
+type fixedRates map[string]float64
+
+func (r fixedRates) SEKPerUnit(ctx context.Context, code string) (float64, error) {
+	rate, ok := r[code]
+	if !ok {
+		return 0, currency.ErrRateUnavailable
+	}
+	return rate, nil
+}
+
+func TestCreateProduct_EnteredPriceNormalizedToSEK(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	service.SetExchangeRates(fixedRates{"EUR": 11.45})
+
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.PriceSEK != nil && *p.PriceSEK == 57250 && p.EnteredPrice == nil
+	})).Return(&models.Product{}, nil)
+
+	_, err := service.Create(context.Background(), &models.Product{
+		Title: "Pony", EnteredPrice: &models.Money{Amount: 5000, Currency: "eur"},
+	})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	var detailErr *models.DetailError
+	_, err = service.Create(context.Background(), &models.Product{
+		Title: "Pony", EnteredPrice: &models.Money{Amount: 5000, Currency: "NOK"},
+	})
+	if assert.ErrorAs(t, err, &detailErr) {
+		assert.Equal(t, "entered_price.currency", detailErr.Field)
+	}
+}
+
+func TestConvertPrices(t *testing.T) {
+	service := services.NewProductService(new(mockProducts.MockProductRepo), new(mockSystem.MockSettingsRepo), config.NewZerologService())
+	service.SetExchangeRates(fixedRates{"EUR": 11.45})
+
+	price := 100000.0
+	products := []*models.Product{{PriceSEK: &price}, {}}
+
+	assert.NoError(t, service.ConvertPrices(context.Background(), "eur", products))
+	assert.Equal(t, &models.Money{Amount: 8733.62, Currency: "EUR", SEKPerUnit: 11.45}, products[0].ConvertedPrice)
+	assert.Nil(t, products[1].ConvertedPrice)
+
+	assert.ErrorIs(t, service.ConvertPrices(context.Background(), "USD", products), currency.ErrUnsupportedCurrency)
+
+	_, err := service.Search(context.Background(), &models.ProductFilters{Currency: "DKK", MinPrice: &price}, false)
+	assert.ErrorIs(t, err, currency.ErrRateUnavailable)
+}
+
+func TestCreateProduct_RepostFlaggedForModeration(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	sellerID, originalID := uuid.New(), uuid.New()
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+	mockSettings.On("DuplicateListingPolicy", mock.Anything).Return("flag", nil)
+	mockSettings.On("DuplicateListingThreshold", mock.Anything).Return(80, nil)
+	mockRepo.On("FindDuplicateCandidates", mock.Anything, mock.MatchedBy(func(q models.DuplicateQuery) bool {
+		return q.UserID == sellerID && q.Title == "Bella, 7 year old KWPN mare" && q.PrimaryMediaID == nil &&
+			q.Description == "Bella is a calm and brave mare, jumped 1.20 m"
+	})).Return([]models.DuplicateCandidate{
+		{ProductID: originalID, UserID: sellerID, Title: "KWPN mare Bella 7 years old!", Description: "Bella is a calm and brave mare, jumped 1.20 m."},
+		{ProductID: uuid.New(), UserID: uuid.New(), Title: "Bella KWPN", Description: "A different horse entirely with its own story."},
+	}, nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.Status == models.StatusPendingApproval && p.PublishedAt == nil
+	})).Return(&models.Product{ID: uuid.New(), Status: models.StatusPendingApproval}, nil)
+	mockRepo.On("SaveDuplicateFlags", mock.Anything, mock.Anything, mock.MatchedBy(func(matches []models.DuplicateMatch) bool {
+		return len(matches) == 1 && matches[0].DuplicateOf == originalID && matches[0].SameSeller &&
+			assert.ObjectsAreEqual([]string{models.DuplicateReasonDescription}, matches[0].Reasons)
+	})).Return(nil)
+
+	description := "Bella is a calm and brave mare, jumped 1.20 m"
+	created, err := service.Create(context.Background(), &models.Product{
+		UserID: sellerID, Type: models.TypeHorse, Title: "Bella, 7 year old KWPN mare", Description: &description, Status: models.StatusPublished,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusPendingApproval, created.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateStatus_DuplicateImageBlocked(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	primary, otherID := uuid.New(), uuid.New()
+	existing := &models.Product{
+		ID: uuid.New(), UserID: uuid.New(), Type: models.TypeEquipment, Title: "Dressage saddle", Status: models.StatusDraft,
+		Media: []models.ProductMedia{{MediaID: uuid.New()}, {MediaID: primary, IsPrimary: true}},
+	}
+	distance := 3
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+	mockSettings.On("DuplicateListingPolicy", mock.Anything).Return("block", nil)
+	mockSettings.On("DuplicateListingThreshold", mock.Anything).Return(80, nil)
+	mockRepo.On("FindDuplicateCandidates", mock.Anything, mock.MatchedBy(func(q models.DuplicateQuery) bool {
+		return q.ProductID == existing.ID && q.PrimaryMediaID != nil && *q.PrimaryMediaID == primary
+	})).Return([]models.DuplicateCandidate{{ProductID: otherID, UserID: uuid.New(), Title: "Saddle for sale", ImageDistance: &distance}}, nil)
+
+	err := service.UpdateStatus(context.Background(), existing.ID.String(), models.StatusPublished, existing.UserID.String(), false, nil)
+
+	var duplicateErr *models.DuplicateError
+	if assert.ErrorAs(t, err, &duplicateErr) {
+		assert.Equal(t, otherID, duplicateErr.Match.DuplicateOf)
+		assert.False(t, duplicateErr.Match.SameSeller)
+		assert.Equal(t, []string{models.DuplicateReasonImage}, duplicateErr.Match.Reasons)
+	}
+	mockRepo.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolveDuplicateFlag_ConfirmArchives(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	adminID := uuid.New()
+	flagged := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusPendingApproval}
+	original := &models.Product{ID: uuid.New(), UserID: flagged.UserID, Status: models.StatusPublished}
+	flag := &models.DuplicateFlag{ID: uuid.New(), ProductID: flagged.ID, Status: models.DuplicateFlagOpen, DuplicateMatch: models.DuplicateMatch{DuplicateOf: original.ID}}
+	mockRepo.On("FindDuplicateFlag", mock.Anything, flag.ID.String()).Return(flag, nil)
+	mockRepo.On("ResolveDuplicateFlag", mock.Anything, flag.ID.String(), models.DuplicateFlagConfirmed, adminID).Return(nil)
+	mockRepo.On("FindByID", mock.Anything, flagged.ID.String()).Return(flagged, nil)
+	mockRepo.On("FindByID", mock.Anything, original.ID.String()).Return(original, nil)
+	mockRepo.On("UpdateStatus", mock.Anything, flagged.ID.String(), models.StatusArchived, mock.Anything).Return(nil)
+
+	resolved, err := service.ResolveDuplicateFlag(context.Background(), flag.ID.String(), models.DuplicateFlagConfirmed, adminID.String())
+
+	assert.NoError(t, err)
+	assert.Equal(t, original, resolved.Original)
+	mockRepo.AssertExpectations(t)
+
+	_, err = service.ResolveDuplicateFlag(context.Background(), flag.ID.String(), models.DuplicateFlagOpen, adminID.String())
+	assert.ErrorIs(t, err, services.ErrInvalidResolution)
+}
+
+func TestUpdateStatus_HiddenListingStaysHidden(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	// Taken down by staff after reports
+	hiddenAt := time.Now().Add(-time.Hour)
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusArchived, HiddenAt: &hiddenAt}
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockRepo.On("UpdateStatus", mock.Anything, existing.ID.String(), models.StatusDraft, mock.Anything).Return(nil)
+
+	for _, status := range []models.ProductStatus{models.StatusSold, models.StatusPublished, models.StatusPendingApproval} {
+		err := service.UpdateStatus(context.Background(), existing.ID.String(), status, existing.UserID.String(), false, nil)
+		assert.ErrorIs(t, err, services.ErrListingHidden, status)
+	}
+	mockRepo.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	_, err := service.Renew(context.Background(), existing.ID.String(), existing.UserID.String(), false)
+	assert.ErrorIs(t, err, services.ErrCannotRenew)
+
+	// The seller may still withdraw it
+	err = service.UpdateStatus(context.Background(), existing.ID.String(), models.StatusDraft, existing.UserID.String(), false, nil)
+	assert.NoError(t, err)
+
+	// Even marked sold, a hidden listing stays out of public view
+	sold := *existing
+	sold.Status = models.StatusSold
+	assert.False(t, models.Viewer{}.CanSee(&sold))
+	assert.True(t, models.Viewer{UserID: existing.UserID.String()}.CanSee(&sold))
+}
+
+func TestUpdateProduct_MaterialEditNeedsApproval(t *testing.T) {
+	price, lower := 50000.0, 45000.0
+	tests := []struct {
+		name     string
+		input    *models.Product
+		requeued bool
+		changed  []string
+	}{
+		{"title", &models.Product{Title: "Bay gelding, 8 years", PriceSEK: &price, Service: &models.Service{}}, true, []string{"title"}},
+		{"price", &models.Product{Title: "Bay gelding", PriceSEK: &lower, Service: &models.Service{}}, false, []string{"price_sek"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockProducts.MockProductRepo)
+			mockSettings := new(mockSystem.MockSettingsRepo)
+			service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+			mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(true, nil)
+
+			existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Type: models.TypeService, Status: models.StatusPublished, Title: "Bay gelding", PriceSEK: &price, Language: "sv", Service: &models.Service{}}
+			saved := *existing
+			saved.Title, saved.PriceSEK = tt.input.Title, tt.input.PriceSEK
+			if tt.requeued {
+				saved.Status = models.StatusPendingApproval
+			}
+			mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+			mockRepo.On("Update", mock.Anything, mock.Anything, mock.MatchedBy(func(r *models.Revision) bool {
+				return r.ProductID == existing.ID && r.RequiresApproval == tt.requeued && *r.EditedBy == existing.UserID &&
+					assert.ObjectsAreEqual(tt.changed, r.ChangedFields) && r.Content.Title == tt.input.Title
+			}), mock.MatchedBy(func(baseline *models.ListingContent) bool {
+				return baseline.Title == "Bay gelding"
+			}), mock.Anything).Return(&saved, nil).Once()
+			mockRepo.On("DeleteAutosave", mock.Anything, existing.ID).Return(nil)
+
+			updated, err := service.Update(context.Background(), existing.ID.String(), tt.input, existing.UserID.String(), false, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, saved.Status, updated.Status)
+			mockRepo.AssertExpectations(t)
+			mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+			mockRepo.AssertNotCalled(t, "AddRevision", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	breed, otherBreed := "Arabian", "Friesian"
+	product := &models.Product{ID: uuid.New(), UserID: uuid.New(), Type: models.TypeHorse, Status: models.StatusPublished}
+	revisions := []*models.Revision{
+		{ProductID: product.ID, Version: 3, Content: models.ListingContent{Title: "Friesian mare", Horse: &models.Horse{Breed: &otherBreed}}},
+		{ProductID: product.ID, Version: 2, Content: models.ListingContent{Title: "Arabian mare", Horse: &models.Horse{Breed: &breed}}},
+		{ProductID: product.ID, Version: 1, Content: models.ListingContent{Title: "Arabian mare", Horse: &models.Horse{}}},
+	}
+	mockRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockRepo.On("FindRevisions", mock.Anything, product.ID.String()).Return(revisions, nil)
+
+	diff, err := service.DiffRevisions(context.Background(), product.ID.String(), 0, 0, product.UserID.String(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, diff.FromVersion)
+	assert.Equal(t, 3, diff.ToVersion)
+	assert.Equal(t, []models.FieldChange{
+		{Field: "horse.breed", From: "Arabian", To: "Friesian"},
+		{Field: "title", From: "Arabian mare", To: "Friesian mare"},
+	}, diff.Changes)
+
+	diff, err = service.DiffRevisions(context.Background(), product.ID.String(), 1, 2, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.FieldChange{{Field: "horse.breed", To: "Arabian"}}, diff.Changes)
+
+	_, err = service.DiffRevisions(context.Background(), product.ID.String(), 0, 1, "", true)
+	assert.ErrorIs(t, err, services.ErrRevisionNotFound)
+	_, err = service.DiffRevisions(context.Background(), product.ID.String(), 0, 0, uuid.NewString(), false)
+	assert.ErrorIs(t, err, services.ErrUnauthorized)
+}
+
+func TestRestoreRevision_SavesOldVersionAsNewEdit(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	price := 30000.0
+	existing := &models.Product{ID: uuid.New(), UserID: uuid.New(), Type: models.TypeService, Status: models.StatusDraft, Title: "Farrier, weekends", Language: "sv", Service: &models.Service{}}
+	old := &models.Revision{ProductID: existing.ID, Version: 1, Content: models.ListingContent{Title: "Farrier", PriceSEK: &price, Service: &models.Service{}}}
+	restored := *existing
+	restored.Title, restored.PriceSEK = "Farrier", &price
+	mockRepo.On("FindByID", mock.Anything, existing.ID.String()).Return(existing, nil)
+	mockRepo.On("FindRevision", mock.Anything, existing.ID.String(), 1).Return(old, nil)
+	mockRepo.On("FindRevision", mock.Anything, existing.ID.String(), 9).Return(nil, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.ID == existing.ID && p.Title == "Farrier" && *p.PriceSEK == price && p.Status == models.StatusDraft
+	}), mock.MatchedBy(func(r *models.Revision) bool {
+		return r.RestoredFrom != nil && *r.RestoredFrom == 1 && assert.ObjectsAreEqual([]string{"price_sek", "title"}, r.ChangedFields)
+	}), mock.Anything, mock.Anything).Return(&restored, nil)
+	mockRepo.On("DeleteAutosave", mock.Anything, existing.ID).Return(nil)
+
+	updated, err := service.RestoreRevision(context.Background(), existing.ID.String(), 1, existing.UserID.String(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Farrier", updated.Title)
+
+	_, err = service.RestoreRevision(context.Background(), existing.ID.String(), 9, existing.UserID.String(), false)
+	assert.ErrorIs(t, err, services.ErrRevisionNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAutosave_OnlyJSONObjects(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	product := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusDraft}
+	mockRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockRepo.On("SaveAutosave", mock.Anything, mock.MatchedBy(func(a *models.Autosave) bool {
+		return a.ProductID == product.ID && a.UserID == product.UserID && string(a.Content) == `{"title":"Half done"}`
+	})).Return(nil)
+
+	_, err := service.Autosave(context.Background(), product.ID.String(), []byte(` {"title":"Half done"} `), product.UserID.String(), false)
+	assert.NoError(t, err)
+	for _, content := range []string{``, `[1]`, `{"title":`} {
+		_, err = service.Autosave(context.Background(), product.ID.String(), []byte(content), product.UserID.String(), false)
+		assert.ErrorIs(t, err, services.ErrInvalidAutosave)
+	}
+	mockRepo.AssertNumberOfCalls(t, "SaveAutosave", 1)
+}
+
+func TestFindByID_Visibility(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	seller := uuid.New()
+	listings := map[models.ProductStatus]*models.Product{}
+	for _, status := range []models.ProductStatus{models.StatusPublished, models.StatusSold, models.StatusDraft, models.StatusPendingApproval, models.StatusDeleted} {
+		p := &models.Product{ID: uuid.New(), UserID: seller, Status: status}
+		listings[status] = p
+		mockRepo.On("FindByID", mock.Anything, p.ID.String()).Return(p, nil)
+	}
+
+	anonymous := models.Viewer{}
+	stranger := models.Viewer{UserID: uuid.NewString(), IncludeDeleted: true}
+	owner := models.Viewer{UserID: seller.String()}
+	admin := models.Viewer{UserID: uuid.NewString(), IsAdmin: true}
+	tests := []struct {
+		viewer  models.Viewer
+		status  models.ProductStatus
+		visible bool
+	}{
+		{anonymous, models.StatusPublished, true},
+		{anonymous, models.StatusSold, true},
+		{anonymous, models.StatusDraft, false},
+		{anonymous, models.StatusPendingApproval, false},
+		{anonymous, models.StatusDeleted, false},
+		{stranger, models.StatusDraft, false},
+		{stranger, models.StatusDeleted, false},
+		{owner, models.StatusDraft, true},
+		{owner, models.StatusPendingApproval, true},
+		{owner, models.StatusDeleted, false},
+		{models.Viewer{UserID: seller.String(), IncludeDeleted: true}, models.StatusDeleted, true},
+		{admin, models.StatusDraft, true},
+		{admin, models.StatusDeleted, false},
+		{models.Viewer{IsAdmin: true, IncludeDeleted: true}, models.StatusDeleted, true},
+	}
+	for _, tt := range tests {
+		p, err := service.FindByID(context.Background(), listings[tt.status].ID.String(), tt.viewer)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.visible, p != nil, "%+v reading a %s listing", tt.viewer, tt.status)
+	}
+}
+
+func TestPriceHistory_DraftHiddenFromOthers(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	draft := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusDraft}
+	mockRepo.On("FindByID", mock.Anything, draft.ID.String()).Return(draft, nil)
+	mockRepo.On("FindPriceHistory", mock.Anything, draft.ID.String()).Return([]models.PriceChange{}, nil)
+
+	_, err := service.PriceHistory(context.Background(), draft.ID.String(), models.Viewer{})
+	assert.Equal(t, services.ErrProductNotFound, err)
+
+	_, err = service.PriceHistory(context.Background(), draft.ID.String(), models.Viewer{UserID: draft.UserID.String()})
+	assert.NoError(t, err)
+}
+
+func TestDashboard_CountsEveryTab(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	seller := uuid.New()
+	expires := time.Now().Add(50 * time.Hour)
+	live := &models.Product{ID: uuid.New(), UserID: seller, Status: models.StatusPublished, ExpiresAt: &expires}
+	draft := &models.Product{ID: uuid.New(), UserID: seller, Status: models.StatusDraft}
+	mockSettings.On("ExpiryReminderDays", mock.Anything).Return(5, nil)
+	mockRepo.On("CountSellerListings", mock.Anything, seller.String(), mock.AnythingOfType("time.Time")).
+		Return(map[models.ProductStatus]int{models.StatusPublished: 1, models.StatusDraft: 1}, 1, nil)
+	mockRepo.On("FindSellerListings", mock.Anything, seller.String(), models.DashboardFilters{Limit: 20}).
+		Return([]*models.SellerListing{{Product: live, Inquiries: 3}, {Product: draft}}, nil)
+
+	dashboard, err := service.Dashboard(context.Background(), seller.String(), models.DashboardFilters{})
+	assert.NoError(t, err)
+	assert.Len(t, dashboard.Counts, len(models.DashboardStatuses))
+	assert.Equal(t, 1, dashboard.Counts[models.StatusPublished])
+	assert.Equal(t, 0, dashboard.Counts[models.StatusSold])
+	assert.Equal(t, 1, dashboard.Expiring)
+	if assert.Len(t, dashboard.Listings, 2) {
+		assert.Equal(t, 2, *dashboard.Listings[0].DaysUntilExpiry)
+		assert.Nil(t, dashboard.Listings[1].DaysUntilExpiry)
+	}
+
+	_, err = service.Dashboard(context.Background(), seller.String(), models.DashboardFilters{Status: models.StatusDeleted})
+	assert.Equal(t, services.ErrInvalidSearch, err)
+}
+
+func TestBulkAction_RenewExpiring(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+
+	seller := uuid.New()
+	hiddenAt := time.Now()
+	expiring := &models.Product{ID: uuid.New(), UserID: seller, Status: models.StatusPublished}
+	hidden := &models.Product{ID: uuid.New(), UserID: seller, Status: models.StatusPublished, HiddenAt: &hiddenAt}
+	mockSettings.On("ExpiryReminderDays", mock.Anything).Return(5, nil)
+	mockSettings.On("ListingDurationDays", mock.Anything).Return(60, nil)
+	mockRepo.On("FindSellerListingIDs", mock.Anything, seller.String(), models.StatusPublished, mock.AnythingOfType("*time.Time")).
+		Return([]uuid.UUID{expiring.ID, hidden.ID}, nil)
+	mockRepo.On("FindByID", mock.Anything, expiring.ID.String()).Return(expiring, nil)
+	mockRepo.On("FindByID", mock.Anything, hidden.ID.String()).Return(hidden, nil)
+	mockRepo.On("Renew", mock.Anything, expiring.ID.String(), mock.AnythingOfType("time.Time")).Return(nil)
+
+	result, err := service.BulkAction(context.Background(), seller.String(), models.BulkRenewExpiring)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{expiring.ID}, result.Updated)
+	assert.Equal(t, map[uuid.UUID]string{hidden.ID: services.ErrCannotRenew.Error()}, result.Failed)
+	mockRepo.AssertExpectations(t)
+
+	_, err = service.BulkAction(context.Background(), seller.String(), "delete_all")
+	assert.Equal(t, services.ErrInvalidBulkAction, err)
+}
+
+func TestBulkAction_ArchiveSold(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	seller := uuid.New()
+	sold := &models.Product{ID: uuid.New(), UserID: seller, Status: models.StatusSold}
+	mockRepo.On("FindSellerListingIDs", mock.Anything, seller.String(), models.StatusSold, (*time.Time)(nil)).Return([]uuid.UUID{sold.ID}, nil)
+	mockRepo.On("FindByID", mock.Anything, sold.ID.String()).Return(sold, nil)
+	mockRepo.On("UpdateStatus", mock.Anything, sold.ID.String(), models.StatusArchived, mock.Anything).Return(nil)
+
+	result, err := service.BulkAction(context.Background(), seller.String(), models.BulkArchiveSold)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{sold.ID}, result.Updated)
+	assert.Empty(t, result.Failed)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSimilar(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	service := services.NewProductService(mockRepo, new(mockSystem.MockSettingsRepo), config.NewZerologService())
+
+	horse := &models.Product{ID: uuid.New(), Status: models.StatusPublished, Type: models.TypeHorse}
+	draft := &models.Product{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusDraft, Type: models.TypeHorse}
+	match := &models.SimilarListing{Product: &models.Product{ID: uuid.New()}, Score: 5, Matched: []string{models.SimilarBreed, models.SimilarPrice}}
+	mockRepo.On("FindByID", mock.Anything, horse.ID.String()).Return(horse, nil)
+	mockRepo.On("FindByID", mock.Anything, draft.ID.String()).Return(draft, nil)
+	mockRepo.On("FindSimilar", mock.Anything, horse, 8).Return([]*models.SimilarListing{match}, nil).Once()
+	mockRepo.On("FindSimilar", mock.Anything, horse, 24).Return(nil, nil).Once()
+
+	listings, err := service.Similar(context.Background(), horse.ID.String(), 0, models.Viewer{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.SimilarListing{match}, listings)
+
+	listings, err = service.Similar(context.Background(), horse.ID.String(), 500, models.Viewer{})
+	assert.NoError(t, err)
+	assert.Empty(t, listings)
+	assert.NotNil(t, listings)
+
+	_, err = service.Similar(context.Background(), draft.ID.String(), 0, models.Viewer{})
+	assert.Equal(t, services.ErrProductNotFound, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWeightClass(t *testing.T) {
+	for weight, class := range map[int][2]int{500: {0, 750}, 750: {0, 750}, 1300: {750, 2000}, 3500: {2000, 3500}, 4000: {3500, 0}} {
+		low, high := models.WeightClass(weight)
+		assert.Equal(t, class, [2]int{low, high}, "total weight %d", weight)
+	}
+}
+
+func TestCreateProduct_Translations(t *testing.T) {
+	mockRepo := new(mockProducts.MockProductRepo)
+	mockRepo.On("AddRevision", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteAutosave", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockSettings := new(mockSystem.MockSettingsRepo)
+	service := services.NewProductService(mockRepo, mockSettings, config.NewZerologService())
+	mockSettings.On("IsProductApprovalRequired", mock.Anything).Return(false, nil)
+
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.Language == models.DefaultLanguage && p.Translations["en"].Title == "Friendly mare"
+	})).Return(&models.Product{}, nil)
+	_, err := service.Create(context.Background(), &models.Product{Title: "Snäll sto", Type: models.TypeHorse, Horse: &models.Horse{},
+		Translations: map[string]models.Translation{"en": {Title: " Friendly mare "}}})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	var detailErr *models.DetailError
+	for field, p := range map[string]*models.Product{
+		"language":              {Title: "Mare", Language: "de"},
+		"translations.de":       {Title: "Mare", Translations: map[string]models.Translation{"de": {Title: "Stute"}}},
+		"translations.sv":       {Title: "Mare", Translations: map[string]models.Translation{"sv": {Title: "Sto"}}},
+		"translations.en.title": {Title: "Sto", Translations: map[string]models.Translation{"en": {Title: " "}}},
+	} {
+		p.Type, p.Horse = models.TypeHorse, &models.Horse{}
+		_, err = service.Create(context.Background(), p)
+		if assert.ErrorAs(t, err, &detailErr, field) {
+			assert.Equal(t, field, detailErr.Field)
+		}
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	assert.Equal(t, "en", models.NegotiateLanguage("EN", "sv-SE"))
+	assert.Equal(t, "sv", models.NegotiateLanguage("de", "de-DE, en;q=0.5, sv-SE;q=0.8"))
+	assert.Equal(t, "en", models.NegotiateLanguage("", "en-GB,en;q=0.9,sv;q=0"))
+	assert.Equal(t, "", models.NegotiateLanguage("", "de, fr;q=0.9"))
+	assert.Equal(t, "", models.NegotiateLanguage("", ""))
+}
+
+func TestLocalize(t *testing.T) {
+	description := "Snäll och framåt"
+	p := &models.Product{Title: "Snäll sto", Description: &description, Language: "sv",
+		Translations: map[string]models.Translation{"en": {Title: "Friendly mare"}}}
+
+	p.Localize("sv")
+	assert.Equal(t, "Snäll sto", p.Title)
+
+	p.Localize("en")
+	assert.Equal(t, "Friendly mare", p.Title)
+	assert.Nil(t, p.Description)
+	assert.Equal(t, "en", p.Language)
+	assert.Equal(t, map[string]models.Translation{"sv": {Title: "Snäll sto", Description: &description}}, p.Translations)
+
+	untranslated := &models.Product{Title: "Sto", Language: "sv"}
+	untranslated.Localize("en")
+	assert.Equal(t, "Sto", untranslated.Title)
+	assert.Equal(t, "sv", untranslated.Language)
+}
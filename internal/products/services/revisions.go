@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrNoAutosave       = errors.New("no autosaved edit")
+	ErrInvalidAutosave  = errors.New("autosave content must be a JSON object of at most 256 KB")
+)
+
+const maxAutosaveBytes = 256 << 10
+
+// edit is who saves an edit of a listing.
+type edit struct {
+	editorID string
+	isAdmin  bool
+	// restoredFrom is the version being restored, if any
+	restoredFrom *int
+	// ifMatch are the versions the edit applies to, nil for any
+	ifMatch []time.Time
+}
+
+// editRevision is the new version of an edited listing, nil when the edit
+// changes none of its content. A material edit of a published listing by
+// its seller sends it back to moderation when approval is required.
+func (s *ProductServiceImp) editRevision(ctx context.Context, existing *models.Product, input *models.Product, e edit) (*models.Revision, *models.ListingContent) {
+	before, after := models.ContentOf(existing), models.ContentOf(input)
+	changes := before.Diff(after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	fields := make([]string, len(changes))
+	for i, c := range changes {
+		fields[i] = c.Field
+	}
+	return &models.Revision{
+		ProductID:        existing.ID,
+		Content:          after,
+		ChangedFields:    fields,
+		EditedBy:         parseUserID(e.editorID),
+		RestoredFrom:     e.restoredFrom,
+		RequiresApproval: !e.isAdmin && existing.Status == models.StatusPublished && models.IsMaterial(changes) && s.approvalRequired(ctx),
+	}, &before
+}
+
+// recordCreated stores the first version of a new listing.
+func (s *ProductServiceImp) recordCreated(ctx context.Context, created *models.Product) {
+	rev := &models.Revision{ProductID: created.ID, Content: models.ContentOf(created), ChangedFields: []string{}, EditedBy: &created.UserID}
+	if err := s.repo.AddRevision(ctx, rev, nil); err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to record listing revision", map[string]any{"error": err.Error(), "product_id": created.ID})
+	}
+}
+
+// approvalRequired defaults to requiring approval when the setting can't be read.
+func (s *ProductServiceImp) approvalRequired(ctx context.Context) bool {
+	required, err := s.settingsRepo.IsProductApprovalRequired(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.WarnLevel, "Failed to read approval setting, requiring approval", map[string]any{"error": err.Error()})
+		return true
+	}
+	return required
+}
+
+func parseUserID(userID string) *uuid.UUID {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+// Revisions returns every version of a listing, newest first, to its
+// seller and admins.
+func (s *ProductServiceImp) Revisions(ctx context.Context, id string, userID string, isAdmin bool) ([]*models.Revision, error) {
+	p, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindRevisions(ctx, p.ID.String())
+}
+
+func (s *ProductServiceImp) Revision(ctx context.Context, id string, version int, userID string, isAdmin bool) (*models.Revision, error) {
+	p, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.findRevision(ctx, p.ID.String(), version)
+}
+
+func (s *ProductServiceImp) findRevision(ctx context.Context, productID string, version int) (*models.Revision, error) {
+	rev, err := s.repo.FindRevision(ctx, productID, version)
+	if err != nil {
+		return nil, err
+	}
+	if rev == nil {
+		return nil, ErrRevisionNotFound
+	}
+	return rev, nil
+}
+
+// DiffRevisions compares two versions of a listing. to defaults to the
+// latest version and from to the one before to.
+func (s *ProductServiceImp) DiffRevisions(ctx context.Context, id string, from, to int, userID string, isAdmin bool) (*models.RevisionDiff, error) {
+	revisions, err := s.Revisions(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*models.Revision, len(revisions))
+	for _, rev := range revisions {
+		byVersion[rev.Version] = rev
+	}
+	if to == 0 && len(revisions) > 0 {
+		to = revisions[0].Version
+	}
+	if from == 0 {
+		from = to - 1
+	}
+
+	older, newer := byVersion[from], byVersion[to]
+	if older == nil || newer == nil {
+		return nil, ErrRevisionNotFound
+	}
+	return &models.RevisionDiff{
+		ProductID:   newer.ProductID,
+		FromVersion: from,
+		ToVersion:   to,
+		Changes:     older.Content.Diff(newer.Content),
+	}, nil
+}
+
+// RestoreRevision saves an old version of a listing as its newest one. It
+// is validated, and moderated, like any other edit.
+func (s *ProductServiceImp) RestoreRevision(ctx context.Context, id string, version int, userID string, isAdmin bool) (*models.Product, error) {
+	existing, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := s.findRevision(ctx, existing.ID.String(), version)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &models.Product{}
+	rev.Content.Apply(input)
+	if err := s.prepareUpdate(ctx, existing, input, userID); err != nil {
+		return nil, err
+	}
+	updated, err := s.saveUpdated(ctx, existing, input, edit{editorID: userID, isAdmin: isAdmin, restoredFrom: &version})
+	if err != nil {
+		return nil, err
+	}
+	s.discardAutosave(ctx, existing.ID)
+	return updated, nil
+}
+
+// Autosave keeps the edit in progress of a listing so it survives a closed
+// tab. The content is only checked to be a JSON object.
+func (s *ProductServiceImp) Autosave(ctx context.Context, id string, content json.RawMessage, userID string, isAdmin bool) (*models.Autosave, error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || len(content) > maxAutosaveBytes || content[0] != '{' || !json.Valid(content) {
+		return nil, ErrInvalidAutosave
+	}
+	p, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	author := parseUserID(userID)
+	if author == nil {
+		return nil, ErrUnauthorized
+	}
+
+	autosave := &models.Autosave{ProductID: p.ID, UserID: *author, Content: content}
+	if err := s.repo.SaveAutosave(ctx, autosave); err != nil {
+		return nil, err
+	}
+	return autosave, nil
+}
+
+func (s *ProductServiceImp) FindAutosave(ctx context.Context, id string, userID string, isAdmin bool) (*models.Autosave, error) {
+	p, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	autosave, err := s.repo.FindAutosave(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if autosave == nil {
+		return nil, ErrNoAutosave
+	}
+	return autosave, nil
+}
+
+func (s *ProductServiceImp) DiscardAutosave(ctx context.Context, id string, userID string, isAdmin bool) error {
+	p, err := s.editableProduct(ctx, id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteAutosave(ctx, p.ID)
+}
+
+// discardAutosave drops the edit in progress once an edit is saved.
+func (s *ProductServiceImp) discardAutosave(ctx context.Context, productID uuid.UUID) {
+	if err := s.repo.DeleteAutosave(ctx, productID); err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to discard autosaved edit", map[string]any{"error": err.Error(), "product_id": productID})
+	}
+}
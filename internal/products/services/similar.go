@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+const (
+	defaultSimilarListings = 8
+	maxSimilarListings     = 24
+)
+
+// Similar recommends published listings like the one the viewer is looking at.
+func (s *ProductServiceImp) Similar(ctx context.Context, id string, limit int, viewer models.Viewer) ([]*models.SimilarListing, error) {
+	p, err := s.findVisible(ctx, id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrProductNotFound
+	}
+	if limit <= 0 {
+		limit = defaultSimilarListings
+	}
+	limit = min(limit, maxSimilarListings)
+
+	listings, err := s.repo.FindSimilar(ctx, p, limit)
+	if err != nil {
+		return nil, err
+	}
+	if listings == nil {
+		listings = []*models.SimilarListing{}
+	}
+	return listings, nil
+}
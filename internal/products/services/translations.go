@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+// prepareTranslations sets the listing's language and checks its
+// translations. On edits the language is kept when not given, like the
+// type, while translations left out are removed with the rest of the
+// content.
+func prepareTranslations(p *models.Product, existing *models.Product) error {
+	if p.Language == "" && existing != nil {
+		p.Language = existing.Language
+	}
+	if p.Language == "" {
+		p.Language = models.DefaultLanguage
+	}
+	if !models.IsLanguage(p.Language) {
+		return &models.DetailError{Field: "language", Reason: "must be one of " + strings.Join(models.Languages, ", ")}
+	}
+
+	translations := make(map[string]models.Translation, len(p.Translations))
+	for language, t := range p.Translations {
+		field := "translations." + language
+		switch {
+		case !models.IsLanguage(language):
+			return &models.DetailError{Field: field, Reason: "language must be one of " + strings.Join(models.Languages, ", ")}
+		case language == p.Language:
+			// Switching the listing to a language it was translated to
+			// drops the translation, the title now is in that language
+			if existing != nil && existing.Language != p.Language {
+				continue
+			}
+			return &models.DetailError{Field: field, Reason: "is the listing's own language"}
+		}
+		t.Title = strings.TrimSpace(t.Title)
+		if t.Title == "" {
+			return &models.DetailError{Field: field + ".title", Reason: "is required"}
+		}
+		translations[language] = t
+	}
+	p.Translations = translations
+	return nil
+}
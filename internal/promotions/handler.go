@@ -0,0 +1,101 @@
+package promotions
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+)
+
+type PromotionHandler struct {
+	logger  config.Logging
+	service *PromotionService
+}
+
+func NewPromotionHandler(logger config.Logging, service *PromotionService) *PromotionHandler {
+	return &PromotionHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Create features a listing on behalf of an admin.
+func (h *PromotionHandler) Create(c *gin.Context) {
+	var req CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("product_id, placement and ends_at are required"))
+		return
+	}
+	promotion, err := h.service.Create(c.Request.Context(), &req, c.GetString("user_id"), SourceAdmin)
+	if err != nil {
+		h.respondError(c, err, "Failed to create promotion")
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(promotion))
+}
+
+func (h *PromotionHandler) List(c *gin.Context) {
+	limit, err1 := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	offset, err2 := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err1 != nil || err2 != nil || limit < 0 || offset < 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("invalid limit or offset"))
+		return
+	}
+	promotions, err := h.service.List(c.Request.Context(), PromotionFilters{
+		ProductID: c.Query("product_id"),
+		Status:    Status(c.Query("status")),
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to list promotions")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(promotions))
+}
+
+func (h *PromotionHandler) Get(c *gin.Context) {
+	promotion, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to load promotion")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(promotion))
+}
+
+func (h *PromotionHandler) Cancel(c *gin.Context) {
+	promotion, err := h.service.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to cancel promotion")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(promotion))
+}
+
+// Click is reported by clients when a featured listing is opened.
+func (h *PromotionHandler) Click(c *gin.Context) {
+	if err := h.service.RecordClick(c.Request.Context(), c.Param("id"), analytics.VisitorFromContext(c)); err != nil {
+		h.respondError(c, err, "Failed to record click")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Click recorded"))
+}
+
+func (h *PromotionHandler) respondError(c *gin.Context, err error, message string) {
+	var promotionErr *PromotionError
+	switch {
+	case errors.As(err, &promotionErr), errors.Is(err, ErrNotPromotable):
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, ErrListingNotFound), errors.Is(err, ErrPromotionNotFound):
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, ErrNotActive):
+		c.JSON(http.StatusConflict, common.NewErrorResponse(err.Error()))
+	default:
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, message, map[string]any{"error": err.Error(), "path": c.Request.URL.Path})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse(message))
+	}
+}
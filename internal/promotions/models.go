@@ -0,0 +1,88 @@
+package promotions
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Placement is where a promoted listing is featured.
+type Placement string
+
+const (
+	PlacementFrontPage Placement = "front_page"
+	PlacementCategory  Placement = "category"
+)
+
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusExpired   Status = "expired"
+	StatusCancelled Status = "cancelled"
+)
+
+// Source records who paid for, or granted, the promotion.
+type Source string
+
+const (
+	SourceAdmin   Source = "admin"
+	SourcePayment Source = "payment"
+)
+
+// Promotion features a listing on a placement between StartsAt and EndsAt.
+// An active promotion is only running once StartsAt has passed.
+type Promotion struct {
+	ID          uuid.UUID  `json:"id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	Placement   Placement  `json:"placement"`
+	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	Status      Status     `json:"status"`
+	Source      Source     `json:"source"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	Impressions int64      `json:"impressions"`
+	Clicks      int64      `json:"clicks"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Running reports whether the promotion is featured at now.
+func (p *Promotion) Running(now time.Time) bool {
+	return p.Status == StatusActive && !now.Before(p.StartsAt) && now.Before(p.EndsAt)
+}
+
+type CreatePromotionRequest struct {
+	ProductID string    `json:"product_id" binding:"required"`
+	Placement Placement `json:"placement" binding:"required"`
+	// CategoryID defaults to the listing's category for category placements
+	CategoryID *string `json:"category_id"`
+	// StartsAt defaults to now
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   time.Time  `json:"ends_at" binding:"required"`
+}
+
+type PromotionFilters struct {
+	ProductID string
+	Status    Status
+	Limit     int
+	Offset    int
+}
+
+// Listing is the promoted listing as far as promotions are concerned.
+type Listing struct {
+	ProductID  uuid.UUID
+	CategoryID *uuid.UUID
+	Status     string
+}
+
+// PromotionError rejects an invalid promotion.
+type PromotionError struct {
+	Field  string
+	Reason string
+}
+
+func (e *PromotionError) Error() string {
+	return e.Field + ": " + e.Reason
+}
@@ -0,0 +1,190 @@
+package promotions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	"github.com/hfleury/horsemarketplacebk/internal/db"
+	"github.com/lib/pq"
+)
+
+type PromotionRepository interface {
+	// FindListing returns nil when the listing does not exist or is deleted.
+	FindListing(ctx context.Context, productID string) (*Listing, error)
+	Create(ctx context.Context, p *Promotion) error
+	FindByID(ctx context.Context, id string) (*Promotion, error)
+	FindAll(ctx context.Context, filters PromotionFilters) ([]*Promotion, error)
+	// Cancel reports false when the promotion is not active.
+	Cancel(ctx context.Context, id uuid.UUID) (bool, error)
+	// ExpireEnded marks the active promotions whose window has passed as
+	// expired and returns how many there were.
+	ExpireEnded(ctx context.Context) (int64, error)
+	// AddCounts stores a flushed batch of impressions and clicks, keyed by
+	// promotion id. A batch already stored is skipped.
+	AddCounts(ctx context.Context, batch string, counts []analytics.DailyCount) error
+}
+
+type PromotionRepoPsql struct {
+	logger config.Logging
+	psql   db.Database
+}
+
+func NewPromotionRepoPsql(psql db.Database, logger config.Logging) *PromotionRepoPsql {
+	return &PromotionRepoPsql{
+		psql:   psql,
+		logger: logger,
+	}
+}
+
+const promotionColumns = `id, product_id, placement, category_id, starts_at, ends_at, status, source, created_by, impressions, clicks, created_at, updated_at`
+
+func scanPromotion(row interface{ Scan(...any) error }) (*Promotion, error) {
+	var p Promotion
+	err := row.Scan(&p.ID, &p.ProductID, &p.Placement, &p.CategoryID, &p.StartsAt, &p.EndsAt, &p.Status, &p.Source,
+		&p.CreatedBy, &p.Impressions, &p.Clicks, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PromotionRepoPsql) FindListing(ctx context.Context, productID string) (*Listing, error) {
+	var l Listing
+	err := r.psql.QueryRow(ctx, `SELECT id, category_id, status FROM authentic.products WHERE id = $1 AND status <> 'deleted'`, productID).
+		Scan(&l.ProductID, &l.CategoryID, &l.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *PromotionRepoPsql) Create(ctx context.Context, p *Promotion) error {
+	query := `
+		INSERT INTO authentic.promotions (product_id, placement, category_id, starts_at, ends_at, source, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, status, impressions, clicks, created_at, updated_at
+	`
+	return r.psql.QueryRow(ctx, query, p.ProductID, p.Placement, p.CategoryID, p.StartsAt, p.EndsAt, p.Source, p.CreatedBy).
+		Scan(&p.ID, &p.Status, &p.Impressions, &p.Clicks, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (r *PromotionRepoPsql) FindByID(ctx context.Context, id string) (*Promotion, error) {
+	p, err := scanPromotion(r.psql.QueryRow(ctx, `SELECT `+promotionColumns+` FROM authentic.promotions WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *PromotionRepoPsql) FindAll(ctx context.Context, filters PromotionFilters) ([]*Promotion, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	if filters.ProductID != "" {
+		args = append(args, filters.ProductID)
+		conds = append(conds, fmt.Sprintf("product_id = $%d", len(args)))
+	}
+	if filters.Status != "" {
+		args = append(args, filters.Status)
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	query := `SELECT ` + promotionColumns + ` FROM authentic.promotions`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, filters.Limit, filters.Offset)
+	query += fmt.Sprintf(" ORDER BY starts_at DESC, created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.psql.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []*Promotion{}
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+	return promotions, rows.Err()
+}
+
+func (r *PromotionRepoPsql) Cancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.psql.Execute(ctx, `UPDATE authentic.promotions SET status = 'cancelled', updated_at = NOW() WHERE id = $1 AND status = 'active'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *PromotionRepoPsql) ExpireEnded(ctx context.Context) (int64, error) {
+	res, err := r.psql.Execute(ctx, `UPDATE authentic.promotions SET status = 'expired', updated_at = NOW() WHERE status = 'active' AND ends_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *PromotionRepoPsql) AddCounts(ctx context.Context, batch string, counts []analytics.DailyCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(counts))
+	impressions := make([]int64, len(counts))
+	clicks := make([]int64, len(counts))
+	for i, c := range counts {
+		ids[i] = c.ProductID.String()
+		impressions[i] = int64(c.Impressions)
+		clicks[i] = int64(c.Clicks)
+	}
+
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Batches share the ledger of the listing analytics, their names differ
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO authentic.analytics_flushed_batches (batch) VALUES ($1)
+		ON CONFLICT DO NOTHING
+	`, batch)
+	if err != nil {
+		return err
+	}
+	if stored, _ := result.RowsAffected(); stored == 0 {
+		return nil
+	}
+
+	// A promotion is counted once per day in the batch, hence the sums
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE authentic.promotions p
+		SET impressions = p.impressions + t.impressions, clicks = p.clicks + t.clicks
+		FROM (
+			SELECT id, SUM(impressions) AS impressions, SUM(clicks) AS clicks
+			FROM unnest($1::uuid[], $2::bigint[], $3::bigint[]) AS u(id, impressions, clicks)
+			GROUP BY id
+		) t
+		WHERE p.id = t.id
+	`, pq.Array(ids), pq.Array(impressions), pq.Array(clicks)); err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to store promotion counters", map[string]any{"error": err.Error()})
+		return err
+	}
+	return tx.Commit()
+}
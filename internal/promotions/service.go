@@ -0,0 +1,221 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	"github.com/hibiken/asynq"
+)
+
+var (
+	ErrListingNotFound   = errors.New("listing not found")
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrNotPromotable     = errors.New("only published listings can be promoted")
+	ErrNotActive         = errors.New("the promotion is not active")
+)
+
+const (
+	// maxPromotionDays caps the window of a single promotion
+	maxPromotionDays = 90
+	defaultPageSize  = 50
+	maxPageSize      = 200
+)
+
+type PromotionService struct {
+	repo PromotionRepository
+	// buffer deduplicates impressions and clicks per visitor and holds them
+	// until HandleFlushTask stores them
+	buffer analytics.EventBuffer
+	logger config.Logging
+}
+
+func NewPromotionService(repo PromotionRepository, buffer analytics.EventBuffer, logger config.Logging) *PromotionService {
+	return &PromotionService{
+		repo:   repo,
+		buffer: buffer,
+		logger: logger,
+	}
+}
+
+// Create features a published listing for the requested window. Admins
+// create promotions with SourceAdmin, checkouts with SourcePayment.
+func (s *PromotionService) Create(ctx context.Context, req *CreatePromotionRequest, createdBy string, source Source) (*Promotion, error) {
+	if source != SourceAdmin && source != SourcePayment {
+		return nil, &PromotionError{Field: "source", Reason: "must be admin or payment"}
+	}
+	if _, err := uuid.Parse(req.ProductID); err != nil {
+		return nil, ErrListingNotFound
+	}
+	listing, err := s.repo.FindListing(ctx, req.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		return nil, ErrListingNotFound
+	}
+	if listing.Status != "published" {
+		return nil, ErrNotPromotable
+	}
+
+	promotion := &Promotion{ProductID: listing.ProductID, Placement: req.Placement, Source: source}
+	if err := placePromotion(promotion, listing, req.CategoryID); err != nil {
+		return nil, err
+	}
+	if err := schedulePromotion(promotion, req.StartsAt, req.EndsAt, time.Now()); err != nil {
+		return nil, err
+	}
+	if id, err := uuid.Parse(createdBy); err == nil {
+		promotion.CreatedBy = &id
+	}
+
+	if err := s.repo.Create(ctx, promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func placePromotion(p *Promotion, listing *Listing, categoryID *string) error {
+	switch p.Placement {
+	case PlacementFrontPage:
+		if categoryID != nil {
+			return &PromotionError{Field: "category_id", Reason: "is only allowed for category placements"}
+		}
+	case PlacementCategory:
+		if categoryID == nil {
+			if listing.CategoryID == nil {
+				return &PromotionError{Field: "category_id", Reason: "is required, the listing has no category"}
+			}
+			p.CategoryID = listing.CategoryID
+			return nil
+		}
+		id, err := uuid.Parse(*categoryID)
+		if err != nil {
+			return &PromotionError{Field: "category_id", Reason: "must be a valid id"}
+		}
+		p.CategoryID = &id
+	default:
+		return &PromotionError{Field: "placement", Reason: "must be front_page or category"}
+	}
+	return nil
+}
+
+func schedulePromotion(p *Promotion, startsAt *time.Time, endsAt time.Time, now time.Time) error {
+	p.StartsAt = now
+	if startsAt != nil {
+		p.StartsAt = *startsAt
+	}
+	p.EndsAt = endsAt
+	if !p.EndsAt.After(p.StartsAt) || !p.EndsAt.After(now) {
+		return &PromotionError{Field: "ends_at", Reason: "must be in the future and after starts_at"}
+	}
+	if p.EndsAt.Sub(p.StartsAt) > maxPromotionDays*24*time.Hour {
+		return &PromotionError{Field: "ends_at", Reason: fmt.Sprintf("must be at most %d days after starts_at", maxPromotionDays)}
+	}
+	return nil
+}
+
+func (s *PromotionService) List(ctx context.Context, filters PromotionFilters) ([]*Promotion, error) {
+	if filters.Limit <= 0 {
+		filters.Limit = defaultPageSize
+	}
+	filters.Limit = min(filters.Limit, maxPageSize)
+	return s.repo.FindAll(ctx, filters)
+}
+
+func (s *PromotionService) Get(ctx context.Context, id string) (*Promotion, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPromotionNotFound
+	}
+	promotion, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promotion == nil {
+		return nil, ErrPromotionNotFound
+	}
+	return promotion, nil
+}
+
+// Cancel stops an active promotion, keeping its counters.
+func (s *PromotionService) Cancel(ctx context.Context, id string) (*Promotion, error) {
+	promotion, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cancelled, err := s.repo.Cancel(ctx, promotion.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, ErrNotActive
+	}
+	promotion.Status = StatusCancelled
+	return promotion, nil
+}
+
+// RecordImpressions counts one impression for each promotion shown in a
+// search result, once per visitor and dedup window. Failures are logged,
+// they must not fail the search.
+func (s *PromotionService) RecordImpressions(ctx context.Context, promotionIDs []uuid.UUID, visitor analytics.Visitor) {
+	if len(promotionIDs) == 0 || analytics.IsBot(visitor.UserAgent) {
+		return
+	}
+	for _, id := range promotionIDs {
+		if _, err := s.buffer.Record(ctx, id.String(), visitor, analytics.EventImpression); err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to record promotion impression", map[string]any{"error": err.Error(), "promotion_id": id})
+			return
+		}
+	}
+}
+
+// RecordClick counts a click on a featured listing, once per visitor and
+// dedup window. Promotions that are not running refuse clicks.
+func (s *PromotionService) RecordClick(ctx context.Context, id string, visitor analytics.Visitor) error {
+	promotion, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !promotion.Running(time.Now()) {
+		return ErrNotActive
+	}
+	if analytics.IsBot(visitor.UserAgent) {
+		return nil
+	}
+	_, err = s.buffer.Record(ctx, promotion.ID.String(), visitor, analytics.EventClick)
+	return err
+}
+
+// HandleFlushTask moves the buffered impressions and clicks into Postgres.
+// A batch that fails to store stays in Redis and is retried by the next
+// run, which can't count it twice.
+func (s *PromotionService) HandleFlushTask(ctx context.Context, t *asynq.Task) error {
+	batch, counts, err := s.buffer.Take(ctx)
+	if err != nil {
+		return fmt.Errorf("take promotion batch: %w", err)
+	}
+	if batch == "" {
+		return nil
+	}
+	if err := s.repo.AddCounts(ctx, batch, counts); err != nil {
+		return fmt.Errorf("store promotion batch: %w", err)
+	}
+	return s.buffer.Done(ctx, batch)
+}
+
+// HandleExpireTask marks the promotions whose window has passed as expired.
+// Searches already ignore them, this keeps the status honest.
+func (s *PromotionService) HandleExpireTask(ctx context.Context, t *asynq.Task) error {
+	n, err := s.repo.ExpireEnded(ctx)
+	if err != nil {
+		return fmt.Errorf("expire promotions: %w", err)
+	}
+	if n > 0 {
+		s.logger.Log(ctx, config.InfoLevel, "Expired promotions", map[string]any{"count": n})
+	}
+	return nil
+}
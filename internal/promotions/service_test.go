@@ -0,0 +1,158 @@
+package promotions_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	mockAnalytics "github.com/hfleury/horsemarketplacebk/internal/mocks/analytics"
+	mockPromotions "github.com/hfleury/horsemarketplacebk/internal/mocks/promotions"
+	"github.com/hfleury/horsemarketplacebk/internal/promotions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newService() (*promotions.PromotionService, *mockPromotions.MockPromotionRepo, *mockAnalytics.MockEventBuffer) {
+	repo := new(mockPromotions.MockPromotionRepo)
+	buffer := new(mockAnalytics.MockEventBuffer)
+	return promotions.NewPromotionService(repo, buffer, config.NewZerologService()), repo, buffer
+}
+
+func TestCreate_CategoryDefaultsToListingCategory(t *testing.T) {
+	service, repo, _ := newService()
+	category := uuid.New()
+	listing := &promotions.Listing{ProductID: uuid.New(), CategoryID: &category, Status: "published"}
+	admin := uuid.New()
+	endsAt := time.Now().Add(7 * 24 * time.Hour)
+	repo.On("FindListing", mock.Anything, listing.ProductID.String()).Return(listing, nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(p *promotions.Promotion) bool {
+		return p.ProductID == listing.ProductID && p.Placement == promotions.PlacementCategory && *p.CategoryID == category &&
+			p.Source == promotions.SourceAdmin && *p.CreatedBy == admin && p.EndsAt.Equal(endsAt) && !p.StartsAt.After(time.Now())
+	})).Return(nil)
+
+	_, err := service.Create(context.Background(), &promotions.CreatePromotionRequest{
+		ProductID: listing.ProductID.String(), Placement: promotions.PlacementCategory, EndsAt: endsAt,
+	}, admin.String(), promotions.SourceAdmin)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestCreate_Rejected(t *testing.T) {
+	service, repo, _ := newService()
+	published := &promotions.Listing{ProductID: uuid.New(), Status: "published"}
+	draft := &promotions.Listing{ProductID: uuid.New(), Status: "draft"}
+	repo.On("FindListing", mock.Anything, published.ProductID.String()).Return(published, nil)
+	repo.On("FindListing", mock.Anything, draft.ProductID.String()).Return(draft, nil)
+	repo.On("FindListing", mock.Anything, mock.Anything).Return(nil, nil)
+
+	now := time.Now()
+	category := uuid.NewString()
+	tests := []struct {
+		name string
+		req  promotions.CreatePromotionRequest
+		want string
+	}{
+		{"unknown listing", promotions.CreatePromotionRequest{ProductID: uuid.NewString(), Placement: promotions.PlacementFrontPage, EndsAt: now.Add(time.Hour)}, promotions.ErrListingNotFound.Error()},
+		{"draft", promotions.CreatePromotionRequest{ProductID: draft.ProductID.String(), Placement: promotions.PlacementFrontPage, EndsAt: now.Add(time.Hour)}, promotions.ErrNotPromotable.Error()},
+		{"placement", promotions.CreatePromotionRequest{ProductID: published.ProductID.String(), Placement: "sidebar", EndsAt: now.Add(time.Hour)}, "placement"},
+		{"front page category", promotions.CreatePromotionRequest{ProductID: published.ProductID.String(), Placement: promotions.PlacementFrontPage, CategoryID: &category, EndsAt: now.Add(time.Hour)}, "category_id"},
+		{"no category", promotions.CreatePromotionRequest{ProductID: published.ProductID.String(), Placement: promotions.PlacementCategory, EndsAt: now.Add(time.Hour)}, "category_id"},
+		{"ended", promotions.CreatePromotionRequest{ProductID: published.ProductID.String(), Placement: promotions.PlacementFrontPage, EndsAt: now.Add(-time.Hour)}, "ends_at"},
+		{"too long", promotions.CreatePromotionRequest{ProductID: published.ProductID.String(), Placement: promotions.PlacementFrontPage, EndsAt: now.Add(100 * 24 * time.Hour)}, "ends_at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Create(context.Background(), &tt.req, "", promotions.SourcePayment)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.want)
+			}
+		})
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCancel_OnlyActive(t *testing.T) {
+	service, repo, _ := newService()
+	promotion := &promotions.Promotion{ID: uuid.New(), Status: promotions.StatusExpired}
+	repo.On("FindByID", mock.Anything, promotion.ID.String()).Return(promotion, nil)
+	repo.On("Cancel", mock.Anything, promotion.ID).Return(false, nil)
+
+	_, err := service.Cancel(context.Background(), promotion.ID.String())
+	assert.ErrorIs(t, err, promotions.ErrNotActive)
+
+	_, err = service.Cancel(context.Background(), "not-an-id")
+	assert.ErrorIs(t, err, promotions.ErrPromotionNotFound)
+}
+
+func TestRecordImpressions_IgnoresBots(t *testing.T) {
+	service, _, buffer := newService()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	visitor := analytics.Visitor{UserAgent: "Mozilla/5.0"}
+	buffer.On("Record", mock.Anything, ids[0].String(), visitor, analytics.EventImpression).Return(true, nil).Once()
+	buffer.On("Record", mock.Anything, ids[1].String(), visitor, analytics.EventImpression).Return(false, nil).Once()
+
+	service.RecordImpressions(context.Background(), ids, analytics.Visitor{UserAgent: "Googlebot/2.1"})
+	service.RecordImpressions(context.Background(), nil, visitor)
+	service.RecordImpressions(context.Background(), ids, visitor)
+
+	buffer.AssertExpectations(t)
+}
+
+func TestRecordClick(t *testing.T) {
+	service, repo, buffer := newService()
+	now := time.Now()
+	promotion := &promotions.Promotion{ID: uuid.New(), Status: promotions.StatusActive, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}
+	visitor := analytics.Visitor{UserAgent: "Mozilla/5.0"}
+	repo.On("FindByID", mock.Anything, promotion.ID.String()).Return(promotion, nil)
+	repo.On("FindByID", mock.Anything, mock.Anything).Return(nil, nil)
+	buffer.On("Record", mock.Anything, promotion.ID.String(), visitor, analytics.EventClick).Return(true, nil).Once()
+
+	assert.NoError(t, service.RecordClick(context.Background(), promotion.ID.String(), visitor))
+	assert.NoError(t, service.RecordClick(context.Background(), promotion.ID.String(), analytics.Visitor{UserAgent: "curl/8.0"}))
+	assert.ErrorIs(t, service.RecordClick(context.Background(), uuid.NewString(), analytics.Visitor{}), promotions.ErrPromotionNotFound)
+
+	buffer.AssertExpectations(t)
+}
+
+func TestRecordClick_RefusesPromotionsNotRunning(t *testing.T) {
+	service, repo, buffer := newService()
+	now := time.Now()
+	for _, promotion := range []*promotions.Promotion{
+		{ID: uuid.New(), Status: promotions.StatusCancelled, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+		{ID: uuid.New(), Status: promotions.StatusActive, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)},
+		{ID: uuid.New(), Status: promotions.StatusActive, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+	} {
+		repo.On("FindByID", mock.Anything, promotion.ID.String()).Return(promotion, nil)
+		assert.ErrorIs(t, service.RecordClick(context.Background(), promotion.ID.String(), analytics.Visitor{UserAgent: "Mozilla/5.0"}), promotions.ErrNotActive)
+	}
+	buffer.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleFlushTask_KeepsBatchOnFailure(t *testing.T) {
+	service, repo, buffer := newService()
+	counts := []analytics.DailyCount{{ProductID: uuid.New(), Impressions: 4, Clicks: 1}}
+	buffer.On("Take", mock.Anything).Return("promotions:flushing:1", counts, nil)
+	repo.On("AddCounts", mock.Anything, "promotions:flushing:1", counts).Return(errors.New("db down")).Once()
+	repo.On("AddCounts", mock.Anything, "promotions:flushing:1", counts).Return(nil).Once()
+	buffer.On("Done", mock.Anything, "promotions:flushing:1").Return(nil).Once()
+
+	assert.Error(t, service.HandleFlushTask(context.Background(), nil))
+	buffer.AssertNotCalled(t, "Done", mock.Anything, mock.Anything)
+	assert.NoError(t, service.HandleFlushTask(context.Background(), nil))
+
+	repo.AssertExpectations(t)
+	buffer.AssertExpectations(t)
+}
+
+func TestHandleExpireTask(t *testing.T) {
+	service, repo, _ := newService()
+	repo.On("ExpireEnded", mock.Anything).Return(int64(2), nil)
+
+	assert.NoError(t, service.HandleExpireTask(context.Background(), nil))
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,94 @@
+package reports
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+)
+
+type ReportHandler struct {
+	logger  config.Logging
+	service *ReportService
+}
+
+func NewReportHandler(logger config.Logging, service *ReportService) *ReportHandler {
+	return &ReportHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Report files the caller's report of a listing.
+func (h *ReportHandler) Report(c *gin.Context) {
+	var req CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("A reason is required"))
+		return
+	}
+	report, err := h.service.Report(c.Request.Context(), c.Param("id"), c.GetString("user_id"), &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to report listing")
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(report))
+}
+
+// Queue is the triage view of listings with open reports.
+func (h *ReportHandler) Queue(c *gin.Context) {
+	limit, err1 := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	offset, err2 := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err1 != nil || err2 != nil || limit < 0 || offset < 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("invalid limit or offset"))
+		return
+	}
+	queue, err := h.service.Queue(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.respondError(c, err, "Failed to list reports")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(queue))
+}
+
+func (h *ReportHandler) Listing(c *gin.Context) {
+	reports, err := h.service.ListingReports(c.Request.Context(), c.Param("productId"))
+	if err != nil {
+		h.respondError(c, err, "Failed to load reports")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(reports))
+}
+
+func (h *ReportHandler) Resolve(c *gin.Context) {
+	var req ResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(ErrInvalidAction.Error()))
+		return
+	}
+	resolved, err := h.service.Resolve(c.Request.Context(), c.Param("productId"), c.GetString("user_id"), &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to resolve reports")
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(resolved))
+}
+
+func (h *ReportHandler) respondError(c *gin.Context, err error, message string) {
+	var reportErr *ReportError
+	switch {
+	case errors.As(err, &reportErr), errors.Is(err, ErrInvalidAction), errors.Is(err, ErrOwnListing):
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, ErrListingNotFound):
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, ErrAlreadyReported), errors.Is(err, ErrNoOpenReports):
+		c.JSON(http.StatusConflict, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, ErrRateLimited):
+		c.JSON(http.StatusTooManyRequests, common.NewErrorResponse(err.Error()))
+	default:
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, message, map[string]any{"error": err.Error(), "path": c.Request.URL.Path})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse(message))
+	}
+}
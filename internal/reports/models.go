@@ -0,0 +1,96 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Reason string
+
+const (
+	ReasonScam           Reason = "scam"
+	ReasonAnimalWelfare  Reason = "animal_welfare"
+	ReasonMiscategorised Reason = "miscategorised"
+	ReasonProhibited     Reason = "prohibited"
+	ReasonOffensive      Reason = "offensive"
+	ReasonOther          Reason = "other"
+)
+
+var Reasons = []Reason{ReasonScam, ReasonAnimalWelfare, ReasonMiscategorised, ReasonProhibited, ReasonOffensive, ReasonOther}
+
+type Status string
+
+const (
+	StatusOpen     Status = "open"
+	StatusResolved Status = "resolved"
+)
+
+// Action is what staff decided about a reported listing.
+type Action string
+
+const (
+	ActionDismiss  Action = "dismiss"   // nothing wrong, the listing is shown again
+	ActionWarn     Action = "warn"      // the seller is warned, the listing is shown again
+	ActionTakeDown Action = "take_down" // the listing is archived and stays hidden
+)
+
+// Report is one user's report of a listing.
+type Report struct {
+	ID             uuid.UUID  `json:"id"`
+	ProductID      uuid.UUID  `json:"product_id"`
+	ReporterID     uuid.UUID  `json:"reporter_id"`
+	Reason         Reason     `json:"reason"`
+	Details        *string    `json:"details,omitempty"`
+	Status         Status     `json:"status"`
+	Resolution     *Action    `json:"resolution,omitempty"`
+	ResolutionNote *string    `json:"resolution_note,omitempty"`
+	ResolvedBy     *uuid.UUID `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	// ReporterEmail is only used to send the outcome
+	ReporterEmail string `json:"-"`
+}
+
+type CreateReportRequest struct {
+	Reason  Reason `json:"reason" binding:"required"`
+	Details string `json:"details"`
+}
+
+type ResolveRequest struct {
+	Action Action `json:"action" binding:"required"`
+	// Note is included in the emails to the reporters and the seller
+	Note string `json:"note"`
+}
+
+// Listing is a reported listing as staff see it.
+type Listing struct {
+	ProductID   uuid.UUID  `json:"product_id"`
+	Title       string     `json:"title"`
+	Status      string     `json:"status"`
+	SellerID    uuid.UUID  `json:"seller_id"`
+	SellerEmail string     `json:"-"`
+	HiddenAt    *time.Time `json:"hidden_at,omitempty"`
+}
+
+// ListingReports aggregates the open reports of a listing for triage.
+type ListingReports struct {
+	Listing
+	Reports   int            `json:"reports"`
+	Reporters int            `json:"reporters"`
+	Reasons   map[Reason]int `json:"reasons"`
+	FirstAt   time.Time      `json:"first_reported_at"`
+	LatestAt  time.Time      `json:"latest_reported_at"`
+	// Open is only filled in when a single listing is looked at
+	Open []Report `json:"open,omitempty"`
+}
+
+// ReportError rejects an invalid report.
+type ReportError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ReportError) Error() string {
+	return e.Field + ": " + e.Reason
+}
@@ -0,0 +1,223 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/db"
+)
+
+type ReportRepository interface {
+	// FindListing returns nil when the listing does not exist or is deleted.
+	FindListing(ctx context.Context, productID string) (*Listing, error)
+	// CountSince counts the reports the user made since the given time.
+	CountSince(ctx context.Context, reporterID uuid.UUID, since time.Time) (int, error)
+	// Create reports false when the user already has an open report of the listing.
+	Create(ctx context.Context, r *Report) (bool, error)
+	// CountOpenReporters counts the users with an open report of the listing.
+	CountOpenReporters(ctx context.Context, productID uuid.UUID) (int, error)
+	// Hide takes a published listing out of view until an admin reviews it.
+	// It reports false when the listing was not published.
+	Hide(ctx context.Context, productID uuid.UUID) (bool, error)
+	// FindQueue pages through the listings with open reports, hidden ones
+	// and those reported by the most users first.
+	FindQueue(ctx context.Context, limit, offset int) ([]*ListingReports, error)
+	FindOpen(ctx context.Context, productID uuid.UUID) ([]Report, error)
+	// Resolve closes the open reports of a listing and applies the action to
+	// it in one transaction. It returns the reports it closed.
+	Resolve(ctx context.Context, productID uuid.UUID, action Action, note *string, resolvedBy uuid.UUID) ([]Report, error)
+}
+
+type ReportRepoPsql struct {
+	logger config.Logging
+	psql   db.Database
+}
+
+func NewReportRepoPsql(psql db.Database, logger config.Logging) *ReportRepoPsql {
+	return &ReportRepoPsql{
+		psql:   psql,
+		logger: logger,
+	}
+}
+
+func (r *ReportRepoPsql) FindListing(ctx context.Context, productID string) (*Listing, error) {
+	query := `
+		SELECT p.id, p.title, p.status, p.user_id, u.email, p.hidden_at
+		FROM authentic.products p
+		JOIN authentic.users u ON u.id = p.user_id
+		WHERE p.id = $1 AND p.status <> 'deleted'
+	`
+	var l Listing
+	err := r.psql.QueryRow(ctx, query, productID).Scan(&l.ProductID, &l.Title, &l.Status, &l.SellerID, &l.SellerEmail, &l.HiddenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *ReportRepoPsql) CountSince(ctx context.Context, reporterID uuid.UUID, since time.Time) (int, error) {
+	var n int
+	err := r.psql.QueryRow(ctx, `SELECT COUNT(*) FROM authentic.listing_reports WHERE reporter_id = $1 AND created_at >= $2`, reporterID, since).Scan(&n)
+	return n, err
+}
+
+func (r *ReportRepoPsql) Create(ctx context.Context, report *Report) (bool, error) {
+	query := `
+		INSERT INTO authentic.listing_reports (product_id, reporter_id, reason, details)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (product_id, reporter_id) WHERE status = 'open' DO NOTHING
+		RETURNING id, status, created_at
+	`
+	err := r.psql.QueryRow(ctx, query, report.ProductID, report.ReporterID, report.Reason, report.Details).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *ReportRepoPsql) CountOpenReporters(ctx context.Context, productID uuid.UUID) (int, error) {
+	var n int
+	err := r.psql.QueryRow(ctx, `SELECT COUNT(DISTINCT reporter_id) FROM authentic.listing_reports WHERE product_id = $1 AND status = 'open'`, productID).Scan(&n)
+	return n, err
+}
+
+func (r *ReportRepoPsql) Hide(ctx context.Context, productID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE authentic.products
+		SET status = 'pending_approval', hidden_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'published'
+	`
+	res, err := r.psql.Execute(ctx, query, productID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *ReportRepoPsql) FindQueue(ctx context.Context, limit, offset int) ([]*ListingReports, error) {
+	query := `
+		SELECT p.id, p.title, p.status, p.user_id, p.hidden_at,
+			COUNT(*), COUNT(DISTINCT lr.reporter_id), MIN(lr.created_at), MAX(lr.created_at),
+			(SELECT json_object_agg(reason, n) FROM (
+				SELECT reason, COUNT(*) AS n FROM authentic.listing_reports
+				WHERE product_id = p.id AND status = 'open' GROUP BY reason
+			) reasons)
+		FROM authentic.listing_reports lr
+		JOIN authentic.products p ON p.id = lr.product_id
+		WHERE lr.status = 'open'
+		GROUP BY p.id
+		ORDER BY p.hidden_at IS NULL, COUNT(DISTINCT lr.reporter_id) DESC, MIN(lr.created_at)
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := r.psql.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := []*ListingReports{}
+	for rows.Next() {
+		lr := &ListingReports{}
+		var reasons []byte
+		if err := rows.Scan(&lr.ProductID, &lr.Title, &lr.Status, &lr.SellerID, &lr.HiddenAt,
+			&lr.Reports, &lr.Reporters, &lr.FirstAt, &lr.LatestAt, &reasons); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(reasons, &lr.Reasons); err != nil {
+			return nil, err
+		}
+		queue = append(queue, lr)
+	}
+	return queue, rows.Err()
+}
+
+const reportColumns = `lr.id, lr.product_id, lr.reporter_id, lr.reason, lr.details, lr.status,
+	lr.resolution, lr.resolution_note, lr.resolved_by, lr.resolved_at, lr.created_at, u.email`
+
+func (r *ReportRepoPsql) FindOpen(ctx context.Context, productID uuid.UUID) ([]Report, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM authentic.listing_reports lr
+		JOIN authentic.users u ON u.id = lr.reporter_id
+		WHERE lr.product_id = $1 AND lr.status = 'open'
+		ORDER BY lr.created_at
+	`
+	rows, err := r.psql.Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	return scanReports(rows)
+}
+
+func (r *ReportRepoPsql) Resolve(ctx context.Context, productID uuid.UUID, action Action, note *string, resolvedBy uuid.UUID) ([]Report, error) {
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH lr AS (
+			UPDATE authentic.listing_reports
+			SET status = 'resolved', resolution = $2, resolution_note = $3, resolved_by = $4, resolved_at = NOW()
+			WHERE product_id = $1 AND status = 'open'
+			RETURNING *
+		)
+		SELECT `+reportColumns+`
+		FROM lr
+		JOIN authentic.users u ON u.id = lr.reporter_id
+		ORDER BY lr.created_at
+	`, productID, action, note, resolvedBy)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := scanReports(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var query string
+	switch action {
+	case ActionTakeDown:
+		query = `
+			UPDATE authentic.products
+			SET status = 'archived', hidden_at = COALESCE(hidden_at, NOW()), updated_at = NOW()
+			WHERE id = $1 AND status <> 'deleted'
+		`
+	default:
+		// A listing hidden by the reports goes back up as it was
+		query = `
+			UPDATE authentic.products
+			SET status = 'published', hidden_at = NULL, updated_at = NOW()
+			WHERE id = $1 AND status = 'pending_approval' AND hidden_at IS NOT NULL
+		`
+	}
+	if _, err := tx.ExecContext(ctx, query, productID); err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to apply report resolution", map[string]any{"error": err.Error(), "product_id": productID.String()})
+		return nil, err
+	}
+	return resolved, tx.Commit()
+}
+
+func scanReports(rows *sql.Rows) ([]Report, error) {
+	defer rows.Close()
+	var reports []Report
+	for rows.Next() {
+		var rp Report
+		if err := rows.Scan(&rp.ID, &rp.ProductID, &rp.ReporterID, &rp.Reason, &rp.Details, &rp.Status,
+			&rp.Resolution, &rp.ResolutionNote, &rp.ResolvedBy, &rp.ResolvedAt, &rp.CreatedAt, &rp.ReporterEmail); err != nil {
+			return nil, err
+		}
+		reports = append(reports, rp)
+	}
+	return reports, rows.Err()
+}
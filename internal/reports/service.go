@@ -0,0 +1,278 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/email"
+	"github.com/hfleury/horsemarketplacebk/internal/system"
+)
+
+var (
+	ErrListingNotFound = errors.New("listing not found")
+	ErrOwnListing      = errors.New("you cannot report your own listing")
+	ErrAlreadyReported = errors.New("you have already reported this listing")
+	ErrRateLimited     = errors.New("you have sent too many reports, please try again later")
+	ErrNoOpenReports   = errors.New("the listing has no open reports")
+	ErrInvalidAction   = errors.New("action must be dismiss, warn or take_down")
+)
+
+const (
+	// reportsPerDay is how many listings a user may report in 24 hours
+	reportsPerDay    = 10
+	maxDetailsRunes  = 2000
+	defaultQueueSize = 50
+	maxQueueSize     = 200
+)
+
+type ReportService struct {
+	repo     ReportRepository
+	settings system.SettingsRepository
+	sender   email.Sender
+	logger   config.Logging
+}
+
+func NewReportService(repo ReportRepository, settings system.SettingsRepository, logger config.Logging) *ReportService {
+	return &ReportService{
+		repo:     repo,
+		settings: settings,
+		logger:   logger,
+	}
+}
+
+// SetEmailSender enables telling reporters and sellers about hidden listings
+// and resolved reports.
+func (s *ReportService) SetEmailSender(sender email.Sender) {
+	s.sender = sender
+}
+
+// Report records a user's report of a published or sold listing. Once
+// enough users have open reports the listing is hidden until reviewed.
+func (s *ReportService) Report(ctx context.Context, productID string, reporterID string, req *CreateReportRequest) (*Report, error) {
+	reporter, err := uuid.Parse(reporterID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+	report, err := newReport(req)
+	if err != nil {
+		return nil, err
+	}
+
+	listing, err := s.findListing(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.Status != "published" && listing.Status != "sold" {
+		return nil, ErrListingNotFound
+	}
+	if listing.SellerID == reporter {
+		return nil, ErrOwnListing
+	}
+
+	recent, err := s.repo.CountSince(ctx, reporter, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	if recent >= reportsPerDay {
+		return nil, ErrRateLimited
+	}
+
+	report.ProductID, report.ReporterID = listing.ProductID, reporter
+	created, err := s.repo.Create(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAlreadyReported
+	}
+
+	if err := s.hideIfReportedEnough(ctx, listing); err != nil {
+		// The report itself is stored, staff will still see it
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to check report threshold", map[string]any{"error": err.Error(), "product_id": productID})
+	}
+	return report, nil
+}
+
+func (s *ReportService) findListing(ctx context.Context, productID string) (*Listing, error) {
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, ErrListingNotFound
+	}
+	listing, err := s.repo.FindListing(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		return nil, ErrListingNotFound
+	}
+	return listing, nil
+}
+
+func newReport(req *CreateReportRequest) (*Report, error) {
+	if !slices.Contains(Reasons, req.Reason) {
+		return nil, &ReportError{Field: "reason", Reason: "must be one of scam, animal_welfare, miscategorised, prohibited, offensive or other"}
+	}
+	details := strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(details) > maxDetailsRunes {
+		return nil, &ReportError{Field: "details", Reason: fmt.Sprintf("must be at most %d characters", maxDetailsRunes)}
+	}
+	if details == "" && req.Reason == ReasonOther {
+		return nil, &ReportError{Field: "details", Reason: "is required when the reason is other"}
+	}
+	report := &Report{Reason: req.Reason}
+	if details != "" {
+		report.Details = &details
+	}
+	return report, nil
+}
+
+func (s *ReportService) hideIfReportedEnough(ctx context.Context, listing *Listing) error {
+	if listing.Status != "published" {
+		return nil
+	}
+	threshold, err := s.settings.ReportHideThreshold(ctx)
+	if err != nil {
+		s.logger.Log(ctx, config.WarnLevel, "Failed to read report threshold, using default", map[string]any{"error": err.Error()})
+	}
+	reporters, err := s.repo.CountOpenReporters(ctx, listing.ProductID)
+	if err != nil || reporters < threshold {
+		return err
+	}
+	hidden, err := s.repo.Hide(ctx, listing.ProductID)
+	if err != nil || !hidden {
+		return err
+	}
+	s.logger.Log(ctx, config.InfoLevel, "Listing hidden after reports", map[string]any{"product_id": listing.ProductID.String(), "reporters": reporters})
+	s.send(ctx, listing.SellerEmail, fmt.Sprintf("\"%s\" is hidden pending review", listing.Title),
+		fmt.Sprintf("Hello,\n\nYour listing \"%s\" has been reported by several users and is hidden until our staff have reviewed it. You will hear from us once they have.\n\n/products/%s",
+			listing.Title, listing.ProductID))
+	return nil
+}
+
+// Queue pages through the listings with open reports, for staff.
+func (s *ReportService) Queue(ctx context.Context, limit, offset int) ([]*ListingReports, error) {
+	if limit <= 0 {
+		limit = defaultQueueSize
+	}
+	return s.repo.FindQueue(ctx, min(limit, maxQueueSize), offset)
+}
+
+// ListingReports returns a listing with its open reports.
+func (s *ReportService) ListingReports(ctx context.Context, productID string) (*ListingReports, error) {
+	listing, err := s.findListing(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	open, err := s.repo.FindOpen(ctx, listing.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(listing, open), nil
+}
+
+func aggregate(listing *Listing, open []Report) *ListingReports {
+	lr := &ListingReports{Listing: *listing, Reasons: map[Reason]int{}, Open: open}
+	reporters := make(map[uuid.UUID]bool)
+	for i, r := range open {
+		lr.Reports++
+		lr.Reasons[r.Reason]++
+		reporters[r.ReporterID] = true
+		if i == 0 || r.CreatedAt.Before(lr.FirstAt) {
+			lr.FirstAt = r.CreatedAt
+		}
+		if r.CreatedAt.After(lr.LatestAt) {
+			lr.LatestAt = r.CreatedAt
+		}
+	}
+	lr.Reporters = len(reporters)
+	return lr
+}
+
+// Resolve closes every open report of a listing with the staff's decision
+// and emails the outcome to the reporters and the seller.
+func (s *ReportService) Resolve(ctx context.Context, productID string, adminID string, req *ResolveRequest) ([]Report, error) {
+	switch req.Action {
+	case ActionDismiss, ActionWarn, ActionTakeDown:
+	default:
+		return nil, ErrInvalidAction
+	}
+	admin, err := uuid.Parse(adminID)
+	if err != nil {
+		return nil, err
+	}
+	listing, err := s.findListing(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var note *string
+	if n := strings.TrimSpace(req.Note); n != "" {
+		note = &n
+	}
+	resolved, err := s.repo.Resolve(ctx, listing.ProductID, req.Action, note, admin)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolved) == 0 {
+		return nil, ErrNoOpenReports
+	}
+
+	for _, r := range resolved {
+		subject, body := reporterEmail(listing, req.Action, note)
+		s.send(ctx, r.ReporterEmail, subject, body)
+	}
+	subject, body := sellerEmail(listing, req.Action, note)
+	s.send(ctx, listing.SellerEmail, subject, body)
+	return resolved, nil
+}
+
+func reporterEmail(listing *Listing, action Action, note *string) (string, string) {
+	var outcome string
+	switch action {
+	case ActionTakeDown:
+		outcome = "The listing broke our rules and has been taken down."
+	case ActionWarn:
+		outcome = "The seller has been warned and asked to correct the listing."
+	default:
+		outcome = "Our staff found no breach of our rules, so the listing stays up."
+	}
+	return fmt.Sprintf("Your report of \"%s\"", listing.Title),
+		fmt.Sprintf("Hello,\n\nThank you for reporting \"%s\". %s%s\n\n/products/%s", listing.Title, outcome, noteLine(note), listing.ProductID)
+}
+
+func sellerEmail(listing *Listing, action Action, note *string) (string, string) {
+	switch action {
+	case ActionTakeDown:
+		return fmt.Sprintf("\"%s\" has been taken down", listing.Title),
+			fmt.Sprintf("Hello,\n\nYour listing \"%s\" was reported and our staff found it breaks our rules, so it has been taken down.%s\n\n/products/%s", listing.Title, noteLine(note), listing.ProductID)
+	case ActionWarn:
+		return fmt.Sprintf("A warning about \"%s\"", listing.Title),
+			fmt.Sprintf("Hello,\n\nYour listing \"%s\" was reported and our staff ask you to correct it. Repeated breaches can get your listings taken down.%s\n\n/products/%s", listing.Title, noteLine(note), listing.ProductID)
+	default:
+		return fmt.Sprintf("Reports of \"%s\" dismissed", listing.Title),
+			fmt.Sprintf("Hello,\n\nYour listing \"%s\" was reported, but our staff found no breach of our rules. No action is needed.%s\n\n/products/%s", listing.Title, noteLine(note), listing.ProductID)
+	}
+}
+
+func noteLine(note *string) string {
+	if note == nil {
+		return ""
+	}
+	return "\n\nNote from our staff: " + *note
+}
+
+// send never fails the caller, a lost notification is only logged.
+func (s *ReportService) send(ctx context.Context, to, subject, body string) {
+	if s.sender == nil || to == "" {
+		return
+	}
+	if err := s.sender.Send(ctx, to, subject, body); err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to send report email", map[string]any{"error": err.Error(), "subject": subject})
+	}
+}
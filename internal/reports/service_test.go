@@ -0,0 +1,124 @@
+package reports_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	mockReports "github.com/hfleury/horsemarketplacebk/internal/mocks/reports"
+	mockSystem "github.com/hfleury/horsemarketplacebk/internal/mocks/system"
+	"github.com/hfleury/horsemarketplacebk/internal/reports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type sentEmail struct{ to, subject, body string }
+
+// recordingSender keeps every email sent.
+type recordingSender struct {
+	sent []sentEmail
+}
+
+func (r *recordingSender) Send(ctx context.Context, to, subject, body string) error {
+	r.sent = append(r.sent, sentEmail{to, subject, body})
+	return nil
+}
+
+func newService() (*reports.ReportService, *mockReports.MockReportRepo, *mockSystem.MockSettingsRepo, *recordingSender) {
+	repo, settings, sender := new(mockReports.MockReportRepo), new(mockSystem.MockSettingsRepo), &recordingSender{}
+	service := reports.NewReportService(repo, settings, config.NewZerologService())
+	service.SetEmailSender(sender)
+	return service, repo, settings, sender
+}
+
+func publishedListing() *reports.Listing {
+	return &reports.Listing{ProductID: uuid.New(), Title: "Bay gelding", Status: "published", SellerID: uuid.New(), SellerEmail: "seller@example.com"}
+}
+
+func TestReport_HidesListingAtThreshold(t *testing.T) {
+	service, repo, settings, sender := newService()
+	listing := publishedListing()
+	reporter := uuid.New()
+	repo.On("FindListing", mock.Anything, listing.ProductID.String()).Return(listing, nil)
+	repo.On("CountSince", mock.Anything, reporter, mock.Anything).Return(2, nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(r *reports.Report) bool {
+		return r.ProductID == listing.ProductID && r.ReporterID == reporter && r.Reason == reports.ReasonScam && *r.Details == "Asks for payment upfront"
+	})).Return(true, nil)
+	settings.On("ReportHideThreshold", mock.Anything).Return(3, nil)
+	repo.On("CountOpenReporters", mock.Anything, listing.ProductID).Return(3, nil)
+	repo.On("Hide", mock.Anything, listing.ProductID).Return(true, nil)
+
+	_, err := service.Report(context.Background(), listing.ProductID.String(), reporter.String(), &reports.CreateReportRequest{
+		Reason: reports.ReasonScam, Details: "  Asks for payment upfront ",
+	})
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	if assert.Len(t, sender.sent, 1) {
+		assert.Equal(t, "seller@example.com", sender.sent[0].to)
+		assert.Contains(t, sender.sent[0].subject, "hidden pending review")
+	}
+}
+
+func TestReport_Rejected(t *testing.T) {
+	service, repo, _, _ := newService()
+	listing := publishedListing()
+	draft := &reports.Listing{ProductID: uuid.New(), Status: "draft", SellerID: uuid.New()}
+	limited, repeat := uuid.New(), uuid.New()
+	repo.On("FindListing", mock.Anything, listing.ProductID.String()).Return(listing, nil)
+	repo.On("FindListing", mock.Anything, draft.ProductID.String()).Return(draft, nil)
+	repo.On("CountSince", mock.Anything, limited, mock.Anything).Return(10, nil)
+	repo.On("CountSince", mock.Anything, repeat, mock.Anything).Return(0, nil)
+	repo.On("Create", mock.Anything, mock.Anything).Return(false, nil)
+
+	scam := &reports.CreateReportRequest{Reason: reports.ReasonScam}
+	ctx := context.Background()
+	_, err := service.Report(ctx, listing.ProductID.String(), listing.SellerID.String(), scam)
+	assert.ErrorIs(t, err, reports.ErrOwnListing)
+	_, err = service.Report(ctx, listing.ProductID.String(), limited.String(), scam)
+	assert.ErrorIs(t, err, reports.ErrRateLimited)
+	_, err = service.Report(ctx, listing.ProductID.String(), repeat.String(), scam)
+	assert.ErrorIs(t, err, reports.ErrAlreadyReported)
+	_, err = service.Report(ctx, draft.ProductID.String(), repeat.String(), scam)
+	assert.ErrorIs(t, err, reports.ErrListingNotFound)
+	_, err = service.Report(ctx, "not-a-listing", repeat.String(), scam)
+	assert.ErrorIs(t, err, reports.ErrListingNotFound)
+
+	var reportErr *reports.ReportError
+	_, err = service.Report(ctx, listing.ProductID.String(), repeat.String(), &reports.CreateReportRequest{Reason: reports.ReasonOther})
+	if assert.ErrorAs(t, err, &reportErr) {
+		assert.Equal(t, "details", reportErr.Field)
+	}
+	_, err = service.Report(ctx, listing.ProductID.String(), repeat.String(), &reports.CreateReportRequest{Reason: "spam"})
+	if assert.ErrorAs(t, err, &reportErr) {
+		assert.Equal(t, "reason", reportErr.Field)
+	}
+}
+
+func TestResolve_TakeDownNotifiesReportersAndSeller(t *testing.T) {
+	service, repo, _, sender := newService()
+	listing := publishedListing()
+	adminID := uuid.New()
+	repo.On("FindListing", mock.Anything, listing.ProductID.String()).Return(listing, nil)
+	repo.On("Resolve", mock.Anything, listing.ProductID, reports.ActionTakeDown, mock.MatchedBy(func(note *string) bool {
+		return note != nil && *note == "Sold elsewhere as a different horse"
+	}), adminID).Return([]reports.Report{{ReporterEmail: "a@example.com"}, {ReporterEmail: "b@example.com"}}, nil)
+
+	resolved, err := service.Resolve(context.Background(), listing.ProductID.String(), adminID.String(), &reports.ResolveRequest{
+		Action: reports.ActionTakeDown, Note: "Sold elsewhere as a different horse",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, resolved, 2)
+	if assert.Len(t, sender.sent, 3) {
+		assert.Equal(t, "a@example.com", sender.sent[0].to)
+		assert.Contains(t, sender.sent[0].body, "taken down")
+		assert.Equal(t, "seller@example.com", sender.sent[2].to)
+		assert.True(t, strings.Contains(sender.sent[2].body, "Sold elsewhere as a different horse"))
+	}
+
+	_, err = service.Resolve(context.Background(), listing.ProductID.String(), adminID.String(), &reports.ResolveRequest{Action: "ban"})
+	assert.ErrorIs(t, err, reports.ErrInvalidAction)
+}
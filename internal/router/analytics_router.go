@@ -0,0 +1,23 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
+	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
+	"github.com/hfleury/horsemarketplacebk/internal/middleware"
+)
+
+func registerAnalyticsRoutes(router *gin.Engine, logger config.Logging, statsService *analytics.StatsService, tokenService *services.TokenService) {
+	handler := analytics.NewStatsHandler(logger, statsService)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
+
+	// Public, signed in visitors are deduplicated by user id
+	router.POST("/products/:id/contact-click", authMiddleware.OptionalAuth(), handler.ContactClick)
+
+	me := router.Group("/api/v1/me/products")
+	me.Use(authMiddleware.RequireAuth())
+	{
+		me.GET("/:id/stats", handler.ProductStats)
+	}
+}
@@ -20,6 +20,8 @@ func registerCategoryRoutes(router *gin.Engine, logger config.Logging, categoryS
 			// Public routes
 			catRoutes.GET("", categoryHandler.GetAllCategories)
 			catRoutes.GET("/search", categoryHandler.GetCategoryByName)
+			catRoutes.GET("/:id", categoryHandler.GetCategory)
+			catRoutes.GET("/:id/attributes", categoryHandler.ListAttributes)
 
 			// Admin protected routes
 			protected := catRoutes.Group("")
@@ -29,6 +31,9 @@ func registerCategoryRoutes(router *gin.Engine, logger config.Logging, categoryS
 				protected.POST("", categoryHandler.CreateCategory)
 				protected.PUT("/:id", categoryHandler.UpdateCategory)
 				protected.DELETE("/:id", categoryHandler.DeleteCategory)
+				protected.POST("/:id/attributes", categoryHandler.CreateAttribute)
+				protected.PUT("/:id/attributes/:attributeId", categoryHandler.UpdateAttribute)
+				protected.DELETE("/:id/attributes/:attributeId", categoryHandler.DeleteAttribute)
 			}
 		}
 	}
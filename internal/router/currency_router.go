@@ -0,0 +1,23 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/hfleury/horsemarketplacebk/internal/middleware"
+)
+
+func registerCurrencyRoutes(router *gin.Engine, logger config.Logging, rateService *currency.RateService, tokenService *services.TokenService) {
+	handler := currency.NewRateHandler(logger, rateService)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
+
+	router.GET("/api/v1/exchange-rates", handler.List)
+
+	admin := router.Group("/api/v1/admin/exchange-rates")
+	admin.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.PUT("", handler.Update)
+		admin.POST("/import", handler.Import)
+	}
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/feeds"
+)
+
+func registerFeedRoutes(router *gin.Engine, logger config.Logging, feedService *feeds.FeedService) {
+	handler := feeds.NewFeedHandler(logger, feedService)
+
+	// Public, only published listings are ever included
+	router.GET("/feeds/products/:format", handler.Products)
+	router.GET("/feeds/categories/:id/:format", handler.Category)
+	router.GET("/sitemap.xml", handler.SitemapIndex)
+	router.GET("/sitemaps/:name", handler.Sitemap)
+}
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
+	"github.com/hfleury/horsemarketplacebk/internal/imports"
+	"github.com/hfleury/horsemarketplacebk/internal/middleware"
+)
+
+func registerImportRoutes(router *gin.Engine, logger config.Logging, importService *imports.ImportService, tokenService *services.TokenService) {
+	handler := imports.NewImportHandler(logger, importService)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
+
+	me := router.Group("/api/v1/me/imports")
+	me.Use(authMiddleware.RequireAuth())
+	{
+		me.POST("", handler.Submit)
+		me.GET("", handler.List)
+		me.GET("/:id", handler.Get)
+		me.POST("/:id/apply", handler.Apply)
+	}
+}
@@ -0,0 +1,26 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
+	"github.com/hfleury/horsemarketplacebk/internal/middleware"
+	"github.com/hfleury/horsemarketplacebk/internal/promotions"
+)
+
+func registerPromotionRoutes(router *gin.Engine, logger config.Logging, promotionService *promotions.PromotionService, tokenService *services.TokenService) {
+	handler := promotions.NewPromotionHandler(logger, promotionService)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
+
+	// Public, reported when a featured listing is opened
+	router.POST("/promotions/:id/click", authMiddleware.OptionalAuth(), handler.Click)
+
+	admin := router.Group("/api/v1/admin/promotions")
+	admin.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.POST("", handler.Create)
+		admin.GET("", handler.List)
+		admin.GET("/:id", handler.Get)
+		admin.DELETE("/:id", handler.Cancel)
+	}
+}
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
+	"github.com/hfleury/horsemarketplacebk/internal/middleware"
+	"github.com/hfleury/horsemarketplacebk/internal/reports"
+)
+
+func registerReportRoutes(router *gin.Engine, logger config.Logging, reportService *reports.ReportService, tokenService *services.TokenService) {
+	handler := reports.NewReportHandler(logger, reportService)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
+
+	router.POST("/products/:id/report", authMiddleware.RequireAuth(), handler.Report)
+
+	admin := router.Group("/api/v1/admin/reports")
+	admin.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("", handler.Queue)
+		admin.GET("/:productId", handler.Listing)
+		admin.POST("/:productId/resolve", handler.Resolve)
+	}
+}
@@ -3,38 +3,93 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/analytics"
 	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
 	categoryServices "github.com/hfleury/horsemarketplacebk/internal/categories/services"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/hfleury/horsemarketplacebk/internal/feeds"
+	"github.com/hfleury/horsemarketplacebk/internal/imports"
 	"github.com/hfleury/horsemarketplacebk/internal/media"
 	"github.com/hfleury/horsemarketplacebk/internal/middleware"
 	productHandlers "github.com/hfleury/horsemarketplacebk/internal/products/handlers"
 	productServices "github.com/hfleury/horsemarketplacebk/internal/products/services"
+	"github.com/hfleury/horsemarketplacebk/internal/promotions"
+	"github.com/hfleury/horsemarketplacebk/internal/reports"
+	savedSearchServices "github.com/hfleury/horsemarketplacebk/internal/savedsearches/services"
 )
 
-func SetupRouter(router *gin.Engine, logger config.Logging, userService *services.UserService, tokenService *services.TokenService, categoryService *categoryServices.CategoryService, mediaService *media.MediaService, productService productServices.ProductService, productHandler *productHandlers.ProductHandler) *gin.Engine {
+func SetupRouter(router *gin.Engine, logger config.Logging, userService *services.UserService, tokenService *services.TokenService, categoryService *categoryServices.CategoryService, mediaService *media.MediaService, productService productServices.ProductService, productHandler *productHandlers.ProductHandler, savedSearchService *savedSearchServices.SavedSearchService, statsService *analytics.StatsService, importService *imports.ImportService, feedService *feeds.FeedService, rateService *currency.RateService, reportService *reports.ReportService, promotionService *promotions.PromotionService) *gin.Engine {
 	router.Use(middleware.CORSMiddleware())
 	registerUserRoutes(router, logger, userService, tokenService)
 	registerCategoryRoutes(router, logger, categoryService, tokenService)
 	registerMediaRoutes(router, logger, mediaService, tokenService)
 	registerProductRoutes(router, logger, productHandler, tokenService)
+	registerSavedSearchRoutes(router, logger, savedSearchService, tokenService)
+	registerAnalyticsRoutes(router, logger, statsService, tokenService)
+	registerImportRoutes(router, logger, importService, tokenService)
+	registerFeedRoutes(router, logger, feedService)
+	registerCurrencyRoutes(router, logger, rateService, tokenService)
+	registerReportRoutes(router, logger, reportService, tokenService)
+	registerPromotionRoutes(router, logger, promotionService, tokenService)
 
 	return router
 }
 
 func registerProductRoutes(router *gin.Engine, logger config.Logging, handler *productHandlers.ProductHandler, tokenService *services.TokenService) {
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
 	products := router.Group("/products")
 	{
-		// Public
-		products.GET("", handler.List)
-		products.GET("/:id", handler.Get)
+		// Public, the viewer decides which listings are visible and is_favorited
+		products.GET("", authMiddleware.OptionalAuth(), handler.List)
+		products.GET("/:id", authMiddleware.OptionalAuth(), handler.Get)
+		products.GET("/:id/pedigree", authMiddleware.OptionalAuth(), handler.Pedigree)
+		products.GET("/:id/price-history", authMiddleware.OptionalAuth(), handler.PriceHistory)
+		products.GET("/:id/similar", authMiddleware.OptionalAuth(), handler.Similar)
+		products.POST("/breeding/compatibility", authMiddleware.OptionalAuth(), handler.BreedingCompatibility)
 
 		// Protected
-		authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
 		protected := products.Use(authMiddleware.RequireAuth())
 		{
 			protected.POST("", handler.Create)
+			protected.PUT("/:id", handler.Update)
 			protected.DELETE("/:id", handler.Delete)
 			protected.PATCH("/:id/status", handler.UpdateStatus)
+			protected.POST("/:id/renew", handler.Renew)
+			protected.POST("/:id/favorite", handler.AddFavorite)
+			protected.DELETE("/:id/favorite", handler.RemoveFavorite)
+			protected.POST("/:id/media", handler.AddMedia)
+			protected.PUT("/:id/media/order", handler.ReorderMedia)
+			protected.PUT("/:id/media/:mediaId/primary", handler.SetPrimaryMedia)
+			protected.DELETE("/:id/media/:mediaId", handler.RemoveMedia)
+			protected.GET("/:id/revisions", handler.ListRevisions)
+			protected.GET("/:id/revisions/diff", handler.DiffRevisions)
+			protected.GET("/:id/revisions/:version", handler.GetRevision)
+			protected.POST("/:id/revisions/:version/restore", handler.RestoreRevision)
+			protected.GET("/:id/autosave", handler.GetAutosave)
+			protected.PUT("/:id/autosave", handler.Autosave)
+			protected.DELETE("/:id/autosave", handler.DiscardAutosave)
 		}
 	}
+
+	// Public, the renew token from the reminder email is the credential
+	router.GET("/api/v1/products/renew", handler.ConfirmRenewByToken)
+	router.POST("/api/v1/products/renew", handler.RenewByToken)
+
+	admin := router.Group("/api/v1/admin/products")
+	admin.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"))
+	{
+		admin.GET("/identity", handler.SearchIdentity)
+		admin.POST("/:id/identity/verify", handler.VerifyIdentity)
+		admin.DELETE("/:id/identity/verify", handler.RevokeIdentity)
+		admin.GET("/duplicates", handler.ListDuplicateFlags)
+		admin.PUT("/duplicates/:flagId", handler.ResolveDuplicateFlag)
+	}
+
+	me := router.Group("/api/v1/me")
+	me.Use(authMiddleware.RequireAuth())
+	{
+		me.GET("/favorites", handler.ListFavorites)
+		me.GET("/products", handler.Dashboard)
+		me.POST("/products/bulk", handler.BulkAction)
+	}
 }
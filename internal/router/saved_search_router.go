@@ -0,0 +1,31 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/auth/services"
+	"github.com/hfleury/horsemarketplacebk/internal/middleware"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/handlers"
+	savedSearchServices "github.com/hfleury/horsemarketplacebk/internal/savedsearches/services"
+)
+
+func registerSavedSearchRoutes(router *gin.Engine, logger config.Logging, savedSearchService *savedSearchServices.SavedSearchService, tokenService *services.TokenService) {
+	handler := handlers.NewSavedSearchHandler(savedSearchService, logger)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, logger)
+
+	v1 := router.Group("/api/v1")
+	{
+		// Public, the unsubscribe token is the credential
+		v1.GET("/saved-searches/unsubscribe", handler.ConfirmUnsubscribe)
+		v1.POST("/saved-searches/unsubscribe", handler.Unsubscribe)
+
+		me := v1.Group("/me/saved-searches")
+		me.Use(authMiddleware.RequireAuth())
+		{
+			me.GET("", handler.List)
+			me.POST("", handler.Create)
+			me.PUT("/:id", handler.Update)
+			me.DELETE("/:id", handler.Delete)
+		}
+	}
+}
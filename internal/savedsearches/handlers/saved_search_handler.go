@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/common"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/models"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/services"
+)
+
+type SavedSearchHandler struct {
+	service *services.SavedSearchService
+	logger  config.Logging
+}
+
+func NewSavedSearchHandler(service *services.SavedSearchService, logger config.Logging) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *SavedSearchHandler) Create(c *gin.Context) {
+	var req models.CreateSavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid payload"))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	search, err := h.service.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		h.respondError(c, "Failed to create saved search", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(search))
+}
+
+func (h *SavedSearchHandler) List(c *gin.Context) {
+	searches, err := h.service.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.respondError(c, "Failed to list saved searches", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(searches))
+}
+
+func (h *SavedSearchHandler) Update(c *gin.Context) {
+	var req models.UpdateSavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Invalid payload"))
+		return
+	}
+
+	search, err := h.service.Update(c.Request.Context(), c.Param("id"), c.GetString("user_id"), req)
+	if err != nil {
+		h.respondError(c, "Failed to update saved search", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(search))
+}
+
+func (h *SavedSearchHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id"), c.GetString("user_id")); err != nil {
+		h.respondError(c, "Failed to delete saved search", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("Saved search deleted"))
+}
+
+// ConfirmUnsubscribe is the link from the alert emails. It only asks to
+// confirm, the unsubscribe is the POST of the page.
+func (h *SavedSearchHandler) ConfirmUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Token required"))
+		return
+	}
+
+	common.WriteConfirmation(c, "Unsubscribe", "Stop the emails for this saved search?", "Unsubscribe", token)
+}
+
+// Unsubscribe is public and authenticated by the token alone. The token
+// may also be left in the link's query, so mail clients can post to the
+// link itself as RFC 8058 one-click unsubscribe does.
+func (h *SavedSearchHandler) Unsubscribe(c *gin.Context) {
+	token := common.LinkToken(c)
+	if token == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("Token required"))
+		return
+	}
+
+	if err := h.service.Unsubscribe(c.Request.Context(), token); err != nil {
+		h.respondError(c, "Failed to unsubscribe", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse("You will no longer receive emails for this saved search"))
+}
+
+func (h *SavedSearchHandler) respondError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, services.ErrSavedSearchNotFound):
+		c.JSON(http.StatusNotFound, common.NewErrorResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidFrequency), errors.Is(err, services.ErrNameRequired), errors.Is(err, services.ErrUnknownLocation),
+		errors.Is(err, currency.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse(err.Error()))
+	default:
+		h.logger.Log(c.Request.Context(), config.ErrorLevel, msg, map[string]any{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse(msg))
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	productModels "github.com/hfleury/horsemarketplacebk/internal/products/models"
+)
+
+type Frequency string
+
+const (
+	FrequencyInstant Frequency = "instant"
+	FrequencyDaily   Frequency = "daily"
+	FrequencyOff     Frequency = "off"
+)
+
+func (f Frequency) IsValid() bool {
+	switch f {
+	case FrequencyInstant, FrequencyDaily, FrequencyOff:
+		return true
+	}
+	return false
+}
+
+type SavedSearch struct {
+	ID               uuid.UUID                    `json:"id"`
+	UserID           uuid.UUID                    `json:"user_id"`
+	Name             string                       `json:"name"`
+	Filters          productModels.ProductFilters `json:"filters"`
+	Frequency        Frequency                    `json:"frequency"`
+	UnsubscribeToken string                       `json:"-"`
+	LastNotifiedAt   *time.Time                   `json:"last_notified_at"`
+	CreatedAt        time.Time                    `json:"created_at"`
+	UpdatedAt        time.Time                    `json:"updated_at"`
+
+	// Owner's address, only loaded when sending notifications
+	UserEmail string `json:"-"`
+}
+
+// PendingMatch is a listing waiting to go out in a daily digest.
+type PendingMatch struct {
+	Search       *SavedSearch
+	ProductID    uuid.UUID
+	ProductTitle string
+	PriceSEK     *float64
+	// ReducedFromSEK is set when the match is a price drop
+	ReducedFromSEK *float64
+}
+
+type CreateSavedSearchRequest struct {
+	Name      string                       `json:"name" binding:"required"`
+	Filters   productModels.ProductFilters `json:"filters"`
+	Frequency Frequency                    `json:"frequency"`
+}
+
+type UpdateSavedSearchRequest struct {
+	Name      *string                       `json:"name"`
+	Filters   *productModels.ProductFilters `json:"filters"`
+	Frequency *Frequency                    `json:"frequency"`
+}
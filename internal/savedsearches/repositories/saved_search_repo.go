@@ -0,0 +1,277 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/db"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/models"
+	"github.com/lib/pq"
+)
+
+type SavedSearchRepository interface {
+	Create(ctx context.Context, search *models.SavedSearch) (*models.SavedSearch, error)
+	Update(ctx context.Context, search *models.SavedSearch) (*models.SavedSearch, error)
+	Delete(ctx context.Context, id string, userID string) (bool, error)
+	FindByID(ctx context.Context, id string) (*models.SavedSearch, error)
+	FindByUser(ctx context.Context, userID string) ([]*models.SavedSearch, error)
+	// FindActive returns every search with notifications on, with the owner's email.
+	FindActive(ctx context.Context) ([]*models.SavedSearch, error)
+	// DisableByToken turns notifications off for the search owning the unsubscribe token.
+	DisableByToken(ctx context.Context, token string) (bool, error)
+	// RecordMatch stores a match and reports whether it is yet to be
+	// notified, either new or left over by an alert that failed.
+	RecordMatch(ctx context.Context, searchID uuid.UUID, productID uuid.UUID) (bool, error)
+	// RecordPriceDrop stores a price drop of a matching listing as a pending
+	// match, whether or not the listing matched before. It reports whether
+	// the drop is yet to be notified, the same drop is only notified once.
+	RecordPriceDrop(ctx context.Context, searchID uuid.UUID, productID uuid.UUID, reducedFromSEK float64) (bool, error)
+	MarkNotified(ctx context.Context, searchID uuid.UUID, productIDs []uuid.UUID) error
+	// FindPendingDigestMatches returns un-notified matches of daily searches for published listings.
+	FindPendingDigestMatches(ctx context.Context) ([]*models.PendingMatch, error)
+}
+
+type SavedSearchRepoPsql struct {
+	logger config.Logging
+	psql   db.Database
+}
+
+func NewSavedSearchRepoPsql(psql db.Database, logger config.Logging) *SavedSearchRepoPsql {
+	return &SavedSearchRepoPsql{
+		psql:   psql,
+		logger: logger,
+	}
+}
+
+const savedSearchColumns = `s.id, s.user_id, s.name, s.filters, s.frequency, s.unsubscribe_token, s.last_notified_at, s.created_at, s.updated_at`
+
+func scanSavedSearch(row interface{ Scan(...any) error }, extra ...any) (*models.SavedSearch, error) {
+	var s models.SavedSearch
+	var filters []byte
+	dest := []any{&s.ID, &s.UserID, &s.Name, &filters, &s.Frequency, &s.UnsubscribeToken, &s.LastNotifiedAt, &s.CreatedAt, &s.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if len(filters) > 0 {
+		if err := json.Unmarshal(filters, &s.Filters); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+func (r *SavedSearchRepoPsql) Create(ctx context.Context, search *models.SavedSearch) (*models.SavedSearch, error) {
+	filters, err := json.Marshal(search.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO authentic.saved_searches AS s (id, user_id, name, filters, frequency, unsubscribe_token)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + savedSearchColumns
+
+	id := uuid.New()
+	if search.ID != uuid.Nil {
+		id = search.ID
+	}
+
+	created, err := scanSavedSearch(r.psql.QueryRow(ctx, query, id, search.UserID, search.Name, filters, search.Frequency, search.UnsubscribeToken))
+	if err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to create saved search", map[string]any{"error": err.Error()})
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *SavedSearchRepoPsql) Update(ctx context.Context, search *models.SavedSearch) (*models.SavedSearch, error) {
+	filters, err := json.Marshal(search.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE authentic.saved_searches AS s
+		SET name = $2, filters = $3, frequency = $4, updated_at = NOW()
+		WHERE s.id = $1
+		RETURNING ` + savedSearchColumns
+
+	updated, err := scanSavedSearch(r.psql.QueryRow(ctx, query, search.ID, search.Name, filters, search.Frequency))
+	if err != nil {
+		r.logger.Log(ctx, config.ErrorLevel, "Failed to update saved search", map[string]any{"error": err.Error()})
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *SavedSearchRepoPsql) Delete(ctx context.Context, id string, userID string) (bool, error) {
+	result, err := r.psql.Execute(ctx, `DELETE FROM authentic.saved_searches WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *SavedSearchRepoPsql) FindByID(ctx context.Context, id string) (*models.SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + ` FROM authentic.saved_searches s WHERE s.id = $1`
+	s, err := scanSavedSearch(r.psql.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *SavedSearchRepoPsql) FindByUser(ctx context.Context, userID string) ([]*models.SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + ` FROM authentic.saved_searches s WHERE s.user_id = $1 ORDER BY s.created_at DESC`
+	rows, err := r.psql.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []*models.SavedSearch{}
+	for rows.Next() {
+		s, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+func (r *SavedSearchRepoPsql) FindActive(ctx context.Context) ([]*models.SavedSearch, error) {
+	query := `
+		SELECT ` + savedSearchColumns + `, u.email
+		FROM authentic.saved_searches s
+		JOIN authentic.users u ON u.id = s.user_id
+		WHERE s.frequency <> 'off' AND u.is_active = TRUE
+	`
+	rows, err := r.psql.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var searches []*models.SavedSearch
+	for rows.Next() {
+		var email string
+		s, err := scanSavedSearch(rows, &email)
+		if err != nil {
+			return nil, err
+		}
+		s.UserEmail = email
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+func (r *SavedSearchRepoPsql) DisableByToken(ctx context.Context, token string) (bool, error) {
+	result, err := r.psql.Execute(ctx, `UPDATE authentic.saved_searches SET frequency = 'off', updated_at = NOW() WHERE unsubscribe_token = $1`, token)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *SavedSearchRepoPsql) RecordMatch(ctx context.Context, searchID uuid.UUID, productID uuid.UUID) (bool, error) {
+	query := `
+		INSERT INTO authentic.saved_search_matches (saved_search_id, product_id)
+		VALUES ($1, $2)
+		ON CONFLICT (saved_search_id, product_id) DO UPDATE SET matched_at = authentic.saved_search_matches.matched_at
+		WHERE authentic.saved_search_matches.notified_at IS NULL
+	`
+	result, err := r.psql.Execute(ctx, query, searchID, productID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *SavedSearchRepoPsql) RecordPriceDrop(ctx context.Context, searchID uuid.UUID, productID uuid.UUID, reducedFromSEK float64) (bool, error) {
+	query := `
+		INSERT INTO authentic.saved_search_matches (saved_search_id, product_id, reduced_from_sek)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (saved_search_id, product_id) DO UPDATE SET
+			reduced_from_sek = EXCLUDED.reduced_from_sek, matched_at = NOW(), notified_at = NULL
+		WHERE authentic.saved_search_matches.notified_at IS NULL
+			OR authentic.saved_search_matches.reduced_from_sek IS DISTINCT FROM EXCLUDED.reduced_from_sek
+	`
+	result, err := r.psql.Execute(ctx, query, searchID, productID, reducedFromSEK)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *SavedSearchRepoPsql) MarkNotified(ctx context.Context, searchID uuid.UUID, productIDs []uuid.UUID) error {
+	ids := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = id.String()
+	}
+
+	tx, err := r.psql.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE authentic.saved_search_matches SET notified_at = NOW()
+		WHERE saved_search_id = $1 AND product_id = ANY($2::uuid[])
+	`, searchID, pq.Array(ids)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE authentic.saved_searches SET last_notified_at = NOW() WHERE id = $1`, searchID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SavedSearchRepoPsql) FindPendingDigestMatches(ctx context.Context) ([]*models.PendingMatch, error) {
+	query := `
+		SELECT ` + savedSearchColumns + `, u.email, p.id, p.title, p.price_sek, m.reduced_from_sek
+		FROM authentic.saved_search_matches m
+		JOIN authentic.saved_searches s ON s.id = m.saved_search_id
+		JOIN authentic.users u ON u.id = s.user_id
+		JOIN authentic.products p ON p.id = m.product_id
+		WHERE m.notified_at IS NULL AND s.frequency = 'daily' AND p.status = 'published' AND u.is_active = TRUE
+		ORDER BY s.id, m.matched_at
+	`
+	rows, err := r.psql.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Share one *SavedSearch between the matches of the same search
+	searches := make(map[uuid.UUID]*models.SavedSearch)
+	var matches []*models.PendingMatch
+	for rows.Next() {
+		var m models.PendingMatch
+		var email string
+		s, err := scanSavedSearch(rows, &email, &m.ProductID, &m.ProductTitle, &m.PriceSEK, &m.ReducedFromSEK)
+		if err != nil {
+			return nil, err
+		}
+		if existing, ok := searches[s.ID]; ok {
+			s = existing
+		} else {
+			s.UserEmail = email
+			searches[s.ID] = s
+		}
+		m.Search = s
+		matches = append(matches, &m)
+	}
+	return matches, rows.Err()
+}
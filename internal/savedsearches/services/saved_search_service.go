@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	"github.com/hfleury/horsemarketplacebk/internal/currency"
+	"github.com/hfleury/horsemarketplacebk/internal/email"
+	"github.com/hfleury/horsemarketplacebk/internal/geo"
+	productModels "github.com/hfleury/horsemarketplacebk/internal/products/models"
+	productRepos "github.com/hfleury/horsemarketplacebk/internal/products/repositories"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/models"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/repositories"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+	"github.com/hibiken/asynq"
+)
+
+var (
+	ErrSavedSearchNotFound = errors.New("saved search not found")
+	ErrInvalidFrequency    = errors.New("frequency must be one of instant, daily or off")
+	ErrNameRequired        = errors.New("saved search name is required")
+	ErrUnknownLocation     = errors.New("unknown location")
+)
+
+type SavedSearchService struct {
+	repo        repositories.SavedSearchRepository
+	productRepo productRepos.ProductRepository
+	sender      email.Sender
+	logger      config.Logging
+	gazetteer   *geo.Gazetteer
+}
+
+func NewSavedSearchService(repo repositories.SavedSearchRepository, productRepo productRepos.ProductRepository, sender email.Sender, logger config.Logging) *SavedSearchService {
+	return &SavedSearchService{
+		repo:        repo,
+		productRepo: productRepo,
+		sender:      sender,
+		logger:      logger,
+	}
+}
+
+// SetGazetteer allows saving searches near a named place; the place is
+// resolved to coordinates once, when the search is saved.
+func (s *SavedSearchService) SetGazetteer(g *geo.Gazetteer) {
+	s.gazetteer = g
+}
+
+func (s *SavedSearchService) Create(ctx context.Context, userID string, req models.CreateSavedSearchRequest) (*models.SavedSearch, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	frequency := req.Frequency
+	if frequency == "" {
+		frequency = models.FrequencyInstant
+	}
+	if !frequency.IsValid() {
+		return nil, ErrInvalidFrequency
+	}
+
+	filters, err := s.normalizeFilters(req.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	search := &models.SavedSearch{
+		UserID:           uid,
+		Name:             name,
+		Filters:          filters,
+		Frequency:        frequency,
+		UnsubscribeToken: uuid.New().String(),
+	}
+	return s.repo.Create(ctx, search)
+}
+
+func (s *SavedSearchService) List(ctx context.Context, userID string) ([]*models.SavedSearch, error) {
+	return s.repo.FindByUser(ctx, userID)
+}
+
+func (s *SavedSearchService) Update(ctx context.Context, id string, userID string, req models.UpdateSavedSearchRequest) (*models.SavedSearch, error) {
+	existing, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Someone else's search is reported as missing rather than forbidden
+	if existing == nil || existing.UserID.String() != userID {
+		return nil, ErrSavedSearchNotFound
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrNameRequired
+		}
+		existing.Name = name
+	}
+	if req.Filters != nil {
+		filters, err := s.normalizeFilters(*req.Filters)
+		if err != nil {
+			return nil, err
+		}
+		existing.Filters = filters
+	}
+	if req.Frequency != nil {
+		if !req.Frequency.IsValid() {
+			return nil, ErrInvalidFrequency
+		}
+		existing.Frequency = *req.Frequency
+	}
+
+	return s.repo.Update(ctx, existing)
+}
+
+func (s *SavedSearchService) Delete(ctx context.Context, id string, userID string) error {
+	deleted, err := s.repo.Delete(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// Unsubscribe turns off notifications for the search the token belongs to.
+func (s *SavedSearchService) Unsubscribe(ctx context.Context, token string) error {
+	ok, err := s.repo.DisableByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// HandleProductPublishedTask matches a newly published listing against all
+// active saved searches. Instant searches are notified right away, daily
+// ones keep the match for the next digest. The task fails when an alert
+// could not be sent, so it is retried for the searches not yet notified.
+func (s *SavedSearchService) HandleProductPublishedTask(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.ProductPublishedPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	product, err := s.productRepo.FindByID(ctx, payload.ProductID)
+	if err != nil {
+		return fmt.Errorf("find product: %w", err)
+	}
+	if product == nil || product.Status != productModels.StatusPublished {
+		return nil
+	}
+
+	searches, err := s.matchingSearches(ctx, product)
+	if err != nil {
+		return err
+	}
+
+	unsent := 0
+	for _, search := range searches {
+		pending, err := s.repo.RecordMatch(ctx, search.ID, product.ID)
+		if err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to record saved search match", map[string]any{"error": err.Error(), "saved_search_id": search.ID})
+			continue
+		}
+		if !pending || search.Frequency != models.FrequencyInstant {
+			continue
+		}
+
+		subject := fmt.Sprintf("New listing matching \"%s\"", search.Name)
+		body := fmt.Sprintf("Hello,\n\nA new listing matches your saved search \"%s\":\n\n%s\n\n%s",
+			search.Name, listingLine(product.ID, product.Title, product.PriceSEK), unsubscribeFooter(search))
+		if !s.sendAlert(ctx, search, product.ID, subject, body) {
+			unsent++
+		}
+	}
+
+	if unsent > 0 {
+		return fmt.Errorf("%d saved search alerts not sent", unsent)
+	}
+	return nil
+}
+
+// HandlePriceDroppedTask stores a lowered price as a match of every saved
+// search the listing matches. Instant searches are notified right away,
+// daily ones see the drop in the next digest. Like new listings, the task
+// is retried for the alerts that could not be sent.
+func (s *SavedSearchService) HandlePriceDroppedTask(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.PriceDroppedPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	product, err := s.productRepo.FindByID(ctx, payload.ProductID)
+	if err != nil {
+		return fmt.Errorf("find product: %w", err)
+	}
+	// The price may have gone back up before the task ran
+	if product == nil || product.Status != productModels.StatusPublished || product.PriceSEK == nil || *product.PriceSEK >= payload.OldPriceSEK {
+		return nil
+	}
+
+	searches, err := s.matchingSearches(ctx, product)
+	if err != nil {
+		return err
+	}
+
+	unsent := 0
+	for _, search := range searches {
+		pending, err := s.repo.RecordPriceDrop(ctx, search.ID, product.ID, payload.OldPriceSEK)
+		if err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to record saved search price drop", map[string]any{"error": err.Error(), "saved_search_id": search.ID})
+			continue
+		}
+		if !pending || search.Frequency != models.FrequencyInstant {
+			continue
+		}
+
+		subject := fmt.Sprintf("Price drop on a listing matching \"%s\"", search.Name)
+		body := fmt.Sprintf("Hello,\n\nA listing matching your saved search \"%s\" is now cheaper:\n\n%s\n\n%s",
+			search.Name, matchLine(product.ID, product.Title, product.PriceSEK, &payload.OldPriceSEK), unsubscribeFooter(search))
+		if !s.sendAlert(ctx, search, product.ID, subject, body) {
+			unsent++
+		}
+	}
+
+	if unsent > 0 {
+		return fmt.Errorf("%d saved search price drop alerts not sent", unsent)
+	}
+	return nil
+}
+
+// matchingSearches returns the active saved searches the listing matches,
+// in one query whatever their number. Sellers don't need alerts about their
+// own listings.
+func (s *SavedSearchService) matchingSearches(ctx context.Context, product *productModels.Product) ([]*models.SavedSearch, error) {
+	active, err := s.repo.FindActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("find saved searches: %w", err)
+	}
+
+	var candidates []*models.SavedSearch
+	var filters []*productModels.ProductFilters
+	for _, search := range active {
+		if search.UserID == product.UserID {
+			continue
+		}
+		candidates = append(candidates, search)
+		filters = append(filters, &search.Filters)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	matches, err := s.productRepo.MatchFilters(ctx, product.ID.String(), filters)
+	if err != nil {
+		return nil, fmt.Errorf("match saved searches: %w", err)
+	}
+	var matching []*models.SavedSearch
+	for i, search := range candidates {
+		if matches[i] {
+			matching = append(matching, search)
+		}
+	}
+	return matching, nil
+}
+
+// sendAlert emails an instant alert and marks the match notified once it
+// is sent. It reports whether the alert was sent.
+func (s *SavedSearchService) sendAlert(ctx context.Context, search *models.SavedSearch, productID uuid.UUID, subject, body string) bool {
+	if err := s.sender.Send(ctx, search.UserEmail, subject, body); err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to send saved search alert", map[string]any{"error": err.Error(), "saved_search_id": search.ID})
+		return false
+	}
+	if err := s.repo.MarkNotified(ctx, search.ID, []uuid.UUID{productID}); err != nil {
+		s.logger.Log(ctx, config.ErrorLevel, "Failed to mark saved search notified", map[string]any{"error": err.Error(), "saved_search_id": search.ID})
+	}
+	return true
+}
+
+// HandleDailyDigestTask sends one email per daily saved search listing every
+// match collected since the previous digest.
+func (s *SavedSearchService) HandleDailyDigestTask(ctx context.Context, t *asynq.Task) error {
+	pending, err := s.repo.FindPendingDigestMatches(ctx)
+	if err != nil {
+		return fmt.Errorf("find pending matches: %w", err)
+	}
+
+	var order []uuid.UUID
+	grouped := make(map[uuid.UUID][]*models.PendingMatch)
+	for _, m := range pending {
+		if _, ok := grouped[m.Search.ID]; !ok {
+			order = append(order, m.Search.ID)
+		}
+		grouped[m.Search.ID] = append(grouped[m.Search.ID], m)
+	}
+
+	for _, searchID := range order {
+		matches := grouped[searchID]
+		search := matches[0].Search
+
+		lines := make([]string, 0, len(matches))
+		productIDs := make([]uuid.UUID, 0, len(matches))
+		what := "new listings"
+		for _, m := range matches {
+			lines = append(lines, matchLine(m.ProductID, m.ProductTitle, m.PriceSEK, m.ReducedFromSEK))
+			productIDs = append(productIDs, m.ProductID)
+			if m.ReducedFromSEK != nil {
+				what = "new and reduced listings"
+			}
+		}
+
+		subject := fmt.Sprintf("%d %s matching \"%s\"", len(matches), what, search.Name)
+		body := fmt.Sprintf("Hello,\n\nHere are today's %s for your saved search \"%s\":\n\n%s\n\n%s",
+			what, search.Name, strings.Join(lines, "\n"), unsubscribeFooter(search))
+		if err := s.sender.Send(ctx, search.UserEmail, subject, body); err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to send saved search digest", map[string]any{"error": err.Error(), "saved_search_id": searchID})
+			continue
+		}
+		if err := s.repo.MarkNotified(ctx, searchID, productIDs); err != nil {
+			s.logger.Log(ctx, config.ErrorLevel, "Failed to mark saved search notified", map[string]any{"error": err.Error(), "saved_search_id": searchID})
+		}
+	}
+
+	return nil
+}
+
+// normalizeFilters drops the parts of a search that don't make sense to
+// persist (pagination, status: alerts are only about published listings)
+// and resolves a named place to coordinates.
+func (s *SavedSearchService) normalizeFilters(f productModels.ProductFilters) (productModels.ProductFilters, error) {
+	f.Limit, f.Offset = 0, 0
+	f.Status = ""
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Currency != "" {
+		code, err := currency.Normalize(f.Currency)
+		if err != nil {
+			return f, err
+		}
+		f.Currency = code
+	}
+
+	if f.NearPlace != "" && !f.HasLocation() {
+		if s.gazetteer == nil {
+			return f, ErrUnknownLocation
+		}
+		place := s.gazetteer.Lookup(f.NearPlace)
+		if place == nil {
+			return f, ErrUnknownLocation
+		}
+		lat, lng := place.Lat, place.Lng
+		f.NearLat, f.NearLng = &lat, &lng
+	}
+	return f, nil
+}
+
+func listingLine(id uuid.UUID, title string, price *float64) string {
+	if price != nil {
+		return fmt.Sprintf("- %s (%.0f SEK): /products/%s", title, *price, id)
+	}
+	return fmt.Sprintf("- %s: /products/%s", title, id)
+}
+
+// matchLine is listingLine for a match that may be a price drop.
+func matchLine(id uuid.UUID, title string, price *float64, reducedFrom *float64) string {
+	if reducedFrom != nil && price != nil {
+		return fmt.Sprintf("- %s (reduced from %.0f to %.0f SEK): /products/%s", title, *reducedFrom, *price, id)
+	}
+	return listingLine(id, title, price)
+}
+
+func unsubscribeFooter(search *models.SavedSearch) string {
+	return fmt.Sprintf("To stop these emails, visit:\n/api/v1/saved-searches/unsubscribe?token=%s", search.UnsubscribeToken)
+}
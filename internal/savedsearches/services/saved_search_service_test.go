@@ -0,0 +1,168 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hfleury/horsemarketplacebk/config"
+	mockemail "github.com/hfleury/horsemarketplacebk/internal/mocks/email"
+	mockProducts "github.com/hfleury/horsemarketplacebk/internal/mocks/products"
+	mockSavedSearches "github.com/hfleury/horsemarketplacebk/internal/mocks/savedsearches"
+	productModels "github.com/hfleury/horsemarketplacebk/internal/products/models"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/models"
+	"github.com/hfleury/horsemarketplacebk/internal/savedsearches/services"
+	"github.com/hfleury/horsemarketplacebk/internal/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateSavedSearch_InvalidFrequency(t *testing.T) {
+	mockRepo := new(mockSavedSearches.MockSavedSearchRepo)
+	service := services.NewSavedSearchService(mockRepo, new(mockProducts.MockProductRepo), mockemail.NewMockSender(), config.NewZerologService())
+
+	_, err := service.Create(context.Background(), uuid.New().String(), models.CreateSavedSearchRequest{
+		Name:      "Mares",
+		Frequency: "hourly",
+	})
+
+	assert.Equal(t, services.ErrInvalidFrequency, err)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateSavedSearch_DropsPagination(t *testing.T) {
+	mockRepo := new(mockSavedSearches.MockSavedSearchRepo)
+	service := services.NewSavedSearchService(mockRepo, new(mockProducts.MockProductRepo), mockemail.NewMockSender(), config.NewZerologService())
+
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.SavedSearch) bool {
+		return s.Frequency == models.FrequencyInstant && s.Filters.Limit == 0 && s.Filters.Breed == "Friesian" && s.UnsubscribeToken != ""
+	})).Return(&models.SavedSearch{}, nil)
+
+	_, err := service.Create(context.Background(), uuid.New().String(), models.CreateSavedSearchRequest{
+		Name:    " Friesians ",
+		Filters: productModels.ProductFilters{Breed: "Friesian", Limit: 20},
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandleProductPublished_InstantAndDaily(t *testing.T) {
+	mockRepo := new(mockSavedSearches.MockSavedSearchRepo)
+	mockProductRepo := new(mockProducts.MockProductRepo)
+	sender := mockemail.NewMockSender()
+	service := services.NewSavedSearchService(mockRepo, mockProductRepo, sender, config.NewZerologService())
+
+	product := &productModels.Product{ID: uuid.New(), UserID: uuid.New(), Title: "Lovely mare", Status: productModels.StatusPublished}
+	instant := &models.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Name: "Mares", Frequency: models.FrequencyInstant, UserEmail: "instant@example.com", UnsubscribeToken: "tok"}
+	daily := &models.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Name: "Daily", Frequency: models.FrequencyDaily, UserEmail: "daily@example.com"}
+	own := &models.SavedSearch{ID: uuid.New(), UserID: product.UserID, Name: "Own", Frequency: models.FrequencyInstant}
+
+	mockProductRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockRepo.On("FindActive", mock.Anything).Return([]*models.SavedSearch{instant, daily, own}, nil)
+	mockProductRepo.On("MatchFilters", mock.Anything, product.ID.String(), []*productModels.ProductFilters{&instant.Filters, &daily.Filters}).Return([]bool{true, true}, nil)
+	mockRepo.On("RecordMatch", mock.Anything, instant.ID, product.ID).Return(true, nil)
+	mockRepo.On("RecordMatch", mock.Anything, daily.ID, product.ID).Return(true, nil)
+	mockRepo.On("MarkNotified", mock.Anything, instant.ID, []uuid.UUID{product.ID}).Return(nil)
+
+	task, _ := tasks.NewProductPublishedTask(product.ID.String())
+	err := service.HandleProductPublishedTask(context.Background(), task)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "instant@example.com", sender.LastTo)
+	assert.True(t, strings.Contains(sender.LastBody, "unsubscribe?token=tok"))
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "RecordMatch", mock.Anything, own.ID, mock.Anything)
+	mockRepo.AssertNotCalled(t, "MarkNotified", mock.Anything, daily.ID, mock.Anything)
+}
+
+func TestHandleDailyDigest_OneEmailPerSearch(t *testing.T) {
+	mockRepo := new(mockSavedSearches.MockSavedSearchRepo)
+	sender := mockemail.NewMockSender()
+	service := services.NewSavedSearchService(mockRepo, new(mockProducts.MockProductRepo), sender, config.NewZerologService())
+
+	search := &models.SavedSearch{ID: uuid.New(), Name: "Ponies", Frequency: models.FrequencyDaily, UserEmail: "daily@example.com"}
+	first, second := uuid.New(), uuid.New()
+	mockRepo.On("FindPendingDigestMatches", mock.Anything).Return([]*models.PendingMatch{
+		{Search: search, ProductID: first, ProductTitle: "Pony one"},
+		{Search: search, ProductID: second, ProductTitle: "Pony two"},
+	}, nil)
+	mockRepo.On("MarkNotified", mock.Anything, search.ID, []uuid.UUID{first, second}).Return(nil)
+
+	err := service.HandleDailyDigestTask(context.Background(), tasks.NewSavedSearchDigestTask())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "daily@example.com", sender.LastTo)
+	assert.True(t, strings.Contains(sender.LastBody, "Pony one"))
+	assert.True(t, strings.Contains(sender.LastBody, "Pony two"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandlePriceDropped_RecordsDropForMatchingSearches(t *testing.T) {
+	mockRepo := new(mockSavedSearches.MockSavedSearchRepo)
+	mockProductRepo := new(mockProducts.MockProductRepo)
+	sender := mockemail.NewMockSender()
+	service := services.NewSavedSearchService(mockRepo, mockProductRepo, sender, config.NewZerologService())
+
+	price := 60000.0
+	product := &productModels.Product{ID: uuid.New(), UserID: uuid.New(), Title: "Lovely mare", Status: productModels.StatusPublished, PriceSEK: &price}
+	instant := &models.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Name: "Mares", Frequency: models.FrequencyInstant, UserEmail: "instant@example.com"}
+	daily := &models.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Name: "Daily", Frequency: models.FrequencyDaily}
+
+	mockProductRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockRepo.On("FindActive", mock.Anything).Return([]*models.SavedSearch{instant, daily}, nil)
+	mockProductRepo.On("MatchFilters", mock.Anything, product.ID.String(), mock.Anything).Return([]bool{true, true}, nil)
+	mockRepo.On("RecordPriceDrop", mock.Anything, instant.ID, product.ID, 75000.0).Return(true, nil)
+	mockRepo.On("RecordPriceDrop", mock.Anything, daily.ID, product.ID, 75000.0).Return(true, nil)
+	mockRepo.On("MarkNotified", mock.Anything, instant.ID, []uuid.UUID{product.ID}).Return(nil)
+
+	task, _ := tasks.NewPriceDroppedTask(tasks.PriceDroppedPayload{ProductID: product.ID.String(), OldPriceSEK: 75000, PriceSEK: 60000})
+	err := service.HandlePriceDroppedTask(context.Background(), task)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "instant@example.com", sender.LastTo)
+	assert.True(t, strings.Contains(sender.LastBody, "reduced from 75000 to 60000 SEK"))
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "MarkNotified", mock.Anything, daily.ID, mock.Anything)
+
+	// Raised again before the task ran
+	price = 80000
+	sender.LastTo = ""
+	err = service.HandlePriceDroppedTask(context.Background(), task)
+	assert.NoError(t, err)
+	assert.Empty(t, sender.LastTo)
+	mockRepo.AssertNumberOfCalls(t, "FindActive", 1)
+}
+
+func TestHandleProductPublished_RetriesUnsentAlerts(t *testing.T) {
+	mockRepo := new(mockSavedSearches.MockSavedSearchRepo)
+	mockProductRepo := new(mockProducts.MockProductRepo)
+	sender := mockemail.NewMockSender()
+	service := services.NewSavedSearchService(mockRepo, mockProductRepo, sender, config.NewZerologService())
+
+	product := &productModels.Product{ID: uuid.New(), UserID: uuid.New(), Title: "Lovely mare", Status: productModels.StatusPublished}
+	matching := &models.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Name: "Mares", Frequency: models.FrequencyInstant, UserEmail: "instant@example.com"}
+	other := &models.SavedSearch{ID: uuid.New(), UserID: uuid.New(), Name: "Trailers", Frequency: models.FrequencyInstant}
+
+	mockProductRepo.On("FindByID", mock.Anything, product.ID.String()).Return(product, nil)
+	mockRepo.On("FindActive", mock.Anything).Return([]*models.SavedSearch{matching, other}, nil)
+	mockProductRepo.On("MatchFilters", mock.Anything, product.ID.String(), mock.Anything).Return([]bool{true, false}, nil)
+	mockRepo.On("RecordMatch", mock.Anything, matching.ID, product.ID).Return(true, nil)
+	task, _ := tasks.NewProductPublishedTask(product.ID.String())
+
+	sender.Err = errors.New("smtp down")
+	err := service.HandleProductPublishedTask(context.Background(), task)
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "MarkNotified", mock.Anything, mock.Anything, mock.Anything)
+
+	// The retry finds the match still pending
+	sender.Err = nil
+	mockRepo.On("MarkNotified", mock.Anything, matching.ID, []uuid.UUID{product.ID}).Return(nil)
+	err = service.HandleProductPublishedTask(context.Background(), task)
+	assert.NoError(t, err)
+	assert.Equal(t, "instant@example.com", sender.LastTo)
+	mockProductRepo.AssertNumberOfCalls(t, "MatchFilters", 2)
+	mockRepo.AssertNotCalled(t, "RecordMatch", mock.Anything, other.ID, mock.Anything)
+}
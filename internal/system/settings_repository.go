@@ -3,6 +3,7 @@ package system
 import (
 	"context"
 	"database/sql"
+	"strconv"
 
 	"github.com/hfleury/horsemarketplacebk/config"
 	"github.com/hfleury/horsemarketplacebk/internal/db"
@@ -12,8 +13,42 @@ type SettingsRepository interface {
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, value string) error
 	IsProductApprovalRequired(ctx context.Context) (bool, error)
+	// ListingDurationDays is how long a published listing stays up.
+	ListingDurationDays(ctx context.Context) (int, error)
+	// ExpiryReminderDays is how long before expiry the seller is reminded.
+	ExpiryReminderDays(ctx context.Context) (int, error)
+	// PedigreeMaxGenerations is how deep a horse pedigree may go, at most 10.
+	PedigreeMaxGenerations(ctx context.Context) (int, error)
+	// DuplicateListingPolicy is what happens to a listing published while it
+	// looks like a duplicate of another active one.
+	DuplicateListingPolicy(ctx context.Context) (string, error)
+	// DuplicateListingThreshold is the score, in percent, from which a
+	// listing counts as a duplicate.
+	DuplicateListingThreshold(ctx context.Context) (int, error)
+	// ReportHideThreshold is how many users must report a listing before it
+	// is hidden pending review.
+	ReportHideThreshold(ctx context.Context) (int, error)
 }
 
+// Values of the duplicate_listing_policy setting
+const (
+	DuplicatePolicyOff   = "off"   // publish as usual
+	DuplicatePolicyFlag  = "flag"  // hold for moderation with a duplicate flag
+	DuplicatePolicyBlock = "block" // refuse to publish
+)
+
+const (
+	defaultListingDurationDays = 60
+	defaultExpiryReminderDays  = 5
+	defaultPedigreeGenerations = 5
+	defaultDuplicateThreshold  = 80
+	defaultReportHideThreshold = 3
+
+	// maxPedigreeGenerations is the deepest lineage product_horse_ancestors
+	// can spell in its path column
+	maxPedigreeGenerations = 10
+)
+
 type SettingsRepoPsql struct {
 	logger config.Logging
 	psql   db.Database
@@ -56,3 +91,56 @@ func (r *SettingsRepoPsql) IsProductApprovalRequired(ctx context.Context) (bool,
 	}
 	return val == "true", nil
 }
+
+func (r *SettingsRepoPsql) ListingDurationDays(ctx context.Context) (int, error) {
+	return r.getPositiveInt(ctx, "listing_duration_days", defaultListingDurationDays)
+}
+
+func (r *SettingsRepoPsql) ExpiryReminderDays(ctx context.Context) (int, error) {
+	return r.getPositiveInt(ctx, "listing_expiry_reminder_days", defaultExpiryReminderDays)
+}
+
+func (r *SettingsRepoPsql) PedigreeMaxGenerations(ctx context.Context) (int, error) {
+	n, err := r.getPositiveInt(ctx, "pedigree_max_generations", defaultPedigreeGenerations)
+	if n > maxPedigreeGenerations {
+		n = maxPedigreeGenerations
+	}
+	return n, err
+}
+
+func (r *SettingsRepoPsql) DuplicateListingPolicy(ctx context.Context) (string, error) {
+	val, err := r.Get(ctx, "duplicate_listing_policy")
+	if err != nil {
+		return DuplicatePolicyFlag, err
+	}
+	switch val {
+	case DuplicatePolicyOff, DuplicatePolicyBlock:
+		return val, nil
+	}
+	return DuplicatePolicyFlag, nil
+}
+
+func (r *SettingsRepoPsql) DuplicateListingThreshold(ctx context.Context) (int, error) {
+	n, err := r.getPositiveInt(ctx, "duplicate_listing_threshold", defaultDuplicateThreshold)
+	if n > 100 {
+		n = 100
+	}
+	return n, err
+}
+
+func (r *SettingsRepoPsql) ReportHideThreshold(ctx context.Context) (int, error) {
+	return r.getPositiveInt(ctx, "report_hide_threshold", defaultReportHideThreshold)
+}
+
+// getPositiveInt falls back to def when the setting is missing or invalid.
+func (r *SettingsRepoPsql) getPositiveInt(ctx context.Context, key string, def int) (int, error) {
+	val, err := r.Get(ctx, key)
+	if err != nil {
+		return def, err
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		return def, nil
+	}
+	return n, nil
+}
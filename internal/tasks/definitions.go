@@ -7,9 +7,23 @@ import (
 )
 
 const (
-	TypeProcessImage = "image:process"
+	TypeProcessImage      = "image:process"
+	TypeProductPublished  = "product:published"
+	TypeSavedSearchDigest = "saved_search:digest"
+	TypeNotifyWatchers    = "product:notify_watchers"
+	TypeFlushProductStats = "analytics:flush"
+	TypeListingExpiry     = "product:expiry"
+	TypeListingImport     = "import:listings"
+	TypePriceDropped      = "product:price_dropped"
+	TypeExpirePromotions  = "promotion:expire"
+	TypeFlushPromotions   = "promotion:flush"
 )
 
+// Enqueuer is the subset of *asynq.Client services need to schedule work.
+type Enqueuer interface {
+	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
 type ProcessImagePayload struct {
 	MediaID string `json:"media_id"`
 }
@@ -21,3 +35,91 @@ func NewProcessImageTask(mediaID string) (*asynq.Task, error) {
 	}
 	return asynq.NewTask(TypeProcessImage, payload), nil
 }
+
+type ProductPublishedPayload struct {
+	ProductID string `json:"product_id"`
+}
+
+func NewProductPublishedTask(productID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(ProductPublishedPayload{ProductID: productID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeProductPublished, payload), nil
+}
+
+// NewSavedSearchDigestTask is registered with the scheduler to send the
+// daily saved search digests.
+func NewSavedSearchDigestTask() *asynq.Task {
+	return asynq.NewTask(TypeSavedSearchDigest, nil)
+}
+
+// NotifyWatchersPayload describes a change to a listing that users who
+// favorited it should hear about.
+type NotifyWatchersPayload struct {
+	ProductID string `json:"product_id"`
+	Field     string `json:"field"` // status or price
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+}
+
+func NewNotifyWatchersTask(p NotifyWatchersPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeNotifyWatchers, payload), nil
+}
+
+// NewFlushProductStatsTask is registered with the scheduler to move the
+// buffered view and click counters into Postgres.
+func NewFlushProductStatsTask() *asynq.Task {
+	return asynq.NewTask(TypeFlushProductStats, nil)
+}
+
+// NewListingExpiryTask is registered with the scheduler to archive expired
+// listings and send renewal reminders.
+func NewListingExpiryTask() *asynq.Task {
+	return asynq.NewTask(TypeListingExpiry, nil)
+}
+
+type ListingImportPayload struct {
+	ImportID string `json:"import_id"`
+}
+
+// NewListingImportTask processes an uploaded dealer feed.
+func NewListingImportTask(importID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(ListingImportPayload{ImportID: importID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeListingImport, payload), nil
+}
+
+// PriceDroppedPayload is a lowered price saved searches matching the
+// listing should hear about.
+type PriceDroppedPayload struct {
+	ProductID   string  `json:"product_id"`
+	OldPriceSEK float64 `json:"old_price_sek"`
+	PriceSEK    float64 `json:"price_sek"`
+}
+
+func NewPriceDroppedTask(p PriceDroppedPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypePriceDropped, payload), nil
+}
+
+// NewExpirePromotionsTask is registered with the scheduler to expire
+// promotions whose window has passed.
+func NewExpirePromotionsTask() *asynq.Task {
+	return asynq.NewTask(TypeExpirePromotions, nil)
+}
+
+// NewFlushPromotionsTask is registered with the scheduler to move the
+// buffered impression and click counters of promotions into Postgres.
+func NewFlushPromotionsTask() *asynq.Task {
+	return asynq.NewTask(TypeFlushPromotions, nil)
+}
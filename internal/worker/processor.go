@@ -105,6 +105,11 @@ func (p *Processor) HandleProcessImageTask(ctx context.Context, t *asynq.Task) e
 		return fmt.Errorf("update variants: %w", err)
 	}
 
+	// Used to spot listings reposted with the same photos
+	if err := p.repo.UpdatePerceptualHash(ctx, m.ID, media.PerceptualHash(img)); err != nil {
+		return fmt.Errorf("update perceptual hash: %w", err)
+	}
+
 	p.logger.Log(nil, config.InfoLevel, "Image processing completed", map[string]any{"media_id": payload.MediaID})
 	return nil
 }
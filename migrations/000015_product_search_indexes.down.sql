@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS authentic.idx_product_equipment_condition;
+DROP INDEX IF EXISTS authentic.idx_product_equipment_make;
+DROP INDEX IF EXISTS authentic.idx_product_vehicles_make;
+DROP INDEX IF EXISTS authentic.idx_product_horses_color;
+DROP INDEX IF EXISTS authentic.idx_product_horses_gender;
+DROP INDEX IF EXISTS authentic.idx_product_horses_breed;
+DROP INDEX IF EXISTS authentic.idx_products_city_lower;
+DROP INDEX IF EXISTS authentic.idx_products_price_sek;
@@ -0,0 +1,9 @@
+-- Indexes backing the product search filters and facet counts
+CREATE INDEX IF NOT EXISTS idx_products_price_sek ON authentic.products(price_sek);
+CREATE INDEX IF NOT EXISTS idx_products_city_lower ON authentic.products(LOWER(city));
+CREATE INDEX IF NOT EXISTS idx_product_horses_breed ON authentic.product_horses(breed);
+CREATE INDEX IF NOT EXISTS idx_product_horses_gender ON authentic.product_horses(gender);
+CREATE INDEX IF NOT EXISTS idx_product_horses_color ON authentic.product_horses(color);
+CREATE INDEX IF NOT EXISTS idx_product_vehicles_make ON authentic.product_vehicles(make);
+CREATE INDEX IF NOT EXISTS idx_product_equipment_make ON authentic.product_equipment(make);
+CREATE INDEX IF NOT EXISTS idx_product_equipment_condition ON authentic.product_equipment(condition);
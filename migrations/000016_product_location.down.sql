@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS authentic.idx_products_lat_lng;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS longitude;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS latitude;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS postal_code;
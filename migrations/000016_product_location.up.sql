@@ -0,0 +1,6 @@
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS postal_code VARCHAR(10);
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+-- Bounding box prefilter for radius searches
+CREATE INDEX IF NOT EXISTS idx_products_lat_lng ON authentic.products(latitude, longitude);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS authentic.saved_search_matches;
+DROP TABLE IF EXISTS authentic.saved_searches;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS authentic.saved_searches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES authentic.users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}',
+    frequency VARCHAR(20) NOT NULL DEFAULT 'instant', -- instant, daily, off
+    unsubscribe_token TEXT NOT NULL UNIQUE,
+    last_notified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_saved_searches_user_id ON authentic.saved_searches(user_id);
+CREATE INDEX idx_saved_searches_frequency ON authentic.saved_searches(frequency);
+
+-- Listings that matched a saved search. notified_at stays NULL until the
+-- match went out in an instant alert or a daily digest.
+CREATE TABLE IF NOT EXISTS authentic.saved_search_matches (
+    saved_search_id UUID NOT NULL REFERENCES authentic.saved_searches(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMPTZ,
+    PRIMARY KEY (saved_search_id, product_id)
+);
+
+CREATE INDEX idx_saved_search_matches_pending ON authentic.saved_search_matches(saved_search_id) WHERE notified_at IS NULL;
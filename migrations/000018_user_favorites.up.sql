@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS authentic.user_favorites (
+    user_id UUID NOT NULL REFERENCES authentic.users(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, product_id)
+);
+
+-- Favorite counts and watcher lookups go by product
+CREATE INDEX idx_user_favorites_product_id ON authentic.user_favorites(product_id);
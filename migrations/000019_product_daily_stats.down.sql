@@ -0,0 +1 @@
+DROP TABLE IF EXISTS authentic.product_daily_stats;
@@ -0,0 +1,9 @@
+-- Per listing and day counters, flushed in batches from the Redis buffer.
+-- Favorites per day come from user_favorites.created_at.
+CREATE TABLE IF NOT EXISTS authentic.product_daily_stats (
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views INTEGER NOT NULL DEFAULT 0,
+    contact_clicks INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, day)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS authentic.idx_product_media_primary;
+DROP INDEX IF EXISTS authentic.idx_product_media_order;
+DROP INDEX IF EXISTS authentic.idx_media_user_id;
+ALTER TABLE authentic.media DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,7 @@
+-- Only the uploader may attach a file to a listing
+ALTER TABLE authentic.media ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES authentic.users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_media_user_id ON authentic.media(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_product_media_order ON authentic.product_media(product_id, "order");
+-- At most one primary image per listing
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_media_primary ON authentic.product_media(product_id) WHERE is_primary;
@@ -0,0 +1,6 @@
+DELETE FROM authentic.system_settings WHERE key IN ('listing_duration_days', 'listing_expiry_reminder_days');
+DROP INDEX IF EXISTS authentic.idx_products_expires_at;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS renew_token;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS expiry_reminder_sent_at;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS published_at;
@@ -0,0 +1,19 @@
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ;
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS expiry_reminder_sent_at TIMESTAMPTZ;
+-- Credential of the one-click renew link sent with the reminder
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS renew_token TEXT UNIQUE;
+
+CREATE INDEX IF NOT EXISTS idx_products_expires_at ON authentic.products(expires_at) WHERE status = 'published';
+
+INSERT INTO authentic.system_settings (key, value, description)
+VALUES
+    ('listing_duration_days', '60', 'Number of days a published listing stays up before it is archived.'),
+    ('listing_expiry_reminder_days', '5', 'Number of days before expiry the seller is reminded to renew.')
+ON CONFLICT (key) DO NOTHING;
+
+-- Listings published before expiry existed get a full period from now
+UPDATE authentic.products
+SET published_at = COALESCE(published_at, updated_at),
+    expires_at = NOW() + INTERVAL '60 days'
+WHERE status = 'published' AND expires_at IS NULL;
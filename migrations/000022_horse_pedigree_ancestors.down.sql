@@ -0,0 +1,2 @@
+DELETE FROM authentic.system_settings WHERE key = 'pedigree_max_generations';
+DROP TABLE IF EXISTS authentic.product_horse_ancestors;
@@ -0,0 +1,17 @@
+-- Searchable copy of product_horses.pedigree, one row per known ancestor.
+-- path spells the lineage: s = sire, d = dam, ds = dam's sire, ...
+CREATE TABLE IF NOT EXISTS authentic.product_horse_ancestors (
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    path VARCHAR(10) NOT NULL,
+    generation SMALLINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    registration_number VARCHAR(50),
+    PRIMARY KEY (product_id, path)
+);
+
+CREATE INDEX idx_horse_ancestors_name ON authentic.product_horse_ancestors(LOWER(name), path);
+CREATE INDEX idx_horse_ancestors_registration ON authentic.product_horse_ancestors(registration_number) WHERE registration_number IS NOT NULL;
+
+INSERT INTO authentic.system_settings (key, value, description)
+VALUES ('pedigree_max_generations', '5', 'Number of generations a horse pedigree may contain.')
+ON CONFLICT (key) DO NOTHING;
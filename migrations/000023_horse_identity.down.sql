@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS authentic.idx_product_horses_microchip;
+DROP INDEX IF EXISTS authentic.idx_product_horses_ueln;
+
+ALTER TABLE authentic.product_horses
+    DROP COLUMN IF EXISTS identity_verified_by,
+    DROP COLUMN IF EXISTS identity_verified_at,
+    DROP COLUMN IF EXISTS passport_media_id,
+    DROP COLUMN IF EXISTS passport_issuer,
+    DROP COLUMN IF EXISTS microchip,
+    DROP COLUMN IF EXISTS ueln;
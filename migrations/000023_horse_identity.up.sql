@@ -0,0 +1,12 @@
+-- Registry identifiers of a horse, checked against the uploaded passport scan by an admin
+ALTER TABLE authentic.product_horses
+    ADD COLUMN IF NOT EXISTS ueln VARCHAR(15),
+    ADD COLUMN IF NOT EXISTS microchip VARCHAR(15),
+    ADD COLUMN IF NOT EXISTS passport_issuer VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS passport_media_id UUID REFERENCES authentic.media(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS identity_verified_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS identity_verified_by UUID REFERENCES authentic.users(id) ON DELETE SET NULL;
+
+-- text_pattern_ops serves the prefix searches of the admin lookup
+CREATE INDEX IF NOT EXISTS idx_product_horses_ueln ON authentic.product_horses(ueln text_pattern_ops) WHERE ueln IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_product_horses_microchip ON authentic.product_horses(microchip text_pattern_ops) WHERE microchip IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS authentic.product_services;
+DROP TABLE IF EXISTS authentic.product_properties;
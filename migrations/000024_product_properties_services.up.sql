@@ -0,0 +1,25 @@
+-- Product Properties (Stables, farms, land)
+CREATE TABLE IF NOT EXISTS authentic.product_properties (
+    product_id UUID PRIMARY KEY REFERENCES authentic.products(id) ON DELETE CASCADE,
+    land_area_ha DECIMAL(10, 2),
+    stall_count INT,
+    paddock_count INT,
+    arena_width_m INT,
+    arena_length_m INT,
+    living_space_m2 INT
+);
+
+-- Product Services (Farriers, transport, vets, training)
+CREATE TABLE IF NOT EXISTS authentic.product_services (
+    product_id UUID PRIMARY KEY REFERENCES authentic.products(id) ON DELETE CASCADE,
+    service_kind VARCHAR(50),
+    coverage_areas TEXT[], -- Cities or regions served
+    coverage_radius_km INT,
+    pricing_unit VARCHAR(20), -- hour, day, visit, km, fixed, ...
+    available_days TEXT[], -- mon .. sun
+    availability_note TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_properties_stall_count ON authentic.product_properties(stall_count);
+CREATE INDEX IF NOT EXISTS idx_product_properties_land_area ON authentic.product_properties(land_area_ha);
+CREATE INDEX IF NOT EXISTS idx_product_services_kind ON authentic.product_services(service_kind);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS authentic.product_attributes;
+DROP TABLE IF EXISTS authentic.category_attributes;
@@ -0,0 +1,34 @@
+-- Typed attributes admins define per category. Subcategories inherit the
+-- attributes of their parents.
+CREATE TABLE IF NOT EXISTS authentic.category_attributes (
+    id UUID PRIMARY KEY,
+    category_id UUID NOT NULL REFERENCES authentic.categories(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    label VARCHAR(100) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('enum', 'integer', 'text', 'boolean')),
+    unit VARCHAR(20),
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    allowed_values TEXT[],
+    min_value BIGINT,
+    max_value BIGINT,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (category_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_attributes_key ON authentic.category_attributes(key);
+
+-- One value per listing and attribute, in the column matching the attribute type
+CREATE TABLE IF NOT EXISTS authentic.product_attributes (
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    attribute_id UUID NOT NULL REFERENCES authentic.category_attributes(id) ON DELETE CASCADE,
+    value_text TEXT,
+    value_int BIGINT,
+    value_bool BOOLEAN,
+    PRIMARY KEY (product_id, attribute_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_attributes_text ON authentic.product_attributes(attribute_id, LOWER(value_text)) WHERE value_text IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_product_attributes_int ON authentic.product_attributes(attribute_id, value_int) WHERE value_int IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_product_attributes_bool ON authentic.product_attributes(attribute_id, value_bool) WHERE value_bool IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS authentic.import_media;
+DROP TABLE IF EXISTS authentic.listing_imports;
+DROP INDEX IF EXISTS authentic.idx_products_external_id;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,38 @@
+-- Dealer supplied id of a listing imported from a feed, re-imports update the listing
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS external_id VARCHAR(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_external_id ON authentic.products(user_id, external_id)
+    WHERE external_id IS NOT NULL AND status <> 'deleted';
+
+-- Uploaded feeds, processed in the background. The feed is kept until it has
+-- been applied so a dry run can be applied without uploading it again.
+CREATE TABLE IF NOT EXISTS authentic.listing_imports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES authentic.users(id) ON DELETE CASCADE,
+    format VARCHAR(5) NOT NULL CHECK (format IN ('csv', 'xml')),
+    status VARCHAR(10) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    mapping JSONB NOT NULL DEFAULT '{}',
+    source BYTEA,
+    archive BYTEA,
+    total_rows INT NOT NULL DEFAULT 0,
+    created_count INT NOT NULL DEFAULT 0,
+    updated_count INT NOT NULL DEFAULT 0,
+    failed_count INT NOT NULL DEFAULT 0,
+    row_errors JSONB NOT NULL DEFAULT '[]',
+    failure TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_listing_imports_user ON authentic.listing_imports(user_id, created_at DESC);
+
+-- Images fetched or unpacked by imports, so importing a feed again reuses them
+CREATE TABLE IF NOT EXISTS authentic.import_media (
+    user_id UUID NOT NULL REFERENCES authentic.users(id) ON DELETE CASCADE,
+    source TEXT NOT NULL, -- image URL, or sha256: and the hash of an archive file
+    media_id UUID NOT NULL REFERENCES authentic.media(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, source)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE authentic.saved_search_matches DROP COLUMN IF EXISTS reduced_from_sek;
+DROP INDEX IF EXISTS authentic.idx_products_price_reduced_at;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS price_reduced_at;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS reduced_from_sek;
+DROP TABLE IF EXISTS authentic.product_price_history;
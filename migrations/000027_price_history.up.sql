@@ -0,0 +1,21 @@
+-- Every price a listing has had. old_price_sek is NULL for the price the
+-- listing was created with.
+CREATE TABLE IF NOT EXISTS authentic.product_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    old_price_sek DECIMAL(12, 2),
+    price_sek DECIMAL(12, 2),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_history_product ON authentic.product_price_history(product_id, changed_at);
+
+-- The price before the latest reduction, kept while the listing shows the
+-- "reduced" badge. Successive reductions keep the original price.
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS reduced_from_sek DECIMAL(12, 2);
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS price_reduced_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_products_price_reduced_at ON authentic.products(price_reduced_at) WHERE price_reduced_at IS NOT NULL;
+
+-- A saved search match can be a price drop of a listing that matched before
+ALTER TABLE authentic.saved_search_matches ADD COLUMN IF NOT EXISTS reduced_from_sek DECIMAL(12, 2);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS authentic.exchange_rates;
@@ -0,0 +1,8 @@
+-- How many SEK one unit of a currency is worth. Prices are stored in SEK and
+-- converted for display and for price filters in another currency.
+CREATE TABLE IF NOT EXISTS authentic.exchange_rates (
+    currency CHAR(3) PRIMARY KEY,
+    sek_per_unit DECIMAL(14, 6) NOT NULL CHECK (sek_per_unit > 0),
+    updated_by UUID REFERENCES authentic.users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
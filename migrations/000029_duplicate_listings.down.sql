@@ -0,0 +1,3 @@
+DELETE FROM authentic.system_settings WHERE key IN ('duplicate_listing_policy', 'duplicate_listing_threshold');
+DROP TABLE IF EXISTS authentic.product_duplicate_flags;
+ALTER TABLE authentic.media DROP COLUMN IF EXISTS phash;
@@ -0,0 +1,29 @@
+-- Trigram similarity finds listings with a similar title from any seller
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Perceptual hash (dHash) of an image, computed by the image worker. Close
+-- hashes mean visually similar images.
+ALTER TABLE authentic.media ADD COLUMN IF NOT EXISTS phash BIGINT;
+
+-- Listings held for moderation because they look like another active listing
+CREATE TABLE IF NOT EXISTS authentic.product_duplicate_flags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    duplicate_of UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    score DECIMAL(4, 3) NOT NULL,
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    same_seller BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'confirmed', 'dismissed')),
+    resolved_by UUID REFERENCES authentic.users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, duplicate_of)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_duplicate_flags_open ON authentic.product_duplicate_flags(created_at) WHERE status = 'open';
+
+INSERT INTO authentic.system_settings (key, value, description)
+VALUES
+    ('duplicate_listing_policy', 'flag', 'What happens when a published listing looks like another active listing: off, flag for moderation or block.'),
+    ('duplicate_listing_threshold', '80', 'Similarity score in percent from which a listing counts as a duplicate.')
+ON CONFLICT (key) DO NOTHING;
@@ -0,0 +1,3 @@
+DELETE FROM authentic.system_settings WHERE key = 'report_hide_threshold';
+DROP TABLE IF EXISTS authentic.listing_reports;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS hidden_at;
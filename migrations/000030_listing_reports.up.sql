@@ -0,0 +1,25 @@
+-- Set while a listing is hidden because of abuse reports or taken down by
+-- staff. The seller cannot publish it again without an admin.
+ALTER TABLE authentic.products ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS authentic.listing_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES authentic.users(id) ON DELETE CASCADE,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('scam', 'animal_welfare', 'miscategorised', 'prohibited', 'offensive', 'other')),
+    details TEXT,
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    resolution VARCHAR(10) CHECK (resolution IN ('dismiss', 'warn', 'take_down')),
+    resolution_note TEXT,
+    resolved_by UUID REFERENCES authentic.users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A user has at most one open report per listing
+CREATE UNIQUE INDEX IF NOT EXISTS idx_listing_reports_open ON authentic.listing_reports(product_id, reporter_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_listing_reports_reporter ON authentic.listing_reports(reporter_id, created_at);
+
+INSERT INTO authentic.system_settings (key, value, description)
+VALUES ('report_hide_threshold', '3', 'Number of users reporting a listing before it is hidden until an admin has looked at it.')
+ON CONFLICT (key) DO NOTHING;
@@ -0,0 +1,22 @@
+-- A promotion features a listing on the front page or at the top of its
+-- category for a time window. Expired promotions are kept for their counters.
+CREATE TABLE IF NOT EXISTS authentic.promotions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    placement VARCHAR(20) NOT NULL CHECK (placement IN ('front_page', 'category')),
+    category_id UUID REFERENCES authentic.categories(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'expired', 'cancelled')),
+    source VARCHAR(10) NOT NULL DEFAULT 'admin' CHECK (source IN ('admin', 'payment')),
+    created_by UUID REFERENCES authentic.users(id) ON DELETE SET NULL,
+    impressions BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at),
+    CHECK ((placement = 'category') = (category_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotions_active ON authentic.promotions(product_id, placement) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_promotions_ends_at ON authentic.promotions(ends_at) WHERE status = 'active';
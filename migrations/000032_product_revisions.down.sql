@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS authentic.product_autosaves;
+DROP TABLE IF EXISTS authentic.product_revisions;
@@ -0,0 +1,23 @@
+-- Every saved version of a listing's editable content. Version 1 is the
+-- listing as created, or as it was before its first edit for older listings.
+CREATE TABLE IF NOT EXISTS authentic.product_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    content JSONB NOT NULL,
+    changed_fields TEXT[] NOT NULL DEFAULT '{}',
+    edited_by UUID REFERENCES authentic.users(id) ON DELETE SET NULL,
+    restored_from INT,
+    requires_approval BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, version)
+);
+
+-- The edit a seller has in progress, saved as they type. It is not
+-- validated and is dropped once the edit is saved.
+CREATE TABLE IF NOT EXISTS authentic.product_autosaves (
+    product_id UUID PRIMARY KEY REFERENCES authentic.products(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES authentic.users(id) ON DELETE CASCADE,
+    content JSONB NOT NULL,
+    saved_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
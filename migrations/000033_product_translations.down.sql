@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS authentic.product_translations;
+DROP INDEX IF EXISTS authentic.idx_products_search_vector;
+ALTER TABLE authentic.products DROP COLUMN IF EXISTS search_vector, DROP COLUMN IF EXISTS language;
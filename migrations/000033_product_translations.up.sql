@@ -0,0 +1,25 @@
+-- Listings are written in the seller's language and may carry the title and
+-- description in the other languages. Every version is indexed for full-text
+-- search with the text search configuration of its language.
+ALTER TABLE authentic.products
+    ADD COLUMN IF NOT EXISTS language VARCHAR(2) NOT NULL DEFAULT 'sv' CHECK (language IN ('sv', 'en')),
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector(CASE language WHEN 'en' THEN 'english'::regconfig ELSE 'swedish'::regconfig END,
+                    title || ' ' || COALESCE(description, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON authentic.products USING GIN (search_vector);
+
+CREATE TABLE IF NOT EXISTS authentic.product_translations (
+    product_id UUID NOT NULL REFERENCES authentic.products(id) ON DELETE CASCADE,
+    language VARCHAR(2) NOT NULL CHECK (language IN ('sv', 'en')),
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector(CASE language WHEN 'en' THEN 'english'::regconfig ELSE 'swedish'::regconfig END,
+                    title || ' ' || COALESCE(description, ''))
+    ) STORED,
+    PRIMARY KEY (product_id, language)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_translations_search_vector ON authentic.product_translations USING GIN (search_vector);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS authentic.analytics_flushed_batches;
@@ -0,0 +1,6 @@
+-- Redis batches already stored, so a batch left behind by a flush that
+-- crashed after committing is not counted twice when it is picked up again.
+CREATE TABLE IF NOT EXISTS authentic.analytics_flushed_batches (
+    batch TEXT PRIMARY KEY,
+    flushed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
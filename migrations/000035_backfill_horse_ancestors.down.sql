@@ -0,0 +1,2 @@
+-- The backfilled rows can't be told apart from the ones written since, they
+-- are left in place.
@@ -0,0 +1,25 @@
+-- Fills product_horse_ancestors for horses listed before it existed, from
+-- their stored pedigree. Names and registration numbers are normalized like
+-- the application does; ancestors without a name are skipped, and nothing
+-- deeper than the 10 generations path can spell is copied.
+WITH RECURSIVE tree (product_id, path, horse) AS (
+    SELECT h.product_id, c.path, h.pedigree -> c.key
+    FROM authentic.product_horses h
+    CROSS JOIN (VALUES ('s', 'sire'), ('d', 'dam')) AS c (path, key)
+    WHERE jsonb_typeof(h.pedigree) = 'object'
+      AND NOT EXISTS (SELECT 1 FROM authentic.product_horse_ancestors a WHERE a.product_id = h.product_id)
+    UNION ALL
+    SELECT t.product_id, t.path || c.path, t.horse -> c.key
+    FROM tree t
+    CROSS JOIN (VALUES ('s', 'sire'), ('d', 'dam')) AS c (path, key)
+    WHERE jsonb_typeof(t.horse) = 'object' AND length(t.path) < 10
+)
+INSERT INTO authentic.product_horse_ancestors (product_id, path, generation, name, registration_number)
+SELECT product_id, path, length(path),
+       left(regexp_replace(btrim(horse ->> 'name'), '\s+', ' ', 'g'), 100),
+       NULLIF(left(upper(replace(btrim(horse ->> 'registration_number'), ' ', '')), 50), '')
+FROM tree
+WHERE jsonb_typeof(horse) = 'object'
+  AND jsonb_typeof(horse -> 'name') = 'string'
+  AND btrim(horse ->> 'name') <> ''
+ON CONFLICT (product_id, path) DO NOTHING;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS trg_products_horse_listing_active ON authentic.products;
+DROP TRIGGER IF EXISTS trg_product_horses_listing_active ON authentic.product_horses;
+DROP FUNCTION IF EXISTS authentic.products_sync_horse_listing_active();
+DROP FUNCTION IF EXISTS authentic.product_horses_set_listing_active();
+DROP INDEX IF EXISTS authentic.idx_product_horses_active_microchip;
+DROP INDEX IF EXISTS authentic.idx_product_horses_active_ueln;
+ALTER TABLE authentic.product_horses DROP COLUMN IF EXISTS listing_active;
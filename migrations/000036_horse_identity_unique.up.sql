@@ -0,0 +1,58 @@
+-- Enforces that a UELN or microchip appears in at most one active listing.
+-- listing_active mirrors whether the product is a draft, pending approval or
+-- published so the unique indexes can be partial on it; triggers keep it in
+-- sync with the product status.
+ALTER TABLE authentic.product_horses
+    ADD COLUMN IF NOT EXISTS listing_active BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Listings that were already duplicated before the constraint existed keep
+-- their status, but only the oldest active one claims the identifiers. The
+-- others fail with a duplicate error the next time they are saved.
+WITH ranked AS (
+    SELECT h.product_id,
+           CASE WHEN h.ueln IS NULL THEN 1
+                ELSE row_number() OVER (PARTITION BY h.ueln ORDER BY p.created_at, p.id) END AS ueln_rank,
+           CASE WHEN h.microchip IS NULL THEN 1
+                ELSE row_number() OVER (PARTITION BY h.microchip ORDER BY p.created_at, p.id) END AS microchip_rank
+    FROM authentic.product_horses h
+    JOIN authentic.products p ON p.id = h.product_id
+    WHERE p.status IN ('draft', 'pending_approval', 'published')
+)
+UPDATE authentic.product_horses h
+SET listing_active = TRUE
+FROM ranked r
+WHERE r.product_id = h.product_id AND r.ueln_rank = 1 AND r.microchip_rank = 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_horses_active_ueln
+    ON authentic.product_horses(ueln) WHERE listing_active AND ueln IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_horses_active_microchip
+    ON authentic.product_horses(microchip) WHERE listing_active AND microchip IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION authentic.product_horses_set_listing_active() RETURNS trigger AS $$
+BEGIN
+    SELECT p.status IN ('draft', 'pending_approval', 'published') INTO NEW.listing_active
+    FROM authentic.products p WHERE p.id = NEW.product_id;
+    NEW.listing_active := COALESCE(NEW.listing_active, FALSE);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION authentic.products_sync_horse_listing_active() RETURNS trigger AS $$
+BEGIN
+    IF (OLD.status IN ('draft', 'pending_approval', 'published')) IS DISTINCT FROM
+       (NEW.status IN ('draft', 'pending_approval', 'published')) THEN
+        UPDATE authentic.product_horses SET listing_active = listing_active WHERE product_id = NEW.id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_product_horses_listing_active ON authentic.product_horses;
+CREATE TRIGGER trg_product_horses_listing_active
+    BEFORE INSERT OR UPDATE ON authentic.product_horses
+    FOR EACH ROW EXECUTE FUNCTION authentic.product_horses_set_listing_active();
+
+DROP TRIGGER IF EXISTS trg_products_horse_listing_active ON authentic.products;
+CREATE TRIGGER trg_products_horse_listing_active
+    AFTER UPDATE OF status ON authentic.products
+    FOR EACH ROW EXECUTE FUNCTION authentic.products_sync_horse_listing_active();
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS authentic.idx_products_listed_title_trgm;
@@ -0,0 +1,5 @@
+-- Serves the similar title lookup of the duplicate check, which otherwise
+-- compares the title of every listing
+CREATE INDEX IF NOT EXISTS idx_products_listed_title_trgm
+    ON authentic.products USING gin (lower(title) gin_trgm_ops)
+    WHERE status IN ('pending_approval', 'published');
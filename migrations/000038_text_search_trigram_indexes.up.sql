@@ -0,0 +1,8 @@
+-- Serve the substring match of the text search, which otherwise reads the
+-- title and description of every listing. Not partial: admins search
+-- listings in any status.
+CREATE INDEX IF NOT EXISTS idx_products_title_trgm
+    ON authentic.products USING gin (title gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_products_description_trgm
+    ON authentic.products USING gin (description gin_trgm_ops);